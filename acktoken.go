@@ -0,0 +1,90 @@
+package sqs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAckTokenExpired is returned when acting on an AckToken whose message
+// may already have been redelivered.
+var ErrAckTokenExpired = errors.New("sqs: ack token expired")
+
+// An AckToken holds what is needed to delete or extend a received message
+// later, possibly from another process or service than the one that
+// received it.
+type AckToken struct {
+	QueueURL      string    `json:"queue_url"`
+	ReceiptHandle string    `json:"receipt_handle"`
+	Expires       time.Time `json:"expires"`
+}
+
+// AckToken returns a token for m, received from q with the given
+// visibility timeout, in seconds.
+func (q *Queue) AckToken(m *Message, visibilityTimeout int) *AckToken {
+	return &AckToken{
+		QueueURL:      q.URL(),
+		ReceiptHandle: m.ReceiptHandle,
+		Expires:       time.Now().Add(time.Duration(visibilityTimeout) * time.Second).UTC(),
+	}
+}
+
+// String returns the token encoded for transport, as accepted by
+// ParseAckToken.
+func (t *AckToken) String() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseAckToken decodes a token encoded by AckToken.String.
+func ParseAckToken(s string) (*AckToken, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("sqs: invalid ack token: %s", err)
+	}
+	var t AckToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("sqs: invalid ack token: %s", err)
+	}
+	if t.QueueURL == "" || t.ReceiptHandle == "" {
+		return nil, errors.New("sqs: invalid ack token: missing queue URL or receipt handle")
+	}
+	return &t, nil
+}
+
+func (sqs *SQS) ackTarget(t *AckToken) (*Queue, *Message, error) {
+	if time.Now().After(t.Expires) {
+		return nil, nil, ErrAckTokenExpired
+	}
+	q, err := sqs.queueFromUrl(t.QueueURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return q, &Message{ReceiptHandle: t.ReceiptHandle}, nil
+}
+
+// Ack deletes the message of t.
+func (sqs *SQS) Ack(t *AckToken) error {
+	q, m, err := sqs.ackTarget(t)
+	if err != nil {
+		return err
+	}
+	return q.DeleteMessage(m)
+}
+
+// Extend sets the visibility timeout of the message of t, in seconds from
+// now, and moves the expiry of t accordingly. A timeout of zero releases
+// the message for redelivery.
+func (sqs *SQS) Extend(t *AckToken, visibilityTimeout int) error {
+	q, m, err := sqs.ackTarget(t)
+	if err != nil {
+		return err
+	}
+	if err := q.ChangeMessageVisibility(m, visibilityTimeout); err != nil {
+		return err
+	}
+	t.Expires = time.Now().Add(time.Duration(visibilityTimeout) * time.Second).UTC()
+	return nil
+}
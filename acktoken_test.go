@@ -0,0 +1,40 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestAckToken(c *C) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path+" "+r.FormValue("Action")+" "+r.FormValue("ReceiptHandle"))
+		fmt.Fprint(w, "<Response/>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	tok := q.AckToken(&Message{ReceiptHandle: "rh+/="}, 30)
+	t, err := ParseAckToken(tok.String())
+	c.Assert(err, IsNil)
+	c.Assert(t.QueueURL, Equals, srv.URL+"/123/q")
+	c.Assert(t.ReceiptHandle, Equals, "rh+/=")
+
+	c.Assert(sqs.Extend(t, 60), IsNil)
+	c.Assert(sqs.Ack(t), IsNil)
+	c.Assert(calls, DeepEquals, []string{
+		"/123/q ChangeMessageVisibility rh+/=",
+		"/123/q DeleteMessage rh+/=",
+	})
+
+	expired := q.AckToken(&Message{ReceiptHandle: "rh"}, -1)
+	c.Assert(sqs.Ack(expired), Equals, ErrAckTokenExpired)
+
+	_, err = ParseAckToken("not a token")
+	c.Assert(err, ErrorMatches, "sqs: invalid ack token: .*")
+}
@@ -0,0 +1,96 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AnnotationsAttribute is the message attribute holding the annotations of
+// a message, as a JSON array.
+const AnnotationsAttribute = "annotations"
+
+// MaxAnnotations is the number of annotations kept on a message; older ones
+// are dropped first.
+const MaxAnnotations = 10
+
+// An Annotation is an operator note recorded on a message when it is moved
+// or replayed by hand, such as during a redrive.
+type Annotation struct {
+	Action string    `json:"action"` // e.g. "redrive" or "replay"
+	By     string    `json:"by,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	Ticket string    `json:"ticket,omitempty"`
+	Source string    `json:"source,omitempty"` // name of the queue the message was moved from
+	At     time.Time `json:"at"`
+}
+
+// Annotations returns the annotations of m, oldest first. m must have been
+// received with its AnnotationsAttribute, e.g. with MessageAttributeNames
+// set to "All".
+func Annotations(m *Message) ([]Annotation, error) {
+	v, ok := m.MessageAttributes[AnnotationsAttribute]
+	if !ok {
+		return nil, nil
+	}
+	var as []Annotation
+	if err := json.Unmarshal([]byte(v.StringValue), &as); err != nil {
+		return nil, fmt.Errorf("sqs: invalid %s attribute: %s", AnnotationsAttribute, err)
+	}
+	return as, nil
+}
+
+// Annotate returns a copy of the attributes of m with a appended to its
+// annotations, ready to be sent along with the body of m. At defaults to
+// the current time.
+func Annotate(m *Message, a Annotation) (MessageAttributes, error) {
+	as, err := Annotations(m)
+	if err != nil {
+		return nil, err
+	}
+	if a.At.IsZero() {
+		a.At = time.Now().UTC()
+	}
+	as = append(as, a)
+	if len(as) > MaxAnnotations {
+		as = as[len(as)-MaxAnnotations:]
+	}
+	b, err := json.Marshal(as)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make(MessageAttributes, len(m.MessageAttributes)+1)
+	for name, v := range m.MessageAttributes {
+		attrs[name] = v
+	}
+	attrs[AnnotationsAttribute] = MessageAttributeValue{DataType: "String", StringValue: string(b)}
+	return attrs, nil
+}
+
+// MoveMessage sends m, with its attributes and annotations, to dst and then
+// deletes it from q. If a is not nil it is appended to the annotations, with
+// its Source defaulting to the name of q. m must have been received with
+// all its attributes for them to be preserved.
+//
+// It returns the ID of the message in dst.
+func (q *Queue) MoveMessage(m *Message, dst *Queue, a *Annotation) (string, error) {
+	attrs := m.MessageAttributes
+	if a != nil {
+		note := *a
+		if note.Source == "" {
+			note.Source = q.Name()
+		}
+		var err error
+		if attrs, err = Annotate(m, note); err != nil {
+			return "", err
+		}
+	}
+	if err := dst.awaitPurge(dst.Context()); err != nil {
+		return "", err
+	}
+	id, err := dst.SendMessageWithOpt(m.Body, &SendMessageOpt{MessageAttributes: attrs})
+	if err != nil {
+		return "", err
+	}
+	return id, q.DeleteMessage(m)
+}
@@ -0,0 +1,42 @@
+package sqs
+
+import (
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestAnnotate(c *C) {
+	m := &Message{MessageAttributes: MessageAttributes{
+		"type": {DataType: "String", StringValue: "order.created"},
+	}}
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	attrs, err := Annotate(m, Annotation{Action: "redrive", By: "ops", Ticket: "INC-1", At: at})
+	c.Assert(err, IsNil)
+	c.Assert(attrs["type"].StringValue, Equals, "order.created")
+	c.Assert(m.MessageAttributes, HasLen, 1)
+
+	// Annotations are preserved through subsequent moves.
+	m = &Message{MessageAttributes: attrs}
+	attrs, err = Annotate(m, Annotation{Action: "replay", Reason: "fixed handler"})
+	c.Assert(err, IsNil)
+	as, err := Annotations(&Message{MessageAttributes: attrs})
+	c.Assert(err, IsNil)
+	c.Assert(as, HasLen, 2)
+	c.Assert(as[0], DeepEquals, Annotation{Action: "redrive", By: "ops", Ticket: "INC-1", At: at})
+	c.Assert(as[1].Reason, Equals, "fixed handler")
+	c.Assert(as[1].At.IsZero(), Equals, false)
+}
+
+func (s *S) TestAnnotateKeepsLatest(c *C) {
+	m := &Message{}
+	for i := 0; i < MaxAnnotations+3; i++ {
+		attrs, err := Annotate(m, Annotation{Action: "replay", Ticket: string(rune('a' + i))})
+		c.Assert(err, IsNil)
+		m = &Message{MessageAttributes: attrs}
+	}
+	as, err := Annotations(m)
+	c.Assert(err, IsNil)
+	c.Assert(as, HasLen, MaxAnnotations)
+	c.Assert(as[0].Ticket, Equals, "d")
+}
@@ -0,0 +1,59 @@
+package sqs
+
+import "context"
+
+// The interfaces below are satisfied by *SQS and *Queue, so that code
+// using the package can depend on the narrowest one and be tested with a
+// mock. Helpers that only send, such as BatchSender and SendGuard, or only
+// receive and delete, such as DeleteBuffer and Window, accept them. Those
+// that renew visibility timeouts in the background, such as Consumer,
+// need a *Queue, which can be backed by a Local in tests.
+
+// A QueueSender sends messages to a queue.
+type QueueSender interface {
+	SendMessage(body string) (string, error)
+	SendMessageWithOpt(body string, opt *SendMessageOpt) (string, error)
+	SendMessageBatch(entries []SendMessageBatchEntry) (*SendMessageBatchResult, error)
+}
+
+// A QueueReceiver receives and settles the messages of a queue.
+type QueueReceiver interface {
+	ReceiveMessages(opt *ReceiveMessageOpt) ([]*Message, error)
+	DeleteMessage(m *Message) error
+	DeleteMessageBatch(msgs []*Message) (*DeleteMessageBatchResult, error)
+	ChangeMessageVisibility(m *Message, visibilityTimeout int) error
+}
+
+// QueueAPI is the interface of a Queue.
+type QueueAPI interface {
+	QueueSender
+	QueueReceiver
+
+	Name() string
+	URL() string
+	Arn() string
+	GetQueueAttributes(attrs ...Attribute) (*QueueAttributes, error)
+	SetQueueAttributes(attrs map[Attribute]string) error
+	PurgeQueue() error
+	DeleteQueue() error
+	Exists() (bool, error)
+	Ping(ctx context.Context) error
+}
+
+// SQSAPI is the interface of an SQS client.
+type SQSAPI interface {
+	Queue(name string) (*Queue, error)
+	QueueOwnedBy(name, accountId string) (*Queue, error)
+	GetQueueUrl(name string, opt *GetQueueUrlOpt) (string, error)
+	ListQueues(namePrefix string) ([]*Queue, error)
+	ListQueuesPage(opt *ListQueuesOpt) (*ListQueuesPage, error)
+	ListQueuesAll(namePrefix string) ([]*Queue, error)
+	CreateQueue(name string, opt *CreateQueueOpt) (*Queue, error)
+	EnsureQueue(name string, opt *CreateQueueOpt) (*Queue, error)
+	Ping(ctx context.Context) error
+}
+
+var (
+	_ QueueAPI = (*Queue)(nil)
+	_ SQSAPI   = (*SQS)(nil)
+)
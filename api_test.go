@@ -0,0 +1,46 @@
+package sqs
+
+import (
+	"fmt"
+
+	. "launchpad.net/gocheck"
+)
+
+// mockSender records the bodies it is asked to send.
+type mockSender struct {
+	bodies []string
+}
+
+func (m *mockSender) SendMessage(body string) (string, error) {
+	return m.SendMessageWithOpt(body, nil)
+}
+
+func (m *mockSender) SendMessageWithOpt(body string, opt *SendMessageOpt) (string, error) {
+	m.bodies = append(m.bodies, body)
+	return fmt.Sprint(len(m.bodies)), nil
+}
+
+func (m *mockSender) SendMessageBatch(entries []SendMessageBatchEntry) (*SendMessageBatchResult, error) {
+	res := &SendMessageBatchResult{}
+	for _, e := range entries {
+		id, _ := m.SendMessage(e.Body)
+		res.Successful = append(res.Successful, SendMessageBatchResultEntry{Id: e.Id, MessageId: id})
+	}
+	return res, nil
+}
+
+func (s *S) TestMockSender(c *C) {
+	m := &mockSender{}
+	b := &BatchSender{Queue: m}
+	f := b.Send("one", nil)
+	b.Close()
+	id, err := f.Wait()
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, "1")
+
+	g := &SendGuard{}
+	_, sent, err := g.Send(m, "two", nil)
+	c.Assert(err, IsNil)
+	c.Assert(sent, Equals, true)
+	c.Assert(m.bodies, DeepEquals, []string{"one", "two"})
+}
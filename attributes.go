@@ -0,0 +1,99 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrAttributeNotFound is matched, with errors.Is, by the errors the typed
+// attribute getters of Message return for a missing attribute.
+var ErrAttributeNotFound = errors.New("sqs: message attribute not found")
+
+func (m *Message) attribute(name string) (MessageAttributeValue, error) {
+	v, ok := m.MessageAttributes[name]
+	if !ok {
+		return v, fmt.Errorf("%w: %s", ErrAttributeNotFound, name)
+	}
+	return v, nil
+}
+
+// GetString returns the value of the named string or number attribute.
+func (m *Message) GetString(name string) (string, error) {
+	v, err := m.attribute(name)
+	if err != nil {
+		return "", err
+	}
+	if v.BinaryValue != nil {
+		return "", fmt.Errorf("sqs: message attribute %s is %s, not a string", name, v.DataType)
+	}
+	return v.StringValue, nil
+}
+
+// AttributeIs returns a Consumer Filter selecting the messages whose
+// string attribute name has one of values:
+//
+//	consumer.Filter = sqs.AttributeIs("type", "order.created")
+func AttributeIs(name string, values ...string) func(m *Message) bool {
+	return func(m *Message) bool {
+		v, err := m.GetString(name)
+		if err != nil {
+			return false
+		}
+		for _, want := range values {
+			if v == want {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GetInt returns the value of the named attribute as an integer.
+func (m *Message) GetInt(name string) (int64, error) {
+	s, err := m.GetString(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("sqs: message attribute %s: invalid integer %q", name, s)
+	}
+	return n, nil
+}
+
+// GetBinary returns the value of the named binary attribute.
+func (m *Message) GetBinary(name string) ([]byte, error) {
+	v, err := m.attribute(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(v.DataType, "Binary") {
+		return nil, fmt.Errorf("sqs: message attribute %s is %s, not binary", name, v.DataType)
+	}
+	return v.BinaryValue, nil
+}
+
+// GetTime returns the value of the named attribute as a time. Number
+// attributes hold milliseconds since the epoch, like the timestamps of SQS;
+// string attributes hold RFC 3339 times.
+func (m *Message) GetTime(name string) (time.Time, error) {
+	v, err := m.attribute(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if strings.HasPrefix(v.DataType, "Number") {
+		t, err := parseEpochMillis(v.StringValue)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("sqs: message attribute %s: invalid timestamp %q", name, v.StringValue)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v.StringValue)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("sqs: message attribute %s: invalid time %q", name, v.StringValue)
+	}
+	return t, nil
+}
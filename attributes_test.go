@@ -0,0 +1,43 @@
+package sqs
+
+import (
+	"errors"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestMessageAttributeGetters(c *C) {
+	m := &Message{MessageAttributes: MessageAttributes{
+		"type":    {DataType: "String", StringValue: "order.created"},
+		"count":   {DataType: "Number", StringValue: "42"},
+		"blob":    {DataType: "Binary", BinaryValue: []byte{1, 2}},
+		"sent":    {DataType: "Number", StringValue: "1238099229000"},
+		"created": {DataType: "String", StringValue: "2020-01-02T03:04:05Z"},
+	}}
+
+	v, err := m.GetString("type")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "order.created")
+	n, err := m.GetInt("count")
+	c.Assert(err, IsNil)
+	c.Assert(n, Equals, int64(42))
+	b, err := m.GetBinary("blob")
+	c.Assert(err, IsNil)
+	c.Assert(b, DeepEquals, []byte{1, 2})
+	t, err := m.GetTime("sent")
+	c.Assert(err, IsNil)
+	c.Assert(t.Equal(time.Unix(1238099229, 0)), Equals, true)
+	t, err = m.GetTime("created")
+	c.Assert(err, IsNil)
+	c.Assert(t.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)), Equals, true)
+
+	_, err = m.GetString("missing")
+	c.Assert(errors.Is(err, ErrAttributeNotFound), Equals, true)
+	_, err = m.GetInt("type")
+	c.Assert(err, ErrorMatches, `sqs: message attribute type: invalid integer "order.created"`)
+	_, err = m.GetBinary("type")
+	c.Assert(err, ErrorMatches, "sqs: message attribute type is String, not binary")
+	_, err = m.GetString("blob")
+	c.Assert(err, ErrorMatches, "sqs: message attribute blob is Binary, not a string")
+}
@@ -0,0 +1,115 @@
+package sqs
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Is reports whether the failure of the entry matches target, one of the
+// sentinel errors matched by ErrorResponse, such as ErrReceiptHandleInvalid.
+func (e *BatchResultErrorEntry) Is(target error) bool {
+	return ErrorResponse{EmbeddedError: EmbeddedError{Code: e.Code}}.Is(target)
+}
+
+// A BatchError reports the entries of a batch request that failed while
+// the others succeeded. It matches, with errors.Is and errors.As, any of
+// its failed entries.
+type BatchError struct {
+	Action string
+	// Entries is the number of entries of the request.
+	Entries int
+	Failed  []BatchResultErrorEntry
+}
+
+func (e *BatchError) Error() string {
+	msg := fmt.Sprintf("sqs: %s: %d of %d entries failed", e.Action, len(e.Failed), e.Entries)
+	if len(e.Failed) > 0 {
+		f := &e.Failed[0]
+		msg += fmt.Sprintf(", first %s: %s: %s", f.Id, f.Code, f.Message)
+	}
+	return msg
+}
+
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i := range e.Failed {
+		errs[i] = &e.Failed[i]
+	}
+	return errs
+}
+
+// Ids returns the ids of the failed entries.
+func (e *BatchError) Ids() []string {
+	ids := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		ids[i] = f.Id
+	}
+	return ids
+}
+
+// Retryable returns the failed entries that are not the sender's fault,
+// which may succeed if sent again as they are.
+func (e *BatchError) Retryable() []BatchResultErrorEntry {
+	var retryable []BatchResultErrorEntry
+	for _, f := range e.Failed {
+		if !f.SenderFault {
+			retryable = append(retryable, f)
+		}
+	}
+	return retryable
+}
+
+// batchError returns a *BatchError for the failed entries of a batch
+// request, or nil if none failed.
+func batchError(action string, entries int, failed []BatchResultErrorEntry) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	return &BatchError{Action: action, Entries: entries, Failed: failed}
+}
+
+// Err returns a *BatchError reporting the failed entries, or nil if every
+// entry succeeded.
+func (r *SendMessageBatchResult) Err() error {
+	return batchError("SendMessageBatch", len(r.Successful)+len(r.Failed), r.Failed)
+}
+
+// FailedEntries returns the entries, among those sent, that failed, in
+// order, to be sent again.
+func (r *SendMessageBatchResult) FailedEntries(entries []SendMessageBatchEntry) []SendMessageBatchEntry {
+	failed := make(map[string]bool, len(r.Failed))
+	for _, f := range r.Failed {
+		failed[f.Id] = true
+	}
+	var again []SendMessageBatchEntry
+	for _, e := range entries {
+		if failed[e.Id] {
+			again = append(again, e)
+		}
+	}
+	return again
+}
+
+// Err returns a *BatchError reporting the failed entries, or nil if every
+// entry succeeded.
+func (r *DeleteMessageBatchResult) Err() error {
+	return batchError("DeleteMessageBatch", len(r.Successful)+len(r.Failed), r.Failed)
+}
+
+// FailedMessages returns the messages, among those passed to
+// DeleteMessageBatch, that failed to be deleted, in order.
+func (r *DeleteMessageBatchResult) FailedMessages(msgs []*Message) []*Message {
+	failed := make(map[int]bool, len(r.Failed))
+	for _, f := range r.Failed {
+		if i, err := strconv.Atoi(f.Id); err == nil {
+			failed[i] = true
+		}
+	}
+	var again []*Message
+	for i, m := range msgs {
+		if failed[i] {
+			again = append(again, m)
+		}
+	}
+	return again
+}
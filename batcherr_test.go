@@ -0,0 +1,78 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestSendMessageBatchError(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		fmt.Fprint(w, "<SendMessageBatchResponse><SendMessageBatchResult>")
+		for i := 1; r.Form.Get(fmt.Sprintf("SendMessageBatchRequestEntry.%d.Id", i)) != ""; i++ {
+			p := fmt.Sprintf("SendMessageBatchRequestEntry.%d.", i)
+			switch id := r.Form.Get(p + "Id"); r.Form.Get(p + "MessageBody") {
+			case "bad":
+				fmt.Fprintf(w, "<BatchResultErrorEntry><Id>%s</Id><Code>InvalidMessageContents</Code><Message>no</Message><SenderFault>true</SenderFault></BatchResultErrorEntry>", id)
+			case "unlucky":
+				fmt.Fprintf(w, "<BatchResultErrorEntry><Id>%s</Id><Code>InternalError</Code><Message>oops</Message></BatchResultErrorEntry>", id)
+			default:
+				fmt.Fprintf(w, "<SendMessageBatchResultEntry><Id>%s</Id><MessageId>m-%s</MessageId></SendMessageBatchResultEntry>", id, id)
+			}
+		}
+		fmt.Fprint(w, "</SendMessageBatchResult></SendMessageBatchResponse>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	sqs.DisableChecksums = true
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	entries := []SendMessageBatchEntry{
+		{Id: "a", Body: "unlucky"},
+		{Id: "b", Body: "fine"},
+		{Id: "c", Body: "bad"},
+	}
+	res, err := q.SendMessageBatch(entries)
+	c.Assert(err, IsNil)
+	err = res.Err()
+	c.Assert(err, ErrorMatches, "sqs: SendMessageBatch: 2 of 3 entries failed, first a: InternalError: oops")
+	var be *BatchError
+	c.Assert(errors.As(err, &be), Equals, true)
+	c.Assert(be.Ids(), DeepEquals, []string{"a", "c"})
+	c.Assert(be.Retryable(), DeepEquals, []BatchResultErrorEntry{{Id: "a", Code: "InternalError", Message: "oops"}})
+	var entry *BatchResultErrorEntry
+	c.Assert(errors.As(err, &entry), Equals, true)
+	c.Assert(entry.Id, Equals, "a")
+	c.Assert(res.FailedEntries(entries), DeepEquals, []SendMessageBatchEntry{entries[0], entries[2]})
+
+	res, err = q.SendMessageBatch(entries[1:2])
+	c.Assert(err, IsNil)
+	c.Assert(res.Err(), IsNil)
+	c.Assert(res.FailedEntries(entries[1:2]), HasLen, 0)
+}
+
+func (s *S) TestDeleteMessageBatchError(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("partial", nil)
+	c.Assert(err, IsNil)
+	for _, body := range []string{"one", "two"} {
+		_, err := q.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+	received, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 2})
+	c.Assert(err, IsNil)
+	c.Assert(received, HasLen, 2)
+	msgs := []*Message{received[0], {ReceiptHandle: "stale"}, received[1]}
+
+	res, err := q.DeleteMessageBatch(msgs)
+	c.Assert(err, IsNil)
+	err = res.Err()
+	c.Assert(err, ErrorMatches, "sqs: DeleteMessageBatch: 1 of 3 entries failed, first 1: ReceiptHandleIsInvalid: ")
+	c.Assert(errors.Is(err, ErrReceiptHandleInvalid), Equals, true)
+	c.Assert(errors.Is(err, ErrQueueNotFound), Equals, false)
+	c.Assert(res.FailedMessages(msgs), DeepEquals, []*Message{msgs[1]})
+}
@@ -0,0 +1,215 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxBatchSize is the largest number of entries of a batch request.
+const MaxBatchSize = 10
+
+// A SendFuture is the pending result of a message sent with a BatchSender.
+type SendFuture struct {
+	done chan struct{}
+	id   string
+	err  error
+}
+
+// Done returns a channel closed once the message was sent or failed.
+func (f *SendFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the message to be sent and returns its ID.
+func (f *SendFuture) Wait() (string, error) {
+	<-f.done
+	return f.id, f.err
+}
+
+func (f *SendFuture) resolve(id string, err error) {
+	f.id, f.err = id, err
+	close(f.done)
+}
+
+// resolve resolves f and counts its outcome.
+func (b *BatchSender) resolve(f *SendFuture, id string, err error) {
+	if err != nil {
+		atomic.AddInt64(&b.failed, 1)
+	} else {
+		atomic.AddInt64(&b.sent, 1)
+	}
+	f.resolve(id, err)
+}
+
+// A BatchSender buffers messages and sends them to Queue with
+// SendMessageBatch once MaxBatchSize messages or DefaultMaxBodySize bytes
+// are pending, or Linger after the first pending message, whichever comes
+// first. This cuts the number of requests up to tenfold for producers
+// sending many messages.
+type BatchSender struct {
+	Queue QueueSender
+	// Linger is how long a message may wait for the batch to fill; it
+	// defaults to 100 milliseconds.
+	Linger time.Duration
+
+	queued, sent, failed int64
+
+	mu      sync.Mutex
+	pending []SendMessageBatchEntry
+	futures []*SendFuture
+	size    int
+	timer   *time.Timer
+	wg      sync.WaitGroup
+}
+
+// Send queues body for sending and returns the future of its result.
+func (b *BatchSender) Send(body string, opt *SendMessageOpt) *SendFuture {
+	e := SendMessageBatchEntry{Body: body}
+	if opt != nil {
+		e.DelaySeconds = opt.DelaySeconds
+		e.MessageAttributes = opt.MessageAttributes
+		e.MessageGroupId = opt.MessageGroupId
+		e.MessageDeduplicationId = opt.MessageDeduplicationId
+		e.AWSTraceHeader = opt.AWSTraceHeader
+	}
+	f := &SendFuture{done: make(chan struct{})}
+	size := entrySize(e)
+
+	atomic.AddInt64(&b.queued, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) > 0 && b.size+size > DefaultMaxBodySize {
+		b.flushLocked()
+	}
+	e.Id = strconv.Itoa(len(b.pending))
+	b.pending = append(b.pending, e)
+	b.futures = append(b.futures, f)
+	b.size += size
+	switch {
+	case len(b.pending) >= MaxBatchSize || b.size >= DefaultMaxBodySize:
+		b.flushLocked()
+	case len(b.pending) == 1:
+		linger := b.Linger
+		if linger <= 0 {
+			linger = 100 * time.Millisecond
+		}
+		b.timer = time.AfterFunc(linger, b.Flush)
+	}
+	return f
+}
+
+// Flush sends the pending messages without waiting for the batch to fill.
+func (b *BatchSender) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+// A SendReport accounts for the messages of a BatchSender, so that
+// operators can verify a clean shutdown and resend the messages left
+// unsent.
+type SendReport struct {
+	Queued int64 // messages passed to Send
+	Sent   int64 // messages accepted by SQS
+	Failed int64 // messages whose future holds an error
+	Unsent int64 // messages buffered or in a batch in flight
+}
+
+// Clean reports whether every message was sent.
+func (r SendReport) Clean() bool {
+	return r.Failed == 0 && r.Unsent == 0
+}
+
+// Report returns the counts of the sender so far.
+func (b *BatchSender) Report() SendReport {
+	r := SendReport{
+		Queued: atomic.LoadInt64(&b.queued),
+		Sent:   atomic.LoadInt64(&b.sent),
+		Failed: atomic.LoadInt64(&b.failed),
+	}
+	r.Unsent = r.Queued - r.Sent - r.Failed
+	return r
+}
+
+// Close flushes the pending messages, waits for every batch in flight and
+// returns the report of the sender.
+func (b *BatchSender) Close() SendReport {
+	return b.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but stops waiting once ctx is done; the
+// messages of the batches still in flight then are counted as Unsent.
+func (b *BatchSender) CloseContext(ctx context.Context) SendReport {
+	b.Flush()
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	return b.Report()
+}
+
+func (b *BatchSender) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+	entries, futures := b.pending, b.futures
+	b.pending, b.futures, b.size = nil, nil, 0
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.send(entries, futures)
+	}()
+}
+
+func (b *BatchSender) send(entries []SendMessageBatchEntry, futures []*SendFuture) {
+	res, err := b.Queue.SendMessageBatch(entries)
+	if err != nil {
+		for _, f := range futures {
+			b.resolve(f, "", err)
+		}
+		return
+	}
+	for _, e := range res.Successful {
+		if i, err := strconv.Atoi(e.Id); err == nil && i < len(futures) {
+			b.resolve(futures[i], e.MessageId, nil)
+		}
+	}
+	for i := range res.Failed {
+		e := &res.Failed[i]
+		if i, err := strconv.Atoi(e.Id); err == nil && i < len(futures) {
+			b.resolve(futures[i], "", e)
+		}
+	}
+	for _, f := range futures {
+		select {
+		case <-f.done:
+		default:
+			b.resolve(f, "", errMissingBatchEntry)
+		}
+	}
+}
+
+var errMissingBatchEntry = errors.New("sqs: batch entry missing from response")
+
+// entrySize returns the size an entry counts towards the payload limit of
+// a batch: its body and attribute names, types and values.
+func entrySize(e SendMessageBatchEntry) int {
+	n := len(e.Body)
+	for name, v := range e.MessageAttributes {
+		n += len(name) + len(v.DataType) + len(v.StringValue) + len(v.BinaryValue)
+	}
+	return n
+}
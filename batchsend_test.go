@@ -0,0 +1,83 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestBatchSender(c *C) {
+	var mu sync.Mutex
+	var batches []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		c.Check(r.Form.Get("Action"), Equals, "SendMessageBatch")
+		fmt.Fprint(w, "<SendMessageBatchResponse><SendMessageBatchResult>")
+		n := 0
+		for i := 1; ; i++ {
+			p := fmt.Sprintf("SendMessageBatchRequestEntry.%d.", i)
+			id := r.Form.Get(p + "Id")
+			if id == "" {
+				break
+			}
+			n++
+			if r.Form.Get(p+"MessageBody") == "bad" {
+				fmt.Fprintf(w, "<BatchResultErrorEntry><Id>%s</Id><Code>InvalidMessageContents</Code><Message>no</Message><SenderFault>true</SenderFault></BatchResultErrorEntry>", id)
+				continue
+			}
+			fmt.Fprintf(w, "<SendMessageBatchResultEntry><Id>%s</Id><MessageId>m-%s</MessageId></SendMessageBatchResultEntry>", id, r.Form.Get(p+"MessageBody"))
+		}
+		fmt.Fprint(w, "</SendMessageBatchResult></SendMessageBatchResponse>")
+		mu.Lock()
+		batches = append(batches, n)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	b := &BatchSender{Queue: &Queue{SQS: sqs, path: "/123/q"}, Linger: 10 * time.Millisecond}
+
+	var futures []*SendFuture
+	for i := 0; i < 12; i++ {
+		futures = append(futures, b.Send(fmt.Sprint(i), nil))
+	}
+	bad := b.Send("bad", nil)
+	id, err := futures[11].Wait()
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, "m-11")
+	_, err = bad.Wait()
+	c.Assert(err, ErrorMatches, "sqs: batch entry 2 failed: InvalidMessageContents: no")
+
+	// A body that would overflow the payload limit starts a new batch.
+	b.Send(strings.Repeat("x", DefaultMaxBodySize-10), nil)
+	b.Send("y", nil)
+	b.Send(strings.Repeat("z", 20), nil)
+	c.Assert(b.Close(), DeepEquals, SendReport{Queued: 16, Sent: 15, Failed: 1})
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Ints(batches)
+	c.Assert(batches, DeepEquals, []int{1, 2, 3, 10})
+}
+
+func (s *S) TestBatchSenderOpt(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("orders.fifo", nil)
+	c.Assert(err, IsNil)
+	b := &BatchSender{Queue: q}
+	_, err = b.Send("one", &SendMessageOpt{MessageGroupId: "g", MessageDeduplicationId: "d1", AWSTraceHeader: "Root=1-5759e988-bd862e3fe1be46a994272793"}).Wait()
+	c.Assert(err, IsNil)
+	c.Assert(b.Close(), DeepEquals, SendReport{Queued: 1, Sent: 1})
+
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{AttributeNames: []Attribute{All}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].SystemAttributes.MessageGroupId, Equals, "g")
+	c.Assert(msgs[0].SystemAttributes.MessageDeduplicationId, Equals, "d1")
+	c.Assert(msgs[0].SystemAttributes.AWSTraceHeader, Equals, "Root=1-5759e988-bd862e3fe1be46a994272793")
+}
@@ -0,0 +1,210 @@
+package sqs
+
+// The benchmarks compare the request paths of the client against canned
+// responses, so that regressions in signing, encoding and decoding show up
+// without network noise. Run them with allocation counts:
+//
+//	go test -gocheck.b -gocheck.bmem -gocheck.f Benchmark
+//
+// The HTTP benchmarks go through a loopback server; the others use an
+// in-process Doer. The Decode benchmarks compare the decoding of the same
+// receive response with QueryProtocol and JSONProtocol.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+const benchSendResponse = `<SendMessageResponse><SendMessageResult><MessageId>id</MessageId><MD5OfMessageBody>b3ec4d3be2b8a86de2b2e6e6ddb1bbf1</MD5OfMessageBody></SendMessageResult></SendMessageResponse>`
+
+// benchReceiveResponse holds ten messages with attributes.
+var benchReceiveResponse = func() []byte {
+	resp := &xmlReceiveResponse{}
+	for i := 0; i < 10; i++ {
+		resp.Messages = append(resp.Messages, toXMLMessage(&Message{
+			Id:                fmt.Sprintf("id-%d", i),
+			ReceiptHandle:     fmt.Sprintf("handle-%d", i),
+			Body:              strings.Repeat("x", 1024),
+			MessageAttributes: MessageAttributes{"kind": {DataType: "String", StringValue: "bench"}},
+			SystemAttributes:  SystemAttributes{ApproximateReceiveCount: 1},
+		}))
+	}
+	b, _ := xml.Marshal(resp)
+	return b
+}()
+
+// benchReceiveJSON is benchReceiveResponse in the JSON protocol.
+var benchReceiveJSON = func() []byte {
+	var resp xmlReceiveResponse
+	xml.Unmarshal(benchReceiveResponse, &resp)
+	var doc struct {
+		Messages []map[string]interface{}
+	}
+	for _, m := range resp.Messages {
+		attrs := make(map[string]string)
+		for _, a := range m.Attribute {
+			attrs[a.Name] = a.Value
+		}
+		mattrs := make(map[string]interface{})
+		for _, a := range m.MessageAttribute {
+			mattrs[a.Name] = map[string]string{"DataType": a.Value.DataType, "StringValue": a.Value.StringValue}
+		}
+		doc.Messages = append(doc.Messages, map[string]interface{}{
+			"MessageId":         m.MessageId,
+			"ReceiptHandle":     m.ReceiptHandle,
+			"MD5OfBody":         m.MD5OfBody,
+			"Body":              m.Body,
+			"Attributes":        attrs,
+			"MessageAttributes": mattrs,
+		})
+	}
+	b, _ := json.Marshal(doc)
+	return b
+}()
+
+// benchClient returns a client whose requests are answered with body,
+// in-process.
+func (s *S) benchClient(body []byte) *SQS {
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			ioutil.ReadAll(req.Body)
+		}
+		return xmlResponse(req, http.StatusOK, body), nil
+	})
+	return sqs
+}
+
+// benchServer returns a client of a loopback server answering with body.
+func (s *S) benchServer(body []byte) (*SQS, func()) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Write(body)
+	}))
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	return sqs, srv.Close
+}
+
+func benchSendParams() url.Values {
+	return url.Values{"MessageBody": {strings.Repeat("x", 1024)}}
+}
+
+func (s *S) BenchmarkSendGET(c *C) {
+	sqs := s.benchClient([]byte(benchSendResponse))
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		var resp sendMessageResponse
+		if err := sqs.callMethod(context.Background(), "GET", "SendMessage", "/123/q", benchSendParams(), &resp); err != nil {
+			c.Fatal(err)
+		}
+	}
+}
+
+func (s *S) BenchmarkSendPOST(c *C) {
+	sqs := s.benchClient([]byte(benchSendResponse))
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		var resp sendMessageResponse
+		if err := sqs.callMethod(context.Background(), "POST", "SendMessage", "/123/q", benchSendParams(), &resp); err != nil {
+			c.Fatal(err)
+		}
+	}
+}
+
+func (s *S) BenchmarkSendGETHTTP(c *C) {
+	sqs, done := s.benchServer([]byte(benchSendResponse))
+	defer done()
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		var resp sendMessageResponse
+		if err := sqs.callMethod(context.Background(), "GET", "SendMessage", "/123/q", benchSendParams(), &resp); err != nil {
+			c.Fatal(err)
+		}
+	}
+}
+
+func (s *S) BenchmarkSendPOSTHTTP(c *C) {
+	sqs, done := s.benchServer([]byte(benchSendResponse))
+	defer done()
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		var resp sendMessageResponse
+		if err := sqs.callMethod(context.Background(), "POST", "SendMessage", "/123/q", benchSendParams(), &resp); err != nil {
+			c.Fatal(err)
+		}
+	}
+}
+
+func (s *S) BenchmarkSendMessage(c *C) {
+	q := &Queue{SQS: s.benchClient([]byte(benchSendResponse)), path: "/123/q"}
+	q.DisableChecksums = true
+	body := strings.Repeat("x", 1024)
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		if _, err := q.SendMessage(body); err != nil {
+			c.Fatal(err)
+		}
+	}
+}
+
+func (s *S) BenchmarkReceiveMessages(c *C) {
+	q := &Queue{SQS: s.benchClient(benchReceiveResponse), path: "/123/q"}
+	opt := &ReceiveMessageOpt{MaxNumberOfMessages: 10, MessageAttributeNames: []string{"All"}, AttributeNames: []Attribute{All}}
+	c.SetBytes(int64(len(benchReceiveResponse)))
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		msgs, err := q.ReceiveMessages(opt)
+		if err != nil || len(msgs) != 10 {
+			c.Fatalf("received %d messages: %v", len(msgs), err)
+		}
+	}
+}
+
+func (s *S) BenchmarkLocalRoundTrip(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("bench", nil)
+	c.Assert(err, IsNil)
+	body := strings.Repeat("x", 1024)
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		if _, err := q.SendMessage(body); err != nil {
+			c.Fatal(err)
+		}
+		msgs, err := q.ReceiveMessages(nil)
+		if err != nil || len(msgs) != 1 {
+			c.Fatalf("received %d messages: %v", len(msgs), err)
+		}
+		if err := q.DeleteMessage(msgs[0]); err != nil {
+			c.Fatal(err)
+		}
+	}
+}
+
+func (s *S) benchDecode(c *C, proto Protocol, body []byte) {
+	c.SetBytes(int64(len(body)))
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		var resp receiveMessageResponse
+		if err := proto.decode(bytes.NewReader(body), &resp); err != nil || len(resp.Messages) != 10 {
+			c.Fatalf("decoded %d messages: %v", len(resp.Messages), err)
+		}
+	}
+}
+
+func (s *S) BenchmarkDecodeReceiveXML(c *C) {
+	s.benchDecode(c, QueryProtocol, benchReceiveResponse)
+}
+
+func (s *S) BenchmarkDecodeReceiveJSON(c *C) {
+	s.benchDecode(c, JSONProtocol, benchReceiveJSON)
+}
@@ -0,0 +1,159 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched, with errors.Is, by the CircuitOpenError
+// returned for requests rejected by an open CircuitBreaker.
+var ErrCircuitOpen = errors.New("sqs: circuit open")
+
+// A CircuitOpenError is returned, without issuing a request, while a
+// CircuitBreaker is open.
+type CircuitOpenError struct {
+	// Until is when the breaker lets a probe request through.
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("sqs: circuit open until %s", e.Until.Format(time.RFC3339))
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // requests flow
+	CircuitOpen                         // requests fail fast
+	CircuitHalfOpen                     // one probe request at a time
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// A CircuitBreaker stops a client from issuing requests after persistent
+// failures, such as a degraded region, so that callers fail fast with a
+// CircuitOpenError instead of piling up behind timeouts. Transport errors,
+// including timeouts, and 5xx responses count as failures; throttling is
+// left to the client's cooldowns. Once Cooldown has elapsed the breaker
+// half-opens and lets one probe request through at a time: a success
+// closes it, a failure opens it again.
+//
+// The zero value is ready to use.
+type CircuitBreaker struct {
+	// Failures is the number of consecutive failures opening the
+	// breaker, default 5.
+	Failures int
+	// Cooldown is how long the breaker stays open, default 30 seconds.
+	Cooldown time.Duration
+	// OnStateChange, when set, is called on every state change, with the
+	// breaker locked.
+	OnStateChange func(from, to CircuitState)
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	until    time.Time
+	probing  bool
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && !time.Now().Before(b.until) {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Allow returns nil if a request may be issued now, to be followed by a
+// call to Record with its outcome, or a *CircuitOpenError.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen {
+		if time.Now().Before(b.until) {
+			return &CircuitOpenError{Until: b.until}
+		}
+		b.set(CircuitHalfOpen)
+	}
+	if b.state == CircuitHalfOpen {
+		if b.probing {
+			return &CircuitOpenError{Until: b.until}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record accounts for the outcome of a request allowed by Allow.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if errors.Is(err, context.Canceled) {
+		// The outcome says nothing about the service.
+		return
+	}
+	if !circuitFailure(err) {
+		b.failures = 0
+		b.set(CircuitClosed)
+		return
+	}
+	b.failures++
+	max := b.Failures
+	if max <= 0 {
+		max = 5
+	}
+	if b.state == CircuitHalfOpen || b.failures >= max {
+		cooldown := b.Cooldown
+		if cooldown <= 0 {
+			cooldown = 30 * time.Second
+		}
+		b.until = time.Now().Add(cooldown)
+		b.set(CircuitOpen)
+	}
+}
+
+func (b *CircuitBreaker) set(state CircuitState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	if b.OnStateChange != nil {
+		b.OnStateChange(from, state)
+	}
+}
+
+// circuitFailure reports whether err is a transport error or a 5xx
+// response.
+func circuitFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		return true
+	}
+	return e.StatusCode >= 500
+}
@@ -0,0 +1,74 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestCircuitBreaker(c *C) {
+	var failing int32 = 1
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, "<ErrorResponse><Error><Code>ServiceUnavailable</Code></Error></ErrorResponse>")
+			return
+		}
+		fmt.Fprint(w, "<ReceiveMessageResponse><ReceiveMessageResult></ReceiveMessageResult></ReceiveMessageResponse>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	var changes []string
+	sqs.CircuitBreaker = &CircuitBreaker{
+		Failures: 2,
+		Cooldown: 20 * time.Millisecond,
+		OnStateChange: func(from, to CircuitState) {
+			changes = append(changes, from.String()+">"+to.String())
+		},
+	}
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	for i := 0; i < 2; i++ {
+		_, err := q.ReceiveMessages(nil)
+		c.Assert(errors.Is(err, ErrCircuitOpen), Equals, false)
+	}
+	_, err := q.ReceiveMessages(nil)
+	c.Assert(errors.Is(err, ErrCircuitOpen), Equals, true)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(2))
+	c.Assert(sqs.CircuitBreaker.State(), Equals, CircuitOpen)
+
+	// A failed probe opens the breaker again.
+	time.Sleep(25 * time.Millisecond)
+	c.Assert(sqs.CircuitBreaker.State(), Equals, CircuitHalfOpen)
+	_, err = q.ReceiveMessages(nil)
+	c.Assert(errors.Is(err, ErrCircuitOpen), Equals, false)
+	_, err = q.ReceiveMessages(nil)
+	c.Assert(errors.Is(err, ErrCircuitOpen), Equals, true)
+
+	// A successful probe closes it.
+	atomic.StoreInt32(&failing, 0)
+	time.Sleep(25 * time.Millisecond)
+	_, err = q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(sqs.CircuitBreaker.State(), Equals, CircuitClosed)
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(4))
+	c.Assert(changes, DeepEquals, []string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"})
+}
+
+func (s *S) TestCircuitBreakerIgnoresClientErrors(c *C) {
+	b := &CircuitBreaker{Failures: 1}
+	c.Assert(b.Allow(), IsNil)
+	b.Record(&ErrorResponse{StatusCode: 400, EmbeddedError: EmbeddedError{Code: "InvalidParameterValue"}})
+	c.Assert(b.State(), Equals, CircuitClosed)
+	c.Assert(b.Allow(), IsNil)
+	b.Record(errors.New("dial tcp: i/o timeout"))
+	c.Assert(b.State(), Equals, CircuitOpen)
+}
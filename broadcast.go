@@ -0,0 +1,53 @@
+package sqs
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A Broadcaster sends every message to a set of queues concurrently, a
+// fan-out for systems without SNS where several consumers each own a
+// queue.
+type Broadcaster struct {
+	Queues []QueueSender
+}
+
+// A BroadcastResult is the outcome of a broadcast to one queue.
+type BroadcastResult struct {
+	Queue     QueueSender
+	MessageId string
+	Err       error
+}
+
+// Send sends body with opt, which may be nil, to every queue and returns
+// their results, in the order of Queues. The error reports the queues that
+// failed, if any, with the first of their errors; the message was still
+// delivered to the others, so retrying a whole broadcast may duplicate it.
+func (b *Broadcaster) Send(body string, opt *SendMessageOpt) ([]BroadcastResult, error) {
+	results := make([]BroadcastResult, len(b.Queues))
+	var wg sync.WaitGroup
+	for i, q := range b.Queues {
+		wg.Add(1)
+		go func(r *BroadcastResult, q QueueSender) {
+			defer wg.Done()
+			r.Queue = q
+			r.MessageId, r.Err = q.SendMessageWithOpt(body, opt)
+		}(&results[i], q)
+	}
+	wg.Wait()
+
+	var failed int
+	var first error
+	for _, r := range results {
+		if r.Err != nil {
+			if first == nil {
+				first = r.Err
+			}
+			failed++
+		}
+	}
+	if first != nil {
+		return results, fmt.Errorf("sqs: broadcast to %d of %d queues failed: %w", failed, len(results), first)
+	}
+	return results, nil
+}
@@ -0,0 +1,41 @@
+package sqs
+
+import (
+	"errors"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestBroadcaster(c *C) {
+	sqs := NewLocal().SQS()
+	var queues []QueueSender
+	for _, name := range []string{"billing", "search", "audit"} {
+		q, err := sqs.CreateQueue(name, nil)
+		c.Assert(err, IsNil)
+		queues = append(queues, q)
+	}
+	b := &Broadcaster{Queues: queues}
+
+	results, err := b.Send("order created", &SendMessageOpt{
+		MessageAttributes: MessageAttributes{"kind": {DataType: "String", StringValue: "order"}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(results, HasLen, 3)
+	for i, r := range results {
+		c.Assert(r.Queue, Equals, queues[i])
+		c.Assert(r.Err, IsNil)
+		m, err := queues[i].(*Queue).ReceiveMessages(&ReceiveMessageOpt{MessageAttributeNames: []string{"All"}})
+		c.Assert(err, IsNil)
+		c.Assert(m, HasLen, 1)
+		c.Assert(m[0].Id, Equals, r.MessageId)
+		c.Assert(m[0].MessageAttributes["kind"].StringValue, Equals, "order")
+	}
+
+	c.Assert(queues[1].(*Queue).DeleteQueue(), IsNil)
+	results, err = b.Send("order paid", nil)
+	c.Assert(err, ErrorMatches, "sqs: broadcast to 1 of 3 queues failed: .*")
+	c.Assert(errors.Is(err, ErrQueueNotFound), Equals, true)
+	c.Assert(results[0].Err, IsNil)
+	c.Assert(results[1].Err, NotNil)
+	c.Assert(results[2].Err, IsNil)
+}
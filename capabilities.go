@@ -0,0 +1,250 @@
+package sqs
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A Support tells whether an endpoint supports a feature.
+type Support int
+
+const (
+	// SupportUnknown means the feature was not probed, or the probe
+	// failed; see Capabilities.Errs.
+	SupportUnknown Support = iota
+	// Supported means the endpoint supports the feature.
+	Supported
+	// Unsupported means the endpoint rejected or ignored the feature.
+	Unsupported
+)
+
+func (s Support) String() string {
+	switch s {
+	case SupportUnknown:
+		return "unknown"
+	case Supported:
+		return "supported"
+	case Unsupported:
+		return "unsupported"
+	}
+	return "invalid"
+}
+
+// A Feature is a capability of an endpoint that a client may depend on.
+type Feature string
+
+// The features detected by Probe.
+const (
+	FeatureJSONProtocol Feature = "the JSON protocol"
+	FeatureFIFO         Feature = "FIFO queues"
+	FeatureLongPolling  Feature = "long polling"
+)
+
+// Capabilities describe what an SQS endpoint supports, so that the same
+// binary can target SQS in production and a feature-limited emulator
+// locally. Set them as the Capabilities of a client to have requests
+// depending on an unsupported feature fail with an *UnsupportedError
+// rather than misbehave: requests of JSONProtocol, and the creation of
+// FIFO queues. Receives still work without long polling, returning at
+// once.
+type Capabilities struct {
+	// Endpoint is the endpoint probed.
+	Endpoint string
+	// APIVersion is the version of the API the endpoint answers with, as
+	// the namespace of its responses, e.g. "2012-11-05"; it is empty if
+	// the endpoint does not say.
+	APIVersion string
+
+	// JSONProtocol tells whether the endpoint accepts JSONProtocol
+	// requests signed by the client's Signer.
+	JSONProtocol Support
+	// FIFO tells whether FIFO queues enforce their message groups.
+	FIFO Support
+	// LongPolling tells whether receives wait for messages.
+	LongPolling Support
+
+	// Errs holds the errors of the probes that failed.
+	Errs []error
+}
+
+// Support returns the support of f.
+func (c *Capabilities) Support(f Feature) Support {
+	switch f {
+	case FeatureJSONProtocol:
+		return c.JSONProtocol
+	case FeatureFIFO:
+		return c.FIFO
+	case FeatureLongPolling:
+		return c.LongPolling
+	}
+	return SupportUnknown
+}
+
+// Require returns an *UnsupportedError for the first of features the
+// endpoint does not support, and nil if it may support them all.
+func (c *Capabilities) Require(features ...Feature) error {
+	for _, f := range features {
+		if c.Support(f) == Unsupported {
+			return &UnsupportedError{Feature: f, Endpoint: c.Endpoint}
+		}
+	}
+	return nil
+}
+
+// An UnsupportedError reports a request depending on a feature its
+// endpoint does not support.
+type UnsupportedError struct {
+	Feature  Feature
+	Endpoint string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("sqs: %s not supported by %s", e.Feature, e.Endpoint)
+}
+
+// require checks that the endpoint of the client supports features, when
+// its Capabilities are known.
+func (sqs *SQS) require(features ...Feature) error {
+	if sqs.Capabilities == nil {
+		return nil
+	}
+	return sqs.Capabilities.Require(features...)
+}
+
+// ProbeOpt holds the options of Probe.
+type ProbeOpt struct {
+	// QueuePrefix, if set, enables the probes of FIFO queues and long
+	// polling, which create and then delete queues whose names start with
+	// QueuePrefix. They are not run otherwise, as they change the account.
+	QueuePrefix string
+}
+
+// probeResponse records the namespace of a response.
+type probeResponse struct {
+	XMLName xml.Name
+	ResponseMetadata
+}
+
+// Probe detects the capabilities of the endpoint of the client. Features
+// whose probes fail are left unknown, and the failures recorded in Errs;
+// Probe only returns an error when the endpoint cannot be reached at all.
+// opt may be nil.
+func (sqs *SQS) Probe(opt *ProbeOpt) (*Capabilities, error) {
+	if opt == nil {
+		opt = &ProbeOpt{}
+	}
+	c := &Capabilities{Endpoint: sqs.endpoint()}
+	params := url.Values{"MaxResults": {"1"}}
+
+	query := sqs.probeClient(QueryProtocol)
+	var resp probeResponse
+	if err := query.call("ListQueues", "/", params, &resp); err != nil {
+		return nil, err
+	}
+	if i := strings.LastIndex(strings.TrimSuffix(resp.XMLName.Space, "/"), "/"); i >= 0 {
+		c.APIVersion = strings.TrimSuffix(resp.XMLName.Space[i+1:], "/")
+	}
+
+	// Endpoints without the JSON protocol may answer in a format of
+	// their own; any client error then means they do not support it.
+	jsonClient := sqs.probeClient(JSONProtocol)
+	var status int
+	jsonClient.Hooks.AfterResponse = func(action string, r *http.Response, err error) {
+		if r != nil {
+			status = r.StatusCode
+		}
+	}
+	var jsonResp ResponseMetadata
+	err := jsonClient.call("ListQueues", "/", params, &jsonResp)
+	if err != nil && status >= 400 && status < 500 {
+		err = errUnsupported
+	}
+	c.JSONProtocol = c.probe(err)
+
+	if opt.QueuePrefix != "" {
+		suffix := fmt.Sprint(time.Now().UnixNano())
+		c.FIFO = c.probe(query.probeFIFO(opt.QueuePrefix + "-fifo-" + suffix + ".fifo"))
+		c.LongPolling = c.probe(query.probeLongPolling(opt.QueuePrefix + "-poll-" + suffix))
+	}
+	return c, nil
+}
+
+// errUnsupported is returned by probes to report an unsupported feature.
+var errUnsupported = errors.New("sqs: unsupported")
+
+// probe returns the support reported by the outcome err of a probe. A
+// service error other than an authentication failure means the feature is
+// unsupported; other errors are recorded.
+func (c *Capabilities) probe(err error) Support {
+	var resp *ErrorResponse
+	switch {
+	case err == nil:
+		return Supported
+	case IsAuthFailure(err):
+		// Says nothing of the feature.
+	case err == errUnsupported, errors.As(err, &resp):
+		return Unsupported
+	}
+	c.Errs = append(c.Errs, err)
+	return SupportUnknown
+}
+
+// probeClient returns a client of the same endpoint, with the same
+// credentials, speaking proto.
+func (sqs *SQS) probeClient(proto Protocol) *SQS {
+	sqs.authMu.RLock()
+	defer sqs.authMu.RUnlock()
+	return &SQS{
+		Auth:       sqs.Auth,
+		Region:     sqs.Region,
+		Endpoint:   sqs.Endpoint,
+		Signer:     sqs.Signer,
+		Protocol:   proto,
+		Client:     sqs.Client,
+		Transport:  sqs.Transport,
+		Middleware: sqs.Middleware,
+		creds:      sqs.creds,
+		queueCreds: sqs.queueCreds,
+	}
+}
+
+// probeFIFO creates a FIFO queue and sends it a message without a message
+// group, which only a genuine FIFO queue rejects.
+func (sqs *SQS) probeFIFO(name string) error {
+	q, err := sqs.CreateQueue(name, &CreateQueueOpt{FifoQueue: true})
+	if err != nil {
+		return err
+	}
+	defer q.DeleteQueue()
+	_, err = q.SendMessage("probe")
+	switch code := ErrorCode(err); {
+	case err == nil:
+		return errUnsupported
+	case code == ErrCodeMissingParameter, code == ErrCodeInvalidParameterValue:
+		return nil
+	}
+	return err
+}
+
+// probeLongPolling creates an empty queue and checks that a receive waits
+// for messages.
+func (sqs *SQS) probeLongPolling(name string) error {
+	q, err := sqs.CreateQueue(name, nil)
+	if err != nil {
+		return err
+	}
+	defer q.DeleteQueue()
+	start := time.Now()
+	if _, err := q.ReceiveMessages(&ReceiveMessageOpt{WaitTimeSeconds: 1}); err != nil {
+		return err
+	}
+	if time.Since(start) < 500*time.Millisecond {
+		return errUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package sqs
+
+import (
+	"net/http"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestProbe(c *C) {
+	sqs := NewLocal().SQS()
+	caps, err := sqs.Probe(&ProbeOpt{QueuePrefix: "probe"})
+	c.Assert(err, IsNil)
+	c.Assert(caps.Errs, HasLen, 0)
+	c.Assert(caps.JSONProtocol, Equals, Unsupported)
+	c.Assert(caps.FIFO, Equals, Unsupported)
+	c.Assert(caps.LongPolling, Equals, Supported)
+	queues, err := sqs.ListQueues("probe")
+	c.Assert(err, IsNil)
+	c.Assert(queues, HasLen, 0)
+
+	sqs.Capabilities = caps
+	_, err = sqs.CreateQueue("jobs.fifo", &CreateQueueOpt{FifoQueue: true})
+	c.Assert(err, ErrorMatches, "sqs: FIFO queues not supported by "+caps.Endpoint)
+	_, err = sqs.CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	sqs.Protocol = JSONProtocol
+	_, err = sqs.ListQueues("")
+	c.Assert(err, FitsTypeOf, &UnsupportedError{})
+}
+
+func (s *S) TestProbeFIFO(c *C) {
+	l := NewLocal()
+	sqs := l.SQS()
+	// Reject messages without a group, like a FIFO queue.
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		q := requestParams(c, req)
+		if q.Get("Action") == "SendMessage" && strings.HasSuffix(q.Get("QueueUrl"), ".fifo") && q.Get("MessageGroupId") == "" {
+			return xmlError(req, ErrCodeMissingParameter, "MessageGroupId is required"), nil
+		}
+		return l.Do(req)
+	})
+	caps, err := sqs.Probe(&ProbeOpt{QueuePrefix: "probe"})
+	c.Assert(err, IsNil)
+	c.Assert(caps.FIFO, Equals, Supported)
+	c.Assert(caps.Require(FeatureFIFO, FeatureLongPolling), IsNil)
+	c.Assert(caps.Require(FeatureJSONProtocol), ErrorMatches, "sqs: the JSON protocol not supported by .*")
+
+	caps, err = sqs.Probe(nil)
+	c.Assert(err, IsNil)
+	c.Assert(caps.FIFO, Equals, SupportUnknown)
+	c.Assert(caps.LongPolling, Equals, SupportUnknown)
+}
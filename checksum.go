@@ -0,0 +1,35 @@
+package sqs
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// A ChecksumError is returned when the MD5 digest SQS reports for a message
+// body does not match the body, which means it was corrupted in transit.
+type ChecksumError struct {
+	MessageId string
+	Expected  string // digest reported by SQS
+	Actual    string // digest of the body
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("sqs: MD5 mismatch for message %s: expected %s, got %s", e.MessageId, e.Expected, e.Actual)
+}
+
+// verifyMD5 checks body against the digest reported by SQS. An empty
+// digest, as returned by some SQS emulators, is not checked.
+func (sqs *SQS) verifyMD5(id, body, expected string) error {
+	if sqs.DisableChecksums || expected == "" {
+		return nil
+	}
+	sum := md5.Sum([]byte(body))
+	var actual [2 * md5.Size]byte
+	hex.Encode(actual[:], sum[:])
+	if !strings.EqualFold(string(actual[:]), expected) {
+		return &ChecksumError{MessageId: id, Expected: expected, Actual: string(actual[:])}
+	}
+	return nil
+}
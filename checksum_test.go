@@ -0,0 +1,22 @@
+package sqs
+
+import (
+	"errors"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestVerifyMD5(c *C) {
+	// MD5 of "This is a test message", from the SQS documentation.
+	c.Assert(s.sqs.verifyMD5("id", "This is a test message", "fafb00f5732ab283681e124bf8747ed1"), IsNil)
+	c.Assert(s.sqs.verifyMD5("id", "anything", ""), IsNil)
+
+	err := s.sqs.verifyMD5("id", "This is a test message!", "fafb00f5732ab283681e124bf8747ed1")
+	var ce *ChecksumError
+	c.Assert(errors.As(err, &ce), Equals, true)
+	c.Assert(ce.MessageId, Equals, "id")
+	c.Assert(ce.Expected, Equals, "fafb00f5732ab283681e124bf8747ed1")
+
+	sqs := &SQS{DisableChecksums: true}
+	c.Assert(sqs.verifyMD5("id", "This is a test message!", "fafb00f5732ab283681e124bf8747ed1"), IsNil)
+}
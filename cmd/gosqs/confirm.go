@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/librato/gosqs"
+)
+
+func init() {
+	register(&command{
+		name: "purge",
+		args: "<queue>",
+		help: "Delete every message of a queue.\n\n" +
+			"Asks for the queue name to confirm, unless -yes is given.",
+		init: func(fs *flag.FlagSet) runFunc {
+			yes := fs.Bool("yes", false, "do not ask for confirmation")
+			return func(e *env, args []string) error {
+				return runDestructive(e, args, "purge", *yes, (*sqs.Queue).PurgeQueue)
+			}
+		},
+	})
+	register(&command{
+		name: "delete-queue",
+		args: "<queue>",
+		help: "Delete a queue and its messages.\n\n" +
+			"Asks for the queue name to confirm, unless -yes is given.",
+		init: func(fs *flag.FlagSet) runFunc {
+			yes := fs.Bool("yes", false, "do not ask for confirmation")
+			return func(e *env, args []string) error {
+				return runDestructive(e, args, "delete", *yes, (*sqs.Queue).DeleteQueue)
+			}
+		},
+	})
+}
+
+// errAborted is returned when the operator does not confirm.
+var errAborted = errors.New("aborted")
+
+// confirm asks the operator to confirm verb on q by typing its name,
+// showing its current depth, unless yes is set.
+func (e *env) confirm(q *sqs.Queue, verb string, yes bool) error {
+	if yes {
+		return nil
+	}
+	st, err := q.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stderr, "About to %s queue %s with %d visible, %d in flight and %d delayed messages.\n",
+		verb, q.Name(), st.Visible, st.InFlight, st.Delayed)
+	fmt.Fprintf(e.stderr, "Type the queue name to confirm: ")
+	line, err := bufio.NewReader(e.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if strings.TrimSpace(line) != q.Name() {
+		return errAborted
+	}
+	return nil
+}
+
+// resultJSON is the JSON schema of commands acting on one queue.
+type resultJSON struct {
+	Queue  string `json:"queue"`
+	Action string `json:"action"`
+}
+
+// runDestructive runs do on the queue named by args once confirmed.
+func runDestructive(e *env, args []string, verb string, yes bool, do func(q *sqs.Queue) error) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	q, err := e.queue(args[0])
+	if err != nil {
+		return err
+	}
+	if err := e.confirm(q, verb, yes); err != nil {
+		return err
+	}
+	if err := do(q); err != nil {
+		return err
+	}
+	return e.out.write(resultJSON{Queue: q.Name(), Action: verb}, func(w io.Writer) {
+		fmt.Fprintf(w, "%s: %sd\n", q.Name(), verb)
+	})
+}
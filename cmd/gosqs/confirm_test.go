@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/librato/gosqs"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestPurgeConfirmation(c *C) {
+	q, err := s.local.SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("one")
+	c.Assert(err, IsNil)
+
+	s.stdin = strings.NewReader("job\n")
+	status, _, stderr := s.gosqs("purge", "jobs")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Equals, "About to purge queue jobs with 1 visible, 0 in flight and 0 delayed messages.\n"+
+		"Type the queue name to confirm: gosqs purge: aborted\n")
+	attrs, err := q.GetQueueAttributes(sqs.All)
+	c.Assert(err, IsNil)
+	c.Assert(attrs.ApproximateNumberOfMessages(), Equals, 1)
+
+	s.stdin = strings.NewReader("jobs\n")
+	status, out, _ := s.gosqs("purge", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(out, Equals, "jobs: purged\n")
+}
+
+func (s *S) TestDeleteQueueYes(c *C) {
+	_, err := s.local.SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	status, out, _ := s.gosqs("delete-queue", "-yes", "-output", "json", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(out, Equals, "{\n  \"queue\": \"jobs\",\n  \"action\": \"delete\"\n}\n")
+	_, err = s.local.SQS().Queue("jobs")
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,180 @@
+// Command gosqs is a command-line client for Amazon SQS built on the sqs
+// package.
+//
+// Usage:
+//
+//	gosqs <command> [flags] [args]
+//
+// Every command accepts -region, -endpoint and -output; run
+// "gosqs help" for the list of commands. Credentials are read from
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+//
+// With -output json every command writes one JSON document whose schema
+// is stable across releases: fields are only ever added.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/librato/goamz-aws/aws"
+	"github.com/librato/gosqs"
+)
+
+// A command is a subcommand of gosqs.
+type command struct {
+	name string
+	args string
+	help string
+	// init defines the flags of the command on fs and returns the function
+	// running it. It is called for every invocation, so that flag values
+	// are not shared between them.
+	init func(fs *flag.FlagSet) runFunc
+}
+
+type runFunc func(e *env, args []string) error
+
+var commands []*command
+
+func register(c *command) {
+	commands = append(commands, c)
+	sort.Slice(commands, func(i, j int) bool { return commands[i].name < commands[j].name })
+}
+
+// env is the state shared by the commands of one invocation.
+type env struct {
+	ctx      context.Context // done on interrupt
+	region   string
+	endpoint string
+	out      *output
+	stdin    io.Reader
+	stderr   io.Writer
+
+	// client returns the SQS client; tests replace it.
+	client func(e *env) (*sqs.SQS, error)
+	sqs    *sqs.SQS
+}
+
+// newClient returns a client for the region and endpoint of e, with
+// credentials from the environment.
+func newClient(e *env) (*sqs.SQS, error) {
+	region, ok := aws.Regions[e.region]
+	if !ok {
+		return nil, fmt.Errorf("unknown region %q", e.region)
+	}
+	auth, err := aws.EnvAuth()
+	if err != nil {
+		return nil, err
+	}
+	c := sqs.New(auth, region)
+	c.Endpoint = e.endpoint
+	return c, nil
+}
+
+// lockoutWait is how long commands wait out the lockouts following a
+// purge or a queue deletion, which last a minute, instead of failing.
+const lockoutWait = 2 * time.Minute
+
+// SQS returns the client, creating it on first use.
+func (e *env) SQS() (*sqs.SQS, error) {
+	if e.sqs == nil {
+		c, err := e.client(e)
+		if err != nil {
+			return nil, err
+		}
+		c.LockoutWait = lockoutWait
+		e.sqs = c
+	}
+	return e.sqs, nil
+}
+
+// queue returns the named queue.
+func (e *env) queue(name string) (*sqs.Queue, error) {
+	c, err := e.SQS()
+	if err != nil {
+		return nil, err
+	}
+	return c.Queue(name)
+}
+
+// errUsage reports invalid arguments; the command's usage is printed.
+var errUsage = errors.New("invalid arguments")
+
+// run runs the command line args and returns the exit status.
+func run(args []string, e *env, stdout io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return 0
+	}
+	var cmd *command
+	for _, c := range commands {
+		if c.name == args[0] {
+			cmd = c
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(e.stderr, "gosqs: unknown command %q\n", args[0])
+		usage(e.stderr)
+		return 2
+	}
+	fs := flag.NewFlagSet("gosqs "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.StringVar(&e.region, "region", envOr("AWS_REGION", "us-east-1"), "AWS `region`")
+	fs.StringVar(&e.endpoint, "endpoint", os.Getenv("SQS_ENDPOINT"), "endpoint `URL` overriding the region's")
+	format := fs.String("output", "table", "output `format`: table or json")
+	runCmd := cmd.init(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: gosqs %s [flags] %s\n\n%s\n\n", cmd.name, cmd.args, cmd.help)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	out, err := newOutput(stdout, *format)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "gosqs: %s\n", err)
+		return 2
+	}
+	e.out = out
+	if err := runCmd(e, fs.Args()); err != nil {
+		if err == errUsage {
+			fs.Usage()
+			return 2
+		}
+		fmt.Fprintf(e.stderr, "gosqs %s: %s\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: gosqs <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, strings.SplitN(c.help, "\n", 2)[0])
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	e := &env{ctx: ctx, stdin: os.Stdin, stderr: os.Stderr, client: newClient}
+	status := run(os.Args[1:], e, os.Stdout)
+	stop()
+	os.Exit(status)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/librato/gosqs"
+	. "launchpad.net/gocheck"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type S struct {
+	local *sqs.Local
+	stdin io.Reader
+}
+
+var _ = Suite(&S{})
+
+func (s *S) SetUpTest(c *C) {
+	s.local = sqs.NewLocal()
+	s.stdin = strings.NewReader("")
+}
+
+// gosqs runs the command line args against the local backend and returns
+// its exit status, stdout and stderr.
+func (s *S) gosqs(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	e := &env{ctx: context.Background(), stdin: s.stdin, stderr: &stderr, client: func(*env) (*sqs.SQS, error) { return s.local.SQS(), nil }}
+	status := run(args, e, &stdout)
+	return status, stdout.String(), stderr.String()
+}
+
+func (s *S) TestQueues(c *C) {
+	for _, name := range []string{"jobs", "jobs-dlq", "mail"} {
+		_, err := s.local.SQS().CreateQueue(name, nil)
+		c.Assert(err, IsNil)
+	}
+	status, out, _ := s.gosqs("queues", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(out, Equals, ""+
+		"NAME      URL\n"+
+		"jobs      http://local.invalid/000000000000/jobs\n"+
+		"jobs-dlq  http://local.invalid/000000000000/jobs-dlq\n")
+
+	status, out, _ = s.gosqs("queues", "-output", "json", "mail")
+	c.Assert(status, Equals, 0)
+	var v struct {
+		Queues []queueJSON `json:"queues"`
+	}
+	c.Assert(json.Unmarshal([]byte(out), &v), IsNil)
+	c.Assert(v.Queues, DeepEquals, []queueJSON{{Name: "mail", URL: "http://local.invalid/000000000000/mail"}})
+}
+
+func (s *S) TestAttrsJSON(c *C) {
+	_, err := s.local.SQS().CreateQueue("jobs", &sqs.CreateQueueOpt{DelaySeconds: 5})
+	c.Assert(err, IsNil)
+	status, out, _ := s.gosqs("attrs", "--output=json", "jobs")
+	c.Assert(status, Equals, 0)
+	var v attrsJSON
+	c.Assert(json.Unmarshal([]byte(out), &v), IsNil)
+	c.Assert(v.Queue, Equals, "jobs")
+	c.Assert(v.Attributes["DelaySeconds"], Equals, "5")
+}
+
+func (s *S) TestLockoutWait(c *C) {
+	e := &env{client: func(*env) (*sqs.SQS, error) { return s.local.SQS(), nil }}
+	client, err := e.SQS()
+	c.Assert(err, IsNil)
+	c.Assert(client.LockoutWait, Equals, lockoutWait)
+}
+
+func (s *S) TestUsageErrors(c *C) {
+	status, _, stderr := s.gosqs("nope")
+	c.Assert(status, Equals, 2)
+	c.Assert(stderr, Matches, `gosqs: unknown command "nope"\n(.|\n)*`)
+
+	status, _, stderr = s.gosqs("attrs")
+	c.Assert(status, Equals, 2)
+	c.Assert(stderr, Matches, `usage: gosqs attrs \[flags\] <queue>(.|\n)*`)
+
+	status, _, stderr = s.gosqs("queues", "-output", "yaml")
+	c.Assert(status, Equals, 2)
+	c.Assert(stderr, Equals, "gosqs: unknown output format \"yaml\"\n")
+
+	status, _, stderr = s.gosqs("attrs", "missing")
+	c.Assert(status, Equals, 1)
+	c.Assert(stderr, Matches, "gosqs attrs: .*NonExistentQueue.*\n")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/librato/gosqs"
+)
+
+// messageJSON is the JSON schema of a message. Binary attributes are
+// base64-encoded.
+type messageJSON struct {
+	ID           string            `json:"id"`
+	Sent         *time.Time        `json:"sent,omitempty"`
+	ReceiveCount int               `json:"receive_count,omitempty"`
+	Body         string            `json:"body"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+func toMessageJSON(m *sqs.Message) messageJSON {
+	v := messageJSON{ID: m.Id, ReceiveCount: m.SystemAttributes.ApproximateReceiveCount, Body: m.Body}
+	if t := m.SystemAttributes.SentTimestamp; !t.IsZero() {
+		t = t.UTC()
+		v.Sent = &t
+	}
+	for name, a := range m.MessageAttributes {
+		if v.Attributes == nil {
+			v.Attributes = make(map[string]string)
+		}
+		if a.BinaryValue != nil {
+			v.Attributes[name] = base64.StdEncoding.EncodeToString(a.BinaryValue)
+		} else {
+			v.Attributes[name] = a.StringValue
+		}
+	}
+	return v
+}
+
+// printMessage writes m as one line, for commands streaming messages.
+func (e *env) printMessage(m *sqs.Message) error {
+	v := toMessageJSON(m)
+	return e.out.line(v, func(w io.Writer) {
+		sent := "-"
+		if v.Sent != nil {
+			sent = v.Sent.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s  %s  %s\n", sent, v.ID, v.Body)
+	})
+}
+
+// sleep waits for d or until e is interrupted, and reports whether it was
+// not.
+func (e *env) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-e.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// output writes the results of a command as a human-readable table or as
+// JSON.
+type output struct {
+	w    io.Writer
+	json bool
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case "table", "":
+		return &output{w: w}, nil
+	case "json":
+		return &output{w: w, json: true}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// write writes v, encoded as indented JSON, or calls table with a writer
+// whose tab-separated columns are aligned.
+func (o *output) write(v interface{}, table func(w io.Writer)) error {
+	if o.json {
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// line writes v as one line of compact JSON, or calls table to write one
+// line of text, for commands streaming their results.
+func (o *output) line(v interface{}, table func(w io.Writer)) error {
+	if o.json {
+		return json.NewEncoder(o.w).Encode(v)
+	}
+	table(o.w)
+	return nil
+}
+
+// frame writes one refresh of a live view: a line of compact JSON, or a
+// table, after clearing the terminal if clear is set.
+func (o *output) frame(v interface{}, clear bool, table func(w io.Writer)) error {
+	if o.json {
+		return o.line(v, nil)
+	}
+	if clear {
+		fmt.Fprint(o.w, "\033[H\033[2J")
+	}
+	return o.write(v, table)
+}
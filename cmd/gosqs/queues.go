@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/librato/gosqs"
+)
+
+func init() {
+	register(&command{
+		name: "queues",
+		args: "[prefix]",
+		help: "List the queues, optionally those whose name starts with prefix.",
+		init: func(*flag.FlagSet) runFunc { return runQueues },
+	})
+	register(&command{
+		name: "attrs",
+		args: "<queue>",
+		help: "Show the attributes of a queue.",
+		init: func(*flag.FlagSet) runFunc { return runAttrs },
+	})
+}
+
+// queueJSON is the JSON schema of a queue.
+type queueJSON struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func runQueues(e *env, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	var prefix string
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+	c, err := e.SQS()
+	if err != nil {
+		return err
+	}
+	queues, err := c.ListQueuesAll(prefix)
+	if err != nil {
+		return err
+	}
+	list := []queueJSON{}
+	for _, q := range queues {
+		list = append(list, queueJSON{Name: q.Name(), URL: q.URL()})
+	}
+	return e.out.write(map[string]interface{}{"queues": list}, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tURL")
+		for _, q := range list {
+			fmt.Fprintf(w, "%s\t%s\n", q.Name, q.URL)
+		}
+	})
+}
+
+// attrsJSON is the JSON schema of the attrs command.
+type attrsJSON struct {
+	Queue      string            `json:"queue"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+func runAttrs(e *env, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	q, err := e.queue(args[0])
+	if err != nil {
+		return err
+	}
+	resp, err := q.GetQueueAttributes(sqs.All)
+	if err != nil {
+		return err
+	}
+	v := attrsJSON{Queue: q.Name(), Attributes: make(map[string]string)}
+	var names []string
+	for _, a := range resp.Attributes {
+		v.Attributes[a.Name] = a.Value
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	return e.out.write(v, func(w io.Writer) {
+		fmt.Fprintln(w, "ATTRIBUTE\tVALUE")
+		for _, name := range names {
+			fmt.Fprintf(w, "%s\t%s\n", name, v.Attributes[name])
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/librato/gosqs"
+)
+
+func init() {
+	register(&command{
+		name: "receive",
+		args: "<queue>",
+		help: "Receive messages from a queue, printing then deleting them.\n\n" +
+			"Stops after -n messages, or once a receive finds the queue empty.\n" +
+			"With -keep, messages are not deleted but hidden for their visibility\n" +
+			"timeout. With -output json, every message is one JSON document per line.\n\n" +
+			"Deleting with -n 0 empties the queue, so it asks for the queue name to\n" +
+			"confirm, unless -yes is given.",
+		init: func(fs *flag.FlagSet) runFunc {
+			var count, wait int
+			var keep, yes bool
+			fs.IntVar(&count, "n", 1, "receive at most `count` messages; 0 receives until the queue is empty")
+			fs.IntVar(&wait, "wait", 0, "wait up to `seconds` for messages, at most 20")
+			fs.BoolVar(&keep, "keep", false, "do not delete the messages")
+			fs.BoolVar(&yes, "yes", false, "do not ask for confirmation")
+			return func(e *env, args []string) error {
+				if len(args) != 1 || count < 0 {
+					return errUsage
+				}
+				return runReceive(e, args[0], count, wait, keep, yes)
+			}
+		},
+	})
+	register(&command{
+		name: "peek",
+		args: "<queue>",
+		help: "Show messages of a queue without consuming them.\n\n" +
+			"Messages are received with a zero visibility timeout, which increments\n" +
+			"their receive count and can move them to a dead letter queue.",
+		init: func(fs *flag.FlagSet) runFunc {
+			var count int
+			fs.IntVar(&count, "n", 10, "show at most `count` messages")
+			return func(e *env, args []string) error {
+				if len(args) != 1 || count <= 0 {
+					return errUsage
+				}
+				return runPeek(e, args[0], count)
+			}
+		},
+	})
+}
+
+func runReceive(e *env, name string, count, wait int, keep, yes bool) error {
+	q, err := e.queue(name)
+	if err != nil {
+		return err
+	}
+	if count == 0 && !keep {
+		if err := e.confirm(q, "drain", yes); err != nil {
+			return err
+		}
+	}
+	q = q.WithContext(e.ctx)
+	received := 0
+	for count == 0 || received < count {
+		n := sqs.MaxBatchSize
+		if count > 0 && count-received < n {
+			n = count - received
+		}
+		msgs, err := q.ReceiveMessages(&sqs.ReceiveMessageOpt{
+			MaxNumberOfMessages:   n,
+			WaitTimeSeconds:       wait,
+			MessageAttributeNames: []string{"All"},
+			AttributeNames:        []sqs.Attribute{sqs.All},
+		})
+		var re *sqs.ReceiveError
+		if errors.As(err, &re) {
+			// Report the messages that could not be decoded, and carry
+			// on with the others.
+			for _, f := range re.Failed {
+				fmt.Fprintf(e.stderr, "gosqs receive: %s\n", f)
+			}
+		} else if err != nil {
+			if e.ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+		for _, m := range msgs {
+			if err := e.printMessage(m); err != nil {
+				return err
+			}
+		}
+		received += len(msgs)
+		if keep {
+			continue
+		}
+		res, err := q.DeleteMessageBatch(msgs)
+		if err != nil {
+			return err
+		}
+		if err := res.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peekJSON is the JSON schema of the peek command.
+type peekJSON struct {
+	Queue    string        `json:"queue"`
+	Messages []messageJSON `json:"messages"`
+}
+
+func runPeek(e *env, name string, count int) error {
+	q, err := e.queue(name)
+	if err != nil {
+		return err
+	}
+	msgs, err := q.WithContext(e.ctx).Peek(count)
+	if err != nil {
+		return err
+	}
+	v := peekJSON{Queue: q.Name(), Messages: []messageJSON{}}
+	for _, m := range msgs {
+		v.Messages = append(v.Messages, toMessageJSON(m))
+	}
+	return e.out.write(v, func(w io.Writer) {
+		fmt.Fprintln(w, "SENT\tID\tRECEIVES\tBODY")
+		for _, m := range v.Messages {
+			sent := "-"
+			if m.Sent != nil {
+				sent = m.Sent.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", sent, m.ID, m.ReceiveCount, m.Body)
+		}
+	})
+}
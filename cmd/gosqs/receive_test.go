@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/librato/gosqs"
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestReceive(c *C) {
+	q, err := s.local.SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	for _, body := range []string{"one", "two", "three"} {
+		_, err = q.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+	status, out, _ := s.gosqs("receive", "-keep", "-n", "1", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(out, Matches, `\S+  \S+  one\n`)
+
+	// Draining the queue is confirmed.
+	s.stdin = strings.NewReader("\n")
+	status, out, stderr := s.gosqs("receive", "-n", "0", "jobs")
+	c.Assert(status, Equals, 1)
+	c.Assert(out, Equals, "")
+	c.Assert(stderr, Equals, "About to drain queue jobs with 2 visible, 1 in flight and 0 delayed messages.\n"+
+		"Type the queue name to confirm: gosqs receive: aborted\n")
+
+	s.stdin = strings.NewReader("jobs\n")
+	status, out, _ = s.gosqs("receive", "-output", "json", "-n", "0", "jobs")
+	c.Assert(status, Equals, 0)
+	var bodies []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var m messageJSON
+		c.Assert(json.Unmarshal([]byte(line), &m), IsNil)
+		bodies = append(bodies, m.Body)
+	}
+	c.Assert(bodies, DeepEquals, []string{"two", "three"})
+
+	// The kept message stays in flight until its visibility timeout expires.
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st, DeepEquals, sqs.QueueStats{InFlight: 1})
+}
+
+func (s *S) TestPeek(c *C) {
+	q, err := s.local.SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("one")
+	c.Assert(err, IsNil)
+	status, out, _ := s.gosqs("peek", "-output", "json", "jobs")
+	c.Assert(status, Equals, 0)
+	var v peekJSON
+	c.Assert(json.Unmarshal([]byte(out), &v), IsNil)
+	c.Assert(v.Queue, Equals, "jobs")
+	c.Assert(v.Messages, HasLen, 1)
+	c.Assert(v.Messages[0].Body, Equals, "one")
+
+	status, out, _ = s.gosqs("peek", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(out, Matches, `SENT +ID +RECEIVES +BODY\n\S+ +\S+ +\d+ +one\n`)
+}
+
+func (s *S) TestReceiveYes(c *C) {
+	q, err := s.local.SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("one")
+	c.Assert(err, IsNil)
+	status, out, stderr := s.gosqs("receive", "-yes", "-n", "0", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(stderr, Equals, "")
+	c.Assert(out, Matches, `\S+  \S+  one\n`)
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Empty(), Equals, true)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/librato/gosqs"
+)
+
+func init() {
+	register(&command{
+		name: "redrive",
+		args: "<dlq> [queue]",
+		help: "Move the messages of a dead letter queue back to a queue.\n\n" +
+			"The queue defaults to the only source queue of the dead letter queue.\n" +
+			"Moved messages are annotated with -by, -reason and -ticket.",
+		init: func(fs *flag.FlagSet) runFunc {
+			opt := &sqs.RedriveOpt{}
+			var attrs attrFilter
+			fs.IntVar(&opt.Max, "max", 0, "move at most `count` messages; 0 moves all")
+			fs.Float64Var(&opt.Rate, "rate", 0, "move at most `n` messages per second; 0 does not limit")
+			fs.Var(&attrs, "attr", "move only messages whose attribute matches `name=value`; repeatable")
+			fs.BoolVar(&opt.DryRun, "dry-run", false, "count the messages that would be moved without moving them")
+			fs.StringVar(&opt.Annotation.By, "by", os.Getenv("USER"), "operator running the redrive")
+			fs.StringVar(&opt.Annotation.Reason, "reason", "", "why the messages are redriven")
+			fs.StringVar(&opt.Annotation.Ticket, "ticket", "", "ticket tracking the redrive")
+			return func(e *env, args []string) error {
+				if len(args) < 1 || len(args) > 2 {
+					return errUsage
+				}
+				if len(attrs) > 0 {
+					opt.Filter = attrs.match
+				}
+				return runRedrive(e, args, opt)
+			}
+		},
+	})
+}
+
+// attrFilter is a repeatable -attr flag of name=value pairs; messages
+// match if every named string attribute has its value. The send command
+// uses it to set the attributes of the messages it sends.
+type attrFilter map[string]string
+
+func (f *attrFilter) String() string {
+	var s []string
+	for name, v := range *f {
+		s = append(s, name+"="+v)
+	}
+	return strings.Join(s, ",")
+}
+
+func (f *attrFilter) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("want name=value, got %q", s)
+	}
+	if *f == nil {
+		*f = make(attrFilter)
+	}
+	(*f)[s[:i]] = s[i+1:]
+	return nil
+}
+
+func (f attrFilter) match(m *sqs.Message) bool {
+	for name, v := range f {
+		if a, ok := m.MessageAttributes[name]; !ok || a.StringValue != v {
+			return false
+		}
+	}
+	return true
+}
+
+// redriveJSON is the JSON schema of the redrive command.
+type redriveJSON struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Moved   int    `json:"moved"`
+	Skipped int    `json:"skipped"`
+	DryRun  bool   `json:"dry_run"`
+}
+
+func runRedrive(e *env, args []string, opt *sqs.RedriveOpt) error {
+	dlq, err := e.queue(args[0])
+	if err != nil {
+		return err
+	}
+	var dst *sqs.Queue
+	if len(args) == 2 {
+		dst, err = e.queue(args[1])
+	} else {
+		dst, err = sourceQueue(dlq)
+	}
+	if err != nil {
+		return err
+	}
+	res, err := dlq.Redrive(e.ctx, dst, opt)
+	if res == nil {
+		return err
+	}
+	v := redriveJSON{From: dlq.Name(), To: dst.Name(), Moved: res.Moved, Skipped: res.Skipped, DryRun: opt.DryRun}
+	werr := e.out.write(v, func(w io.Writer) {
+		verb := "moved"
+		if opt.DryRun {
+			verb = "would move"
+		}
+		fmt.Fprintf(w, "%s %d messages from %s to %s, skipped %d\n", verb, v.Moved, v.From, v.To, v.Skipped)
+	})
+	if err != nil {
+		return err
+	}
+	return werr
+}
+
+// sourceQueue returns the only queue using dlq as its dead letter queue.
+func sourceQueue(dlq *sqs.Queue) (*sqs.Queue, error) {
+	sources, err := dlq.ListDeadLetterSourceQueues()
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) != 1 {
+		return nil, fmt.Errorf("%s is the dead letter queue of %d queues; name the queue to redrive to", dlq.Name(), len(sources))
+	}
+	return sources[0], nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/librato/gosqs"
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestRedrive(c *C) {
+	client := s.local.SQS()
+	dlq, err := client.CreateQueue("jobs-dlq", nil)
+	c.Assert(err, IsNil)
+	_, err = client.CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 4; i++ {
+		_, err := dlq.SendMessageWithOpt(fmt.Sprint(i), &sqs.SendMessageOpt{
+			MessageAttributes: sqs.MessageAttributes{"tenant": {DataType: "String", StringValue: fmt.Sprint(i % 2)}},
+		})
+		c.Assert(err, IsNil)
+	}
+
+	status, out, _ := s.gosqs("redrive", "-dry-run", "-attr", "tenant=1", "jobs-dlq", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(out, Equals, "would move 2 messages from jobs-dlq to jobs, skipped 2\n")
+
+	status, out, _ = s.gosqs("redrive", "-output", "json", "-max", "3", "-rate", "1000", "-reason", "fixed", "jobs-dlq", "jobs")
+	c.Assert(status, Equals, 0)
+	var v redriveJSON
+	c.Assert(json.Unmarshal([]byte(out), &v), IsNil)
+	c.Assert(v, Equals, redriveJSON{From: "jobs-dlq", To: "jobs", Moved: 3})
+
+	status, _, _ = s.gosqs("redrive", "-attr", "tenant", "jobs-dlq", "jobs")
+	c.Assert(status, Equals, 2)
+}
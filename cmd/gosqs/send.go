@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/librato/gosqs"
+)
+
+func init() {
+	register(&command{
+		name: "send",
+		args: "<queue> [body...]",
+		help: "Send messages to a queue.\n\n" +
+			"Every body argument is sent as one message; without any, every line\n" +
+			"read from the standard input is.",
+		init: func(fs *flag.FlagSet) runFunc {
+			opt := &sqs.SendMessageOpt{}
+			var attrs attrFilter
+			fs.IntVar(&opt.DelaySeconds, "delay", 0, "delay the delivery of the messages by `seconds`")
+			fs.Var(&attrs, "attr", "set the string attribute `name=value`; repeatable")
+			fs.StringVar(&opt.MessageGroupId, "group", "", "message group `id`, for FIFO queues")
+			fs.StringVar(&opt.MessageDeduplicationId, "dedup", "", "deduplication `id`, for FIFO queues with one message")
+			return func(e *env, args []string) error {
+				if len(args) < 1 {
+					return errUsage
+				}
+				for name, v := range attrs {
+					if opt.MessageAttributes == nil {
+						opt.MessageAttributes = make(sqs.MessageAttributes)
+					}
+					opt.MessageAttributes[name] = sqs.MessageAttributeValue{DataType: "String", StringValue: v}
+				}
+				return runSend(e, args[0], args[1:], opt)
+			}
+		},
+	})
+}
+
+// sendJSON is the JSON schema of the send command.
+type sendJSON struct {
+	Queue string   `json:"queue"`
+	IDs   []string `json:"ids"`
+}
+
+func runSend(e *env, name string, bodies []string, opt *sqs.SendMessageOpt) error {
+	q, err := e.queue(name)
+	if err != nil {
+		return err
+	}
+	q = q.WithContext(e.ctx)
+	v := sendJSON{Queue: q.Name(), IDs: []string{}}
+	send := func(body string) error {
+		id, err := q.SendMessageWithOpt(body, opt)
+		if err != nil {
+			return err
+		}
+		v.IDs = append(v.IDs, id)
+		return nil
+	}
+	if len(bodies) > 0 {
+		for _, body := range bodies {
+			if err := send(body); err != nil {
+				return err
+			}
+		}
+	} else {
+		sc := bufio.NewScanner(e.stdin)
+		sc.Buffer(nil, sqs.DefaultMaxBodySize+1)
+		for sc.Scan() {
+			if err := send(sc.Text()); err != nil {
+				return err
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+	}
+	return e.out.write(v, func(w io.Writer) {
+		fmt.Fprintln(w, "ID")
+		for _, id := range v.IDs {
+			fmt.Fprintln(w, id)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/librato/gosqs"
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestSend(c *C) {
+	q, err := s.local.SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	status, out, _ := s.gosqs("send", "-output", "json", "-attr", "kind=order", "jobs", "one", "two")
+	c.Assert(status, Equals, 0)
+	var v sendJSON
+	c.Assert(json.Unmarshal([]byte(out), &v), IsNil)
+	c.Assert(v.Queue, Equals, "jobs")
+	c.Assert(v.IDs, HasLen, 2)
+
+	s.stdin = strings.NewReader("three\nfour\n")
+	status, out, _ = s.gosqs("send", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(strings.Split(strings.TrimSpace(out), "\n"), HasLen, 3)
+
+	msgs, err := q.ReceiveMessages(&sqs.ReceiveMessageOpt{MaxNumberOfMessages: 10, MessageAttributeNames: []string{"All"}})
+	c.Assert(err, IsNil)
+	var bodies []string
+	for _, m := range msgs {
+		bodies = append(bodies, m.Body)
+		if m.Body == "one" {
+			c.Assert(m.MessageAttributes["kind"].StringValue, Equals, "order")
+		}
+	}
+	c.Assert(bodies, DeepEquals, []string{"one", "two", "three", "four"})
+
+	status, _, stderr := s.gosqs("send")
+	c.Assert(status, Equals, 2)
+	c.Assert(stderr, Matches, `usage: gosqs send \[flags\] <queue> \[body...\](.|\n)*`)
+}
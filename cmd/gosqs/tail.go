@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+func init() {
+	register(&command{
+		name: "tail",
+		args: "<queue>",
+		help: "Print the new messages of a queue as they arrive, without consuming them.\n\n" +
+			"Messages are peeked: received with a zero visibility timeout, which\n" +
+			"increments their receive count and can move them to a dead letter queue.\n" +
+			"With -output json, every message is one JSON document per line.",
+		init: func(fs *flag.FlagSet) runFunc {
+			var interval time.Duration
+			var count int
+			fs.DurationVar(&interval, "interval", 2*time.Second, "time between peeks")
+			fs.IntVar(&count, "n", 0, "exit after `count` messages; 0 tails until interrupted")
+			return func(e *env, args []string) error {
+				if len(args) != 1 {
+					return errUsage
+				}
+				return runTail(e, args[0], interval, count)
+			}
+		},
+	})
+}
+
+// maxSeen bounds the message IDs tail remembers to recognize messages it
+// printed already.
+const maxSeen = 10000
+
+func runTail(e *env, name string, interval time.Duration, count int) error {
+	q, err := e.queue(name)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	var order []string
+	printed := 0
+	for {
+		msgs, err := q.Peek(10)
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if seen[m.Id] {
+				continue
+			}
+			seen[m.Id] = true
+			if order = append(order, m.Id); len(order) > maxSeen {
+				delete(seen, order[0])
+				order = order[1:]
+			}
+			if err := e.printMessage(m); err != nil {
+				return err
+			}
+			if printed++; printed == count {
+				return nil
+			}
+		}
+		if !e.sleep(interval) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestTail(c *C) {
+	q, err := s.local.SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	for _, body := range []string{"one", "two"} {
+		_, err = q.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+	status, out, _ := s.gosqs("tail", "-output", "json", "-n", "2", "-interval", "1ms", "jobs")
+	c.Assert(status, Equals, 0)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	c.Assert(lines, HasLen, 2)
+	var bodies []string
+	for _, line := range lines {
+		var m messageJSON
+		c.Assert(json.Unmarshal([]byte(line), &m), IsNil)
+		c.Assert(m.Sent, NotNil)
+		bodies = append(bodies, m.Body)
+	}
+	c.Assert(bodies, DeepEquals, []string{"one", "two"})
+
+	// The messages were peeked, not consumed.
+	status, out, _ = s.gosqs("tail", "-n", "1", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(out, Matches, `\S+  \S+  one\n`)
+}
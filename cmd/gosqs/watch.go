@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/librato/gosqs"
+)
+
+func init() {
+	register(&command{
+		name: "watch",
+		args: "[queue...]",
+		help: "Show the depth of queues, refreshed live like top(1).\n\n" +
+			"The queues are those named, or those whose name starts with -prefix.\n" +
+			"-age estimates the age of the oldest message by peeking at the queue,\n" +
+			"which increments the receive count of the messages it sees.\n" +
+			"With -output json, every refresh is one JSON document per line.",
+		init: func(fs *flag.FlagSet) runFunc {
+			var interval time.Duration
+			var count int
+			var prefix string
+			var age bool
+			fs.DurationVar(&interval, "interval", 2*time.Second, "time between refreshes")
+			fs.IntVar(&count, "n", 0, "exit after `count` refreshes; 0 refreshes until interrupted")
+			fs.StringVar(&prefix, "prefix", "", "watch the queues whose name starts with `prefix`")
+			fs.BoolVar(&age, "age", false, "show the age of the oldest message")
+			return func(e *env, args []string) error {
+				if len(args) == 0 && prefix == "" {
+					return errUsage
+				}
+				return runWatch(e, args, prefix, interval, count, age)
+			}
+		},
+	})
+}
+
+// queueStatsJSON is the JSON schema of the depth of a queue.
+type queueStatsJSON struct {
+	Name     string `json:"name"`
+	Visible  int    `json:"visible"`
+	InFlight int    `json:"in_flight"`
+	Delayed  int    `json:"delayed"`
+	// Change is the change of Visible since the previous refresh.
+	Change int `json:"change"`
+	// OldestAge is the age of the oldest message peeked, in seconds, with
+	// -age.
+	OldestAge *float64 `json:"oldest_age,omitempty"`
+}
+
+// watchJSON is the JSON schema of one refresh of watch.
+type watchJSON struct {
+	Time   time.Time        `json:"time"`
+	Queues []queueStatsJSON `json:"queues"`
+}
+
+func runWatch(e *env, names []string, prefix string, interval time.Duration, count int, age bool) error {
+	var queues []*sqs.Queue
+	for _, name := range names {
+		q, err := e.queue(name)
+		if err != nil {
+			return err
+		}
+		queues = append(queues, q)
+	}
+	last := make(map[string]int)
+	for i := 0; count == 0 || i < count; i++ {
+		if prefix != "" {
+			c, err := e.SQS()
+			if err != nil {
+				return err
+			}
+			listed, err := c.ListQueuesAll(prefix)
+			if err != nil {
+				return err
+			}
+			queues = append(queues[:len(names):len(names)], listed...)
+		}
+		v := watchJSON{Time: time.Now().UTC(), Queues: []queueStatsJSON{}}
+		for _, q := range queues {
+			st, err := queueStats(q, age)
+			if err != nil {
+				return err
+			}
+			if prev, ok := last[st.Name]; ok {
+				st.Change = st.Visible - prev
+			}
+			last[st.Name] = st.Visible
+			v.Queues = append(v.Queues, st)
+		}
+		if err := e.out.frame(v, count != 1, func(w io.Writer) { watchTable(w, &v) }); err != nil {
+			return err
+		}
+		if i+1 == count || !e.sleep(interval) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func queueStats(q *sqs.Queue, age bool) (queueStatsJSON, error) {
+	st := queueStatsJSON{Name: q.Name()}
+	stats, err := q.Stats()
+	if err != nil {
+		return st, err
+	}
+	st.Visible, st.InFlight, st.Delayed = stats.Visible, stats.InFlight, stats.Delayed
+	if !age || st.Visible == 0 {
+		return st, nil
+	}
+	msgs, err := q.Peek(10)
+	if err != nil {
+		return st, err
+	}
+	var oldest time.Time
+	for _, m := range msgs {
+		if t := m.SystemAttributes.SentTimestamp; !t.IsZero() && (oldest.IsZero() || t.Before(oldest)) {
+			oldest = t
+		}
+	}
+	if !oldest.IsZero() {
+		secs := time.Since(oldest).Seconds()
+		st.OldestAge = &secs
+	}
+	return st, nil
+}
+
+func watchTable(w io.Writer, v *watchJSON) {
+	fmt.Fprintf(w, "%s\n\n", v.Time.Format(time.RFC3339))
+	fmt.Fprintln(w, "QUEUE\tVISIBLE\tCHANGE\tIN FLIGHT\tDELAYED\tOLDEST")
+	for _, st := range v.Queues {
+		oldest := "-"
+		if st.OldestAge != nil {
+			oldest = (time.Duration(*st.OldestAge) * time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%+d\t%d\t%d\t%s\n", st.Name, st.Visible, st.Change, st.InFlight, st.Delayed, oldest)
+	}
+}
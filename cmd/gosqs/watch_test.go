@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestWatch(c *C) {
+	for _, name := range []string{"jobs", "jobs-dlq"} {
+		_, err := s.local.SQS().CreateQueue(name, nil)
+		c.Assert(err, IsNil)
+	}
+	q, err := s.local.SQS().Queue("jobs")
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("one")
+	c.Assert(err, IsNil)
+
+	status, out, _ := s.gosqs("watch", "-output", "json", "-n", "2", "-interval", "1ms", "-age", "-prefix", "jobs")
+	c.Assert(status, Equals, 0)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	c.Assert(lines, HasLen, 2)
+	var v watchJSON
+	c.Assert(json.Unmarshal([]byte(lines[1]), &v), IsNil)
+	c.Assert(v.Queues, HasLen, 2)
+	c.Assert(v.Queues[0].Name, Equals, "jobs")
+	c.Assert(v.Queues[0].Visible, Equals, 1)
+	c.Assert(v.Queues[0].OldestAge, NotNil)
+	c.Assert(v.Queues[1].OldestAge, IsNil)
+
+	status, out, _ = s.gosqs("watch", "-n", "1", "jobs")
+	c.Assert(status, Equals, 0)
+	c.Assert(out, Matches, `(?s)\S+\n\nQUEUE +VISIBLE +CHANGE +IN FLIGHT +DELAYED +OLDEST\njobs +1 +\+0 +0 +0 +-\n`)
+}
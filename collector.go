@@ -0,0 +1,124 @@
+package sqs
+
+import (
+	"context"
+	"time"
+)
+
+// A BatchHandler processes a batch of messages. Returning nil acknowledges
+// the whole batch; returning an error leaves every message of the batch to
+// be redelivered once its visibility timeout expires.
+type BatchHandler interface {
+	HandleBatch(msgs []*Message) error
+}
+
+// The BatchHandlerFunc type is an adapter to allow the use of an ordinary
+// function as a BatchHandler.
+type BatchHandlerFunc func(msgs []*Message) error
+
+// HandleBatch calls f(msgs).
+func (f BatchHandlerFunc) HandleBatch(msgs []*Message) error {
+	return f(msgs)
+}
+
+// A Collector accumulates messages across receives until it holds
+// BatchSize of them, or MaxWait has passed since the first message of the
+// batch arrived, and then hands them to Handler in one call. It suits sinks
+// such as bulk warehouse loads that strongly prefer large batches.
+//
+// Messages stay invisible to other consumers only for their visibility
+// timeout, so VisibilityTimeout should comfortably exceed MaxWait plus the
+// time Handler takes.
+type Collector struct {
+	Queue     *Queue
+	Handler   BatchHandler
+	BatchSize int
+	MaxWait   time.Duration
+
+	// VisibilityTimeout, in seconds, overrides the queue default for the
+	// collected messages when positive.
+	VisibilityTimeout int
+	// OnError, if set, is called with receive, handler and delete errors.
+	OnError func(err error)
+}
+
+func (c *Collector) onError(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+// Run collects and delivers batches until ctx is done. The batch pending at
+// that point is delivered before Run returns.
+func (c *Collector) Run(ctx context.Context) error {
+	size := c.BatchSize
+	if size <= 0 {
+		size = 10
+	}
+	var (
+		batch    []*Message
+		deadline time.Time
+		b        backoff
+	)
+	for ctx.Err() == nil {
+		opt := &ReceiveMessageOpt{
+			MaxNumberOfMessages: size - len(batch),
+			VisibilityTimeout:   c.VisibilityTimeout,
+			WaitTimeSeconds:     20,
+		}
+		if opt.MaxNumberOfMessages > 10 {
+			opt.MaxNumberOfMessages = 10
+		}
+		pollCtx, cancel := ctx, context.CancelFunc(func() {})
+		if len(batch) > 0 {
+			// Long poll for the time left, rounded up to whole seconds,
+			// but no later than the deadline.
+			left := time.Until(deadline)
+			if left > 20*time.Second {
+				left = 20 * time.Second
+			}
+			opt.WaitTimeSeconds = int((left + time.Second - 1) / time.Second)
+			pollCtx, cancel = context.WithDeadline(ctx, deadline)
+		}
+
+		msgs := receive(pollCtx, c.Queue, opt, &b, c.OnError)
+		cancel()
+		if len(batch) == 0 && len(msgs) > 0 {
+			deadline = time.Now().Add(c.MaxWait)
+		}
+		batch = append(batch, msgs...)
+		if len(batch) >= size || (len(batch) > 0 && !time.Now().Before(deadline)) {
+			c.deliver(batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		c.deliver(batch)
+	}
+	return ctx.Err()
+}
+
+// deliver hands batch to the handler and acknowledges it on success, in
+// batches of MaxBatchSize.
+func (c *Collector) deliver(batch []*Message) {
+	if err := c.Handler.HandleBatch(batch); err != nil {
+		c.onError(err)
+		return
+	}
+	for len(batch) > 0 {
+		n := len(batch)
+		if n > MaxBatchSize {
+			n = MaxBatchSize
+		}
+		res, err := c.Queue.DeleteMessageBatch(batch[:n])
+		if err != nil {
+			c.onError(err)
+		}
+		if res != nil {
+			for i := range res.Failed {
+				c.onError(&res.Failed[i])
+			}
+		}
+		batch = batch[n:]
+	}
+}
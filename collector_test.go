@@ -0,0 +1,163 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+// queueCounts returns the approximate numbers of visible and in-flight
+// messages of q.
+func queueCounts(c *C, q *Queue) (visible, inFlight string) {
+	attrs, err := q.GetQueueAttributes(ApproximateNumberOfMessages, ApproximateNumberOfMessagesNotVisible)
+	c.Assert(err, IsNil)
+	visible, _ = attrs.get(ApproximateNumberOfMessages)
+	inFlight, _ = attrs.get(ApproximateNumberOfMessagesNotVisible)
+	return visible, inFlight
+}
+
+func sendBodies(c *C, q *Queue, bodies ...string) {
+	for _, body := range bodies {
+		_, err := q.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+}
+
+func (s *S) TestCollector(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("collect", nil)
+	c.Assert(err, IsNil)
+	sendBodies(c, q, "1", "2", "3", "4", "5", "6", "7")
+
+	// Full batches are delivered at once, the remainder after MaxWait.
+	ctx, cancel := context.WithCancel(context.Background())
+	var batches [][]string
+	total := 0
+	col := &Collector{
+		Queue:     q,
+		BatchSize: 3,
+		MaxWait:   50 * time.Millisecond,
+		Handler: BatchHandlerFunc(func(msgs []*Message) error {
+			var bodies []string
+			for _, m := range msgs {
+				bodies = append(bodies, m.Body)
+			}
+			batches = append(batches, bodies)
+			if total += len(msgs); total == 7 {
+				cancel()
+			}
+			return nil
+		}),
+	}
+	c.Assert(col.Run(ctx), Equals, context.Canceled)
+	c.Assert(batches, DeepEquals, [][]string{{"1", "2", "3"}, {"4", "5", "6"}, {"7"}})
+	visible, inFlight := queueCounts(c, q)
+	c.Assert(visible+" "+inFlight, Equals, "0 0")
+}
+
+func (s *S) TestCollectorPendingOnCancel(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("collect", nil)
+	c.Assert(err, IsNil)
+	sendBodies(c, q, "1", "2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			if _, inFlight := queueCounts(c, q); inFlight == "2" {
+				cancel()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	var delivered []int
+	col := &Collector{
+		Queue:     q,
+		BatchSize: 10,
+		MaxWait:   time.Minute,
+		Handler: BatchHandlerFunc(func(msgs []*Message) error {
+			delivered = append(delivered, len(msgs))
+			return nil
+		}),
+	}
+	c.Assert(col.Run(ctx), Equals, context.Canceled)
+	c.Assert(delivered, DeepEquals, []int{2})
+}
+
+func (s *S) TestCollectorHandlerError(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("collect", nil)
+	c.Assert(err, IsNil)
+	sendBodies(c, q, "1", "2")
+
+	// A failed batch is left for redelivery.
+	ctx, cancel := context.WithCancel(context.Background())
+	var errs []error
+	col := &Collector{
+		Queue:     q,
+		BatchSize: 2,
+		Handler: BatchHandlerFunc(func(msgs []*Message) error {
+			cancel()
+			return errors.New("warehouse down")
+		}),
+		OnError: func(err error) { errs = append(errs, err) },
+	}
+	c.Assert(col.Run(ctx), Equals, context.Canceled)
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errs[0], ErrorMatches, "warehouse down")
+	visible, inFlight := queueCounts(c, q)
+	c.Assert(visible+" "+inFlight, Equals, "0 2")
+}
+
+func (s *S) TestCollectorMaxWait(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("collect", nil)
+	c.Assert(err, IsNil)
+	sendBodies(c, q, "1", "2")
+
+	// A partial batch is delivered once MaxWait elapsed, not later.
+	const maxWait = 350 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	var delivered []int
+	start := time.Now()
+	col := &Collector{
+		Queue:     q,
+		BatchSize: 25,
+		MaxWait:   maxWait,
+		Handler: BatchHandlerFunc(func(msgs []*Message) error {
+			c.Check(time.Since(start) < maxWait+100*time.Millisecond, Equals, true, Commentf("delivered after %s", time.Since(start)))
+			delivered = append(delivered, len(msgs))
+			cancel()
+			return nil
+		}),
+	}
+	c.Assert(col.Run(ctx), Equals, context.Canceled)
+	c.Assert(delivered, DeepEquals, []int{2})
+}
+
+func (s *S) TestCollectorLargeBatch(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("collect", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 25; i++ {
+		sendBodies(c, q, fmt.Sprint(i))
+	}
+
+	// Batches larger than MaxBatchSize are deleted in chunks.
+	ctx, cancel := context.WithCancel(context.Background())
+	var errs []error
+	col := &Collector{
+		Queue:     q,
+		BatchSize: 25,
+		MaxWait:   time.Minute,
+		Handler: BatchHandlerFunc(func(msgs []*Message) error {
+			c.Check(msgs, HasLen, 25)
+			cancel()
+			return nil
+		}),
+		OnError: func(err error) { errs = append(errs, err) },
+	}
+	c.Assert(col.Run(ctx), Equals, context.Canceled)
+	c.Assert(errs, HasLen, 0)
+	visible, inFlight := queueCounts(c, q)
+	c.Assert(visible+" "+inFlight, Equals, "0 0")
+}
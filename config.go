@@ -0,0 +1,237 @@
+package sqs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// readOnlyAttributes are the queue attributes maintained by SQS, which a
+// QueueConfig leaves out.
+var readOnlyAttributes = map[Attribute]bool{
+	ApproximateNumberOfMessages:           true,
+	ApproximateNumberOfMessagesNotVisible: true,
+	ApproximateNumberOfMessagesDelayed:    true,
+	CreatedTimestamp:                      true,
+	LastModifiedTimestamp:                 true,
+	QueueArn:                              true,
+}
+
+// A QueueConfig is the declarative configuration of a queue: its settable
+// attributes, access policy and tags. It is stored as JSON so it can be
+// kept under version control and applied with ImportQueueConfig.
+//
+// Tags, when not nil, are the exact tags of the queue, so an empty map
+// removes them all; nil Tags leave the tags of the queue alone.
+type QueueConfig struct {
+	Name       string               `json:"name"`
+	Attributes map[Attribute]string `json:"attributes,omitempty"`
+	Policy     json.RawMessage      `json:"policy,omitempty"`
+	Tags       map[string]string    `json:"tags"`
+}
+
+// ReadQueueConfig decodes a configuration written by QueueConfig.Write.
+func ReadQueueConfig(r io.Reader) (*QueueConfig, error) {
+	var c QueueConfig
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Write encodes c to w as JSON.
+func (c *QueueConfig) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// attributes returns every attribute c sets, including the policy.
+func (c *QueueConfig) attributes() (map[Attribute]string, error) {
+	attrs := make(map[Attribute]string, len(c.Attributes)+1)
+	for name, v := range c.Attributes {
+		attrs[name] = v
+	}
+	if len(c.Policy) > 0 {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, c.Policy); err != nil {
+			return nil, fmt.Errorf("sqs: invalid policy in queue config: %s", err)
+		}
+		attrs[Policy] = buf.String()
+	}
+	return attrs, nil
+}
+
+// ExportQueueConfig returns the configuration of the queue.
+func (q *Queue) ExportQueueConfig() (*QueueConfig, error) {
+	attrs, err := q.GetQueueAttributes(All)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := q.ListQueueTags()
+	if err != nil {
+		return nil, err
+	}
+	c := &QueueConfig{Name: q.Name(), Attributes: make(map[Attribute]string)}
+	for _, a := range attrs.Attributes {
+		name := Attribute(a.Name)
+		switch {
+		case readOnlyAttributes[name]:
+		case name == Policy:
+			c.Policy = json.RawMessage(a.Value)
+		default:
+			c.Attributes[name] = a.Value
+		}
+	}
+	c.Tags = tags
+	if c.Tags == nil {
+		c.Tags = make(map[string]string)
+	}
+	return c, nil
+}
+
+// A ConfigChange is one difference between a queue and its configuration.
+type ConfigChange struct {
+	Kind   string // "create", "attribute" or "tag"
+	Name   string
+	Old    string
+	New    string
+	Remove bool // set for tags to be removed
+}
+
+func (c ConfigChange) String() string {
+	switch {
+	case c.Kind == "create":
+		return "create queue " + c.Name
+	case c.Remove:
+		return fmt.Sprintf("remove %s %s (was %q)", c.Kind, c.Name, c.Old)
+	case c.Old == "":
+		return fmt.Sprintf("set %s %s to %q", c.Kind, c.Name, c.New)
+	}
+	return fmt.Sprintf("change %s %s from %q to %q", c.Kind, c.Name, c.Old, c.New)
+}
+
+// DiffQueueConfig returns the changes applying c would make to the queue,
+// sorted by kind and name. Attributes c does not mention are left alone,
+// and so are tags if c.Tags is nil; otherwise tags it does not mention are
+// removed. Policies are compared as JSON values, whatever their formatting.
+func (q *Queue) DiffQueueConfig(c *QueueConfig) ([]ConfigChange, error) {
+	current, err := q.ExportQueueConfig()
+	if err != nil {
+		return nil, err
+	}
+	have, err := current.attributes()
+	if err != nil {
+		return nil, err
+	}
+	want, err := c.attributes()
+	if err != nil {
+		return nil, err
+	}
+	var changes []ConfigChange
+	for name, v := range want {
+		if name == Policy && samePolicy(have[name], v) {
+			continue
+		}
+		if have[name] != v {
+			changes = append(changes, ConfigChange{Kind: "attribute", Name: string(name), Old: have[name], New: v})
+		}
+	}
+	if c.Tags != nil {
+		for k, v := range c.Tags {
+			if old, ok := current.Tags[k]; !ok || old != v {
+				changes = append(changes, ConfigChange{Kind: "tag", Name: k, Old: old, New: v})
+			}
+		}
+		for k, old := range current.Tags {
+			if _, ok := c.Tags[k]; !ok {
+				changes = append(changes, ConfigChange{Kind: "tag", Name: k, Old: old, Remove: true})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// samePolicy reports whether the policy documents a and b are the same
+// JSON value, falling back to comparing them as strings if either is not
+// valid JSON.
+func samePolicy(a, b string) bool {
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return a == b
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// ImportQueueConfig makes the queue named by c match it, creating the queue
+// if needed, and returns the changes made. With dryRun set, it only
+// returns the changes that would be made.
+func (sqs *SQS) ImportQueueConfig(c *QueueConfig, dryRun bool) ([]ConfigChange, error) {
+	want, err := c.attributes()
+	if err != nil {
+		return nil, err
+	}
+	q, err := sqs.Queue(c.Name)
+	if errors.Is(err, ErrQueueNotFound) {
+		changes := []ConfigChange{{Kind: "create", Name: c.Name}}
+		if dryRun {
+			return changes, nil
+		}
+		if q, err = sqs.CreateQueue(c.Name, &CreateQueueOpt{Attributes: want}); err != nil {
+			return nil, err
+		}
+		if len(c.Tags) > 0 {
+			if err := q.TagQueue(c.Tags); err != nil {
+				return nil, err
+			}
+		}
+		return changes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := q.DiffQueueConfig(c)
+	if err != nil || dryRun {
+		return changes, err
+	}
+	attrs := make(map[Attribute]string)
+	tags := make(map[string]string)
+	var untag []string
+	for _, ch := range changes {
+		switch {
+		case ch.Kind == "attribute":
+			attrs[Attribute(ch.Name)] = ch.New
+		case ch.Remove:
+			untag = append(untag, ch.Name)
+		default:
+			tags[ch.Name] = ch.New
+		}
+	}
+	if len(attrs) > 0 {
+		if err := q.SetQueueAttributes(attrs); err != nil {
+			return nil, err
+		}
+	}
+	if len(tags) > 0 {
+		if err := q.TagQueue(tags); err != nil {
+			return nil, err
+		}
+	}
+	if len(untag) > 0 {
+		if err := q.UntagQueue(untag...); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
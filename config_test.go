@@ -0,0 +1,125 @@
+package sqs
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestImportQueueConfig(c *C) {
+	var calls []string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		action := r.Form.Get("Action")
+		switch action {
+		case "GetQueueUrl":
+			fmt.Fprintf(w, "<GetQueueUrlResponse><GetQueueUrlResult><QueueUrl>%s/123/orders</QueueUrl></GetQueueUrlResult></GetQueueUrlResponse>", srv.URL)
+		case "GetQueueAttributes":
+			fmt.Fprint(w, `<GetQueueAttributesResponse><GetQueueAttributesResult>
+<Attribute><Name>VisibilityTimeout</Name><Value>30</Value></Attribute>
+<Attribute><Name>DelaySeconds</Name><Value>0</Value></Attribute>
+<Attribute><Name>QueueArn</Name><Value>arn:aws:sqs:us-east-1:123:orders</Value></Attribute>
+<Attribute><Name>Policy</Name><Value>{"Version":"2012-10-17"}</Value></Attribute>
+</GetQueueAttributesResult></GetQueueAttributesResponse>`)
+		case "ListQueueTags":
+			fmt.Fprint(w, "<ListQueueTagsResponse><ListQueueTagsResult><Tag><Key>team</Key><Value>ops</Value></Tag><Tag><Key>old</Key><Value>x</Value></Tag></ListQueueTagsResult></ListQueueTagsResponse>")
+		default:
+			var params []string
+			for k, v := range r.Form {
+				if k != "Action" && (strings.HasPrefix(k, "Attribute") || strings.HasPrefix(k, "Tag")) {
+					params = append(params, k+"="+v[0])
+				}
+			}
+			calls = append(calls, fmt.Sprintf("%s %d", action, len(params)))
+			fmt.Fprint(w, "<Response/>")
+		}
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+
+	q := &Queue{SQS: sqs, path: "/123/orders"}
+	cfg, err := q.ExportQueueConfig()
+	c.Assert(err, IsNil)
+	c.Assert(cfg.Attributes, DeepEquals, map[Attribute]string{VisibilityTimeout: "30", DelaySeconds: "0"})
+	c.Assert(string(cfg.Policy), Equals, `{"Version":"2012-10-17"}`)
+
+	var buf bytes.Buffer
+	c.Assert(cfg.Write(&buf), IsNil)
+	cfg, err = ReadQueueConfig(&buf)
+	c.Assert(err, IsNil)
+	cfg.Attributes[VisibilityTimeout] = "60"
+	cfg.Tags = map[string]string{"team": "ops", "env": "prod"}
+
+	changes, err := sqs.ImportQueueConfig(cfg, true)
+	c.Assert(err, IsNil)
+	var lines []string
+	for _, ch := range changes {
+		lines = append(lines, ch.String())
+	}
+	c.Assert(lines, DeepEquals, []string{
+		`change attribute VisibilityTimeout from "30" to "60"`,
+		`set tag env to "prod"`,
+		`remove tag old (was "x")`,
+	})
+	c.Assert(calls, HasLen, 0)
+
+	_, err = sqs.ImportQueueConfig(cfg, false)
+	c.Assert(err, IsNil)
+	c.Assert(calls, DeepEquals, []string{"SetQueueAttributes 2", "TagQueue 2", "UntagQueue 1"})
+}
+
+func (s *S) TestDiffQueueConfig(c *C) {
+	tags := "<Tag><Key>team</Key><Value>ops</Value></Tag>"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.Form.Get("Action") {
+		case "GetQueueAttributes":
+			fmt.Fprint(w, `<GetQueueAttributesResponse><GetQueueAttributesResult>
+<Attribute><Name>Policy</Name><Value>{"Version":"2012-10-17","Statement":[]}</Value></Attribute>
+</GetQueueAttributesResult></GetQueueAttributesResponse>`)
+		case "ListQueueTags":
+			fmt.Fprintf(w, "<ListQueueTagsResponse><ListQueueTagsResult>%s</ListQueueTagsResult></ListQueueTagsResponse>", tags)
+		}
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	q := &Queue{SQS: sqs, path: "/123/orders"}
+
+	diff := func(cfg string) []string {
+		config, err := ReadQueueConfig(strings.NewReader(cfg))
+		c.Assert(err, IsNil)
+		changes, err := q.DiffQueueConfig(config)
+		c.Assert(err, IsNil)
+		var lines []string
+		for _, ch := range changes {
+			lines = append(lines, ch.String())
+		}
+		return lines
+	}
+
+	// Reformatting the policy is not a change, and without tags the tags
+	// of the queue are left alone.
+	c.Assert(diff(`{"name": "orders", "policy": {
+  "Statement": [],
+  "Version": "2012-10-17"
+}}`), HasLen, 0)
+	c.Assert(diff(`{"name": "orders", "policy": {"Version": "2012-10-17", "Statement": [{}]}}`), DeepEquals, []string{
+		`change attribute Policy from "{\"Version\":\"2012-10-17\",\"Statement\":[]}" to "{\"Version\":\"2012-10-17\",\"Statement\":[{}]}"`,
+	})
+	c.Assert(diff(`{"name": "orders", "tags": {}}`), DeepEquals, []string{`remove tag team (was "ops")`})
+
+	// Exported configurations keep an empty set of tags.
+	tags = ""
+	cfg, err := q.ExportQueueConfig()
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	c.Assert(cfg.Write(&buf), IsNil)
+	c.Assert(buf.String(), Matches, `(?s).*"tags": \{\}.*`)
+}
@@ -0,0 +1,790 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrHeld is returned by handlers that took over settling a message, for
+// instance by placing it in an Escrow; the Consumer then neither deletes
+// nor releases it.
+var ErrHeld = errors.New("sqs: message held")
+
+// MaxVisibilityTimeout is the longest visibility timeout SQS accepts.
+const MaxVisibilityTimeout = 12 * time.Hour
+
+// A RetryAfterError asks the Consumer to redeliver a message after Delay,
+// overriding its RetryDelay, e.g. when a downstream service answered 429
+// with a Retry-After header.
+type RetryAfterError struct {
+	Delay time.Duration
+	Err   error // the cause, if any
+}
+
+// RetryAfter returns an error asking for the message to be redelivered
+// after d.
+func RetryAfter(d time.Duration) error {
+	return &RetryAfterError{Delay: d}
+}
+
+// RetryAfterResponse returns a RetryAfterError for a 429 or 503 response
+// carrying a Retry-After header, and nil for other responses.
+func RetryAfterResponse(r *http.Response) error {
+	if r.StatusCode != http.StatusTooManyRequests && r.StatusCode != http.StatusServiceUnavailable {
+		return nil
+	}
+	d := parseRetryAfter(r.Header.Get("Retry-After"))
+	if d <= 0 {
+		return nil
+	}
+	return &RetryAfterError{Delay: d, Err: fmt.Errorf("sqs: downstream responded %s", r.Status)}
+}
+
+func (e *RetryAfterError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s (retry after %s)", e.Err, e.Delay)
+	}
+	return fmt.Sprintf("sqs: retry after %s", e.Delay)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// visibilityTimeout returns the timeout, in whole seconds, delaying
+// redelivery by at least e.Delay.
+func (e *RetryAfterError) visibilityTimeout() int {
+	return visibilitySeconds(e.Delay)
+}
+
+// visibilitySeconds returns the visibility timeout, in whole seconds,
+// hiding a message for at least d.
+func visibilitySeconds(d time.Duration) int {
+	if d > MaxVisibilityTimeout {
+		d = MaxVisibilityTimeout
+	}
+	if d < 0 {
+		d = 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// A Handler processes one received message. Returning nil acknowledges and
+// deletes the message; returning an error releases it for redelivery, after
+// RetryDelay, the delay of the RetryPolicy or that of a RetryAfterError.
+type Handler interface {
+	HandleMessage(m *Message) error
+}
+
+// The HandlerFunc type is an adapter to allow the use of an ordinary
+// function as a Handler.
+type HandlerFunc func(m *Message) error
+
+// HandleMessage calls f(m).
+func (f HandlerFunc) HandleMessage(m *Message) error {
+	return f(m)
+}
+
+// A Consumer long-polls Queue in Concurrency goroutines and passes every
+// received message to Handler. Messages are deleted once handled, and made
+// visible again after RetryDelay when the handler fails or panics.
+//
+// A Consumer is driven either by Run, or by Start and Stop or Shutdown.
+type Consumer struct {
+	Queue   *Queue
+	Handler Handler
+
+	// Concurrency is the number of messages handled in parallel; it
+	// defaults to 1. Autoscale, if set, overrides it.
+	Concurrency int
+	// Autoscale, if set, varies the number of goroutines with the backlog
+	// of the queue.
+	Autoscale *AutoscaleOpt
+	// ByMessageGroup handles the messages of each batch by MessageGroupId,
+	// for FIFO queues: serially and in order within a group, and groups in
+	// parallel. Once a message of a group fails, the following ones of the
+	// batch are released unhandled, to be redelivered after it. SQS does
+	// not deliver the messages of a group to other receives meanwhile.
+	ByMessageGroup bool
+	// BatchSize is the number of messages each goroutine receives at
+	// once, up to 10. It defaults to 1, since the messages of a batch are
+	// handled one after the other while all of them are invisible.
+	BatchSize int
+	// VisibilityTimeout, in seconds, overrides the queue default for
+	// received messages when positive.
+	VisibilityTimeout int
+	// RetryDelay, in seconds, is the visibility timeout given to messages
+	// whose handler failed; zero makes them visible again immediately.
+	// Handlers returning a RetryAfterError override it.
+	RetryDelay int
+	// RetryPolicy, if set, replaces RetryDelay, e.g. with an
+	// ExponentialBackoff, and can move failed messages to a dead letter
+	// queue. ApproximateReceiveCount is then received with every message.
+	RetryPolicy RetryPolicy
+	// Deletes, if set, batches the deletes of handled messages. Run and
+	// Stop flush it before returning.
+	Deletes *DeleteBuffer
+	// Dedup, if set, skips the messages already handled.
+	Dedup *DedupOpt
+	// Heartbeat, if set, keeps every message invisible while its handler
+	// runs, for handlers that may outlast the visibility timeout.
+	Heartbeat *HeartbeatOpt
+	// MessageAttributeNames and AttributeNames select the attributes
+	// received with each message; custom attributes default to "All".
+	MessageAttributeNames []string
+	AttributeNames        []Attribute
+	// Filter, if set, selects the messages handled, e.g. with
+	// AttributeIs, for consumers sharing a queue among message types.
+	// The others are released at once, for the consumers of their type,
+	// and counted as Released. Each release counts as a receive for the
+	// redrive policy of the queue, whose maxReceiveCount must allow for
+	// them.
+	Filter func(m *Message) bool
+
+	// MaxInFlightBytes, if positive, bounds the total size of the bodies
+	// of the messages received and not yet handled: receiving pauses while
+	// it is reached. As sizes are only known once received, the budget
+	// can be exceeded by the last batches received, up to Concurrency
+	// batches.
+	MaxInFlightBytes int64
+	// MaxInFlight, if positive, bounds the number of messages received
+	// and not yet handled, such as when Autoscale runs many goroutines:
+	// receiving pauses while it is reached, and resumes as handlers
+	// finish. It can be exceeded by up to Concurrency batches.
+	MaxInFlight int64
+
+	// ReleaseOnShutdown makes Shutdown release the messages received but
+	// not yet handled when its context is done, so that other consumers
+	// get them at once rather than after their visibility timeout.
+	ReleaseOnShutdown bool
+
+	// Group, if set, is informed of the messages in flight, and receiving
+	// stops while the group is paused.
+	Group *GroupMember
+	// OnError, if set, is called with receive, handler and ack errors.
+	OnError func(err error)
+
+	received, processed, acked, nacked, held, deadLettered, released int64
+	duplicates, workers                                              int64
+	bytes, inFlight                                                  budget
+
+	// pending holds the messages received and not yet handled, until
+	// Shutdown abandons them.
+	pendingMu sync.Mutex
+	pending   map[*Message]bool
+	abandoned bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// A ConsumerReport accounts for the messages of a Consumer, so that
+// operators can verify a clean shutdown and reconcile the messages left in
+// flight, which SQS redelivers once their visibility timeout expires.
+type ConsumerReport struct {
+	Received  int64 // messages received
+	Processed int64 // messages whose handler returned
+	Acked     int64 // deleted, or queued for deletion in Deletes
+	Nacked    int64 // released for redelivery after a handler error
+	Held      int64 // taken over by their handler with ErrHeld
+	InFlight  int64 // received but not yet handled
+	Released  int64 // returned unhandled to the queue, see ByMessageGroup, Filter and Shutdown
+
+	// DeadLettered counts the failed messages moved to a dead letter
+	// queue by the RetryPolicy.
+	DeadLettered int64
+	// Duplicates counts the messages deleted unhandled by Dedup.
+	Duplicates int64
+
+	// InFlightBytes is the size of the bodies of the messages in flight.
+	InFlightBytes int64
+}
+
+// Clean reports whether no message was left in flight.
+func (r ConsumerReport) Clean() bool {
+	return r.InFlight == 0
+}
+
+// Report returns the counts of the consumer so far.
+func (c *Consumer) Report() ConsumerReport {
+	r := ConsumerReport{
+		Received:  atomic.LoadInt64(&c.received),
+		Processed: atomic.LoadInt64(&c.processed),
+		Acked:     atomic.LoadInt64(&c.acked),
+		Nacked:    atomic.LoadInt64(&c.nacked),
+		Held:      atomic.LoadInt64(&c.held),
+		Released:  atomic.LoadInt64(&c.released),
+
+		DeadLettered: atomic.LoadInt64(&c.deadLettered),
+		Duplicates:   atomic.LoadInt64(&c.duplicates),
+	}
+	r.InFlight = r.Received - r.Processed - r.Released - r.Duplicates
+	r.InFlightBytes = c.bytes.inUse()
+	return r
+}
+
+func (c *Consumer) onError(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+// Start runs the consumer in the background until Stop is called.
+func (c *Consumer) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel != nil {
+		return
+	}
+	c.pendingMu.Lock()
+	c.abandoned = false
+	c.pendingMu.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	c.cancel, c.done = cancel, done
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+}
+
+// Stop stops receiving, waits for the messages in flight to be handled and
+// returns the report of the consumer.
+func (c *Consumer) Stop() ConsumerReport {
+	return c.StopContext(context.Background())
+}
+
+// StopContext is like Stop, but stops waiting once ctx is done; the
+// messages still being handled then are counted as InFlight. Their
+// handlers keep running, and a later Stop waits for them.
+func (c *Consumer) StopContext(ctx context.Context) ConsumerReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cancel == nil {
+		return c.Report()
+	}
+	c.cancel()
+	select {
+	case <-c.done:
+		c.cancel, c.done = nil, nil
+	case <-ctx.Done():
+	}
+	return c.Report()
+}
+
+// Shutdown stops receiving and waits for the messages in flight to be
+// handled until ctx is done, e.g. at the end of the grace period of a
+// Kubernetes pod termination. With ReleaseOnShutdown, the messages
+// received but not yet handled by then are released for other consumers;
+// those whose handler is running are counted as InFlight, like with
+// StopContext.
+func (c *Consumer) Shutdown(ctx context.Context) ConsumerReport {
+	r := c.StopContext(ctx)
+	if r.InFlight == 0 || !c.ReleaseOnShutdown {
+		return r
+	}
+	c.pendingMu.Lock()
+	c.abandoned = true
+	msgs := make([]*Message, 0, len(c.pending))
+	for m := range c.pending {
+		msgs = append(msgs, m)
+	}
+	c.pending = nil
+	c.pendingMu.Unlock()
+	c.release(msgs)
+	return c.Report()
+}
+
+// track records msgs as pending, or returns false if Shutdown abandoned
+// them already.
+func (c *Consumer) track(msgs []*Message) bool {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if c.abandoned {
+		return false
+	}
+	if c.pending == nil {
+		c.pending = make(map[*Message]bool)
+	}
+	for _, m := range msgs {
+		c.pending[m] = true
+	}
+	return true
+}
+
+// take removes m from the pending messages, or returns false if Shutdown
+// abandoned it.
+func (c *Consumer) take(m *Message) bool {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if !c.pending[m] {
+		return false
+	}
+	delete(c.pending, m)
+	return true
+}
+
+// release makes msgs visible again at once.
+func (c *Consumer) release(msgs []*Message) {
+	for _, m := range msgs {
+		if err := c.Queue.ChangeMessageVisibility(m, 0); err != nil {
+			c.onError(err)
+			continue
+		}
+		atomic.AddInt64(&c.released, 1)
+	}
+}
+
+// AutoscaleOpt configures the autoscaling of a Consumer, which polls the
+// number of visible messages of its queue and runs one goroutine per
+// BacklogPerWorker of them, between Min and Max.
+type AutoscaleOpt struct {
+	// Min and Max bound the number of goroutines; Min defaults to 1 and
+	// Max to Min.
+	Min, Max int
+	// BacklogPerWorker is the number of visible messages that warrants
+	// one more goroutine; it defaults to 10.
+	BacklogPerWorker int
+	// Interval is the time between polls; it defaults to 30 seconds.
+	Interval time.Duration
+}
+
+// workers returns the number of goroutines for a backlog of visible
+// messages.
+func (o *AutoscaleOpt) workers(visible int) int {
+	min, max, per := o.Min, o.Max, o.BacklogPerWorker
+	if min <= 0 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	if per <= 0 {
+		per = 10
+	}
+	n := (visible + per - 1) / per
+	if n < min {
+		n = min
+	}
+	if n > max {
+		n = max
+	}
+	return n
+}
+
+// Workers returns the number of goroutines receiving messages.
+func (c *Consumer) Workers() int {
+	return int(atomic.LoadInt64(&c.workers))
+}
+
+// Run consumes messages until ctx is done, then waits for the messages in
+// flight to be handled.
+func (c *Consumer) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	var stops []chan struct{}
+	// scale starts or stops goroutines to run n of them. Stopped ones
+	// complete their receive, whose messages would otherwise stay
+	// invisible, and handle its messages before exiting.
+	scale := func(n int) {
+		for len(stops) < n {
+			stop := make(chan struct{})
+			stops = append(stops, stop)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.work(ctx, stop)
+			}()
+		}
+		for len(stops) > n {
+			close(stops[len(stops)-1])
+			stops = stops[:len(stops)-1]
+		}
+		atomic.StoreInt64(&c.workers, int64(n))
+	}
+	if opt := c.Autoscale; opt != nil {
+		c.autoscale(ctx, opt, scale)
+	} else {
+		n := c.Concurrency
+		if n <= 0 {
+			n = 1
+		}
+		scale(n)
+	}
+	wg.Wait()
+	atomic.StoreInt64(&c.workers, 0)
+	if c.Deletes != nil {
+		c.Deletes.Close()
+	}
+	return ctx.Err()
+}
+
+// autoscale scales the goroutines of the consumer with the backlog of
+// its queue until ctx is done.
+func (c *Consumer) autoscale(ctx context.Context, opt *AutoscaleOpt, scale func(n int)) {
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	scale(opt.workers(0))
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if st, err := c.Queue.Stats(); err != nil {
+			c.onError(err)
+		} else {
+			scale(opt.workers(st.Visible))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// work receives and handles messages until ctx is done or stop is closed.
+func (c *Consumer) work(ctx context.Context, stop <-chan struct{}) {
+	opt := c.receiveOpt()
+	var b backoff
+	stopped := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return ctx.Err() != nil
+		}
+	}
+	for !stopped() {
+		if c.Group != nil && c.Group.Paused() {
+			b.wait(ctx)
+			continue
+		}
+		if !c.bytes.wait(ctx, c.MaxInFlightBytes) || !c.inFlight.wait(ctx, c.MaxInFlight) {
+			break
+		}
+		msgs := receive(ctx, c.Queue, opt, &b, c.OnError)
+		c.accept(msgs)
+		if c.Group != nil {
+			c.Group.Begin(len(msgs))
+		}
+		if !c.track(msgs) {
+			c.release(msgs)
+		}
+		// Messages already received are handled even once ctx is done,
+		// unless Shutdown abandoned them.
+		if c.ByMessageGroup {
+			c.handleGroups(msgs)
+		} else {
+			c.handleAll(msgs, false)
+		}
+	}
+}
+
+// receiveOpt returns the options of the receives of the consumer.
+func (c *Consumer) receiveOpt() *ReceiveMessageOpt {
+	size := c.BatchSize
+	if size <= 0 {
+		size = 1
+	} else if size > 10 {
+		size = 10
+	}
+	attrs := c.MessageAttributeNames
+	if attrs == nil {
+		attrs = []string{"All"}
+	}
+	opt := &ReceiveMessageOpt{
+		MaxNumberOfMessages:   size,
+		VisibilityTimeout:     c.VisibilityTimeout,
+		WaitTimeSeconds:       20,
+		MessageAttributeNames: attrs,
+		AttributeNames:        c.AttributeNames,
+	}
+	if c.RetryPolicy != nil {
+		opt.AttributeNames = withAttribute(opt.AttributeNames, ApproximateReceiveCount)
+	}
+	if c.ByMessageGroup {
+		opt.AttributeNames = withAttribute(opt.AttributeNames, MessageGroupId)
+	}
+	return opt
+}
+
+// accept accounts for msgs, just received.
+func (c *Consumer) accept(msgs []*Message) {
+	atomic.AddInt64(&c.received, int64(len(msgs)))
+	for _, m := range msgs {
+		c.bytes.acquire(int64(len(m.Body)))
+	}
+	c.inFlight.acquire(int64(len(msgs)))
+}
+
+// handleAll handles msgs one after the other. With ordered, the messages
+// following one that failed are released unhandled.
+func (c *Consumer) handleAll(msgs []*Message, ordered bool) {
+	failed := false
+	for _, m := range msgs {
+		if c.take(m) {
+			if failed || c.Filter != nil && !c.Filter(m) {
+				c.release([]*Message{m})
+			} else if err := c.handle(m); err != nil && ordered {
+				failed = true
+			}
+		}
+		c.bytes.release(int64(len(m.Body)))
+		c.inFlight.release(1)
+		if c.Group != nil {
+			c.Group.Done(1)
+		}
+	}
+}
+
+// handleGroups handles msgs by message group, the groups in parallel.
+func (c *Consumer) handleGroups(msgs []*Message) {
+	var groups [][]*Message
+	index := make(map[string]int)
+	for _, m := range msgs {
+		id := m.SystemAttributes.MessageGroupId
+		i, ok := index[id]
+		if !ok {
+			i = len(groups)
+			index[id] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+	var wg sync.WaitGroup
+	for _, g := range groups {
+		wg.Add(1)
+		go func(g []*Message) {
+			defer wg.Done()
+			c.handleAll(g, true)
+		}(g)
+	}
+	wg.Wait()
+}
+
+// handle passes m to the handler and settles it according to the outcome.
+// It returns the error of the handler, or nil if it succeeded or held m.
+func (c *Consumer) handle(m *Message) error {
+	if c.Dedup != nil && c.Dedup.seen(m, c.onError) {
+		c.duplicate(m)
+		return nil
+	}
+	var err error
+	if c.Heartbeat != nil {
+		opt := *c.Heartbeat
+		if opt.OnError == nil {
+			opt.OnError = c.OnError
+		}
+		h := c.Queue.StartHeartbeat(m, &opt)
+		err = c.call(m)
+		h.Stop()
+	} else {
+		err = c.call(m)
+	}
+	atomic.AddInt64(&c.processed, 1)
+	if c.Dedup != nil && (err == nil || errors.Is(err, ErrHeld)) {
+		c.Dedup.record(m, c.onError)
+	}
+	if errors.Is(err, ErrHeld) {
+		atomic.AddInt64(&c.held, 1)
+		return nil
+	}
+	if err != nil {
+		c.onError(err)
+		c.retry(m, err)
+		return err
+	}
+	if c.Deletes != nil {
+		c.Deletes.Delete(m)
+	} else if err := c.Queue.DeleteMessage(m); err != nil {
+		c.onError(err)
+		return nil
+	}
+	atomic.AddInt64(&c.acked, 1)
+	return nil
+}
+
+// duplicate deletes m, already handled.
+func (c *Consumer) duplicate(m *Message) {
+	if c.Deletes != nil {
+		c.Deletes.Delete(m)
+	} else if err := c.Queue.DeleteMessage(m); err != nil {
+		c.onError(err)
+	}
+	atomic.AddInt64(&c.duplicates, 1)
+}
+
+// retry releases m, whose handler failed with err, or moves it to the dead
+// letter queue of the RetryPolicy.
+func (c *Consumer) retry(m *Message, err error) {
+	delay := c.RetryDelay
+	if c.RetryPolicy != nil {
+		d := c.RetryPolicy.Retry(m, err)
+		if d.DeadLetterQueue != nil {
+			note := &Annotation{Action: "dead letter", Reason: err.Error()}
+			if _, err := c.Queue.MoveMessage(m, d.DeadLetterQueue, note); err != nil {
+				c.onError(err)
+				return
+			}
+			atomic.AddInt64(&c.deadLettered, 1)
+			return
+		}
+		delay = visibilitySeconds(d.Delay)
+	}
+	atomic.AddInt64(&c.nacked, 1)
+	if err := c.Queue.ChangeMessageVisibility(m, retryDelay(err, delay)); err != nil {
+		c.onError(err)
+	}
+}
+
+// withAttribute returns names with name added, unless already there or
+// implied by All.
+func withAttribute(names []Attribute, name Attribute) []Attribute {
+	for _, n := range names {
+		if n == name || n == All {
+			return names
+		}
+	}
+	return append(names[:len(names):len(names)], name)
+}
+
+// call runs the handler, turning a panic into an error.
+func (c *Consumer) call(m *Message) error {
+	return callHandler(c.Handler.HandleMessage, m)
+}
+
+// callHandler runs fn on m, turning a panic into an error.
+func callHandler(fn func(m *Message) error, m *Message) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("sqs: handler panic on message %s: %v", m.Id, v)
+		}
+	}()
+	return fn(m)
+}
+
+// retryDelay returns the visibility timeout releasing a message whose
+// handler failed with err: that of a RetryAfterError, or def.
+func retryDelay(err error, def int) int {
+	var ra *RetryAfterError
+	if errors.As(err, &ra) {
+		return ra.visibilityTimeout()
+	}
+	return def
+}
+
+// ProcessOpt holds the options of ProcessOne.
+type ProcessOpt struct {
+	// VisibilityTimeout, in seconds, overrides the queue default for the
+	// received message when positive.
+	VisibilityTimeout int
+	// WaitTimeSeconds enables long polling for up to 20 seconds.
+	WaitTimeSeconds int
+	// RetryDelay, in seconds, is the visibility timeout given to the
+	// message when fn fails; zero makes it visible again immediately.
+	// A RetryAfterError overrides it.
+	RetryDelay int
+	// MessageAttributeNames and AttributeNames select the attributes
+	// received with the message; custom attributes default to "All".
+	MessageAttributeNames []string
+	AttributeNames        []Attribute
+}
+
+// ProcessOne receives a message and passes it to fn, settling it like a
+// Consumer does: the message is deleted when fn returns nil, left alone
+// when fn returns ErrHeld, and released for redelivery after
+// opt.RetryDelay otherwise. A panic in fn counts as a failure.
+//
+// ok reports whether a message was received. err is the error of fn, or
+// of the requests receiving and settling the message. ctx bounds the
+// receive only; a message received is settled even once ctx is done.
+// opt may be nil.
+func (q *Queue) ProcessOne(ctx context.Context, fn func(m *Message) error, opt *ProcessOpt) (ok bool, err error) {
+	if opt == nil {
+		opt = &ProcessOpt{}
+	}
+	attrs := opt.MessageAttributeNames
+	if attrs == nil {
+		attrs = []string{"All"}
+	}
+	msgs, err := q.WithContext(ctx).ReceiveMessages(&ReceiveMessageOpt{
+		MaxNumberOfMessages:   1,
+		VisibilityTimeout:     opt.VisibilityTimeout,
+		WaitTimeSeconds:       opt.WaitTimeSeconds,
+		MessageAttributeNames: attrs,
+		AttributeNames:        opt.AttributeNames,
+	})
+	if err != nil || len(msgs) == 0 {
+		return false, err
+	}
+	m := msgs[0]
+	err = callHandler(fn, m)
+	switch {
+	case errors.Is(err, ErrHeld):
+		return true, nil
+	case err != nil:
+		if rerr := q.ChangeMessageVisibility(m, retryDelay(err, opt.RetryDelay)); rerr != nil {
+			return true, fmt.Errorf("sqs: releasing message %s after %v: %w", m.Id, err, rerr)
+		}
+		return true, err
+	}
+	return true, q.DeleteMessage(m)
+}
+
+// A budget accounts for the bytes, or the number, of the messages in
+// flight. The zero value is ready to use.
+type budget struct {
+	mu    sync.Mutex
+	used  int64
+	freed chan struct{} // closed when bytes are released
+}
+
+func (b *budget) inUse() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+func (b *budget) acquire(n int64) {
+	b.mu.Lock()
+	b.used += n
+	b.mu.Unlock()
+}
+
+func (b *budget) release(n int64) {
+	b.mu.Lock()
+	b.used -= n
+	if b.freed != nil {
+		close(b.freed)
+		b.freed = nil
+	}
+	b.mu.Unlock()
+}
+
+// wait waits until fewer than max bytes are in use, or max is not
+// positive. It returns false if ctx is done first.
+func (b *budget) wait(ctx context.Context, max int64) bool {
+	for {
+		b.mu.Lock()
+		if max <= 0 || b.used < max {
+			b.mu.Unlock()
+			return true
+		}
+		if b.freed == nil {
+			b.freed = make(chan struct{})
+		}
+		freed := b.freed
+		b.mu.Unlock()
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -0,0 +1,399 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestConsumer(c *C) {
+	var mu sync.Mutex
+	pending := []string{"ok", "fail", "panic"}
+	var deleted, released []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.FormValue("Action") {
+		case "ReceiveMessage":
+			fmt.Fprint(w, "<ReceiveMessageResponse><ReceiveMessageResult>")
+			if len(pending) > 0 {
+				fmt.Fprintf(w, "<Message><MessageId>%s</MessageId><ReceiptHandle>%[1]s</ReceiptHandle><Body>%[1]s</Body></Message>", pending[0])
+				pending = pending[1:]
+			}
+			fmt.Fprint(w, "</ReceiveMessageResult></ReceiveMessageResponse>")
+		case "DeleteMessage":
+			deleted = append(deleted, r.FormValue("ReceiptHandle"))
+			fmt.Fprint(w, "<DeleteMessageResponse/>")
+		case "ChangeMessageVisibility":
+			released = append(released, r.FormValue("ReceiptHandle"))
+			fmt.Fprint(w, "<ChangeMessageVisibilityResponse/>")
+		}
+	}))
+	defer srv.Close()
+
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	handled := make(chan string, 3)
+	var errs []error
+	consumer := &Consumer{
+		Queue: &Queue{SQS: sqs, path: "/123/q"},
+		Handler: HandlerFunc(func(m *Message) error {
+			defer func() { handled <- m.Body }()
+			switch m.Body {
+			case "fail":
+				return errors.New("failed")
+			case "panic":
+				panic("boom")
+			}
+			return nil
+		}),
+		OnError: func(err error) { errs = append(errs, err) },
+	}
+	consumer.Start()
+	for i := 0; i < 3; i++ {
+		<-handled
+	}
+	report := consumer.Stop()
+	c.Assert(report, DeepEquals, ConsumerReport{Received: 3, Processed: 3, Acked: 1, Nacked: 2})
+	c.Assert(report.Clean(), Equals, true)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(deleted, DeepEquals, []string{"ok"})
+	c.Assert(released, DeepEquals, []string{"fail", "panic"})
+	c.Assert(errs, HasLen, 2)
+	c.Assert(errs[1], ErrorMatches, "sqs: handler panic on message panic: boom")
+}
+
+func (s *S) TestConsumerStopContext(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("cutoff", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("slow")
+	c.Assert(err, IsNil)
+	started, release := make(chan bool), make(chan bool)
+	consumer := &Consumer{
+		Queue: q,
+		Handler: HandlerFunc(func(m *Message) error {
+			started <- true
+			<-release
+			return nil
+		}),
+	}
+	consumer.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report := consumer.StopContext(ctx)
+	c.Assert(report.InFlight, Equals, int64(1))
+	c.Assert(report.Clean(), Equals, false)
+
+	close(release)
+	report = consumer.Stop()
+	c.Assert(report, DeepEquals, ConsumerReport{Received: 1, Processed: 1, Acked: 1})
+}
+
+func (s *S) TestConsumerShutdown(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("drain", nil)
+	c.Assert(err, IsNil)
+	for _, body := range []string{"a", "b", "c"} {
+		_, err = q.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+	started, release := make(chan bool), make(chan bool)
+	consumer := &Consumer{
+		Queue:     q,
+		BatchSize: 3,
+		Handler: HandlerFunc(func(m *Message) error {
+			started <- true
+			<-release
+			return nil
+		}),
+		ReleaseOnShutdown: true,
+	}
+	consumer.Start()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	report := consumer.Shutdown(ctx)
+	c.Assert(report.Released, Equals, int64(2))
+	c.Assert(report.InFlight, Equals, int64(1))
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 2)
+
+	close(release)
+	report = consumer.Stop()
+	c.Assert(report, DeepEquals, ConsumerReport{Received: 3, Processed: 1, Acked: 1, Released: 2})
+}
+
+func (s *S) TestConsumerAutoscale(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("autoscale", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 50; i++ {
+		_, err = q.SendMessage("job")
+		c.Assert(err, IsNil)
+	}
+	release := make(chan bool)
+	consumer := &Consumer{
+		Queue: q,
+		Handler: HandlerFunc(func(m *Message) error {
+			<-release
+			return nil
+		}),
+		Autoscale: &AutoscaleOpt{Min: 1, Max: 4, Interval: 10 * time.Millisecond},
+	}
+	waitWorkers := func(n int) {
+		for i := 0; i < 200 && consumer.Workers() != n; i++ {
+			time.Sleep(5 * time.Millisecond)
+		}
+		c.Assert(consumer.Workers(), Equals, n)
+	}
+	consumer.Start()
+	waitWorkers(4)
+	close(release)
+	for i := 0; i < 200 && consumer.Report().Acked < 50; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitWorkers(1)
+	report := consumer.Stop()
+	c.Assert(report.Acked, Equals, int64(50))
+	c.Assert(consumer.Workers(), Equals, 0)
+
+	opt := &AutoscaleOpt{Min: 2, Max: 8, BacklogPerWorker: 100}
+	c.Assert([]int{opt.workers(0), opt.workers(250), opt.workers(5000)}, DeepEquals, []int{2, 3, 8})
+}
+
+func (s *S) TestConsumerByMessageGroup(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("groups.fifo", nil)
+	c.Assert(err, IsNil)
+	for _, body := range []string{"a1", "b1", "a2", "b2", "a3"} {
+		_, err = q.SendMessageWithOpt(body, &SendMessageOpt{MessageGroupId: body[:1]})
+		c.Assert(err, IsNil)
+	}
+	var mu sync.Mutex
+	handled := make(map[string][]string)
+	b1 := make(chan bool)
+	done := make(chan bool)
+	failed := false
+	consumer := &Consumer{
+		Queue:     q,
+		BatchSize: 10,
+		Handler: HandlerFunc(func(m *Message) error {
+			group := m.SystemAttributes.MessageGroupId
+			switch m.Body {
+			case "a1":
+				// Groups run in parallel: b1 is handled meanwhile.
+				<-b1
+			case "b1":
+				close(b1)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			handled[group] = append(handled[group], m.Body)
+			if m.Body == "a2" && !failed {
+				failed = true
+				return errors.New("failed")
+			}
+			if m.Body == "a3" {
+				close(done)
+			}
+			return nil
+		}),
+		ByMessageGroup: true,
+	}
+	consumer.Start()
+	<-done
+	report := consumer.Stop()
+	c.Assert(handled, DeepEquals, map[string][]string{
+		"a": {"a1", "a2", "a2", "a3"},
+		"b": {"b1", "b2"},
+	})
+	c.Assert(report.Released, Equals, int64(1))
+	c.Assert(report.Acked, Equals, int64(5))
+	c.Assert(report.Clean(), Equals, true)
+}
+
+func (s *S) TestConsumerRetryAfter(c *C) {
+	l := NewLocal()
+	sqs := l.SQS()
+	timeouts := make(chan string, 2)
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if RequestAction(req) == "ChangeMessageVisibility" {
+			timeouts <- requestParams(c, req).Get("VisibilityTimeout")
+		}
+		return l.Do(req)
+	})
+	q, err := sqs.CreateQueue("retry-after", nil)
+	c.Assert(err, IsNil)
+	q.SendMessage("limited")
+	q.SendMessage("wrapped")
+
+	resp := &http.Response{StatusCode: 429, Status: "429 Too Many Requests", Header: http.Header{"Retry-After": {"120"}}}
+	consumer := &Consumer{
+		Queue:      q,
+		RetryDelay: 5,
+		Handler: HandlerFunc(func(m *Message) error {
+			if m.Body == "limited" {
+				return RetryAfter(1500 * time.Millisecond)
+			}
+			return fmt.Errorf("calling downstream: %w", RetryAfterResponse(resp))
+		}),
+	}
+	consumer.Start()
+	got := []string{<-timeouts, <-timeouts}
+	consumer.Stop()
+	c.Assert(got, DeepEquals, []string{"2", "120"})
+
+	c.Assert(RetryAfterResponse(&http.Response{StatusCode: 500}), IsNil)
+	c.Assert(RetryAfterResponse(resp), ErrorMatches, `sqs: downstream responded 429 Too Many Requests \(retry after 2m0s\)`)
+}
+
+func (s *S) TestConsumerMaxInFlightBytes(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("budget", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 4; i++ {
+		_, err := q.SendMessage(strings.Repeat("x", 100))
+		c.Assert(err, IsNil)
+	}
+	started, release := make(chan bool, 4), make(chan bool)
+	consumer := &Consumer{
+		Queue:            q,
+		Concurrency:      3,
+		MaxInFlightBytes: 150,
+		Handler: HandlerFunc(func(m *Message) error {
+			started <- true
+			<-release
+			return nil
+		}),
+	}
+	consumer.Start()
+	<-started
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	report := consumer.Report()
+	c.Assert(report.Received, Equals, int64(2))
+	c.Assert(report.InFlightBytes, Equals, int64(200))
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		<-started
+	}
+	report = consumer.Stop()
+	c.Assert(report.Acked, Equals, int64(4))
+	c.Assert(report.InFlightBytes, Equals, int64(0))
+}
+
+func (s *S) TestConsumerMaxInFlight(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("inflight", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 6; i++ {
+		_, err := q.SendMessage("hello")
+		c.Assert(err, IsNil)
+	}
+	started, release := make(chan bool, 6), make(chan bool)
+	consumer := &Consumer{
+		Queue:       q,
+		Concurrency: 3,
+		BatchSize:   2,
+		MaxInFlight: 2,
+		Handler: HandlerFunc(func(m *Message) error {
+			started <- true
+			<-release
+			return nil
+		}),
+	}
+	consumer.Start()
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	report := consumer.Report()
+	c.Assert(report.Received, Equals, int64(2))
+	c.Assert(report.InFlight, Equals, int64(2))
+
+	close(release)
+	for i := 0; i < 5; i++ {
+		<-started
+	}
+	report = consumer.Stop()
+	c.Assert(report.Acked, Equals, int64(6))
+	c.Assert(consumer.inFlight.inUse(), Equals, int64(0))
+}
+
+func (s *S) TestConsumerFilter(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("shared", nil)
+	c.Assert(err, IsNil)
+	for _, typ := range []string{"order.created", "invoice.paid", "order.created"} {
+		_, err := q.SendMessageWithOpt(typ, &SendMessageOpt{MessageAttributes: MessageAttributes{
+			"type": {DataType: "String", StringValue: typ},
+		}})
+		c.Assert(err, IsNil)
+	}
+	handled := make(chan string, 3)
+	consumer := &Consumer{
+		Queue:     q,
+		BatchSize: 10,
+		Filter:    AttributeIs("type", "order.created", "order.cancelled"),
+		Handler:   HandlerFunc(func(m *Message) error { handled <- m.Body; return nil }),
+	}
+	consumer.Start()
+	c.Assert(<-handled, Equals, "order.created")
+	c.Assert(<-handled, Equals, "order.created")
+	report := consumer.Stop()
+	c.Assert(report.Acked, Equals, int64(2))
+	c.Assert(report.Released > 0, Equals, true)
+	c.Assert(report.Clean(), Equals, true)
+
+	// The other type is left for its consumers.
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MessageAttributeNames: []string{"All"}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "invoice.paid")
+	c.Assert(AttributeIs("type", "order.created")(msgs[0]), Equals, false)
+	c.Assert(AttributeIs("missing")(msgs[0]), Equals, false)
+}
+
+func (s *S) TestProcessOne(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("process-one", nil)
+	c.Assert(err, IsNil)
+	q.SendMessage("job")
+	ctx := context.Background()
+
+	ok, err := q.ProcessOne(ctx, func(m *Message) error {
+		return errors.New("failed")
+	}, nil)
+	c.Assert(ok, Equals, true)
+	c.Assert(err, ErrorMatches, "failed")
+	ok, err = q.ProcessOne(ctx, func(m *Message) error {
+		panic("boom")
+	}, nil)
+	c.Assert(ok, Equals, true)
+	c.Assert(err, ErrorMatches, "sqs: handler panic on message .*: boom")
+
+	// Both failures released the message at once.
+	var bodies []string
+	ok, err = q.ProcessOne(ctx, func(m *Message) error {
+		bodies = append(bodies, m.Body)
+		return nil
+	}, nil)
+	c.Assert(ok, Equals, true)
+	c.Assert(err, IsNil)
+	c.Assert(bodies, DeepEquals, []string{"job"})
+
+	ok, err = q.ProcessOne(ctx, func(m *Message) error {
+		c.Fatal("unexpected message")
+		return nil
+	}, nil)
+	c.Assert(ok, Equals, false)
+	c.Assert(err, IsNil)
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Empty(), Equals, true)
+}
@@ -0,0 +1,117 @@
+package sqs
+
+import "context"
+
+// CopyOpt configures CopyQueue.
+type CopyOpt struct {
+	// Move deletes the messages from the source once copied, to migrate
+	// a queue. By default the source keeps them, e.g. for a blue/green
+	// cutover where both queues are consumed for a while.
+	Move bool
+	// Max is the number of messages copied, at most; zero copies every
+	// message.
+	Max int
+	// Rate caps the messages copied per second; zero does not limit it.
+	Rate float64
+	// Filter, when set, selects the messages to copy.
+	Filter func(m *Message) bool
+	// VisibilityTimeout hides the messages seen during the copy, so that
+	// each is copied once, in seconds; default 60. Messages left in the
+	// source are made visible again once the copy is done.
+	VisibilityTimeout int
+}
+
+// CopyResult counts the messages of a copy.
+type CopyResult struct {
+	Copied  int
+	Skipped int // by Filter
+}
+
+// CopyQueue copies the messages of src to dst, stopping once src appears
+// empty, opt.Max messages were copied or ctx is done. src and dst may
+// belong to different clients, hence to different regions or accounts.
+// Messages keep their body and attributes, and the message group and
+// deduplication IDs of FIFO queues; their system attributes, such as
+// SentTimestamp, are those of new messages. opt may be nil.
+//
+// It returns the counts so far along with any error.
+func CopyQueue(ctx context.Context, src, dst *Queue, opt *CopyOpt) (*CopyResult, error) {
+	if opt == nil {
+		opt = &CopyOpt{}
+	}
+	timeout := opt.VisibilityTimeout
+	if timeout <= 0 {
+		timeout = 60
+	}
+	var limiter *RateLimiter
+	if opt.Rate > 0 {
+		limiter = NewRateLimiter(opt.Rate, 1)
+	}
+	res := &CopyResult{}
+	var left []*Message
+	seen := make(map[string]bool)
+	err := func() error {
+		for idle := 0; idle < 3; {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			n := 10
+			if opt.Max > 0 && opt.Max-res.Copied < n {
+				n = opt.Max - res.Copied
+			}
+			msgs, err := src.WithContext(ctx).ReceiveMessages(&ReceiveMessageOpt{
+				MaxNumberOfMessages:   n,
+				VisibilityTimeout:     timeout,
+				MessageAttributeNames: []string{"All"},
+				AttributeNames:        []Attribute{All},
+			})
+			if err != nil {
+				return err
+			}
+			idle++
+			for i, m := range msgs {
+				if seen[m.Id] {
+					continue
+				}
+				seen[m.Id] = true
+				idle = 0
+				if opt.Filter != nil && !opt.Filter(m) {
+					res.Skipped++
+					left = append(left, m)
+					continue
+				}
+				if limiter != nil {
+					if err := limiter.Wait(ctx, ""); err != nil {
+						left = append(left, msgs[i:]...)
+						return err
+					}
+				}
+				if _, err := dst.SendMessageWithOpt(m.Body, &SendMessageOpt{
+					MessageAttributes:      m.MessageAttributes,
+					MessageGroupId:         m.SystemAttributes.MessageGroupId,
+					MessageDeduplicationId: m.SystemAttributes.MessageDeduplicationId,
+				}); err != nil {
+					left = append(left, msgs[i:]...)
+					return err
+				}
+				res.Copied++
+				if !opt.Move {
+					left = append(left, m)
+				} else if err := src.DeleteMessage(m); err != nil {
+					return err
+				}
+			}
+			if opt.Max > 0 && res.Copied >= opt.Max {
+				return nil
+			}
+		}
+		return nil
+	}()
+	// Release the messages left behind, even if ctx is done.
+	for _, m := range left {
+		if e := src.ChangeMessageVisibility(m, 0); e != nil && err == nil {
+			err = e
+		}
+	}
+	return res, err
+}
@@ -0,0 +1,46 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestCopyQueue(c *C) {
+	src, err := NewLocal().SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	// A queue of another backend, as of another region or account.
+	dst, err := NewLocal().SQS().CreateQueue("jobs-v2", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 5; i++ {
+		_, err := src.SendMessageWithOpt(fmt.Sprintf("m%d", i), &SendMessageOpt{
+			MessageAttributes: MessageAttributes{"tenant": {DataType: "String", StringValue: fmt.Sprint(i % 2)}},
+		})
+		c.Assert(err, IsNil)
+	}
+	odd := func(m *Message) bool { return m.MessageAttributes["tenant"].StringValue == "1" }
+
+	res, err := CopyQueue(context.Background(), src, dst, &CopyOpt{Filter: odd})
+	c.Assert(err, IsNil)
+	c.Assert(*res, Equals, CopyResult{Copied: 2, Skipped: 3})
+	st, err := src.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Visible, Equals, 5)
+
+	res, err = CopyQueue(context.Background(), src, dst, &CopyOpt{Move: true, Rate: 1000})
+	c.Assert(err, IsNil)
+	c.Assert(*res, Equals, CopyResult{Copied: 5})
+	st, err = src.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Visible+st.InFlight, Equals, 0)
+
+	msgs, err := dst.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10, MessageAttributeNames: []string{"All"}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 7)
+	tenants := map[string]int{}
+	for _, m := range msgs {
+		tenants[m.MessageAttributes["tenant"].StringValue]++
+	}
+	c.Assert(tenants, DeepEquals, map[string]int{"0": 3, "1": 4})
+}
@@ -0,0 +1,340 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/librato/goamz-aws/aws"
+)
+
+// SecretSessionToken is the secret name read by SecretsCredentials for the
+// session token of temporary credentials.
+const SecretSessionToken = "AWS_SESSION_TOKEN"
+
+// DefaultExpiryWindow is how long before their expiration credentials are
+// refreshed by RefreshingCredentials.
+const DefaultExpiryWindow = 5 * time.Minute
+
+// Credentials are a set of AWS security credentials. Temporary credentials,
+// such as those of an IAM instance role, carry a SecurityToken and an
+// Expiration.
+type Credentials struct {
+	AccessKey     string
+	SecretKey     string
+	SecurityToken string
+	Expiration    time.Time // zero for long-lived credentials
+}
+
+func (c Credentials) auth() aws.Auth {
+	return aws.Auth{AccessKey: c.AccessKey, SecretKey: c.SecretKey}
+}
+
+// A CredentialsProvider supplies credentials on demand. Implementations
+// must be safe for concurrent use.
+type CredentialsProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// The CredentialsFunc type is an adapter to allow the use of an ordinary
+// function as a CredentialsProvider.
+type CredentialsFunc func() (Credentials, error)
+
+// Credentials calls f().
+func (f CredentialsFunc) Credentials() (Credentials, error) {
+	return f()
+}
+
+// StaticCredentials is a CredentialsProvider that always returns itself.
+type StaticCredentials Credentials
+
+// Credentials returns c.
+func (c StaticCredentials) Credentials() (Credentials, error) {
+	return Credentials(c), nil
+}
+
+// SecretsCredentials reads credentials, including an optional session
+// token, from a SecretsProvider.
+type SecretsCredentials struct {
+	Secrets SecretsProvider
+}
+
+// Credentials reads the current credentials from the secrets provider.
+func (s SecretsCredentials) Credentials() (Credentials, error) {
+	auth, err := AuthFromSecrets(s.Secrets)
+	if err != nil {
+		return Credentials{}, err
+	}
+	c := Credentials{AccessKey: auth.AccessKey, SecretKey: auth.SecretKey}
+	token, err := s.Secrets.Secret(SecretSessionToken)
+	switch err {
+	case nil:
+		c.SecurityToken = string(token)
+	case ErrSecretNotFound:
+	default:
+		return Credentials{}, err
+	}
+	return c, nil
+}
+
+// DefaultMetadataEndpoint is the address of the EC2 instance metadata
+// service.
+const DefaultMetadataEndpoint = "http://169.254.169.254"
+
+// InstanceRoleCredentials fetches the temporary credentials of the IAM role
+// attached to the running EC2 instance from the instance metadata service.
+type InstanceRoleCredentials struct {
+	Role     string       // role name; discovered when empty
+	Endpoint string       // defaults to DefaultMetadataEndpoint
+	Client   *http.Client // defaults to a client with a 5s timeout
+}
+
+type instanceRoleResponse struct {
+	Code            string
+	AccessKeyId     string
+	SecretAccessKey string
+	Token           string
+	Expiration      time.Time
+}
+
+// Credentials fetches the role's current credentials.
+func (p *InstanceRoleCredentials) Credentials() (Credentials, error) {
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultMetadataEndpoint
+	}
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	token := p.sessionToken(client, endpoint)
+
+	base := endpoint + "/latest/meta-data/iam/security-credentials/"
+	role := p.Role
+	if role == "" {
+		body, err := metadataGet(client, base, token)
+		if err != nil {
+			return Credentials{}, err
+		}
+		role = strings.TrimSpace(strings.SplitN(string(body), "\n", 2)[0])
+		if role == "" {
+			return Credentials{}, fmt.Errorf("sqs: no IAM role attached to this instance")
+		}
+	}
+	body, err := metadataGet(client, base+role, token)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var resp instanceRoleResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Credentials{}, fmt.Errorf("sqs: could not decode instance role credentials: %s", err)
+	}
+	if resp.Code != "" && resp.Code != "Success" {
+		return Credentials{}, fmt.Errorf("sqs: instance role credentials unavailable: %s", resp.Code)
+	}
+	return Credentials{
+		AccessKey:     resp.AccessKeyId,
+		SecretKey:     resp.SecretAccessKey,
+		SecurityToken: resp.Token,
+		Expiration:    resp.Expiration,
+	}, nil
+}
+
+// sessionToken obtains an IMDSv2 session token, returning "" to fall back
+// to IMDSv1 when the service does not issue one.
+func (p *InstanceRoleCredentials) sessionToken(client *http.Client, endpoint string) string {
+	req, err := http.NewRequest("PUT", endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	r, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer r.Body.Close()
+	if r.StatusCode != 200 {
+		return ""
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func metadataGet(client *http.Client, url_, token string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url_, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+	}
+	r, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != 200 {
+		return nil, fmt.Errorf("sqs: instance metadata request %s failed: %s", url_, r.Status)
+	}
+	return body, nil
+}
+
+// RefreshingCredentials caches the credentials of an underlying provider
+// and fetches new ones once they are within ExpiryWindow of expiring.
+// Long-lived credentials (zero Expiration) are fetched only once.
+type RefreshingCredentials struct {
+	Provider     CredentialsProvider
+	ExpiryWindow time.Duration
+
+	mu     sync.Mutex
+	cached Credentials
+	valid  bool
+}
+
+// NewRefreshingCredentials returns a RefreshingCredentials wrapping p with
+// the DefaultExpiryWindow.
+func NewRefreshingCredentials(p CredentialsProvider) *RefreshingCredentials {
+	return &RefreshingCredentials{Provider: p, ExpiryWindow: DefaultExpiryWindow}
+}
+
+// Credentials returns the cached credentials, refreshing them first if they
+// are about to expire.
+func (r *RefreshingCredentials) Credentials() (Credentials, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.valid && (r.cached.Expiration.IsZero() || time.Now().Add(r.ExpiryWindow).Before(r.cached.Expiration)) {
+		return r.cached, nil
+	}
+	c, err := r.Provider.Credentials()
+	if err != nil {
+		if r.valid && time.Now().Before(r.cached.Expiration) {
+			// Keep using the old credentials while they last.
+			return r.cached, nil
+		}
+		return Credentials{}, err
+	}
+	r.cached, r.valid = c, true
+	return c, nil
+}
+
+// Expire forces the next call to Credentials to fetch new credentials.
+func (r *RefreshingCredentials) Expire() {
+	r.mu.Lock()
+	r.valid = false
+	r.mu.Unlock()
+}
+
+// NewWithCredentials creates a new SQS that signs every request with the
+// credentials of p, refreshing them as they expire.
+func NewWithCredentials(p CredentialsProvider, region aws.Region) *SQS {
+	sqs := New(aws.Auth{}, region)
+	sqs.SetCredentialsProvider(p)
+	return sqs
+}
+
+// SetCredentialsProvider makes the client sign subsequent requests with the
+// credentials of p instead of its Auth. Unless p already is one, it is
+// wrapped in a RefreshingCredentials so it is consulted only when the
+// credentials near expiry.
+func (sqs *SQS) SetCredentialsProvider(p CredentialsProvider) {
+	r, ok := p.(*RefreshingCredentials)
+	if !ok && p != nil {
+		r = NewRefreshingCredentials(p)
+	}
+	sqs.authMu.Lock()
+	sqs.creds = r
+	sqs.authMu.Unlock()
+}
+
+// SetCredentials atomically replaces the credentials the client signs
+// subsequent requests with, e.g. after a key rotation, replacing any
+// provider set with SetCredentialsProvider. Requests already signed,
+// including those in flight, complete with the previous credentials.
+func (sqs *SQS) SetCredentials(c Credentials) {
+	r := NewRefreshingCredentials(StaticCredentials(c))
+	sqs.authMu.Lock()
+	sqs.Auth = c.auth()
+	sqs.creds = r
+	sqs.authMu.Unlock()
+}
+
+// SetQueueCredentialsProvider makes the client sign the requests to the
+// queues whose ARN starts with arnPrefix with the credentials of p, such
+// as those of a role assumed in another account; e.g. the prefix
+// "arn:aws:sqs:us-east-1:123456789012:" selects every queue of that
+// account in the region. The longest matching prefix wins, and other
+// requests use the client's own credentials. A nil p removes the prefix.
+//
+// p is wrapped in a RefreshingCredentials like in SetCredentialsProvider.
+// Use QueueByArn to address the queues of other accounts.
+func (sqs *SQS) SetQueueCredentialsProvider(arnPrefix string, p CredentialsProvider) {
+	r, ok := p.(*RefreshingCredentials)
+	if !ok && p != nil {
+		r = NewRefreshingCredentials(p)
+	}
+	sqs.authMu.Lock()
+	defer sqs.authMu.Unlock()
+	if r == nil {
+		delete(sqs.queueCreds, arnPrefix)
+		return
+	}
+	if sqs.queueCreds == nil {
+		sqs.queueCreds = make(map[string]*RefreshingCredentials)
+	}
+	sqs.queueCreds[arnPrefix] = r
+}
+
+// credentials returns the credentials to sign the next request to path
+// with.
+func (sqs *SQS) credentials(path_ string) (Credentials, error) {
+	creds, auth := sqs.provider(path_)
+	if creds == nil {
+		return Credentials{AccessKey: auth.AccessKey, SecretKey: auth.SecretKey}, nil
+	}
+	return creds.Credentials()
+}
+
+// provider returns the provider of the credentials for path, or nil and
+// the client's Auth.
+func (sqs *SQS) provider(path_ string) (*RefreshingCredentials, aws.Auth) {
+	sqs.authMu.RLock()
+	defer sqs.authMu.RUnlock()
+	creds := sqs.creds
+	if len(sqs.queueCreds) > 0 && queueName(path_) != "" {
+		arn := sqs.arn(path_)
+		best := ""
+		for prefix, r := range sqs.queueCreds {
+			if strings.HasPrefix(arn, prefix) && len(prefix) >= len(best) {
+				best, creds = prefix, r
+			}
+		}
+	}
+	return creds, sqs.Auth
+}
+
+// reauthenticate handles a request to path rejected with err. If the
+// credentials were refused and come from a provider, it expires them so
+// that rotated credentials are fetched, and reports that the request is
+// worth retrying.
+func (sqs *SQS) reauthenticate(path_ string, err error) bool {
+	if !IsAuthFailure(err) {
+		return false
+	}
+	creds, _ := sqs.provider(path_)
+	if creds == nil {
+		return false
+	}
+	creds.Expire()
+	return true
+}
@@ -0,0 +1,154 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/librato/goamz-aws/aws"
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestRefreshingCredentials(c *C) {
+	calls := 0
+	expiry := time.Now().Add(time.Hour)
+	r := NewRefreshingCredentials(CredentialsFunc(func() (Credentials, error) {
+		calls++
+		return Credentials{AccessKey: "key", SecretKey: "secret", SecurityToken: "token", Expiration: expiry}, nil
+	}))
+
+	creds, err := r.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(creds.SecurityToken, Equals, "token")
+	_, err = r.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 1)
+
+	expiry = time.Now().Add(time.Minute)
+	r.Expire()
+	_, err = r.Credentials()
+	c.Assert(err, IsNil)
+	_, err = r.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(calls, Equals, 3)
+}
+
+func (s *S) TestQueueCredentials(c *C) {
+	sqs := New(aws.Auth{AccessKey: "default", SecretKey: "s"}, aws.USEast)
+	sqs.SetQueueCredentialsProvider("arn:aws:sqs:us-east-1:111111111111:", StaticCredentials(Credentials{AccessKey: "account1", SecretKey: "s"}))
+	sqs.SetQueueCredentialsProvider("arn:aws:sqs:us-east-1:111111111111:special", StaticCredentials(Credentials{AccessKey: "special", SecretKey: "s"}))
+
+	key := func(path string) string {
+		creds, err := sqs.credentials(path)
+		c.Assert(err, IsNil)
+		return creds.AccessKey
+	}
+	c.Assert(key("/"), Equals, "default")
+	c.Assert(key("/222222222222/jobs"), Equals, "default")
+	c.Assert(key("/111111111111/jobs"), Equals, "account1")
+	c.Assert(key("/111111111111/special-jobs"), Equals, "special")
+
+	sqs.SetQueueCredentialsProvider("arn:aws:sqs:us-east-1:111111111111:special", nil)
+	c.Assert(key("/111111111111/special-jobs"), Equals, "account1")
+
+	q, err := sqs.QueueByArn("arn:aws:sqs:us-east-1:111111111111:jobs")
+	c.Assert(err, IsNil)
+	c.Assert(q.Arn(), Equals, "arn:aws:sqs:us-east-1:111111111111:jobs")
+	c.Assert(q.URL(), Equals, "https://sqs.us-east-1.amazonaws.com/111111111111/jobs")
+	_, err = sqs.QueueByArn("arn:aws:sqs:eu-west-1:111111111111:jobs")
+	c.Assert(err, ErrorMatches, "sqs: queue .* is not in region us-east-1")
+	_, err = sqs.QueueByArn("jobs")
+	c.Assert(err, ErrorMatches, `sqs: invalid queue ARN "jobs"`)
+}
+
+func (s *S) TestSetCredentials(c *C) {
+	l := NewLocal()
+	sqs := l.SQS()
+	var keys []string
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		key := requestParams(c, req).Get("AWSAccessKeyId")
+		keys = append(keys, key)
+		if key == "revoked" {
+			return xmlError(req, "InvalidClientTokenId", "The security token included in the request is invalid."), nil
+		}
+		return l.Do(req)
+	})
+
+	sqs.SetCredentials(Credentials{AccessKey: "first", SecretKey: "s"})
+	q, err := sqs.CreateQueue("rotated", nil)
+	c.Assert(err, IsNil)
+	sqs.SetCredentials(Credentials{AccessKey: "second", SecretKey: "s"})
+	_, err = q.SendMessage("hello")
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []string{"first", "second"})
+
+	// A refused key is refreshed from the provider and retried once.
+	keys = nil
+	current := "revoked"
+	sqs.SetCredentialsProvider(CredentialsFunc(func() (Credentials, error) {
+		return Credentials{AccessKey: current, SecretKey: "s"}, nil
+	}))
+	_, err = sqs.credentials(q.path)
+	c.Assert(err, IsNil)
+	current = "rotated"
+	_, err = q.SendMessage("hello")
+	c.Assert(err, IsNil)
+	c.Assert(keys, DeepEquals, []string{"revoked", "rotated"})
+}
+
+func (s *S) TestInstanceRoleCredentials(c *C) {
+	var (
+		mu      sync.Mutex
+		fetches int
+		expiry  = time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+		imdsV1  bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == "PUT" && r.URL.Path == "/latest/api/token" && !imdsV1 {
+			c.Check(r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"), Equals, "21600")
+			fmt.Fprint(w, "session")
+			return
+		}
+		if imdsV1 {
+			c.Check(r.Header.Get("X-aws-ec2-metadata-token"), Equals, "")
+		} else {
+			c.Check(r.Header.Get("X-aws-ec2-metadata-token"), Equals, "session")
+		}
+		switch r.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			fmt.Fprint(w, "web\n")
+		case "/latest/meta-data/iam/security-credentials/web":
+			fetches++
+			fmt.Fprintf(w, `{"Code":"Success","AccessKeyId":"AKID%d","SecretAccessKey":"secret%d","Token":"token%d","Expiration":%q}`,
+				fetches, fetches, fetches, expiry.Format(time.RFC3339))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	r := NewRefreshingCredentials(&InstanceRoleCredentials{Endpoint: srv.URL})
+	creds, err := r.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(creds, DeepEquals, Credentials{AccessKey: "AKID1", SecretKey: "secret1", SecurityToken: "token1", Expiration: expiry})
+
+	// Credentials within the expiry window are fetched again, falling back
+	// to IMDSv1 when no session token is issued.
+	mu.Lock()
+	imdsV1 = true
+	expiry = time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	mu.Unlock()
+	creds, err = r.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(creds.AccessKey, Equals, "AKID2")
+	creds, err = r.Credentials()
+	c.Assert(err, IsNil)
+	c.Assert(creds.AccessKey, Equals, "AKID2")
+
+	_, err = (&InstanceRoleCredentials{Endpoint: srv.URL, Role: "admin"}).Credentials()
+	c.Assert(err, ErrorMatches, "sqs: instance metadata request .*/admin failed: 404 Not Found")
+}
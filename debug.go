@@ -0,0 +1,134 @@
+package sqs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// An Exchange is a request to SQS and its response, captured for debugging
+// by SQS.Debug and Hooks.OnResponse.
+type Exchange struct {
+	Action string
+	Method string
+	URL    string
+	// Params holds the parameters of the request as signed, in its query
+	// or form body. They include the signature and, with temporary
+	// credentials, the security token: mind where exchanges are logged.
+	Params url.Values
+	// RequestBody is the body of the request, such as the document of a
+	// JSONProtocol request.
+	RequestBody []byte
+
+	// StatusCode, Header and Body are those of the response, if any.
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// RequestId is the ID SQS gave the request, from the x-amzn-RequestId
+	// header or the body of the response.
+	RequestId string
+	Duration  time.Duration
+}
+
+// ExchangeOf returns the exchange attached to err by SQS.Debug, or nil.
+func ExchangeOf(err error) *Exchange {
+	var e *ErrorResponse
+	if errors.As(err, &e) && e.Exchange != nil {
+		return e.Exchange
+	}
+	var xe *exchangeError
+	if errors.As(err, &xe) {
+		return xe.x
+	}
+	return nil
+}
+
+// An exchangeError attaches its exchange to an error other than an
+// *ErrorResponse.
+type exchangeError struct {
+	x   *Exchange
+	err error
+}
+
+func (e *exchangeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exchangeError) Unwrap() error {
+	return e.err
+}
+
+// capturing reports whether requests are captured as exchanges.
+func (sqs *SQS) capturing() bool {
+	return sqs.Debug || sqs.Hooks.OnResponse != nil
+}
+
+// newExchange captures req, leaving its body to be sent.
+func newExchange(action string, req *http.Request) *Exchange {
+	x := &Exchange{
+		Action: action,
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Params: req.URL.Query(),
+	}
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		if err == nil {
+			x.RequestBody = b
+		}
+		if strings.HasSuffix(req.Header.Get("Content-Type"), "x-www-form-urlencoded") {
+			if form, err := url.ParseQuery(string(b)); err == nil {
+				for k, v := range form {
+					x.Params[k] = append(x.Params[k], v...)
+				}
+			}
+		}
+	}
+	return x
+}
+
+// capture records the response r to the exchange x as its body is read.
+// The returned function completes x once the request is done.
+func (x *Exchange) capture(r *http.Response) func() {
+	x.StatusCode = r.StatusCode
+	x.Header = r.Header
+	var buf bytes.Buffer
+	body := r.Body
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(body, &buf), body}
+	return func() {
+		io.Copy(ioutil.Discard, r.Body)
+		x.Body = buf.Bytes()
+	}
+}
+
+// exchangeDone completes x, the exchange of a request that failed with
+// err, if any, and reports it. With SQS.Debug, it returns err with x
+// attached.
+func (sqs *SQS) exchangeDone(x *Exchange, start time.Time, err error) error {
+	x.Duration = time.Since(start)
+	if x.Header != nil {
+		x.RequestId = requestId(x.Header, x.Body, err)
+	}
+	if sqs.Hooks.OnResponse != nil {
+		sqs.Hooks.OnResponse(x)
+	}
+	if err == nil || !sqs.Debug {
+		return err
+	}
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		e.Exchange = x
+		return err
+	}
+	return &exchangeError{x, err}
+}
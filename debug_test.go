@@ -0,0 +1,64 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestDebug(c *C) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Amzn-Requestid", "req-1")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			fmt.Fprint(w, "<DeleteMessageResponse>")
+			return
+		}
+		fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>ReceiptHandleIsInvalid</Code></Error><RequestId>req-1</RequestId></ErrorResponse>`)
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	var exchanges []*Exchange
+	sqs.Hooks.OnResponse = func(x *Exchange) { exchanges = append(exchanges, x) }
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	// Without Debug, the hook sees every exchange, errors stay as they are.
+	status = http.StatusBadRequest
+	err := q.DeleteMessage(&Message{ReceiptHandle: "rh"})
+	c.Assert(err, FitsTypeOf, &ErrorResponse{})
+	c.Assert(ExchangeOf(err), IsNil)
+	c.Assert(exchanges, HasLen, 1)
+	x := exchanges[0]
+	c.Assert(x.Action, Equals, "DeleteMessage")
+	c.Assert(x.Params.Get("ReceiptHandle"), Equals, "rh")
+	c.Assert(x.Params.Get("Signature"), Not(Equals), "")
+	c.Assert(x.StatusCode, Equals, http.StatusBadRequest)
+	c.Assert(x.RequestId, Equals, "req-1")
+	c.Assert(string(x.Body), Matches, "<ErrorResponse>.*ReceiptHandleIsInvalid.*")
+
+	sqs.Debug = true
+	err = q.DeleteMessage(&Message{ReceiptHandle: "rh"})
+	c.Assert(ExchangeOf(err), Equals, exchanges[1])
+	c.Assert(err.(*ErrorResponse).EmbeddedError.Code, Equals, "ReceiptHandleIsInvalid")
+
+	// Errors other than error responses are wrapped.
+	status = http.StatusOK
+	err = q.DeleteMessage(&Message{ReceiptHandle: "rh"})
+	c.Assert(err, ErrorMatches, "sqs: DeleteMessage: decoding response.*")
+	x = ExchangeOf(err)
+	c.Assert(x, Equals, exchanges[2])
+	c.Assert(string(x.Body), Equals, "<DeleteMessageResponse>")
+
+	fault := errors.New("injected")
+	sqs.Middleware = []Middleware{func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) { return nil, fault })
+	}}
+	err = q.DeleteMessage(&Message{ReceiptHandle: "rh"})
+	c.Assert(errors.Is(err, fault), Equals, true)
+	c.Assert(ExchangeOf(err).StatusCode, Equals, 0)
+}
@@ -0,0 +1,71 @@
+package sqs
+
+import (
+	"sync"
+	"time"
+)
+
+// DedupOpt configures a Consumer skipping the messages it already handled,
+// which SQS may deliver more than once: redeliveries after a failed delete
+// or an expired visibility timeout, and duplicates sent by producers. A
+// message whose key was recorded is deleted without calling the handler.
+//
+// Keys are recorded once the handler succeeded, so a message redelivered
+// while its first delivery is still being handled is handled twice.
+type DedupOpt struct {
+	// Store records the keys of the messages handled; share one, such as
+	// Redis, among the consumers of a queue. It defaults to a
+	// MemoryStorage of the 10000 most recently used keys. Store errors
+	// let messages through.
+	Store Storage
+	// KeyAttribute names a string attribute holding an idempotency key
+	// set by the producer; messages without it, and all of them by
+	// default, are keyed by their message ID.
+	KeyAttribute string
+	// TTL is how long keys are recorded; it defaults to 24 hours.
+	TTL time.Duration
+
+	once  sync.Once
+	store Storage
+}
+
+func (o *DedupOpt) storage() Storage {
+	o.once.Do(func() {
+		o.store = o.Store
+		if o.store == nil {
+			o.store = &MemoryStorage{MaxEntries: 10000}
+		}
+	})
+	return o.store
+}
+
+// key returns the storage key of m.
+func (o *DedupOpt) key(m *Message) string {
+	if o.KeyAttribute != "" {
+		if k, err := m.GetString(o.KeyAttribute); err == nil && k != "" {
+			return "dedup/" + k
+		}
+	}
+	return "dedup/" + m.Id
+}
+
+// seen reports whether the key of m was recorded. Store errors are passed
+// to onError.
+func (o *DedupOpt) seen(m *Message, onError func(error)) bool {
+	_, err := o.storage().Get(o.key(m))
+	if err != nil && err != ErrNotStored {
+		onError(err)
+	}
+	return err == nil
+}
+
+// record records the key of m.
+func (o *DedupOpt) record(m *Message, onError func(error)) {
+	ttl := o.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if err := o.storage().Put(o.key(m), nil, ttl); err != nil {
+		onError(err)
+	}
+}
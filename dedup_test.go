@@ -0,0 +1,82 @@
+package sqs
+
+import (
+	"errors"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestConsumerDedup(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("payments", nil)
+	c.Assert(err, IsNil)
+	send := func(body, key string) {
+		opt := &SendMessageOpt{}
+		if key != "" {
+			opt.MessageAttributes = MessageAttributes{"key": {DataType: "String", StringValue: key}}
+		}
+		_, err := q.SendMessageWithOpt(body, opt)
+		c.Assert(err, IsNil)
+	}
+	send("charge", "k1")
+	send("charge again", "k1")
+	send("refund", "")
+
+	handled := make(chan string, 3)
+	store := &MemoryStorage{}
+	consumer := &Consumer{
+		Queue:     q,
+		BatchSize: 10,
+		Dedup:     &DedupOpt{Store: store, KeyAttribute: "key"},
+		Handler:   HandlerFunc(func(m *Message) error { handled <- m.Body; return nil }),
+	}
+	consumer.Start()
+	c.Assert(<-handled, Equals, "charge")
+	c.Assert(<-handled, Equals, "refund")
+	report := consumer.Stop()
+	c.Assert(report.Acked, Equals, int64(2))
+	c.Assert(report.Duplicates, Equals, int64(1))
+	c.Assert(report.Clean(), Equals, true)
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Visible+st.InFlight, Equals, 0)
+
+	// Keys outlive the consumer in its store.
+	_, err = store.Get("dedup/k1")
+	c.Assert(err, IsNil)
+	send("charge", "k1")
+	send("payout", "k2")
+	consumer.Start()
+	c.Assert(<-handled, Equals, "payout")
+	report = consumer.Stop()
+	c.Assert(report.Duplicates, Equals, int64(2))
+}
+
+func (s *S) TestConsumerDedupFailed(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("payments", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("charge")
+	c.Assert(err, IsNil)
+
+	// Messages whose handler failed are not recorded, so that their
+	// redelivery is handled.
+	attempts := make(chan int, 2)
+	n := 0
+	consumer := &Consumer{
+		Queue: q,
+		Dedup: &DedupOpt{},
+		Handler: HandlerFunc(func(m *Message) error {
+			n++
+			attempts <- n
+			if n == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		}),
+	}
+	consumer.Start()
+	c.Assert(<-attempts, Equals, 1)
+	c.Assert(<-attempts, Equals, 2)
+	report := consumer.Stop()
+	c.Assert(report.Acked, Equals, int64(1))
+	c.Assert(report.Duplicates, Equals, int64(0))
+}
@@ -0,0 +1,75 @@
+package sqs
+
+// QueueOpt holds the default options of the requests of a Queue, so that
+// they are configured in one place rather than at every call site. Each
+// applies to the requests leaving the matching option zero; a default
+// VisibilityTimeout can be overridden with VisibilityZero, the others not
+// with zero.
+type QueueOpt struct {
+	// VisibilityTimeout, WaitTimeSeconds, MaxNumberOfMessages,
+	// MessageAttributeNames and AttributeNames are the defaults of
+	// ReceiveMessageOpt.
+	VisibilityTimeout     int
+	WaitTimeSeconds       int
+	MaxNumberOfMessages   int
+	MessageAttributeNames []string
+	AttributeNames        []Attribute
+	// DelaySeconds is the default delay of the messages sent, with
+	// SendMessageOpt or SendMessageBatchEntry.
+	DelaySeconds int
+}
+
+// WithOptions returns a shallow copy of q whose requests default to the
+// options of opt, replacing those q had; a nil opt removes them.
+func (q *Queue) WithOptions(opt *QueueOpt) *Queue {
+	q2 := *q
+	q2.defaults = nil
+	if opt != nil {
+		o := *opt
+		q2.defaults = &o
+	}
+	return &q2
+}
+
+// Options returns the default options of q's requests.
+func (q *Queue) Options() QueueOpt {
+	if q.defaults == nil {
+		return QueueOpt{}
+	}
+	return *q.defaults
+}
+
+// receiveOpt returns opt, which may be nil, completed with the defaults of
+// q.
+func (q *Queue) receiveOpt(opt *ReceiveMessageOpt) *ReceiveMessageOpt {
+	var o ReceiveMessageOpt
+	if opt != nil {
+		o = *opt
+	}
+	if d := q.defaults; d != nil {
+		if o.VisibilityTimeout == 0 {
+			o.VisibilityTimeout = d.VisibilityTimeout
+		}
+		if o.WaitTimeSeconds == 0 {
+			o.WaitTimeSeconds = d.WaitTimeSeconds
+		}
+		if o.MaxNumberOfMessages == 0 {
+			o.MaxNumberOfMessages = d.MaxNumberOfMessages
+		}
+		if o.MessageAttributeNames == nil {
+			o.MessageAttributeNames = d.MessageAttributeNames
+		}
+		if o.AttributeNames == nil {
+			o.AttributeNames = d.AttributeNames
+		}
+	}
+	return &o
+}
+
+// sendDelay returns the delay of a message sent with delay d.
+func (q *Queue) sendDelay(d int) int {
+	if d == 0 && q.defaults != nil {
+		return q.defaults.DelaySeconds
+	}
+	return d
+}
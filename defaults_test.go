@@ -0,0 +1,54 @@
+package sqs
+
+import (
+	"net/url"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestQueueWithOptions(c *C) {
+	sqs := NewLocal().SQS()
+	var params url.Values
+	sqs.Hooks.BeforeSign = func(action string, p url.Values) { params = p }
+	plain, err := sqs.CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	q := plain.WithOptions(&QueueOpt{
+		VisibilityTimeout:     60,
+		WaitTimeSeconds:       1,
+		MaxNumberOfMessages:   10,
+		MessageAttributeNames: []string{"All"},
+		DelaySeconds:          5,
+	})
+	c.Assert(q.Options().VisibilityTimeout, Equals, 60)
+	c.Assert(plain.Options(), DeepEquals, QueueOpt{})
+
+	_, err = q.SendMessage("hello")
+	c.Assert(err, IsNil)
+	c.Assert(params.Get("DelaySeconds"), Equals, "5")
+	_, err = q.SendMessageWithOpt("hello", &SendMessageOpt{DelaySeconds: 10})
+	c.Assert(err, IsNil)
+	c.Assert(params.Get("DelaySeconds"), Equals, "10")
+	_, err = q.SendMessageBatch([]SendMessageBatchEntry{{Id: "1", Body: "hello"}})
+	c.Assert(err, IsNil)
+	c.Assert(params.Get("SendMessageBatchRequestEntry.1.DelaySeconds"), Equals, "5")
+	_, err = plain.SendMessage("hello")
+	c.Assert(err, IsNil)
+	c.Assert(params.Get("DelaySeconds"), Equals, "")
+
+	_, err = q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(params.Get("VisibilityTimeout"), Equals, "60")
+	c.Assert(params.Get("WaitTimeSeconds"), Equals, "1")
+	c.Assert(params.Get("MaxNumberOfMessages"), Equals, "10")
+	c.Assert(params.Get("MessageAttributeName.1"), Equals, "All")
+
+	opt := &ReceiveMessageOpt{VisibilityTimeout: VisibilityZero, MaxNumberOfMessages: 2}
+	_, err = q.ReceiveMessages(opt)
+	c.Assert(err, IsNil)
+	c.Assert(params.Get("VisibilityTimeout"), Equals, "0")
+	c.Assert(params.Get("MaxNumberOfMessages"), Equals, "2")
+	c.Assert(params.Get("WaitTimeSeconds"), Equals, "1")
+	c.Assert(opt.WaitTimeSeconds, Equals, 0)
+
+	c.Assert(q.WithOptions(nil).Options(), DeepEquals, QueueOpt{})
+}
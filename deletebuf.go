@@ -0,0 +1,88 @@
+package sqs
+
+import (
+	"sync"
+	"time"
+)
+
+// A DeleteBuffer collects acknowledged messages and deletes them from Queue
+// in the background with DeleteMessageBatch, once MaxBatchSize messages are
+// pending or Interval after the first pending one, so that consumers acking
+// many messages per second don't wait on one delete request per message.
+//
+// A message whose delete has not been sent yet may be redelivered if its
+// visibility timeout expires first; keep Interval well below it.
+type DeleteBuffer struct {
+	Queue QueueReceiver
+	// Interval is the longest a message waits for its delete; it
+	// defaults to one second.
+	Interval time.Duration
+	// OnError, if set, is called with request errors and with the
+	// *BatchResultErrorEntry of every message that failed to be deleted.
+	OnError func(err error)
+
+	mu      sync.Mutex
+	pending []*Message
+	timer   *time.Timer
+	wg      sync.WaitGroup
+}
+
+func (b *DeleteBuffer) onError(err error) {
+	if b.OnError != nil {
+		b.OnError(err)
+	}
+}
+
+// Delete queues m for deletion.
+func (b *DeleteBuffer) Delete(m *Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, m)
+	switch {
+	case len(b.pending) >= MaxBatchSize:
+		b.flushLocked()
+	case len(b.pending) == 1:
+		interval := b.Interval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		b.timer = time.AfterFunc(interval, b.Flush)
+	}
+}
+
+// Flush sends the pending deletes without waiting for the batch to fill.
+func (b *DeleteBuffer) Flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushLocked()
+}
+
+// Close flushes the pending deletes and waits for every batch in flight.
+func (b *DeleteBuffer) Close() {
+	b.Flush()
+	b.wg.Wait()
+}
+
+func (b *DeleteBuffer) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+	msgs := b.pending
+	b.pending = nil
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		res, err := b.Queue.DeleteMessageBatch(msgs)
+		if err != nil {
+			b.onError(err)
+			return
+		}
+		for i := range res.Failed {
+			b.onError(&res.Failed[i])
+		}
+	}()
+}
@@ -0,0 +1,58 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestDeleteBuffer(c *C) {
+	var mu sync.Mutex
+	var handles [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		c.Check(r.Form.Get("Action"), Equals, "DeleteMessageBatch")
+		var batch []string
+		fmt.Fprint(w, "<DeleteMessageBatchResponse><DeleteMessageBatchResult>")
+		for i := 1; r.Form.Get(fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.Id", i)) != ""; i++ {
+			p := fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.", i)
+			rh := r.Form.Get(p + "ReceiptHandle")
+			batch = append(batch, rh)
+			if rh == "stale" {
+				fmt.Fprintf(w, "<BatchResultErrorEntry><Id>%s</Id><Code>ReceiptHandleIsInvalid</Code><Message>stale</Message></BatchResultErrorEntry>", r.Form.Get(p+"Id"))
+			}
+		}
+		fmt.Fprint(w, "</DeleteMessageBatchResult></DeleteMessageBatchResponse>")
+		mu.Lock()
+		handles = append(handles, batch)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+
+	var errs []error
+	b := &DeleteBuffer{
+		Queue:    &Queue{SQS: sqs, path: "/123/q"},
+		Interval: 10 * time.Millisecond,
+		OnError:  func(err error) { errs = append(errs, err) },
+	}
+	for i := 0; i < 10; i++ {
+		b.Delete(&Message{ReceiptHandle: fmt.Sprint(i)})
+	}
+	b.Delete(&Message{ReceiptHandle: "stale"})
+	time.Sleep(50 * time.Millisecond)
+	b.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(handles, HasLen, 2)
+	c.Assert(handles[0], HasLen, 10)
+	c.Assert(handles[1], DeepEquals, []string{"stale"})
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errs[0], ErrorMatches, "sqs: batch entry 0 failed: ReceiptHandleIsInvalid: stale")
+}
@@ -0,0 +1,133 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// A RedrivePolicy moves messages that were received MaxReceiveCount times
+// without being deleted to the dead letter queue DeadLetterTargetArn. It is
+// stored as JSON in the RedrivePolicy queue attribute.
+type RedrivePolicy struct {
+	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	MaxReceiveCount     int    `json:"maxReceiveCount"`
+}
+
+// UnmarshalJSON accepts maxReceiveCount both as a number and as the string
+// SQS returns from GetQueueAttributes.
+func (p *RedrivePolicy) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		DeadLetterTargetArn string          `json:"deadLetterTargetArn"`
+		MaxReceiveCount     json.RawMessage `json:"maxReceiveCount"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	p.DeadLetterTargetArn = raw.DeadLetterTargetArn
+	p.MaxReceiveCount = 0
+	if len(raw.MaxReceiveCount) == 0 {
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw.MaxReceiveCount, &n); err != nil {
+		return fmt.Errorf("sqs: invalid maxReceiveCount %s", raw.MaxReceiveCount)
+	}
+	count, err := strconv.Atoi(n.String())
+	if err != nil {
+		return fmt.Errorf("sqs: invalid maxReceiveCount %s", raw.MaxReceiveCount)
+	}
+	p.MaxReceiveCount = count
+	return nil
+}
+
+// String returns the JSON encoding of the policy, as stored in the queue
+// attribute.
+func (p RedrivePolicy) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// MaxMaxReceiveCount is the largest MaxReceiveCount SQS accepts.
+const MaxMaxReceiveCount = 1000
+
+// Validate checks that the policy names a queue ARN and a MaxReceiveCount
+// SQS accepts.
+func (p *RedrivePolicy) Validate() error {
+	if !strings.HasPrefix(p.DeadLetterTargetArn, "arn:") || strings.Count(p.DeadLetterTargetArn, ":") != 5 {
+		return fmt.Errorf("sqs: invalid dead letter queue ARN %q", p.DeadLetterTargetArn)
+	}
+	if p.MaxReceiveCount < 1 || p.MaxReceiveCount > MaxMaxReceiveCount {
+		return fmt.Errorf("sqs: maxReceiveCount must be between 1 and %d, got %d", MaxMaxReceiveCount, p.MaxReceiveCount)
+	}
+	return nil
+}
+
+// ParseRedrivePolicy decodes the value of a RedrivePolicy queue attribute.
+func ParseRedrivePolicy(s string) (*RedrivePolicy, error) {
+	var p RedrivePolicy
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// RedrivePolicy returns the queue's redrive policy, or nil if it has none.
+func (q *Queue) RedrivePolicy() (*RedrivePolicy, error) {
+	attrs, err := q.GetQueueAttributes(RedrivePolicyAttribute)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := attrs.get(RedrivePolicyAttribute)
+	if !ok || v == "" {
+		return nil, nil
+	}
+	return ParseRedrivePolicy(v)
+}
+
+// SetRedrivePolicy validates p and sets it as the queue's redrive policy.
+// A nil policy removes it.
+func (q *Queue) SetRedrivePolicy(p *RedrivePolicy) error {
+	v := ""
+	if p != nil {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		v = p.String()
+	}
+	return q.SetQueueAttributes(map[Attribute]string{RedrivePolicyAttribute: v})
+}
+
+// SetDeadLetterQueue makes dlq the dead letter queue of q for messages
+// received more than maxReceiveCount times.
+func (q *Queue) SetDeadLetterQueue(dlq *Queue, maxReceiveCount int) error {
+	return q.SetRedrivePolicy(&RedrivePolicy{DeadLetterTargetArn: dlq.Arn(), MaxReceiveCount: maxReceiveCount})
+}
+
+type listDeadLetterSourceQueuesResponse struct {
+	Queues []string `xml:"ListDeadLetterSourceQueuesResult>QueueUrl"`
+	ResponseMetadata
+}
+
+// ListDeadLetterSourceQueues returns the queues whose redrive policy names
+// q as their dead letter queue.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_ListDeadLetterSourceQueues.html
+// for more details.
+func (q *Queue) ListDeadLetterSourceQueues() ([]*Queue, error) {
+	var resp listDeadLetterSourceQueuesResponse
+	if err := q.call("ListDeadLetterSourceQueues", q.path, url.Values{}, &resp); err != nil {
+		return nil, err
+	}
+	queues := make([]*Queue, len(resp.Queues))
+	for i, u := range resp.Queues {
+		sq, err := q.SQS.queueFromUrl(u)
+		if err != nil {
+			return nil, err
+		}
+		queues[i] = sq
+	}
+	return queues, nil
+}
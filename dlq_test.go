@@ -0,0 +1,31 @@
+package sqs
+
+import (
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestRedrivePolicy(c *C) {
+	p, err := ParseRedrivePolicy(`{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123:dlq","maxReceiveCount":"5"}`)
+	c.Assert(err, IsNil)
+	c.Assert(*p, Equals, RedrivePolicy{DeadLetterTargetArn: "arn:aws:sqs:us-east-1:123:dlq", MaxReceiveCount: 5})
+	c.Assert(p.String(), Equals, `{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123:dlq","maxReceiveCount":5}`)
+
+	p, err = ParseRedrivePolicy(p.String())
+	c.Assert(err, IsNil)
+	c.Assert(p.MaxReceiveCount, Equals, 5)
+
+	_, err = ParseRedrivePolicy(`{"maxReceiveCount":"five"}`)
+	c.Assert(err, NotNil)
+}
+
+func (s *S) TestRedrivePolicyValidate(c *C) {
+	p := &RedrivePolicy{DeadLetterTargetArn: "arn:aws:sqs:us-east-1:123:dlq", MaxReceiveCount: 5}
+	c.Assert(p.Validate(), IsNil)
+	p.MaxReceiveCount = 0
+	c.Assert(p.Validate(), ErrorMatches, "sqs: maxReceiveCount must be between 1 and 1000, got 0")
+	p.DeadLetterTargetArn = "dlq"
+	c.Assert(p.Validate(), ErrorMatches, `sqs: invalid dead letter queue ARN "dlq"`)
+
+	_, err := NewLocal().SQS().CreateQueue("jobs", (&CreateQueueOpt{}).WithDeadLetter("dlq", 5))
+	c.Assert(err, ErrorMatches, `sqs: invalid dead letter queue ARN "dlq"`)
+}
@@ -0,0 +1,133 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// A DLQAlarmEvent reports that messages arrived in a dead letter queue
+// faster than the configured threshold.
+type DLQAlarmEvent struct {
+	Queue     string    `json:"queue"`
+	Depth     int       `json:"depth"`
+	Delta     int       `json:"delta"`
+	Rate      float64   `json:"rate_per_minute"`
+	Threshold float64   `json:"threshold_per_minute"`
+	At        time.Time `json:"at"`
+}
+
+// A DLQAlarm watches the depth of a dead letter queue and raises an alarm
+// when messages arrive faster than Threshold per minute, giving immediate
+// signal on processing regressions without a CloudWatch alarm.
+//
+// The alarm fires once when the rate first exceeds the threshold and is
+// re-armed as soon as the rate falls back below it.
+type DLQAlarm struct {
+	Queue     *Queue
+	Threshold float64       // messages per minute
+	Interval  time.Duration // depth sampling interval, default one minute
+
+	// OnAlarm, if set, is called for every alarm.
+	OnAlarm func(e DLQAlarmEvent)
+	// WebhookURL, if set, receives every alarm as a JSON POST.
+	WebhookURL string
+	Client     *http.Client
+	// OnError, if set, is called with sampling and webhook errors.
+	OnError func(err error)
+}
+
+func (a *DLQAlarm) onError(err error) {
+	if a.OnError != nil {
+		a.OnError(err)
+	}
+}
+
+// Run samples the queue depth until ctx is done.
+func (a *DLQAlarm) Run(ctx context.Context) error {
+	interval := a.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	last, lastAt, err := a.depth()
+	for err != nil {
+		a.onError(err)
+		if !sleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+		last, lastAt, err = a.depth()
+	}
+
+	firing := false
+	for sleepContext(ctx, interval) {
+		depth, at, err := a.depth()
+		if err != nil {
+			a.onError(err)
+			continue
+		}
+		delta := depth - last
+		rate := float64(delta) / at.Sub(lastAt).Minutes()
+		last, lastAt = depth, at
+		if rate <= a.Threshold {
+			firing = false
+			continue
+		}
+		if firing {
+			continue
+		}
+		firing = true
+		a.fire(DLQAlarmEvent{
+			Queue:     a.Queue.Name(),
+			Depth:     depth,
+			Delta:     delta,
+			Rate:      rate,
+			Threshold: a.Threshold,
+			At:        at,
+		})
+	}
+	return ctx.Err()
+}
+
+func (a *DLQAlarm) depth() (int, time.Time, error) {
+	attrs, err := a.Queue.GetQueueAttributes(ApproximateNumberOfMessages)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	v, _ := attrs.get(ApproximateNumberOfMessages)
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("sqs: invalid ApproximateNumberOfMessages %q", v)
+	}
+	return n, time.Now(), nil
+}
+
+func (a *DLQAlarm) fire(e DLQAlarmEvent) {
+	if a.OnAlarm != nil {
+		a.OnAlarm(e)
+	}
+	if a.WebhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		a.onError(err)
+		return
+	}
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	r, err := client.Post(a.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		a.onError(err)
+		return
+	}
+	r.Body.Close()
+	if r.StatusCode/100 != 2 {
+		a.onError(fmt.Errorf("sqs: DLQ alarm webhook returned %s", r.Status))
+	}
+}
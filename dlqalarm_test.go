@@ -0,0 +1,73 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestDLQAlarm(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	depths := []int{0, 0, 100, 200, 200, 300}
+	var mu sync.Mutex
+	samples := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		i := samples
+		samples++
+		mu.Unlock()
+		if i >= len(depths) {
+			i = len(depths) - 1
+			cancel()
+		}
+		fmt.Fprintf(w, "<GetQueueAttributesResponse><GetQueueAttributesResult><Attribute><Name>ApproximateNumberOfMessages</Name><Value>%d</Value></Attribute></GetQueueAttributesResult></GetQueueAttributesResponse>", depths[i])
+	}))
+	defer srv.Close()
+	var posted []DLQAlarmEvent
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e DLQAlarmEvent
+		c.Check(r.Header.Get("Content-Type"), Equals, "application/json")
+		c.Check(json.NewDecoder(r.Body).Decode(&e), IsNil)
+		posted = append(posted, e)
+		if len(posted) == 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer hook.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+
+	// The alarm fires when the rate first exceeds the threshold, and again
+	// once re-armed by a quiet sample.
+	var alarms []DLQAlarmEvent
+	var errs []error
+	a := &DLQAlarm{
+		Queue:      &Queue{SQS: sqs, path: "/123/orders-dlq"},
+		Threshold:  10,
+		Interval:   10 * time.Millisecond,
+		OnAlarm:    func(e DLQAlarmEvent) { alarms = append(alarms, e) },
+		WebhookURL: hook.URL,
+		OnError:    func(err error) { errs = append(errs, err) },
+	}
+	c.Assert(a.Run(ctx), Equals, context.Canceled)
+	c.Assert(alarms, HasLen, 2)
+	for i, depth := range []int{100, 300} {
+		e := alarms[i]
+		c.Check(e.Queue, Equals, "orders-dlq")
+		c.Check(e.Depth, Equals, depth)
+		c.Check(e.Delta, Equals, 100)
+		c.Check(e.Rate > e.Threshold, Equals, true)
+		c.Check(e.Threshold, Equals, 10.0)
+	}
+	c.Assert(posted, HasLen, 2)
+	c.Assert(posted[1].Depth, Equals, 300)
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errs[0], ErrorMatches, "sqs: DLQ alarm webhook returned 500 Internal Server Error")
+}
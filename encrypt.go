@@ -0,0 +1,182 @@
+package sqs
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// EncryptionAttribute is the message attribute holding the metadata of an
+// encrypted body: the algorithm, the ID of the master key and the
+// encrypted data key, as JSON.
+const EncryptionAttribute = "encryption"
+
+const encryptionAlgorithm = "AES-256-GCM"
+
+// A KeyProvider issues and decrypts the data keys of envelope encryption,
+// in the manner of AWS KMS GenerateDataKey and Decrypt.
+type KeyProvider interface {
+	// GenerateDataKey returns a new 256-bit data key, in plaintext and
+	// encrypted under the master key identified by keyId.
+	GenerateDataKey(ctx context.Context) (keyId string, plaintext, encrypted []byte, err error)
+	// DecryptDataKey returns the plaintext of a data key encrypted under
+	// the master key identified by keyId.
+	DecryptDataKey(ctx context.Context, keyId string, encrypted []byte) ([]byte, error)
+}
+
+type encryptionHeader struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+	DataKey   []byte `json:"key"`
+}
+
+// Encryption is a Transformer encrypting message bodies with a data key
+// of its own per message, itself encrypted by Keys and carried in the
+// EncryptionAttribute, for queues whose contents must stay opaque to
+// anyone with access to the queue alone. It should come last in
+// SQS.Transformers, so bodies are compressed before being encrypted.
+type Encryption struct {
+	Keys KeyProvider
+	// Optional leaves received messages without an EncryptionAttribute
+	// untouched instead of failing, for the migration of a queue.
+	Optional bool
+}
+
+// Encode implements Transformer.
+func (e *Encryption) Encode(ctx context.Context, m *Message) error {
+	keyId, key, encrypted, err := e.Keys.GenerateDataKey(ctx)
+	if err != nil {
+		return fmt.Errorf("sqs: generating data key: %s", err)
+	}
+	sealed, err := seal(key, []byte(m.Body), []byte(keyId))
+	if err != nil {
+		return err
+	}
+	h, err := json.Marshal(encryptionHeader{encryptionAlgorithm, keyId, encrypted})
+	if err != nil {
+		return err
+	}
+	m.Body = base64.StdEncoding.EncodeToString(sealed)
+	m.MessageAttributes[EncryptionAttribute] = MessageAttributeValue{DataType: "String", StringValue: string(h)}
+	return nil
+}
+
+// Decode implements Transformer.
+func (e *Encryption) Decode(ctx context.Context, m *Message) error {
+	v, ok := m.MessageAttributes[EncryptionAttribute]
+	if !ok {
+		if e.Optional {
+			return nil
+		}
+		return errors.New("sqs: message is not encrypted")
+	}
+	var h encryptionHeader
+	if err := json.Unmarshal([]byte(v.StringValue), &h); err != nil {
+		return fmt.Errorf("sqs: invalid encryption attribute: %s", err)
+	}
+	if h.Algorithm != encryptionAlgorithm {
+		return fmt.Errorf("sqs: unsupported encryption algorithm %q", h.Algorithm)
+	}
+	key, err := e.Keys.DecryptDataKey(ctx, h.KeyId, h.DataKey)
+	if err != nil {
+		return fmt.Errorf("sqs: decrypting data key: %s", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(m.Body)
+	if err != nil {
+		return err
+	}
+	body, err := open(key, sealed, []byte(h.KeyId))
+	if err != nil {
+		return err
+	}
+	m.Body = string(body)
+	delete(m.MessageAttributes, EncryptionAttribute)
+	return nil
+}
+
+// Attributes implements AttributeTransformer.
+func (e *Encryption) Attributes() []string {
+	return []string{EncryptionAttribute}
+}
+
+// seal encrypts plaintext with AES-GCM under key, prefixing the random
+// nonce.
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// open decrypts what seal encrypted.
+func open(key, sealed, additional []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sqs: encrypted body too short")
+	}
+	n := aead.NonceSize()
+	b, err := aead.Open(nil, sealed[:n], sealed[n:], additional)
+	if err != nil {
+		return nil, errors.New("sqs: message authentication failed")
+	}
+	return b, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("sqs: data key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// StaticKeys is a KeyProvider wrapping data keys with 256-bit master keys
+// held in memory, for tests and for teams without a key management
+// service. New data keys are wrapped with the master key named Current;
+// older keys are kept to decrypt messages sent before a rotation.
+type StaticKeys struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// GenerateDataKey implements KeyProvider.
+func (k *StaticKeys) GenerateDataKey(ctx context.Context) (string, []byte, []byte, error) {
+	master, ok := k.Keys[k.Current]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("sqs: unknown master key %q", k.Current)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", nil, nil, err
+	}
+	encrypted, err := seal(master, key, []byte(k.Current))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return k.Current, key, encrypted, nil
+}
+
+// DecryptDataKey implements KeyProvider.
+func (k *StaticKeys) DecryptDataKey(ctx context.Context, keyId string, encrypted []byte) ([]byte, error) {
+	master, ok := k.Keys[keyId]
+	if !ok {
+		return nil, fmt.Errorf("sqs: unknown master key %q", keyId)
+	}
+	return open(master, encrypted, []byte(keyId))
+}
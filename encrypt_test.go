@@ -0,0 +1,56 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestEncryption(c *C) {
+	keys := &StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	sqs := NewLocal().SQS()
+	sqs.Transformers = []Transformer{&Compression{Codec: "gzip"}, &Encryption{Keys: keys}}
+	q, err := sqs.CreateQueue("secret", nil)
+	c.Assert(err, IsNil)
+
+	body := strings.Repeat("top secret ", 20)
+	_, err = q.SendMessage(body)
+	c.Assert(err, IsNil)
+
+	// The queue only holds ciphertext.
+	raw := &Queue{SQS: &SQS{Endpoint: sqs.Endpoint, Client: sqs.Client}, path: q.path}
+	msgs, err := raw.ReceiveMessages(&ReceiveMessageOpt{VisibilityTimeout: VisibilityZero, MessageAttributeNames: []string{"All"}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(strings.Contains(msgs[0].Body, "secret"), Equals, false)
+	c.Assert(msgs[0].MessageAttributes[EncryptionAttribute].StringValue, Matches, `\{"alg":"AES-256-GCM","kid":"k1","key":".*"\}`)
+
+	// Rotating the master key keeps older messages readable.
+	keys.Keys["k2"] = bytes.Repeat([]byte{2}, 32)
+	keys.Current = "k2"
+	msgs, err = q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, body)
+	c.Assert(msgs[0].MessageAttributes, HasLen, 0)
+}
+
+func (s *S) TestEncryptionTampering(c *C) {
+	keys := &StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}}
+	e := &Encryption{Keys: keys}
+	ctx := context.Background()
+	m := &Message{Body: "hello", MessageAttributes: MessageAttributes{}}
+	c.Assert(e.Encode(ctx, m), IsNil)
+	sealed, _ := base64.StdEncoding.DecodeString(m.Body)
+	sealed[len(sealed)-1] ^= 1
+	m.Body = base64.StdEncoding.EncodeToString(sealed)
+	c.Assert(e.Decode(ctx, m), ErrorMatches, "sqs: message authentication failed")
+
+	plain := &Message{Body: "plain"}
+	c.Assert(e.Decode(ctx, plain), ErrorMatches, "sqs: message is not encrypted")
+	e.Optional = true
+	c.Assert(e.Decode(ctx, plain), IsNil)
+}
@@ -0,0 +1,139 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Exists reports whether the queue exists. Other errors, such as access
+// being denied, are returned as is.
+func (q *Queue) Exists() (bool, error) {
+	// Asking for no attribute is the cheapest call on a queue.
+	_, err := q.GetQueueAttributes()
+	if errors.Is(err, ErrQueueNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// EnsureQueue returns the named queue, creating it with opt if it does not
+// exist. If it does exist with attributes other than those set in opt,
+// which makes CreateQueue fail with QueueAlreadyExists, they are updated
+// to match opt; attributes opt leaves unset are kept. Whether the queue is
+// a FIFO queue cannot change, so a mismatch there is an error. opt may be
+// nil.
+func (sqs *SQS) EnsureQueue(name string, opt *CreateQueueOpt) (*Queue, error) {
+	if opt == nil {
+		opt = &CreateQueueOpt{}
+	}
+	q, err := sqs.CreateQueue(name, opt)
+	if ErrorCode(err) != ErrCodeQueueAlreadyExists {
+		return q, err
+	}
+	if q, err = sqs.Queue(name); err != nil {
+		return nil, err
+	}
+	want := opt.attributes()
+	names := make([]Attribute, 0, len(want))
+	for name := range want {
+		names = append(names, name)
+	}
+	have, err := q.GetQueueAttributes(names...)
+	if err != nil {
+		return nil, err
+	}
+	update := make(map[Attribute]string)
+	for name, v := range want {
+		if old, _ := have.get(name); old != v {
+			update[name] = v
+		}
+	}
+	if v, ok := update[FifoQueue]; ok {
+		return nil, fmt.Errorf("sqs: queue %s exists with FifoQueue other than %s", q.Name(), v)
+	}
+	if len(update) > 0 {
+		if err := q.SetQueueAttributes(update); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+// WaitOpt configures WaitUntilAttributes.
+type WaitOpt struct {
+	// Interval is the time between reads of the attributes; it defaults
+	// to five seconds.
+	Interval time.Duration
+	// Consecutive is the number of reads in a row that must match,
+	// default 1. As reads may be served by hosts the change has not
+	// reached yet, a couple more guard against a lucky one.
+	Consecutive int
+}
+
+// WaitUntilAttributes polls the attributes of the queue until they match
+// expected, which can take up to a minute after CreateQueue or
+// SetQueueAttributes, or until ctx is done. Policies and other JSON values
+// match when they are equivalent, however formatted. A queue not found yet
+// is waited for too. opt may be nil.
+//
+// The error returned once ctx is done tells the last mismatch and wraps
+// the error of ctx.
+func (q *Queue) WaitUntilAttributes(ctx context.Context, expected map[Attribute]string, opt *WaitOpt) error {
+	if opt == nil {
+		opt = &WaitOpt{}
+	}
+	interval := opt.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	names := make([]Attribute, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	var mismatch string
+	for matched := 0; ; {
+		attrs, err := q.WithContext(ctx).GetQueueAttributes(names...)
+		switch {
+		case errors.Is(err, ErrQueueNotFound):
+			mismatch, matched = "queue not found", 0
+		case err == nil:
+			mismatch = ""
+			for _, name := range names {
+				if v, _ := attrs.get(name); !attributeEqual(v, expected[name]) {
+					mismatch = fmt.Sprintf("attribute %s is %q", name, v)
+					break
+				}
+			}
+			if mismatch != "" {
+				matched = 0
+			} else if matched++; matched >= opt.Consecutive {
+				return nil
+			}
+		case ctx.Err() == nil:
+			return err
+		}
+		if ctx.Err() != nil || !sleepContext(ctx, interval) {
+			if mismatch == "" {
+				mismatch = "not confirmed"
+			}
+			return fmt.Errorf("sqs: waiting for attributes of queue %s, %s: %w", q.Name(), mismatch, ctx.Err())
+		}
+	}
+}
+
+// attributeEqual tells whether the attribute values a and b match, JSON
+// values being compared once decoded.
+func attributeEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
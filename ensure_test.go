@@ -0,0 +1,59 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestEnsureQueue(c *C) {
+	sqs := NewLocal().SQS()
+	q, err := sqs.EnsureQueue("jobs", &CreateQueueOpt{VisibilityTimeout: 30})
+	c.Assert(err, IsNil)
+	ok, err := q.Exists()
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+
+	// Same attributes: nothing to do.
+	_, err = sqs.EnsureQueue("jobs", &CreateQueueOpt{VisibilityTimeout: 30})
+	c.Assert(err, IsNil)
+
+	// CreateQueue fails, EnsureQueue updates the queue instead.
+	opt := &CreateQueueOpt{VisibilityTimeout: 60, DelaySeconds: 5}
+	_, err = sqs.CreateQueue("jobs", opt)
+	c.Assert(ErrorCode(err), Equals, ErrCodeQueueAlreadyExists)
+	q, err = sqs.EnsureQueue("jobs", opt)
+	c.Assert(err, IsNil)
+	attrs, err := q.GetQueueAttributes(All)
+	c.Assert(err, IsNil)
+	c.Assert(attrs.VisibilityTimeout().Seconds(), Equals, 60.0)
+	c.Assert(attrs.DelaySeconds().Seconds(), Equals, 5.0)
+
+	c.Assert(q.DeleteQueue(), IsNil)
+	ok, err = q.Exists()
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+}
+
+func (s *S) TestWaitUntilAttributes(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	policy := `{"Version": "2012-10-17", "Statement": []}`
+	opt := &WaitOpt{Interval: time.Millisecond, Consecutive: 2}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.SetQueueAttributes(map[Attribute]string{VisibilityTimeout: "45", Policy: policy})
+	}()
+	// The policy is matched however formatted.
+	expected := map[Attribute]string{VisibilityTimeout: "45", Policy: `{"Statement":[],"Version":"2012-10-17"}`}
+	c.Assert(q.WaitUntilAttributes(context.Background(), expected, opt), IsNil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = q.WaitUntilAttributes(ctx, map[Attribute]string{VisibilityTimeout: "60"}, opt)
+	c.Assert(errors.Is(err, context.DeadlineExceeded), Equals, true)
+	c.Assert(err, ErrorMatches, `sqs: waiting for attributes of queue jobs, attribute VisibilityTimeout is "45": .*`)
+}
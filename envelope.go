@@ -0,0 +1,124 @@
+package sqs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ContentTypeAttribute is the message attribute recording the media type
+// of a body marshaled by a Codec.
+const ContentTypeAttribute = "content-type"
+
+// A Codec marshals Go values to message bodies and back.
+type Codec interface {
+	// ContentType returns the media type of the bodies, recorded in the
+	// ContentTypeAttribute of the messages.
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+// JSON is the Codec of application/json bodies. It is used for messages
+// without a ContentTypeAttribute.
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string                     { return "application/json" }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)   { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error { return json.Unmarshal(b, v) }
+
+var (
+	contentCodecsMu sync.RWMutex
+	contentCodecs   = map[string]Codec{"application/json": JSON}
+)
+
+// RegisterCodec makes c available to unmarshal the messages of its content
+// type. JSON is built in.
+func RegisterCodec(c Codec) {
+	contentCodecsMu.Lock()
+	defer contentCodecsMu.Unlock()
+	contentCodecs[c.ContentType()] = c
+}
+
+// An UnmarshalError reports a message whose body could not be unmarshaled,
+// as opposed to a failure to receive it. Message is the message received.
+type UnmarshalError struct {
+	Message     *Message
+	ContentType string
+	Err         error
+}
+
+func (e *UnmarshalError) Error() string {
+	return fmt.Sprintf("sqs: unmarshaling %s message %s: %s", e.ContentType, e.Message.Id, e.Err)
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// SendJSON sends v marshaled as JSON.
+func (q *Queue) SendJSON(v interface{}) (string, error) {
+	return q.SendValue(JSON, v, nil)
+}
+
+// SendValue sends v marshaled with c, recording its content type in the
+// ContentTypeAttribute. The attributes of opt are left untouched.
+func (q *Queue) SendValue(c Codec, v interface{}, opt *SendMessageOpt) (string, error) {
+	b, err := c.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	o := SendMessageOpt{}
+	if opt != nil {
+		o = *opt
+	}
+	o.MessageAttributes = make(MessageAttributes, len(o.MessageAttributes)+1)
+	if opt != nil {
+		for name, a := range opt.MessageAttributes {
+			o.MessageAttributes[name] = a
+		}
+	}
+	o.MessageAttributes[ContentTypeAttribute] = MessageAttributeValue{DataType: "String", StringValue: c.ContentType()}
+	return q.SendMessageWithOpt(string(b), &o)
+}
+
+// Unmarshal unmarshals the body of m into v with the codec registered for
+// its ContentTypeAttribute, or JSON if it has none. Errors are
+// *UnmarshalError.
+func (m *Message) Unmarshal(v interface{}) error {
+	contentType := JSON.ContentType()
+	if a, ok := m.MessageAttributes[ContentTypeAttribute]; ok {
+		contentType = a.StringValue
+	}
+	contentCodecsMu.RLock()
+	c, ok := contentCodecs[contentType]
+	contentCodecsMu.RUnlock()
+	if !ok {
+		return &UnmarshalError{Message: m, ContentType: contentType, Err: errors.New("no codec registered")}
+	}
+	if err := c.Unmarshal([]byte(m.Body), v); err != nil {
+		return &UnmarshalError{Message: m, ContentType: contentType, Err: err}
+	}
+	return nil
+}
+
+// ReceiveInto receives one message and unmarshals its body into v, see
+// Message.Unmarshal. It returns the message, to be deleted once handled,
+// or nil if none was available. A message that fails to unmarshal is
+// returned with an *UnmarshalError; it is left in the queue.
+func (q *Queue) ReceiveInto(v interface{}, opt *ReceiveMessageOpt) (*Message, error) {
+	o := ReceiveMessageOpt{}
+	if opt != nil {
+		o = *opt
+	}
+	o.MaxNumberOfMessages = 1
+	o.MessageAttributeNames = append(o.MessageAttributeNames[:len(o.MessageAttributeNames):len(o.MessageAttributeNames)], ContentTypeAttribute)
+	msgs, err := q.ReceiveMessages(&o)
+	if err != nil || len(msgs) == 0 {
+		return nil, err
+	}
+	return msgs[0], msgs[0].Unmarshal(v)
+}
@@ -0,0 +1,44 @@
+package sqs
+
+import (
+	"errors"
+
+	. "launchpad.net/gocheck"
+)
+
+type order struct {
+	ID    int      `json:"id"`
+	Items []string `json:"items"`
+}
+
+func (s *S) TestSendJSONReceiveInto(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("orders", nil)
+	c.Assert(err, IsNil)
+
+	_, err = q.SendJSON(order{ID: 1, Items: []string{"a", "b"}})
+	c.Assert(err, IsNil)
+	var o order
+	m, err := q.ReceiveInto(&o, nil)
+	c.Assert(err, IsNil)
+	c.Assert(o, DeepEquals, order{ID: 1, Items: []string{"a", "b"}})
+	c.Assert(m.MessageAttributes[ContentTypeAttribute].StringValue, Equals, "application/json")
+	c.Assert(q.DeleteMessage(m), IsNil)
+
+	m, err = q.ReceiveInto(&o, nil)
+	c.Assert(err, IsNil)
+	c.Assert(m, IsNil)
+
+	_, err = q.SendMessage("not json")
+	c.Assert(err, IsNil)
+	m, err = q.ReceiveInto(&o, nil)
+	var uerr *UnmarshalError
+	c.Assert(errors.As(err, &uerr), Equals, true)
+	c.Assert(uerr.Message, Equals, m)
+	c.Assert(m.Body, Equals, "not json")
+}
+
+func (s *S) TestUnmarshalUnknownContentType(c *C) {
+	m := &Message{Id: "1", Body: "<x/>", MessageAttributes: MessageAttributes{ContentTypeAttribute: {DataType: "String", StringValue: "application/xml"}}}
+	var v interface{}
+	c.Assert(m.Unmarshal(&v), ErrorMatches, "sqs: unmarshaling application/xml message 1: no codec registered")
+}
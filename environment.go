@@ -0,0 +1,89 @@
+package sqs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Environment names.
+const (
+	Dev     = "dev"
+	Staging = "staging"
+	Prod    = "prod"
+)
+
+// An Environment is a facade over an SQS client for one deployment
+// environment, meant for operator tooling. It confines queue lookups to the
+// environment's queues and, in protected environments, makes destructive
+// operations require an explicit confirmation token.
+type Environment struct {
+	Name string
+	SQS  *SQS
+	// QueuePrefix, if set, is prepended to queue names by Queue, and
+	// destructive operations refuse queues without it.
+	QueuePrefix string
+	// Protected requires confirmation tokens for destructive operations.
+	// NewEnvironment sets it for Prod.
+	Protected bool
+}
+
+// NewEnvironment returns an environment named name, protected if name is
+// Prod, whose queues are prefixed with name and a dash.
+func NewEnvironment(name string, sqs *SQS) *Environment {
+	return &Environment{
+		Name:        name,
+		SQS:         sqs,
+		QueuePrefix: name + "-",
+		Protected:   name == Prod,
+	}
+}
+
+// A ConfirmationError is returned by destructive operations of a protected
+// environment called without the right confirmation token.
+type ConfirmationError struct {
+	Action string
+	Queue  string
+	Token  string // the expected token
+}
+
+func (e *ConfirmationError) Error() string {
+	return fmt.Sprintf("sqs: %s of queue %s requires confirmation token %q", e.Action, e.Queue, e.Token)
+}
+
+// Queue returns the environment's queue with the given unprefixed name.
+func (e *Environment) Queue(name string) (*Queue, error) {
+	return e.SQS.Queue(e.QueuePrefix + name)
+}
+
+// ConfirmationToken returns the token that confirms action on q, to be
+// typed by the operator: "<environment>/<action>/<queue name>".
+func (e *Environment) ConfirmationToken(action string, q *Queue) string {
+	return e.Name + "/" + action + "/" + q.Name()
+}
+
+// check enforces the interlocks of a destructive action on q.
+func (e *Environment) check(action string, q *Queue, token string) error {
+	if !strings.HasPrefix(q.Name(), e.QueuePrefix) {
+		return fmt.Errorf("sqs: queue %s does not belong to environment %s", q.Name(), e.Name)
+	}
+	if want := e.ConfirmationToken(action, q); e.Protected && token != want {
+		return &ConfirmationError{Action: action, Queue: q.Name(), Token: want}
+	}
+	return nil
+}
+
+// PurgeQueue purges q, which needs token in a protected environment.
+func (e *Environment) PurgeQueue(q *Queue, token string) error {
+	if err := e.check("purge", q, token); err != nil {
+		return err
+	}
+	return q.PurgeQueue()
+}
+
+// DeleteQueue deletes q, which needs token in a protected environment.
+func (e *Environment) DeleteQueue(q *Queue, token string) error {
+	if err := e.check("delete", q, token); err != nil {
+		return err
+	}
+	return q.DeleteQueue()
+}
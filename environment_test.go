@@ -0,0 +1,21 @@
+package sqs
+
+import (
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestEnvironmentInterlocks(c *C) {
+	prod := NewEnvironment(Prod, s.sqs)
+	q := &Queue{SQS: s.sqs, path: "/123/prod-orders"}
+	c.Assert(prod.ConfirmationToken("purge", q), Equals, "prod/purge/prod-orders")
+
+	err := prod.PurgeQueue(q, "")
+	c.Assert(err, ErrorMatches, `sqs: purge of queue prod-orders requires confirmation token "prod/purge/prod-orders"`)
+	err = prod.DeleteQueue(q, "prod/purge/prod-orders")
+	c.Assert(err, FitsTypeOf, &ConfirmationError{})
+
+	staging := NewEnvironment(Staging, s.sqs)
+	c.Assert(staging.Protected, Equals, false)
+	err = staging.PurgeQueue(q, "")
+	c.Assert(err, ErrorMatches, "sqs: queue prod-orders does not belong to environment staging")
+}
@@ -0,0 +1,92 @@
+package sqs
+
+import "errors"
+
+// Error codes returned by SQS in ErrorResponse.EmbeddedError.Code.
+const (
+	ErrCodeNonExistentQueue             = "AWS.SimpleQueueService.NonExistentQueue"
+	ErrCodeQueueDoesNotExist            = "QueueDoesNotExist"
+	ErrCodeQueueAlreadyExists           = "QueueAlreadyExists"
+	ErrCodePurgeQueueInProgress         = "AWS.SimpleQueueService.PurgeQueueInProgress"
+	ErrCodeQueueDeletedRecently         = "AWS.SimpleQueueService.QueueDeletedRecently"
+	ErrCodeOverLimit                    = "OverLimit"
+	ErrCodeRequestThrottled             = "RequestThrottled"
+	ErrCodeThrottlingException          = "ThrottlingException"
+	ErrCodeReceiptHandleIsInvalid       = "ReceiptHandleIsInvalid"
+	ErrCodeMessageNotInflight           = "AWS.SimpleQueueService.MessageNotInflight"
+	ErrCodeBatchEntryIdsNotDistinct     = "AWS.SimpleQueueService.BatchEntryIdsNotDistinct"
+	ErrCodeEmptyBatchRequest            = "AWS.SimpleQueueService.EmptyBatchRequest"
+	ErrCodeTooManyEntriesInBatchRequest = "AWS.SimpleQueueService.TooManyEntriesInBatchRequest"
+	ErrCodeInvalidParameterValue        = "InvalidParameterValue"
+	ErrCodeMissingParameter             = "MissingParameter"
+	ErrCodeInvalidAttributeValue        = "InvalidAttributeValue"
+	ErrCodeInvalidAction                = "InvalidAction"
+	ErrCodeAccessDenied                 = "AccessDenied"
+	ErrCodeAccessDeniedException        = "AccessDeniedException"
+	ErrCodeInvalidClientTokenId         = "InvalidClientTokenId"
+	ErrCodeInvalidAccessKeyId           = "InvalidAccessKeyId"
+	ErrCodeSignatureDoesNotMatch        = "SignatureDoesNotMatch"
+	ErrCodeExpiredToken                 = "ExpiredToken"
+)
+
+var (
+	// ErrReceiptHandleInvalid is matched, with errors.Is, by the errors
+	// refusing a receipt handle, malformed or of a message deleted or
+	// received again since.
+	ErrReceiptHandleInvalid = errors.New("sqs: receipt handle is invalid")
+	// ErrMessageNotInflight is matched by the errors of
+	// ChangeMessageVisibility for a message no longer in flight, visible
+	// again since its visibility timeout expired.
+	ErrMessageNotInflight = errors.New("sqs: message not in flight")
+)
+
+// ErrorCode returns the SQS error code of err, or "" if err is not, and
+// does not wrap, an ErrorResponse.
+func ErrorCode(err error) string {
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		return e.EmbeddedError.Code
+	}
+	return ""
+}
+
+// IsNonExistentQueue reports whether err was returned for a queue that does
+// not exist. It is equivalent to errors.Is(err, ErrQueueNotFound).
+func IsNonExistentQueue(err error) bool {
+	return errors.Is(err, ErrQueueNotFound)
+}
+
+// IsThrottled reports whether err is a throttling error, which the client
+// retries up to MaxRetries times before returning it.
+func IsThrottled(err error) bool {
+	_, throttled := throttleHint(err)
+	return throttled
+}
+
+// IsOverLimit reports whether err reports a limit being reached, such as
+// the number of in-flight messages of a queue. Such errors are also
+// throttling errors.
+func IsOverLimit(err error) bool {
+	return ErrorCode(err) == ErrCodeOverLimit
+}
+
+// IsInvalidReceiptHandle reports whether err refused a receipt handle,
+// typically one of a message received again or already deleted: it
+// matches ErrReceiptHandleInvalid or ErrMessageNotInflight.
+func IsInvalidReceiptHandle(err error) bool {
+	switch ErrorCode(err) {
+	case ErrCodeReceiptHandleIsInvalid, ErrCodeMessageNotInflight:
+		return true
+	}
+	return false
+}
+
+// IsAuthFailure reports whether err refused the credentials the request
+// was signed with.
+func IsAuthFailure(err error) bool {
+	switch ErrorCode(err) {
+	case ErrCodeInvalidClientTokenId, ErrCodeInvalidAccessKeyId, ErrCodeSignatureDoesNotMatch, ErrCodeExpiredToken:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestErrorPredicates(c *C) {
+	type predicates struct {
+		nonExistent, throttled, overLimit, invalidHandle, authFailure bool
+	}
+	tests := []struct {
+		code string
+		want predicates
+	}{
+		{ErrCodeNonExistentQueue, predicates{nonExistent: true}},
+		{ErrCodeQueueDoesNotExist, predicates{nonExistent: true}},
+		{ErrCodeQueueAlreadyExists, predicates{}},
+		{ErrCodePurgeQueueInProgress, predicates{}},
+		{ErrCodeOverLimit, predicates{throttled: true, overLimit: true}},
+		{ErrCodeRequestThrottled, predicates{throttled: true}},
+		{ErrCodeThrottlingException, predicates{throttled: true}},
+		{ErrCodeReceiptHandleIsInvalid, predicates{invalidHandle: true}},
+		{ErrCodeMessageNotInflight, predicates{invalidHandle: true}},
+		{ErrCodeInvalidParameterValue, predicates{}},
+		{ErrCodeAccessDenied, predicates{}},
+		{ErrCodeInvalidClientTokenId, predicates{authFailure: true}},
+		{ErrCodeInvalidAccessKeyId, predicates{authFailure: true}},
+		{ErrCodeSignatureDoesNotMatch, predicates{authFailure: true}},
+		{ErrCodeExpiredToken, predicates{authFailure: true}},
+	}
+	for _, t := range tests {
+		resp := &ErrorResponse{StatusCode: 400, EmbeddedError: EmbeddedError{Code: t.code}}
+		for _, err := range []error{resp, fmt.Errorf("sending: %w", resp)} {
+			got := predicates{
+				nonExistent:   IsNonExistentQueue(err),
+				throttled:     IsThrottled(err),
+				overLimit:     IsOverLimit(err),
+				invalidHandle: IsInvalidReceiptHandle(err),
+				authFailure:   IsAuthFailure(err),
+			}
+			c.Check(got, Equals, t.want, Commentf("%s: %v", t.code, err))
+			c.Check(ErrorCode(err), Equals, t.code)
+		}
+	}
+
+	// Errors other than responses match nothing, unless they are 429s.
+	for _, err := range []error{nil, errors.New("connection reset"), fmt.Errorf("wrapped: %w", errors.New(ErrCodeOverLimit))} {
+		c.Check(ErrorCode(err), Equals, "")
+		c.Check(IsNonExistentQueue(err) || IsThrottled(err) || IsOverLimit(err) || IsInvalidReceiptHandle(err) || IsAuthFailure(err), Equals, false)
+	}
+	c.Check(IsThrottled(&ErrorResponse{StatusCode: 429}), Equals, true)
+}
@@ -0,0 +1,130 @@
+package sqs
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrEscrowExpired is returned when confirming or rejecting a message that
+// is no longer held, because its escrow expired or it was never held.
+var ErrEscrowExpired = errors.New("sqs: message not in escrow")
+
+// An Escrow holds handled messages until an external confirmation, such as
+// an acknowledgement from a downstream system, arrives: confirmed messages
+// are deleted and rejected ones released for redelivery. Held messages are
+// kept invisible with a heartbeat, and released automatically once
+// MaxDuration elapses, so no message is lost in a handoff.
+//
+// Consumer handlers that place a message in escrow return ErrHeld.
+type Escrow struct {
+	Queue *Queue
+	// MaxDuration is the longest a message is held; it defaults to 15
+	// minutes.
+	MaxDuration time.Duration
+	// VisibilityTimeout, in seconds, is given to held messages on every
+	// heartbeat; it defaults to 60.
+	VisibilityTimeout int
+	// OnError, if set, is called with heartbeat errors and the errors of
+	// releasing expired messages.
+	OnError func(err error)
+
+	mu   sync.Mutex
+	held map[string]*escrowEntry
+}
+
+type escrowEntry struct {
+	m     *Message
+	h     *Heartbeat
+	timer *time.Timer
+}
+
+func (e *Escrow) onError(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
+
+// Hold places m in escrow under its message ID. Holding a redelivered
+// message replaces the entry of its previous delivery.
+func (e *Escrow) Hold(m *Message) {
+	max := e.MaxDuration
+	if max <= 0 {
+		max = 15 * time.Minute
+	}
+	entry := &escrowEntry{m: m}
+	entry.h = e.Queue.StartHeartbeat(m, &HeartbeatOpt{
+		VisibilityTimeout: e.VisibilityTimeout,
+		MaxDuration:       max,
+		OnError:           e.OnError,
+	})
+	e.mu.Lock()
+	if e.held == nil {
+		e.held = make(map[string]*escrowEntry)
+	}
+	old := e.held[m.Id]
+	e.held[m.Id] = entry
+	entry.timer = time.AfterFunc(max, func() { e.expire(entry) })
+	e.mu.Unlock()
+	if old != nil {
+		old.timer.Stop()
+		old.h.Stop()
+	}
+}
+
+// expire releases entry once MaxDuration elapsed, unless it was taken or
+// replaced by a redelivery in the meantime.
+func (e *Escrow) expire(entry *escrowEntry) {
+	e.mu.Lock()
+	current := e.held[entry.m.Id] == entry
+	if current {
+		delete(e.held, entry.m.Id)
+	}
+	e.mu.Unlock()
+	if !current {
+		return
+	}
+	entry.h.Stop()
+	if err := e.Queue.ChangeMessageVisibility(entry.m, 0); err != nil {
+		e.onError(err)
+	}
+}
+
+// take removes the entry of id from escrow and stops its heartbeat.
+func (e *Escrow) take(id string) (*escrowEntry, error) {
+	e.mu.Lock()
+	entry, ok := e.held[id]
+	delete(e.held, id)
+	e.mu.Unlock()
+	if !ok {
+		return nil, ErrEscrowExpired
+	}
+	entry.timer.Stop()
+	entry.h.Stop()
+	return entry, nil
+}
+
+// Confirm deletes the held message with the given ID.
+func (e *Escrow) Confirm(id string) error {
+	entry, err := e.take(id)
+	if err != nil {
+		return err
+	}
+	return e.Queue.DeleteMessage(entry.m)
+}
+
+// Reject releases the held message with the given ID for redelivery.
+func (e *Escrow) Reject(id string) error {
+	entry, err := e.take(id)
+	if err != nil {
+		return err
+	}
+	return e.Queue.ChangeMessageVisibility(entry.m, 0)
+}
+
+// Len returns the number of held messages.
+func (e *Escrow) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.held)
+}
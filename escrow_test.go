@@ -0,0 +1,75 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestEscrow(c *C) {
+	var mu sync.Mutex
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.FormValue("Action")+" "+r.FormValue("ReceiptHandle")+" "+r.FormValue("VisibilityTimeout"))
+		mu.Unlock()
+		fmt.Fprint(w, "<Response/>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	e := &Escrow{Queue: &Queue{SQS: sqs, path: "/123/q"}, MaxDuration: 30 * time.Millisecond}
+
+	e.Hold(&Message{Id: "1", ReceiptHandle: "rh1"})
+	e.Hold(&Message{Id: "2", ReceiptHandle: "rh2"})
+	e.Hold(&Message{Id: "3", ReceiptHandle: "rh3"})
+	c.Assert(e.Len(), Equals, 3)
+	c.Assert(e.Confirm("1"), IsNil)
+	c.Assert(e.Reject("2"), IsNil)
+	c.Assert(e.Confirm("1"), Equals, ErrEscrowExpired)
+
+	// Message 3 is released once MaxDuration elapses.
+	time.Sleep(60 * time.Millisecond)
+	c.Assert(e.Len(), Equals, 0)
+	c.Assert(e.Confirm("3"), Equals, ErrEscrowExpired)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(calls, DeepEquals, []string{
+		"DeleteMessage rh1 ",
+		"ChangeMessageVisibility rh2 0",
+		"ChangeMessageVisibility rh3 0",
+	})
+}
+
+func (s *S) TestEscrowRedelivered(c *C) {
+	var mu sync.Mutex
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls = append(calls, r.FormValue("Action")+" "+r.FormValue("ReceiptHandle")+" "+r.FormValue("VisibilityTimeout"))
+		mu.Unlock()
+		fmt.Fprint(w, "<Response/>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	e := &Escrow{Queue: &Queue{SQS: sqs, path: "/123/q"}, MaxDuration: 100 * time.Millisecond}
+
+	// The redelivery replaces the first delivery, whose expiry no longer
+	// releases the message.
+	e.Hold(&Message{Id: "1", ReceiptHandle: "rh1"})
+	time.Sleep(50 * time.Millisecond)
+	e.Hold(&Message{Id: "1", ReceiptHandle: "rh2"})
+	time.Sleep(75 * time.Millisecond)
+	c.Assert(e.Len(), Equals, 1)
+	c.Assert(e.Confirm("1"), IsNil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(calls, DeepEquals, []string{"DeleteMessage rh2 "})
+}
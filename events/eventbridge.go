@@ -0,0 +1,41 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// An EventBridgeEvent is an event delivered by an EventBridge rule. Its
+// Detail depends on its Source and DetailType.
+type EventBridgeEvent struct {
+	Version    string          `json:"version"`
+	ID         string          `json:"id"`
+	DetailType string          `json:"detail-type"`
+	Source     string          `json:"source"`
+	Account    string          `json:"account"`
+	Time       time.Time       `json:"time"`
+	Region     string          `json:"region"`
+	Resources  []string        `json:"resources"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// DecodeDetail unmarshals the detail of e into v.
+func (e *EventBridgeEvent) DecodeDetail(v interface{}) error {
+	if err := json.Unmarshal(e.Detail, v); err != nil {
+		return fmt.Errorf("events: decoding detail of %s event %s: %w", e.DetailType, e.ID, err)
+	}
+	return nil
+}
+
+// DecodeEventBridge decodes an EventBridge event.
+func DecodeEventBridge(body string) (*EventBridgeEvent, error) {
+	var e EventBridgeEvent
+	if err := decode(body, "an EventBridge event", &e); err != nil {
+		return nil, err
+	}
+	if e.DetailType == "" || e.Source == "" {
+		return nil, fmt.Errorf("%w: not an EventBridge event", ErrUnrecognized)
+	}
+	return &e, nil
+}
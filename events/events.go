@@ -0,0 +1,34 @@
+// Package events decodes the payloads that AWS services deliver to SQS
+// queues: S3 event notifications, EventBridge events and SES
+// notifications, so that consumers go from a message body to typed
+// values:
+//
+//	e, err := events.DecodeS3(m.Body)
+//	if err != nil {
+//		return err
+//	}
+//	for _, r := range e.Records {
+//		process(r.S3.Bucket.Name, r.S3.Object.DecodedKey())
+//	}
+//
+// Payloads delivered through an SNS topic are wrapped in its envelope;
+// unwrap them first, e.g. with the sqs.SNSUnwrap transformer.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnrecognized is matched by the errors of decoders given a payload of
+// another kind.
+var ErrUnrecognized = errors.New("events: unrecognized payload")
+
+// decode unmarshals body into v, reporting the kind expected on failure.
+func decode(body, kind string, v interface{}) error {
+	if err := json.Unmarshal([]byte(body), v); err != nil {
+		return fmt.Errorf("%w: not %s: %v", ErrUnrecognized, kind, err)
+	}
+	return nil
+}
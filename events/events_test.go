@@ -0,0 +1,94 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+var _ = Suite(&S{})
+
+type S struct{}
+
+func (s *S) TestDecodeS3(c *C) {
+	e, err := DecodeS3(`{"Records":[{"eventVersion":"2.1","eventSource":"aws:s3","awsRegion":"us-east-1",
+"eventTime":"2024-01-02T03:04:05.678Z","eventName":"ObjectCreated:Put",
+"userIdentity":{"principalId":"AWS:AIDAEXAMPLE"},"requestParameters":{"sourceIPAddress":"10.0.0.1"},
+"responseElements":{"x-amz-request-id":"C3D13FE58DE4C810"},
+"s3":{"s3SchemaVersion":"1.0","configurationId":"uploads","bucket":{"name":"photos","ownerIdentity":{"principalId":"A3NL1KOZZKExample"},"arn":"arn:aws:s3:::photos"},
+"object":{"key":"2024/happy+face%281%29.jpg","size":1024,"eTag":"d41d8cd98f00b204e9800998ecf8427e","sequencer":"0055AED6DCD90281E5"}}}]}`)
+	c.Assert(err, IsNil)
+	c.Assert(e.IsTest(), Equals, false)
+	c.Assert(e.Records, HasLen, 1)
+	r := e.Records[0]
+	c.Assert(r.EventName, Equals, "ObjectCreated:Put")
+	c.Assert(r.EventTime.Equal(time.Date(2024, 1, 2, 3, 4, 5, 678e6, time.UTC)), Equals, true)
+	c.Assert(r.ResponseElements["x-amz-request-id"], Equals, "C3D13FE58DE4C810")
+	c.Assert(r.S3.Bucket.Name, Equals, "photos")
+	c.Assert(r.S3.Object.Size, Equals, int64(1024))
+	c.Assert(r.S3.Object.DecodedKey(), Equals, "2024/happy face(1).jpg")
+
+	e, err = DecodeS3(`{"Service":"Amazon S3","Event":"s3:TestEvent","Time":"2024-01-02T03:04:05.678Z","Bucket":"photos","RequestId":"5582815E1AEA5ADF","HostId":"8cLeGAmw098X5cv4Zkwcmo8vvZa3eH3eKxsPzbB9wrR+YstdA6Knx4Ip8EXAMPLE"}`)
+	c.Assert(err, IsNil)
+	c.Assert(e.IsTest(), Equals, true)
+	c.Assert(e.Bucket, Equals, "photos")
+
+	_, err = DecodeS3(`{"version":"0","detail-type":"Object Created","source":"aws.s3"}`)
+	c.Assert(errors.Is(err, ErrUnrecognized), Equals, true)
+	_, err = DecodeS3(`not json`)
+	c.Assert(errors.Is(err, ErrUnrecognized), Equals, true)
+}
+
+func (s *S) TestDecodeEventBridge(c *C) {
+	e, err := DecodeEventBridge(`{"version":"0","id":"6a7e8feb-b491-4cf7-a9f1-bf3703467718","detail-type":"EC2 Instance State-change Notification",
+"source":"aws.ec2","account":"111122223333","time":"2017-12-22T18:43:48Z","region":"us-west-1",
+"resources":["arn:aws:ec2:us-west-1:123456789012:instance/i-1234567890abcdef0"],
+"detail":{"instance-id":"i-1234567890abcdef0","state":"terminated"}}`)
+	c.Assert(err, IsNil)
+	c.Assert(e.Source, Equals, "aws.ec2")
+	c.Assert(e.DetailType, Equals, "EC2 Instance State-change Notification")
+	c.Assert(e.Time.Equal(time.Date(2017, 12, 22, 18, 43, 48, 0, time.UTC)), Equals, true)
+	c.Assert(e.Resources, HasLen, 1)
+	var detail struct {
+		InstanceID string `json:"instance-id"`
+		State      string `json:"state"`
+	}
+	c.Assert(e.DecodeDetail(&detail), IsNil)
+	c.Assert(detail.InstanceID, Equals, "i-1234567890abcdef0")
+	c.Assert(detail.State, Equals, "terminated")
+
+	_, err = DecodeEventBridge(`{"Records":[]}`)
+	c.Assert(errors.Is(err, ErrUnrecognized), Equals, true)
+}
+
+func (s *S) TestDecodeSES(c *C) {
+	n, err := DecodeSES(`{"notificationType":"Bounce","bounce":{"bounceType":"Permanent","bounceSubType":"General",
+"bouncedRecipients":[{"emailAddress":"jane@example.com","action":"failed","status":"5.1.1","diagnosticCode":"smtp; 550 5.1.1 user unknown"}],
+"timestamp":"2016-01-27T14:59:38.237Z","feedbackId":"00000138111222aa-33322211-cccc-cccc-cccc-ddddaaaa0680-000000","reportingMTA":"dsn; a8-70.smtp-out.amazonses.com"},
+"mail":{"timestamp":"2016-01-27T14:59:38.237Z","messageId":"00000138111222aa-33322211-cccc-cccc-cccc-ddddaaaa0680-000000",
+"source":"john@example.com","sendingAccountId":"123456789012","destination":["jane@example.com"],
+"commonHeaders":{"from":["John Doe <john@example.com>"],"to":["Jane Doe <jane@example.com>"],"subject":"Hello"}}}`)
+	c.Assert(err, IsNil)
+	c.Assert(n.Type(), Equals, "Bounce")
+	c.Assert(n.Bounce, NotNil)
+	c.Assert(n.Bounce.BounceType, Equals, "Permanent")
+	c.Assert(n.Bounce.BouncedRecipients[0].EmailAddress, Equals, "jane@example.com")
+	c.Assert(n.Complaint, IsNil)
+	c.Assert(n.Mail.CommonHeaders.Subject, Equals, "Hello")
+	c.Assert(n.Mail.Destination, DeepEquals, []string{"jane@example.com"})
+
+	n, err = DecodeSES(`{"eventType":"Delivery","mail":{"messageId":"m-1"},"delivery":{"timestamp":"2016-01-27T14:59:38.237Z",
+"processingTimeMillis":546,"recipients":["jane@example.com"],"smtpResponse":"250 ok"}}`)
+	c.Assert(err, IsNil)
+	c.Assert(n.Type(), Equals, "Delivery")
+	c.Assert(n.Delivery.ProcessingTimeMillis, Equals, int64(546))
+
+	_, err = DecodeSES(`{"detail-type":"x","source":"y"}`)
+	c.Assert(errors.Is(err, ErrUnrecognized), Equals, true)
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// An S3Event is an S3 event notification. S3 sends a test event, with no
+// records, when the notifications of a bucket are configured; see
+// IsTest.
+type S3Event struct {
+	Records []S3EventRecord
+
+	// The fields of test events.
+	Service string
+	Event   string
+	Bucket  string
+}
+
+// IsTest tells whether e is the test event S3 sends when notifications
+// are configured.
+func (e *S3Event) IsTest() bool {
+	return e.Event == "s3:TestEvent"
+}
+
+// An S3EventRecord reports one event of an S3 object.
+type S3EventRecord struct {
+	EventVersion string    `json:"eventVersion"`
+	EventSource  string    `json:"eventSource"`
+	AWSRegion    string    `json:"awsRegion"`
+	EventTime    time.Time `json:"eventTime"`
+	// EventName is the type of the event, e.g. "ObjectCreated:Put".
+	EventName    string `json:"eventName"`
+	UserIdentity struct {
+		PrincipalID string `json:"principalId"`
+	} `json:"userIdentity"`
+	RequestParameters struct {
+		SourceIPAddress string `json:"sourceIPAddress"`
+	} `json:"requestParameters"`
+	ResponseElements map[string]string `json:"responseElements"`
+	S3               S3Entity          `json:"s3"`
+}
+
+// An S3Entity identifies the bucket and object of an S3EventRecord.
+type S3Entity struct {
+	SchemaVersion   string `json:"s3SchemaVersion"`
+	ConfigurationID string `json:"configurationId"`
+	Bucket          struct {
+		Name          string `json:"name"`
+		ARN           string `json:"arn"`
+		OwnerIdentity struct {
+			PrincipalID string `json:"principalId"`
+		} `json:"ownerIdentity"`
+	} `json:"bucket"`
+	Object S3Object `json:"object"`
+}
+
+// An S3Object is the object of an S3EventRecord.
+type S3Object struct {
+	// Key is URL encoded, as S3 sends it; see DecodedKey.
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	ETag      string `json:"eTag"`
+	VersionID string `json:"versionId"`
+	Sequencer string `json:"sequencer"`
+}
+
+// DecodedKey returns the key of the object, decoded. Keys that fail to
+// decode are returned as sent.
+func (o *S3Object) DecodedKey() string {
+	k, err := url.QueryUnescape(o.Key)
+	if err != nil {
+		return o.Key
+	}
+	return k
+}
+
+// DecodeS3 decodes an S3 event notification.
+func DecodeS3(body string) (*S3Event, error) {
+	var e S3Event
+	if err := decode(body, "an S3 event notification", &e); err != nil {
+		return nil, err
+	}
+	if e.IsTest() {
+		return &e, nil
+	}
+	if len(e.Records) == 0 {
+		return nil, fmt.Errorf("%w: not an S3 event notification", ErrUnrecognized)
+	}
+	for _, r := range e.Records {
+		if r.EventSource != "aws:s3" {
+			return nil, fmt.Errorf("%w: record from %q in an S3 event notification", ErrUnrecognized, r.EventSource)
+		}
+	}
+	return &e, nil
+}
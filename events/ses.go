@@ -0,0 +1,99 @@
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// An SESNotification is a notification of SES about a sent email: a
+// bounce, complaint or delivery notification, or an event of a
+// configuration set.
+type SESNotification struct {
+	// NotificationType is set on notifications, and EventType on the
+	// events of configuration sets; see Type.
+	NotificationType string `json:"notificationType"`
+	EventType        string `json:"eventType"`
+
+	Mail      SESMail       `json:"mail"`
+	Bounce    *SESBounce    `json:"bounce"`
+	Complaint *SESComplaint `json:"complaint"`
+	Delivery  *SESDelivery  `json:"delivery"`
+}
+
+// Type returns the type of n, e.g. "Bounce", "Complaint" or "Delivery".
+func (n *SESNotification) Type() string {
+	if n.NotificationType != "" {
+		return n.NotificationType
+	}
+	return n.EventType
+}
+
+// An SESMail describes the email of an SESNotification.
+type SESMail struct {
+	Timestamp        time.Time `json:"timestamp"`
+	MessageID        string    `json:"messageId"`
+	Source           string    `json:"source"`
+	SourceARN        string    `json:"sourceArn"`
+	SendingAccountID string    `json:"sendingAccountId"`
+	Destination      []string  `json:"destination"`
+	HeadersTruncated bool      `json:"headersTruncated"`
+	Headers          []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"headers"`
+	CommonHeaders struct {
+		From      []string `json:"from"`
+		To        []string `json:"to"`
+		MessageID string   `json:"messageId"`
+		Subject   string   `json:"subject"`
+	} `json:"commonHeaders"`
+}
+
+// An SESBounce reports the recipients an email bounced for.
+type SESBounce struct {
+	// BounceType is "Permanent", "Transient" or "Undetermined".
+	BounceType        string `json:"bounceType"`
+	BounceSubType     string `json:"bounceSubType"`
+	BouncedRecipients []struct {
+		EmailAddress   string `json:"emailAddress"`
+		Action         string `json:"action"`
+		Status         string `json:"status"`
+		DiagnosticCode string `json:"diagnosticCode"`
+	} `json:"bouncedRecipients"`
+	Timestamp    time.Time `json:"timestamp"`
+	FeedbackID   string    `json:"feedbackId"`
+	ReportingMTA string    `json:"reportingMTA"`
+}
+
+// An SESComplaint reports recipients who marked an email as spam.
+type SESComplaint struct {
+	ComplainedRecipients []struct {
+		EmailAddress string `json:"emailAddress"`
+	} `json:"complainedRecipients"`
+	Timestamp             time.Time `json:"timestamp"`
+	FeedbackID            string    `json:"feedbackId"`
+	ComplaintFeedbackType string    `json:"complaintFeedbackType"`
+	UserAgent             string    `json:"userAgent"`
+}
+
+// An SESDelivery reports the delivery of an email to its recipients.
+type SESDelivery struct {
+	Timestamp            time.Time `json:"timestamp"`
+	ProcessingTimeMillis int64     `json:"processingTimeMillis"`
+	Recipients           []string  `json:"recipients"`
+	SMTPResponse         string    `json:"smtpResponse"`
+	ReportingMTA         string    `json:"reportingMTA"`
+	RemoteMTAIP          string    `json:"remoteMtaIp"`
+}
+
+// DecodeSES decodes an SES notification.
+func DecodeSES(body string) (*SESNotification, error) {
+	var n SESNotification
+	if err := decode(body, "an SES notification", &n); err != nil {
+		return nil, err
+	}
+	if n.Type() == "" || n.Mail.MessageID == "" {
+		return nil, fmt.Errorf("%w: not an SES notification", ErrUnrecognized)
+	}
+	return &n, nil
+}
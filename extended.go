@@ -0,0 +1,230 @@
+package sqs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ExtendedPayloadSizeAttribute is the message attribute holding the size
+// of a payload stored outside the queue, as set by the Amazon SQS Extended
+// Client Library. LegacyExtendedPayloadSizeAttribute is its former name.
+const (
+	ExtendedPayloadSizeAttribute       = "ExtendedPayloadSize"
+	LegacyExtendedPayloadSizeAttribute = "SQSLargePayloadSize"
+)
+
+const (
+	payloadPointerClass       = "software.amazon.payloadoffloading.PayloadS3Pointer"
+	legacyPayloadPointerClass = "com.amazon.sqs.javamessaging.MessageS3Pointer"
+
+	bucketMarker = "-..s3BucketName..-"
+	keyMarker    = "-..s3Key..-"
+)
+
+// A PayloadStore stores message bodies too large for SQS, in the manner of
+// S3 buckets and keys. An S3 client adapts to it in a few lines, e.g. with
+// goamz:
+//
+//	func (s s3Store) Put(ctx context.Context, bucket, key string, body []byte) error {
+//		return s.S3.Bucket(bucket).Put(key, body, "application/octet-stream", s3.Private)
+//	}
+type PayloadStore interface {
+	Put(ctx context.Context, bucket, key string, body []byte) error
+	Get(ctx context.Context, bucket, key string) ([]byte, error)
+	Delete(ctx context.Context, bucket, key string) error
+}
+
+// ExtendedPayload is a Transformer storing message bodies larger than
+// Threshold in Bucket of Store, and sending a pointer to them instead, in
+// the format of the Amazon SQS Extended Client Library so Java producers
+// and consumers interoperate.
+//
+// Received pointers are resolved transparently. The receipt handles of
+// such messages carry the pointer, as with the Java library, and their
+// payload is deleted along with them.
+type ExtendedPayload struct {
+	Store  PayloadStore
+	Bucket string
+	// Threshold is the size of message bodies and attributes above which
+	// bodies are stored, by default DefaultMaxBodySize.
+	Threshold int
+	// Always stores every body, whatever its size.
+	Always bool
+	// KeepPayloads leaves stored payloads in place when their messages
+	// are deleted, such as when other consumers still need them.
+	KeepPayloads bool
+}
+
+type payloadPointer struct {
+	Bucket string `json:"s3BucketName"`
+	Key    string `json:"s3Key"`
+}
+
+// Encode implements Transformer.
+func (e *ExtendedPayload) Encode(ctx context.Context, m *Message) error {
+	threshold := e.Threshold
+	if threshold <= 0 {
+		threshold = DefaultMaxBodySize
+	}
+	if !e.Always && entrySize(SendMessageBatchEntry{Body: m.Body, MessageAttributes: m.MessageAttributes}) <= threshold {
+		return nil
+	}
+	key, err := newUUID()
+	if err != nil {
+		return err
+	}
+	if err := e.Store.Put(ctx, e.Bucket, key, []byte(m.Body)); err != nil {
+		return fmt.Errorf("sqs: storing payload: %s", err)
+	}
+	b, err := json.Marshal([]interface{}{payloadPointerClass, payloadPointer{e.Bucket, key}})
+	if err != nil {
+		return err
+	}
+	m.MessageAttributes[ExtendedPayloadSizeAttribute] = MessageAttributeValue{DataType: "Number", StringValue: strconv.Itoa(len(m.Body))}
+	m.Body = string(b)
+	return nil
+}
+
+// Decode implements Transformer.
+func (e *ExtendedPayload) Decode(ctx context.Context, m *Message) error {
+	_, ok := m.MessageAttributes[ExtendedPayloadSizeAttribute]
+	if !ok {
+		if _, ok = m.MessageAttributes[LegacyExtendedPayloadSizeAttribute]; !ok {
+			return nil
+		}
+	}
+	p, err := parsePayloadPointer(m.Body)
+	if err != nil {
+		return err
+	}
+	body, err := e.Store.Get(ctx, p.Bucket, p.Key)
+	if err != nil {
+		return fmt.Errorf("sqs: fetching payload s3://%s/%s: %s", p.Bucket, p.Key, err)
+	}
+	m.Body = string(body)
+	delete(m.MessageAttributes, ExtendedPayloadSizeAttribute)
+	delete(m.MessageAttributes, LegacyExtendedPayloadSizeAttribute)
+	m.ReceiptHandle = bucketMarker + p.Bucket + bucketMarker + keyMarker + p.Key + keyMarker + m.ReceiptHandle
+	return nil
+}
+
+// Attributes implements AttributeTransformer.
+func (e *ExtendedPayload) Attributes() []string {
+	return []string{ExtendedPayloadSizeAttribute, LegacyExtendedPayloadSizeAttribute}
+}
+
+// ReceiptHandle implements HandleTransformer.
+func (e *ExtendedPayload) ReceiptHandle(handle string) string {
+	_, h, ok := splitPayloadHandle(handle)
+	if !ok {
+		return handle
+	}
+	return h
+}
+
+// Deleted implements HandleTransformer.
+func (e *ExtendedPayload) Deleted(ctx context.Context, handle string) error {
+	p, _, ok := splitPayloadHandle(handle)
+	if !ok || e.KeepPayloads {
+		return nil
+	}
+	if err := e.Store.Delete(ctx, p.Bucket, p.Key); err != nil {
+		return fmt.Errorf("sqs: deleting payload s3://%s/%s: %s", p.Bucket, p.Key, err)
+	}
+	return nil
+}
+
+// parsePayloadPointer decodes the body of a pointer message.
+func parsePayloadPointer(body string) (payloadPointer, error) {
+	var p payloadPointer
+	var v []json.RawMessage
+	if err := json.Unmarshal([]byte(body), &v); err != nil || len(v) != 2 {
+		return p, errors.New("sqs: invalid payload pointer")
+	}
+	var class string
+	if err := json.Unmarshal(v[0], &class); err != nil || class != payloadPointerClass && class != legacyPayloadPointerClass {
+		return p, fmt.Errorf("sqs: unknown payload pointer class %s", v[0])
+	}
+	if err := json.Unmarshal(v[1], &p); err != nil || p.Bucket == "" || p.Key == "" {
+		return p, errors.New("sqs: invalid payload pointer")
+	}
+	return p, nil
+}
+
+// splitPayloadHandle splits a receipt handle rewritten by Decode into the
+// pointer and the handle issued by SQS.
+func splitPayloadHandle(handle string) (payloadPointer, string, bool) {
+	var p payloadPointer
+	parts := strings.SplitN(handle, bucketMarker, 3)
+	if len(parts) != 3 || parts[0] != "" {
+		return p, handle, false
+	}
+	p.Bucket = parts[1]
+	parts = strings.SplitN(parts[2], keyMarker, 3)
+	if len(parts) != 3 || parts[0] != "" {
+		return p, handle, false
+	}
+	p.Key = parts[1]
+	return p, parts[2], true
+}
+
+// newUUID returns a random UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// A MemoryPayloadStore is a PayloadStore keeping payloads in memory, for
+// tests and use with Local.
+type MemoryPayloadStore struct {
+	mu       sync.Mutex
+	payloads map[string][]byte
+}
+
+// Put implements PayloadStore.
+func (s *MemoryPayloadStore) Put(ctx context.Context, bucket, key string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.payloads == nil {
+		s.payloads = make(map[string][]byte)
+	}
+	s.payloads[bucket+"/"+key] = append([]byte(nil), body...)
+	return nil
+}
+
+// Get implements PayloadStore.
+func (s *MemoryPayloadStore) Get(ctx context.Context, bucket, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.payloads[bucket+"/"+key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return b, nil
+}
+
+// Delete implements PayloadStore.
+func (s *MemoryPayloadStore) Delete(ctx context.Context, bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.payloads, bucket+"/"+key)
+	return nil
+}
+
+// Len returns the number of payloads stored.
+func (s *MemoryPayloadStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.payloads)
+}
@@ -0,0 +1,55 @@
+package sqs
+
+import (
+	"context"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestExtendedPayload(c *C) {
+	store := &MemoryPayloadStore{}
+	sqs := NewLocal().SQS()
+	sqs.Transformers = []Transformer{&ExtendedPayload{Store: store, Bucket: "payloads", Threshold: 10}}
+	q, err := sqs.CreateQueue("large", nil)
+	c.Assert(err, IsNil)
+
+	_, err = q.SendMessage("small")
+	c.Assert(err, IsNil)
+	large := strings.Repeat("x", 100)
+	_, err = q.SendMessage(large)
+	c.Assert(err, IsNil)
+	c.Assert(store.Len(), Equals, 1)
+
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 2)
+	c.Assert(msgs[0].Body, Equals, "small")
+	c.Assert(msgs[1].Body, Equals, large)
+	c.Assert(msgs[1].MessageAttributes, HasLen, 0)
+	c.Assert(strings.HasPrefix(msgs[1].ReceiptHandle, "-..s3BucketName..-payloads-..s3BucketName..--..s3Key..-"), Equals, true)
+
+	c.Assert(q.ChangeMessageVisibility(msgs[1], 60), IsNil)
+	res, err := q.DeleteMessageBatch(msgs)
+	c.Assert(err, IsNil)
+	c.Assert(res.Successful, HasLen, 2)
+	c.Assert(store.Len(), Equals, 0)
+}
+
+func (s *S) TestExtendedPayloadJavaPointer(c *C) {
+	store := &MemoryPayloadStore{}
+	store.Put(context.Background(), "b", "k", []byte("payload"))
+	e := &ExtendedPayload{Store: store}
+	m := &Message{
+		Body:              `["com.amazon.sqs.javamessaging.MessageS3Pointer",{"s3BucketName":"b","s3Key":"k"}]`,
+		ReceiptHandle:     "handle",
+		MessageAttributes: MessageAttributes{LegacyExtendedPayloadSizeAttribute: {DataType: "Number", StringValue: "7"}},
+	}
+	c.Assert(e.Decode(context.Background(), m), IsNil)
+	c.Assert(m.Body, Equals, "payload")
+	c.Assert(e.ReceiptHandle(m.ReceiptHandle), Equals, "handle")
+	c.Assert(e.ReceiptHandle("plain"), Equals, "plain")
+
+	m = &Message{Body: `["java.lang.String","x"]`, MessageAttributes: MessageAttributes{ExtendedPayloadSizeAttribute: {DataType: "Number", StringValue: "1"}}}
+	c.Assert(e.Decode(context.Background(), m), ErrorMatches, "sqs: unknown payload pointer class .*")
+}
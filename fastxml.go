@@ -0,0 +1,518 @@
+package sqs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// The XML of receive responses dominates the garbage of consumers when
+// decoded with encoding/xml, which allocates every token. Receive
+// responses are instead read into a pooled buffer and scanned in place by
+// xmlScanner, which only allocates the decoded values. Documents it does
+// not handle, such as those with CDATA sections or unknown entities, are
+// decoded with encoding/xml instead, which also reports their errors.
+
+// errSlowPath reports a document that xmlScanner leaves to encoding/xml.
+var errSlowPath = errors.New("sqs: XML not handled by the scanner")
+
+// A fastXMLDecoder decodes itself from a whole XML document with an
+// xmlScanner, returning errSlowPath for documents it cannot handle.
+type fastXMLDecoder interface {
+	decodeFastXML(s *xmlScanner) error
+}
+
+// maxPooledBuffer bounds the buffers kept for reuse, so that a few large
+// responses do not pin memory.
+const maxPooledBuffer = 1 << 20
+
+var bufferPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+func getBuffer() *bytes.Buffer {
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	return b
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() <= maxPooledBuffer {
+		bufferPool.Put(b)
+	}
+}
+
+// decodeFast decodes the XML document of body into resp with the scanner,
+// and falls back to slow, given the whole document, if needed.
+func decodeFast(body io.Reader, resp fastXMLDecoder, slow func(b []byte) error) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if _, err := buf.ReadFrom(body); err != nil {
+		return err
+	}
+	err := resp.decodeFastXML(&xmlScanner{b: buf.Bytes()})
+	if err == errSlowPath {
+		return slow(buf.Bytes())
+	}
+	return err
+}
+
+// The kinds of the tokens of an xmlScanner.
+const (
+	xmlEOF = iota
+	xmlStart
+	xmlEnd
+	xmlText
+)
+
+// An xmlScanner returns the tokens of an XML document as slices of it.
+// Names are stripped of their namespace prefix and attributes are
+// skipped.
+type xmlScanner struct {
+	b   []byte
+	pos int
+	// selfClosed is set after the start token of an empty element, whose
+	// end token comes next.
+	selfClosed []byte
+	// open holds the names of the open elements.
+	open [][]byte
+}
+
+// next returns the next token: the local name of start and end tokens, or
+// the raw text of text tokens.
+func (s *xmlScanner) next() (kind int, data []byte, err error) {
+	if s.selfClosed != nil {
+		name := s.selfClosed
+		s.selfClosed = nil
+		s.open = s.open[:len(s.open)-1]
+		return xmlEnd, name, nil
+	}
+	for {
+		if s.pos >= len(s.b) {
+			return xmlEOF, nil, nil
+		}
+		if s.b[s.pos] != '<' {
+			end := bytes.IndexByte(s.b[s.pos:], '<')
+			if end < 0 {
+				end = len(s.b) - s.pos
+			}
+			text := s.b[s.pos : s.pos+end]
+			s.pos += end
+			return xmlText, text, nil
+		}
+		rest := s.b[s.pos:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end := bytes.Index(rest, []byte("?>"))
+			if end < 0 {
+				return 0, nil, errSlowPath
+			}
+			s.pos += end + 2
+			continue
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end := bytes.Index(rest, []byte("-->"))
+			if end < 0 {
+				return 0, nil, errSlowPath
+			}
+			s.pos += end + 3
+			continue
+		case bytes.HasPrefix(rest, []byte("<!")):
+			// CDATA sections and DOCTYPE declarations.
+			return 0, nil, errSlowPath
+		case bytes.HasPrefix(rest, []byte("</")):
+			end := bytes.IndexByte(rest, '>')
+			if end < 0 {
+				return 0, nil, errSlowPath
+			}
+			s.pos += end + 1
+			name := bytes.TrimSpace(rest[2:end])
+			if len(s.open) == 0 || !bytes.Equal(s.open[len(s.open)-1], name) {
+				// Mismatched, for encoding/xml to report.
+				return 0, nil, errSlowPath
+			}
+			s.open = s.open[:len(s.open)-1]
+			return xmlEnd, localName(name), nil
+		}
+		// A start tag, whose attribute values may hold '>'.
+		i, quote := 1, byte(0)
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			if quote != 0 {
+				if c == quote {
+					quote = 0
+				}
+				continue
+			}
+			if c == '"' || c == '\'' {
+				quote = c
+			} else if c == '>' {
+				break
+			}
+		}
+		if i == len(rest) {
+			return 0, nil, errSlowPath
+		}
+		tag := rest[1:i]
+		s.pos += i + 1
+		selfClosing := len(tag) > 0 && tag[len(tag)-1] == '/'
+		if selfClosing {
+			tag = tag[:len(tag)-1]
+		}
+		n := 0
+		for n < len(tag) && !isXMLSpace(tag[n]) {
+			n++
+		}
+		name := localName(tag[:n])
+		if len(name) == 0 {
+			return 0, nil, errSlowPath
+		}
+		s.open = append(s.open, tag[:n])
+		if selfClosing {
+			s.selfClosed = name
+		}
+		return xmlStart, name, nil
+	}
+}
+
+func isXMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// localName strips the namespace prefix of name.
+func localName(name []byte) []byte {
+	if i := bytes.IndexByte(name, ':'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// text returns the text of the element whose start token was just
+// returned, consuming its end token.
+func (s *xmlScanner) text() (string, error) {
+	var value string
+	for {
+		kind, data, err := s.next()
+		switch {
+		case err != nil:
+			return "", err
+		case kind == xmlText:
+			t, err := unescapeXML(data)
+			if err != nil {
+				return "", err
+			}
+			value += t
+		case kind == xmlEnd:
+			return value, nil
+		default:
+			// Mixed content, or a truncated document.
+			return "", errSlowPath
+		}
+	}
+}
+
+// skip consumes the element whose start token was just returned.
+func (s *xmlScanner) skip() error {
+	for depth := 1; depth > 0; {
+		kind, _, err := s.next()
+		switch {
+		case err != nil:
+			return err
+		case kind == xmlEOF:
+			return errSlowPath
+		case kind == xmlStart:
+			depth++
+		case kind == xmlEnd:
+			depth--
+		}
+	}
+	return nil
+}
+
+// each calls fn with the name of every child element of the element whose
+// start token was just returned, which fn must consume, then consumes its
+// end token. Text between children is ignored.
+func (s *xmlScanner) each(fn func(name []byte) error) error {
+	for {
+		kind, data, err := s.next()
+		switch {
+		case err != nil:
+			return err
+		case kind == xmlEOF:
+			return errSlowPath
+		case kind == xmlEnd:
+			return nil
+		case kind == xmlStart:
+			if err := fn(data); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// root calls fn with the name of every child element of the root element,
+// like each.
+func (s *xmlScanner) root(fn func(name []byte) error) error {
+	for {
+		kind, _, err := s.next()
+		switch {
+		case err != nil:
+			return err
+		case kind == xmlStart:
+			return s.each(fn)
+		case kind != xmlText:
+			return errSlowPath
+		}
+	}
+}
+
+// unescapeXML returns text with its entities and character references
+// replaced, and its line endings normalized, as encoding/xml does. Text
+// with other entities or characters XML forbids is left to encoding/xml.
+func unescapeXML(text []byte) (string, error) {
+	plain := true
+	for _, c := range text {
+		if c == '&' || c == '\r' || c < 0x20 && c != '\t' && c != '\n' {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		if !validXMLText(text) {
+			return "", errSlowPath
+		}
+		return string(text), nil
+	}
+	b := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\r':
+			b = append(b, '\n')
+			if i+1 < len(text) && text[i+1] == '\n' {
+				i++
+			}
+		case c < 0x20 && c != '\t' && c != '\n':
+			return "", errSlowPath
+		case c == '&':
+			end := bytes.IndexByte(text[i:], ';')
+			if end < 0 {
+				return "", errSlowPath
+			}
+			ref := text[i+1 : i+end]
+			i += end
+			switch string(ref) {
+			case "lt":
+				b = append(b, '<')
+			case "gt":
+				b = append(b, '>')
+			case "amp":
+				b = append(b, '&')
+			case "apos":
+				b = append(b, '\'')
+			case "quot":
+				b = append(b, '"')
+			default:
+				r, ok := charRef(ref)
+				if !ok {
+					return "", errSlowPath
+				}
+				b = utf8.AppendRune(b, r)
+			}
+		default:
+			b = append(b, c)
+		}
+	}
+	if !validXMLText(b) {
+		return "", errSlowPath
+	}
+	return string(b), nil
+}
+
+// validXMLText tells whether the non-ASCII characters of text are valid
+// UTF-8 and allowed by XML.
+func validXMLText(text []byte) bool {
+	for i := 0; i < len(text); {
+		if text[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(text[i:])
+		if r == utf8.RuneError && size == 1 || !isXMLChar(r) {
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+// isXMLChar tells whether XML allows r.
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D || r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD || r >= 0x10000 && r <= 0x10FFFF
+}
+
+// charRef decodes a character reference such as "#13" or "#xD".
+func charRef(ref []byte) (rune, bool) {
+	if len(ref) < 2 || ref[0] != '#' {
+		return 0, false
+	}
+	var n uint64
+	var err error
+	if ref[1] == 'x' {
+		n, err = strconv.ParseUint(string(ref[2:]), 16, 32)
+	} else {
+		n, err = strconv.ParseUint(string(ref[1:]), 10, 32)
+	}
+	r := rune(n)
+	if err != nil || !isXMLChar(r) {
+		return 0, false
+	}
+	return r, true
+}
+
+// decodeFastXML implements fastXMLDecoder.
+func (r *receiveMessageResponse) decodeFastXML(s *xmlScanner) error {
+	err := s.root(func(name []byte) error {
+		var err error
+		switch string(name) {
+		case "ReceiveMessageResult":
+			err = s.each(func(name []byte) error {
+				if string(name) != "Message" {
+					return s.skip()
+				}
+				m := &Message{}
+				r.Messages = append(r.Messages, m)
+				return m.decodeFastXML(s)
+			})
+		case "RequestId":
+			r.RequestId, err = s.text()
+		default:
+			err = s.skip()
+		}
+		return err
+	})
+	if err == errSlowPath {
+		*r = receiveMessageResponse{}
+	}
+	return err
+}
+
+// decodeFastXML decodes the Message element whose start token was just
+// returned.
+func (m *Message) decodeFastXML(s *xmlScanner) error {
+	return s.each(func(name []byte) error {
+		var err error
+		switch string(name) {
+		case "MessageId":
+			m.Id, err = s.text()
+		case "ReceiptHandle":
+			m.ReceiptHandle, err = s.text()
+		case "MD5OfBody":
+			m.MD5OfBody, err = s.text()
+		case "Body":
+			m.Body, err = s.text()
+		case "Attribute":
+			var attrName, value string
+			if err := s.each(func(name []byte) error {
+				var err error
+				switch string(name) {
+				case "Name":
+					attrName, err = s.text()
+				case "Value":
+					value, err = s.text()
+				default:
+					err = s.skip()
+				}
+				return err
+			}); err != nil {
+				return err
+			}
+			err = m.SystemAttributes.set(attrName, value)
+		case "MessageAttribute":
+			err = m.decodeFastAttribute(s)
+		default:
+			err = s.skip()
+		}
+		return err
+	})
+}
+
+// decodeFastAttribute decodes the MessageAttribute element whose start
+// token was just returned, like MessageAttributes.UnmarshalXML.
+func (m *Message) decodeFastAttribute(s *xmlScanner) error {
+	var name, binary string
+	var v MessageAttributeValue
+	if err := s.each(func(elem []byte) error {
+		var err error
+		switch string(elem) {
+		case "Name":
+			name, err = s.text()
+		case "Value":
+			err = s.each(func(elem []byte) error {
+				var err error
+				switch string(elem) {
+				case "DataType":
+					v.DataType, err = s.text()
+				case "StringValue":
+					v.StringValue, err = s.text()
+				case "BinaryValue":
+					binary, err = s.text()
+				default:
+					err = s.skip()
+				}
+				return err
+			})
+		default:
+			err = s.skip()
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	if binary != "" {
+		b, err := base64.StdEncoding.DecodeString(binary)
+		if err != nil {
+			// Left to encoding/xml, which reports it.
+			return errSlowPath
+		}
+		v.BinaryValue = b
+	}
+	if m.MessageAttributes == nil {
+		m.MessageAttributes = make(MessageAttributes)
+	}
+	m.MessageAttributes[name] = v
+	return nil
+}
+
+// decodeFastXML implements fastXMLDecoder.
+func (r *sendMessageResponse) decodeFastXML(s *xmlScanner) error {
+	err := s.root(func(name []byte) error {
+		var err error
+		switch string(name) {
+		case "SendMessageResult":
+			err = s.each(func(name []byte) error {
+				var err error
+				switch string(name) {
+				case "MessageId":
+					r.Id, err = s.text()
+				case "MD5OfMessageBody":
+					r.MD5OfMessageBody, err = s.text()
+				default:
+					err = s.skip()
+				}
+				return err
+			})
+		case "RequestId":
+			r.RequestId, err = s.text()
+		default:
+			err = s.skip()
+		}
+		return err
+	})
+	if err == errSlowPath {
+		*r = sendMessageResponse{}
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package sqs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+// decodeBoth decodes doc with the scanner and with encoding/xml.
+func decodeBoth(c *C, doc string) (fast, slow receiveMessageResponse, fastErr, slowErr error) {
+	fastErr = QueryProtocol.decode(strings.NewReader(doc), &fast)
+	slowErr = xml.NewDecoder(strings.NewReader(doc)).Decode(&slow)
+	return
+}
+
+func (s *S) TestFastXMLMatchesEncodingXML(c *C) {
+	docs := []string{
+		string(benchReceiveResponse),
+		`<?xml version="1.0"?>
+<ReceiveMessageResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+  <ReceiveMessageResult>
+    <Message>
+      <MessageId>id-1</MessageId>
+      <ReceiptHandle>a+b/c==</ReceiptHandle>
+      <Body>&lt;order id=&quot;1&quot;&gt; &amp; caf&#233; &#x1F600;&#xD;
+line&apos;s&#13;end&#10;</Body>
+      <!-- a comment -->
+      <Attribute><Name>SentTimestamp</Name><Value>1700000000123</Value></Attribute>
+      <Attribute><Name>ApproximateReceiveCount</Name><Value>3</Value></Attribute>
+      <MessageAttribute><Name>blob</Name><Value><DataType>Binary</DataType><BinaryValue>AQI=</BinaryValue></Value></MessageAttribute>
+      <MessageAttribute><Name>kind</Name><Value><DataType>String</DataType><StringValue>a&#x3C;b</StringValue></Value></MessageAttribute>
+      <Unknown><Nested>x</Nested></Unknown>
+    </Message>
+    <Message><MessageId>id-2</MessageId><Body/></Message>
+  </ReceiveMessageResult>
+  <RequestId>req-1</RequestId>
+</ReceiveMessageResponse>`,
+		"<sqs:ReceiveMessageResponse xmlns:sqs=\"x\"><sqs:ReceiveMessageResult><sqs:Message><sqs:Body>crlf\r\nlone\rcr</sqs:Body></sqs:Message></sqs:ReceiveMessageResult></sqs:ReceiveMessageResponse>",
+		`<ReceiveMessageResponse><ReceiveMessageResult/></ReceiveMessageResponse>`,
+		// Left to encoding/xml.
+		`<ReceiveMessageResponse><ReceiveMessageResult><Message><Body><![CDATA[<raw>]]></Body></Message></ReceiveMessageResult></ReceiveMessageResponse>`,
+		`<ReceiveMessageResponse><ReceiveMessageResult><Message><Body>a<b>c</b></Body></Message></ReceiveMessageResult></ReceiveMessageResponse>`,
+	}
+	for i, doc := range docs {
+		fast, slow, fastErr, slowErr := decodeBoth(c, doc)
+		c.Assert(fastErr, IsNil, Commentf("document %d", i))
+		c.Assert(slowErr, IsNil, Commentf("document %d", i))
+		c.Assert(fast, DeepEquals, slow, Commentf("document %d", i))
+	}
+
+	fast, _, _, _ := decodeBoth(c, docs[1])
+	c.Assert(fast.Messages[0].Body, Equals, "<order id=\"1\"> & café 😀\r\nline's\rend\n")
+	c.Assert(fast.RequestId, Equals, "req-1")
+}
+
+func (s *S) TestFastXMLErrors(c *C) {
+	for _, doc := range []string{
+		`<ReceiveMessageResponse><ReceiveMessageResult><Message><Body>x</Wrong></Message></ReceiveMessageResult></ReceiveMessageResponse>`,
+		`<ReceiveMessageResponse><ReceiveMessageResult><Message><Body>&bogus;</Body></Message>`,
+		`<ReceiveMessageResponse><ReceiveMessageResult><Message><Body>x` + "\x01" + `</Body></Message></ReceiveMessageResult></ReceiveMessageResponse>`,
+		`<ReceiveMessageResponse><ReceiveMessageResult><Message><Body>` + "\xff" + `</Body></Message></ReceiveMessageResult></ReceiveMessageResponse>`,
+		`<ReceiveMessageResponse><ReceiveMessageResult><Message><MessageAttribute><Name>b</Name><Value><BinaryValue>!!</BinaryValue></Value></MessageAttribute></Message></ReceiveMessageResult></ReceiveMessageResponse>`,
+		`<ReceiveMessageResponse><ReceiveMessageResult><Message><Attribute><Name>ApproximateReceiveCount</Name><Value>x</Value></Attribute></Message></ReceiveMessageResult></ReceiveMessageResponse>`,
+	} {
+		_, _, fastErr, slowErr := decodeBoth(c, doc)
+		c.Assert(slowErr, NotNil, Commentf("%s", doc))
+		c.Assert(fastErr, DeepEquals, slowErr, Commentf("%s", doc))
+	}
+}
+
+func (s *S) TestFastXMLSendResponse(c *C) {
+	var resp sendMessageResponse
+	c.Assert(QueryProtocol.decode(bytes.NewReader([]byte(benchSendResponse)), &resp), IsNil)
+	c.Assert(resp.Id, Equals, "id")
+	c.Assert(resp.MD5OfMessageBody, Equals, "b3ec4d3be2b8a86de2b2e6e6ddb1bbf1")
+}
@@ -0,0 +1,288 @@
+package sqs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Message attributes set by FileDrop.
+const (
+	FilenameAttribute   = "filename"
+	LineNumberAttribute = "line"
+)
+
+// A FileDropMode selects how FileDrop turns files into messages.
+type FileDropMode int
+
+const (
+	// FileDropFiles sends the contents of every file as one message.
+	FileDropFiles FileDropMode = iota
+	// FileDropLines sends every non-empty line as its own message.
+	FileDropLines
+)
+
+// A FileDrop enqueues files dropped into a directory, or lines written to a
+// named pipe, bridging legacy batch systems into SQS. Every message carries
+// the file name in its "filename" attribute and, in FileDropLines mode, the
+// line number in its "line" attribute.
+//
+// Files are picked up once they have not been modified for Settle, and are
+// removed, or moved to DoneDir, once fully sent. A file that fails midway is
+// retried on the next scan: in FileDropLines mode from the line that failed,
+// so delivery is at least once. After MaxAttempts failures it is moved to
+// ErrorDir, or left alone until modified if ErrorDir is not set.
+type FileDrop struct {
+	Queue *Queue
+	Mode  FileDropMode
+
+	// Dir is the directory to watch and Pattern the glob file names must
+	// match (default "*"). Pipe, if set instead, is the path of a named
+	// pipe to read lines from.
+	Dir     string
+	Pattern string
+	Pipe    string
+
+	Interval time.Duration // directory scan interval, default one second
+	Settle   time.Duration // default one second
+	DoneDir  string
+	ErrorDir string
+
+	// MaxAttempts is the number of times a file is tried; it defaults
+	// to 3.
+	MaxAttempts int
+
+	// OnError, if set, is called with file and send errors.
+	OnError func(err error)
+
+	failed map[string]*fileDropProgress // by path
+}
+
+// fileDropProgress tracks a file that failed to be sent.
+type fileDropProgress struct {
+	modTime  time.Time
+	attempts int
+	lines    int // lines sent, in FileDropLines mode
+}
+
+func (f *FileDrop) onError(err error) {
+	if f.OnError != nil {
+		f.OnError(err)
+	}
+}
+
+// Run enqueues files or pipe lines until ctx is done.
+func (f *FileDrop) Run(ctx context.Context) error {
+	if f.Pipe != "" {
+		return f.runPipe(ctx)
+	}
+	interval := f.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	for {
+		f.scan()
+		if !sleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// scan sends every settled file in Dir, oldest first.
+func (f *FileDrop) scan() {
+	pattern := f.Pattern
+	if pattern == "" {
+		pattern = "*"
+	}
+	paths, err := filepath.Glob(filepath.Join(f.Dir, pattern))
+	if err != nil {
+		f.onError(err)
+		return
+	}
+	settle := f.Settle
+	if settle <= 0 {
+		settle = time.Second
+	}
+	var files []os.FileInfo
+	byName := make(map[string]string)
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil || !fi.Mode().IsRegular() || time.Since(fi.ModTime()) < settle {
+			continue
+		}
+		files = append(files, fi)
+		byName[fi.Name()] = p
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	if f.failed == nil {
+		f.failed = make(map[string]*fileDropProgress)
+	}
+	maxAttempts := f.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	for _, fi := range files {
+		p := byName[fi.Name()]
+		progress := f.failed[p]
+		if progress == nil || !progress.modTime.Equal(fi.ModTime()) {
+			progress = &fileDropProgress{modTime: fi.ModTime()}
+		}
+		if progress.attempts >= maxAttempts {
+			continue
+		}
+		if err := f.send(p, progress); err != nil {
+			progress.attempts++
+			f.failed[p] = progress
+			f.onError(fmt.Errorf("sqs: file drop %s (attempt %d of %d): %s", p, progress.attempts, maxAttempts, err))
+			if progress.attempts == maxAttempts && f.ErrorDir != "" {
+				if err := os.Rename(p, filepath.Join(f.ErrorDir, fi.Name())); err != nil {
+					f.onError(err)
+				} else {
+					delete(f.failed, p)
+				}
+			}
+			continue
+		}
+		delete(f.failed, p)
+		var err error
+		if f.DoneDir != "" {
+			err = os.Rename(p, filepath.Join(f.DoneDir, fi.Name()))
+		} else {
+			err = os.Remove(p)
+		}
+		if err != nil {
+			f.onError(err)
+		}
+	}
+	for p := range f.failed {
+		if _, ok := byName[filepath.Base(p)]; !ok {
+			delete(f.failed, p) // removed or renamed
+		}
+	}
+}
+
+// send sends the file at p, resuming after the lines progress records as
+// sent, and updating it.
+func (f *FileDrop) send(p string, progress *fileDropProgress) error {
+	name := filepath.Base(p)
+	if f.Mode == FileDropFiles {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 {
+			return nil
+		}
+		_, err = f.Queue.SendMessageWithOpt(string(b), fileDropOpt(name, 0))
+		return err
+	}
+
+	file, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f.sendLines(file, name, &progress.lines)
+}
+
+// sendLines sends the lines of file after the first *sent ones, counting
+// those sent in *sent.
+func (f *FileDrop) sendLines(file *os.File, name string, sent *int) error {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), DefaultMaxBodySize)
+	for n := 1; scanner.Scan(); n++ {
+		if n <= *sent {
+			continue
+		}
+		if len(scanner.Bytes()) > 0 {
+			if _, err := f.Queue.SendMessageWithOpt(scanner.Text(), fileDropOpt(name, n)); err != nil {
+				return err
+			}
+		}
+		*sent = n
+	}
+	return scanner.Err()
+}
+
+// runPipe reads lines from the named pipe, reopening it whenever the
+// writer closes it.
+func (f *FileDrop) runPipe(ctx context.Context) error {
+	name := filepath.Base(f.Pipe)
+	for ctx.Err() == nil {
+		file, err := openPipe(ctx, f.Pipe)
+		if err != nil {
+			if ctx.Err() == nil {
+				f.onError(err)
+				sleepContext(ctx, time.Second)
+			}
+			continue
+		}
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				file.Close()
+			case <-done:
+			}
+		}()
+		var sent int
+		if err := f.sendLines(file, name, &sent); err != nil && ctx.Err() == nil {
+			f.onError(err)
+		}
+		close(done)
+		file.Close()
+	}
+	return ctx.Err()
+}
+
+// openPipe opens the named pipe at path for reading. Opening a pipe blocks
+// until a writer opens it too, so once ctx is done, openPipe opens it for
+// writing itself to release the open, and returns.
+func openPipe(ctx context.Context, path string) (*os.File, error) {
+	type result struct {
+		file *os.File
+		err  error
+	}
+	opened := make(chan result, 1)
+	go func() {
+		file, err := os.Open(path)
+		opened <- result{file, err}
+	}()
+	select {
+	case r := <-opened:
+		return r.file, r.err
+	case <-ctx.Done():
+	}
+	for {
+		// Fails while the reader has yet to open the pipe.
+		if w, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0); err == nil {
+			w.Close()
+		}
+		select {
+		case r := <-opened:
+			if r.file != nil {
+				r.file.Close()
+			}
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func fileDropOpt(name string, line int) *SendMessageOpt {
+	attrs := MessageAttributes{
+		FilenameAttribute: {DataType: "String", StringValue: name},
+	}
+	if line > 0 {
+		attrs[LineNumberAttribute] = MessageAttributeValue{DataType: "Number", StringValue: strconv.Itoa(line)}
+	}
+	return &SendMessageOpt{MessageAttributes: attrs}
+}
@@ -0,0 +1,44 @@
+package sqs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestFileDropPipeCancel(c *C) {
+	pipe := filepath.Join(c.MkDir(), "pipe")
+	c.Assert(syscall.Mkfifo(pipe, 0600), IsNil)
+	q, err := NewLocal().SQS().CreateQueue("piped", nil)
+	c.Assert(err, IsNil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- (&FileDrop{Queue: q, Pipe: pipe}).Run(ctx) }()
+
+	w, err := os.OpenFile(pipe, os.O_WRONLY, 0)
+	c.Assert(err, IsNil)
+	_, err = w.WriteString("x\ny\n")
+	c.Assert(err, IsNil)
+	c.Assert(w.Close(), IsNil)
+	for i := 0; i < 500; i++ {
+		if st, err := q.Stats(); err == nil && st.Visible == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Assert(drainBodies(c, q), DeepEquals, []string{"x", "y"})
+
+	// Run is reopening the pipe, which no writer opens again.
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		c.Assert(err, Equals, context.Canceled)
+	case <-time.After(5 * time.Second):
+		c.Fatal("Run did not return once cancelled")
+	}
+}
@@ -0,0 +1,102 @@
+package sqs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+// fileDropQueue returns a queue of a local backend failing to send the
+// body "bad" as long as *fail is set.
+func fileDropQueue(c *C, fail *bool) *Queue {
+	l := NewLocal()
+	sqs := l.SQS()
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if *fail && requestParams(c, req).Get("MessageBody") == "bad" {
+			return xmlError(req, ErrCodeInvalidParameterValue, "bad body"), nil
+		}
+		return l.Do(req)
+	})
+	q, err := sqs.CreateQueue("dropped", nil)
+	c.Assert(err, IsNil)
+	return q
+}
+
+func drainBodies(c *C, q *Queue) []string {
+	var bodies []string
+	for {
+		msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10, VisibilityTimeout: 60})
+		c.Assert(err, IsNil)
+		if len(msgs) == 0 {
+			return bodies
+		}
+		for _, m := range msgs {
+			bodies = append(bodies, m.Body)
+		}
+	}
+}
+
+func (s *S) TestFileDropLinesResume(c *C) {
+	fail := true
+	q := fileDropQueue(c, &fail)
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "batch"), []byte("a\n\nb\nbad\nc\n"), 0644), IsNil)
+	past := time.Now().Add(-time.Minute)
+	c.Assert(os.Chtimes(filepath.Join(dir, "batch"), past, past), IsNil)
+
+	var errs []error
+	f := &FileDrop{Queue: q, Mode: FileDropLines, Dir: dir, OnError: func(err error) { errs = append(errs, err) }}
+	f.scan()
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errs[0], ErrorMatches, `sqs: file drop .*batch \(attempt 1 of 3\): .*bad body.*`)
+	c.Assert(drainBodies(c, q), DeepEquals, []string{"a", "b"})
+
+	// The retry resumes from the line that failed.
+	fail = false
+	f.scan()
+	c.Assert(errs, HasLen, 1)
+	c.Assert(drainBodies(c, q), DeepEquals, []string{"bad", "c"})
+	_, err := os.Stat(filepath.Join(dir, "batch"))
+	c.Assert(os.IsNotExist(err), Equals, true)
+}
+
+func (s *S) TestFileDropErrorDir(c *C) {
+	fail := true
+	q := fileDropQueue(c, &fail)
+	dir, errDir := c.MkDir(), c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "batch"), []byte("a\nbad\n"), 0644), IsNil)
+	past := time.Now().Add(-time.Minute)
+	c.Assert(os.Chtimes(filepath.Join(dir, "batch"), past, past), IsNil)
+
+	errs := 0
+	f := &FileDrop{Queue: q, Mode: FileDropLines, Dir: dir, ErrorDir: errDir, MaxAttempts: 2, OnError: func(error) { errs++ }}
+	for i := 0; i < 3; i++ {
+		f.scan()
+	}
+	c.Assert(errs, Equals, 2)
+	c.Assert(drainBodies(c, q), DeepEquals, []string{"a"})
+	b, err := ioutil.ReadFile(filepath.Join(errDir, "batch"))
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, "a\nbad\n")
+
+	// Without ErrorDir, the file is left alone until modified.
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, "whole"), []byte("bad"), 0644), IsNil)
+	c.Assert(os.Chtimes(filepath.Join(dir, "whole"), past, past), IsNil)
+	errs = 0
+	f = &FileDrop{Queue: q, Dir: dir, MaxAttempts: 2, OnError: func(error) { errs++ }}
+	for i := 0; i < 3; i++ {
+		f.scan()
+	}
+	c.Assert(errs, Equals, 2)
+	fail = false
+	f.scan()
+	c.Assert(errs, Equals, 2)
+	later := past.Add(time.Second)
+	c.Assert(os.Chtimes(filepath.Join(dir, "whole"), later, later), IsNil)
+	f.scan()
+	c.Assert(drainBodies(c, q), DeepEquals, []string{"bad"})
+}
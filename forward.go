@@ -0,0 +1,176 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A ForwardAction decides what happens to a message after it was forwarded.
+type ForwardAction int
+
+const (
+	// ForwardAck deletes the message.
+	ForwardAck ForwardAction = iota
+	// ForwardNack releases the message for redelivery after NackDelay.
+	ForwardNack
+	// ForwardRetry retries the request in-process, up to MaxRetries
+	// times, before falling back to ForwardNack.
+	ForwardRetry
+)
+
+// An HTTPForwarder consumes a queue by POSTing every message body to URL,
+// so services can consume queues without linking this package. The message
+// ID and custom string attributes are sent as X-Sqs-Message-Id and
+// X-Sqs-Attribute-<Name> headers.
+//
+// By default 2xx responses acknowledge the message, 408, 429 and 5xx
+// responses and transport errors are retried, and any other status releases
+// the message for redelivery; StatusActions overrides this per status code.
+type HTTPForwarder struct {
+	Queue *Queue
+	URL   string
+
+	Client      *http.Client
+	ContentType string // defaults to "text/plain; charset=utf-8"
+	Header      http.Header
+
+	// Concurrency is the number of messages forwarded in parallel; it
+	// defaults to 1.
+	Concurrency int
+	// StatusActions overrides the action taken for individual status
+	// codes.
+	StatusActions map[int]ForwardAction
+	// MaxRetries bounds in-process retries of a message, spaced by
+	// RetryDelay (default one second).
+	MaxRetries int
+	RetryDelay time.Duration
+	// NackDelay, in seconds, is the visibility timeout given to released
+	// messages; zero makes them visible again immediately.
+	NackDelay int
+
+	// OnError, if set, is called with receive, forwarding and ack errors.
+	OnError func(err error)
+}
+
+func (f *HTTPForwarder) onError(err error) {
+	if f.OnError != nil {
+		f.OnError(err)
+	}
+}
+
+// Run forwards messages until ctx is done, then waits for in-flight
+// messages to be settled. Requests still in flight are cancelled with ctx,
+// and their messages released.
+func (f *HTTPForwarder) Run(ctx context.Context) error {
+	n := f.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.work(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (f *HTTPForwarder) work(ctx context.Context) {
+	var b backoff
+	for ctx.Err() == nil {
+		msgs := receive(ctx, f.Queue, &ReceiveMessageOpt{
+			WaitTimeSeconds:       20,
+			MessageAttributeNames: []string{"All"},
+		}, &b, f.OnError)
+		for _, m := range msgs {
+			f.handle(ctx, m)
+		}
+	}
+}
+
+// handle forwards m and settles it according to the outcome.
+func (f *HTTPForwarder) handle(ctx context.Context, m *Message) {
+	var action ForwardAction
+	for attempt := 0; ; attempt++ {
+		var err error
+		action, err = f.forward(ctx, m)
+		if err != nil {
+			f.onError(err)
+		}
+		if action != ForwardRetry {
+			break
+		}
+		if attempt >= f.MaxRetries {
+			action = ForwardNack
+			break
+		}
+		delay := f.RetryDelay
+		if delay <= 0 {
+			delay = time.Second
+		}
+		if !sleepContext(ctx, delay) {
+			action = ForwardNack
+			break
+		}
+	}
+
+	var err error
+	if action == ForwardAck {
+		err = f.Queue.DeleteMessage(m)
+	} else {
+		err = f.Queue.ChangeMessageVisibility(m, f.NackDelay)
+	}
+	if err != nil {
+		f.onError(err)
+	}
+}
+
+// forward POSTs m once and returns the action its outcome calls for.
+func (f *HTTPForwarder) forward(ctx context.Context, m *Message) (ForwardAction, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", f.URL, strings.NewReader(m.Body))
+	if err != nil {
+		return ForwardNack, err
+	}
+	for k, v := range f.Header {
+		req.Header[k] = v
+	}
+	ct := f.ContentType
+	if ct == "" {
+		ct = "text/plain; charset=utf-8"
+	}
+	req.Header.Set("Content-Type", ct)
+	req.Header.Set("X-Sqs-Message-Id", m.Id)
+	for name, v := range m.MessageAttributes {
+		if v.BinaryValue == nil {
+			req.Header.Set("X-Sqs-Attribute-"+name, v.StringValue)
+		}
+	}
+
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	r, err := client.Do(req)
+	if err != nil {
+		return ForwardRetry, err
+	}
+	r.Body.Close()
+
+	if action, ok := f.StatusActions[r.StatusCode]; ok {
+		return action, nil
+	}
+	switch {
+	case r.StatusCode/100 == 2:
+		return ForwardAck, nil
+	case r.StatusCode == http.StatusRequestTimeout, r.StatusCode == http.StatusTooManyRequests, r.StatusCode >= 500:
+		return ForwardRetry, fmt.Errorf("sqs: forwarding message %s: %s", m.Id, r.Status)
+	}
+	return ForwardNack, fmt.Errorf("sqs: forwarding message %s: %s", m.Id, r.Status)
+}
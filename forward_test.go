@@ -0,0 +1,113 @@
+package sqs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestHTTPForwarder(c *C) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body := string(b)
+		mu.Lock()
+		requests[body]++
+		n := requests[body]
+		mu.Unlock()
+		switch {
+		case body == "ok":
+			c.Check(r.Header.Get("Content-Type"), Equals, "text/plain; charset=utf-8")
+			c.Check(r.Header.Get("X-Sqs-Message-Id"), Not(Equals), "")
+			c.Check(r.Header.Get("X-Sqs-Attribute-Kind"), Equals, "greeting")
+			c.Check(r.Header.Get("X-Token"), Equals, "t")
+		case body == "flaky" && n == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case body == "bad":
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	q, err := NewLocal().SQS().CreateQueue("forward", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessageWithOpt("ok", &SendMessageOpt{MessageAttributes: MessageAttributes{"Kind": {DataType: "String", StringValue: "greeting"}}})
+	c.Assert(err, IsNil)
+	for _, body := range []string{"flaky", "bad"} {
+		_, err := q.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+
+	var errs []string
+	f := &HTTPForwarder{
+		Queue:      q,
+		URL:        srv.URL,
+		Header:     http.Header{"X-Token": {"t"}},
+		MaxRetries: 2,
+		RetryDelay: time.Millisecond,
+		NackDelay:  60,
+		OnError: func(err error) {
+			errs = append(errs, err.Error())
+			if len(errs) == 2 {
+				close(done)
+			}
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-done
+		cancel()
+	}()
+	c.Assert(f.Run(ctx), Equals, context.Canceled)
+
+	// The flaky message was retried and acknowledged, the bad one
+	// released with NackDelay.
+	c.Assert(requests, DeepEquals, map[string]int{"ok": 1, "flaky": 2, "bad": 1})
+	c.Assert(errs, HasLen, 2)
+	c.Assert(errs[0], Matches, "sqs: forwarding message .*: 503 Service Unavailable")
+	c.Assert(errs[1], Matches, "sqs: forwarding message .*: 400 Bad Request")
+	attrs, err := q.GetQueueAttributes(ApproximateNumberOfMessages, ApproximateNumberOfMessagesNotVisible)
+	c.Assert(err, IsNil)
+	visible, _ := attrs.get(ApproximateNumberOfMessages)
+	hidden, _ := attrs.get(ApproximateNumberOfMessagesNotVisible)
+	c.Assert(visible+" "+hidden, Equals, "0 1")
+}
+
+func (s *S) TestHTTPForwarderCancel(c *C) {
+	started := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		close(started)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	q, err := NewLocal().SQS().CreateQueue("forward", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("slow")
+	c.Assert(err, IsNil)
+
+	f := &HTTPForwarder{Queue: q, URL: srv.URL, OnError: func(error) {}}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	start := time.Now()
+	c.Assert(f.Run(ctx), Equals, context.Canceled)
+	c.Assert(time.Since(start) < time.Second, Equals, true)
+
+	// The message of the cancelled request is released.
+	msgs, err := q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "slow")
+}
@@ -0,0 +1,239 @@
+package sqs
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Conventional keys for Member.Attributes.
+const (
+	MemberHostAttribute    = "host"
+	MemberPIDAttribute     = "pid"
+	MemberVersionAttribute = "version"
+)
+
+// A Member is one process of a consumer group, as last reported by its
+// heartbeat.
+type Member struct {
+	ID        string
+	Heartbeat time.Time
+	InFlight  int
+	Paused    bool
+	// Attributes holds free-form details about the process, keyed by
+	// convention with the Member*Attribute constants.
+	Attributes map[string]string
+}
+
+// A MembershipStore records the members of consumer groups and the group
+// pause flag. Implementations shared between hosts, such as a database or
+// a key-value store, give a group of processes consuming one queue a
+// common view of each other.
+type MembershipStore interface {
+	// Heartbeat records m as a live member of group.
+	Heartbeat(group string, m Member) error
+	// Leave removes member id from group.
+	Leave(group, id string) error
+	// Members returns every member of group that has not left, including
+	// stale ones.
+	Members(group string) ([]Member, error)
+	// SetPaused sets and Paused reports the pause flag of group.
+	SetPaused(group string, paused bool) error
+	Paused(group string) (bool, error)
+}
+
+// A MemoryMembershipStore is a MembershipStore for the processes of a
+// single host, and for tests.
+type MemoryMembershipStore struct {
+	mu     sync.Mutex
+	groups map[string]map[string]Member
+	paused map[string]bool
+}
+
+// Heartbeat implements MembershipStore.
+func (s *MemoryMembershipStore) Heartbeat(group string, m Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.groups == nil {
+		s.groups = make(map[string]map[string]Member)
+	}
+	if s.groups[group] == nil {
+		s.groups[group] = make(map[string]Member)
+	}
+	s.groups[group][m.ID] = m
+	return nil
+}
+
+// Leave implements MembershipStore.
+func (s *MemoryMembershipStore) Leave(group, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.groups[group], id)
+	return nil
+}
+
+// Members implements MembershipStore.
+func (s *MemoryMembershipStore) Members(group string) ([]Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	members := make([]Member, 0, len(s.groups[group]))
+	for _, m := range s.groups[group] {
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// SetPaused implements MembershipStore.
+func (s *MemoryMembershipStore) SetPaused(group string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused == nil {
+		s.paused = make(map[string]bool)
+	}
+	s.paused[group] = paused
+	return nil
+}
+
+// Paused implements MembershipStore.
+func (s *MemoryMembershipStore) Paused(group string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused[group], nil
+}
+
+// A GroupMember makes a consumer process a member of a consumer group,
+// in the manner of a Kafka consumer group: it heartbeats to Store with its
+// in-flight count, and follows the group's pause flag.
+//
+// Consumers call Begin and Done around every message they handle, and
+// stop receiving while Paused reports true:
+//
+//	for ctx.Err() == nil {
+//		if g.Paused() {
+//			time.Sleep(time.Second)
+//			continue
+//		}
+//		msgs, err := q.ReceiveMessages(opt)
+//		...
+//		g.Begin(len(msgs))
+//		handle(msgs)
+//		g.Done(len(msgs))
+//	}
+type GroupMember struct {
+	Group string
+	ID    string // defaults to "<hostname>-<pid>"
+	Store MembershipStore
+
+	// Interval is the heartbeat interval, default ten seconds.
+	Interval time.Duration
+	// Attributes is reported with every heartbeat; the host and pid are
+	// filled in when missing.
+	Attributes map[string]string
+	// OnError, if set, is called with store errors.
+	OnError func(err error)
+
+	inFlight int64
+	paused   int32
+}
+
+func (g *GroupMember) onError(err error) {
+	if g.OnError != nil {
+		g.OnError(err)
+	}
+}
+
+// Begin records that n messages are being handled.
+func (g *GroupMember) Begin(n int) {
+	atomic.AddInt64(&g.inFlight, int64(n))
+}
+
+// Done records that n messages were handled.
+func (g *GroupMember) Done(n int) {
+	atomic.AddInt64(&g.inFlight, -int64(n))
+}
+
+// InFlight returns the number of messages being handled.
+func (g *GroupMember) InFlight() int {
+	return int(atomic.LoadInt64(&g.inFlight))
+}
+
+// Paused reports whether the group was paused as of the last heartbeat.
+func (g *GroupMember) Paused() bool {
+	return atomic.LoadInt32(&g.paused) != 0
+}
+
+// Run heartbeats until ctx is done, then leaves the group.
+func (g *GroupMember) Run(ctx context.Context) error {
+	host, _ := os.Hostname()
+	pid := strconv.Itoa(os.Getpid())
+	if g.ID == "" {
+		g.ID = host + "-" + pid
+	}
+	attrs := map[string]string{MemberHostAttribute: host, MemberPIDAttribute: pid}
+	for k, v := range g.Attributes {
+		attrs[k] = v
+	}
+	interval := g.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	for {
+		g.heartbeat(attrs)
+		if !sleepContext(ctx, interval) {
+			break
+		}
+	}
+	if err := g.Store.Leave(g.Group, g.ID); err != nil {
+		g.onError(err)
+	}
+	return ctx.Err()
+}
+
+func (g *GroupMember) heartbeat(attrs map[string]string) {
+	paused, err := g.Store.Paused(g.Group)
+	if err != nil {
+		g.onError(err)
+	} else if paused {
+		atomic.StoreInt32(&g.paused, 1)
+	} else {
+		atomic.StoreInt32(&g.paused, 0)
+	}
+	err = g.Store.Heartbeat(g.Group, Member{
+		ID:         g.ID,
+		Heartbeat:  time.Now(),
+		InFlight:   g.InFlight(),
+		Paused:     g.Paused(),
+		Attributes: attrs,
+	})
+	if err != nil {
+		g.onError(err)
+	}
+}
+
+// GroupStatus returns the live members of group, sorted by ID. Members
+// whose last heartbeat is older than ttl are considered gone and left out.
+func GroupStatus(store MembershipStore, group string, ttl time.Duration) ([]Member, error) {
+	members, err := store.Members(group)
+	if err != nil {
+		return nil, err
+	}
+	live := members[:0]
+	for _, m := range members {
+		if time.Since(m.Heartbeat) <= ttl {
+			live = append(live, m)
+		}
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].ID < live[j].ID })
+	return live, nil
+}
+
+// PauseGroup pauses or resumes every member of group at its next
+// heartbeat.
+func PauseGroup(store MembershipStore, group string, paused bool) error {
+	return store.SetPaused(group, paused)
+}
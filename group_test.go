@@ -0,0 +1,50 @@
+package sqs
+
+import (
+	"context"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestGroupMember(c *C) {
+	store := &MemoryMembershipStore{}
+	g := &GroupMember{Group: "workers", ID: "a", Store: store, Interval: 10 * time.Millisecond}
+	g.Begin(3)
+	g.Done(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- g.Run(ctx) }()
+
+	c.Assert(PauseGroup(store, "workers", true), IsNil)
+	for i := 0; i < 100 && !g.Paused(); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	c.Assert(g.Paused(), Equals, true)
+
+	members, err := GroupStatus(store, "workers", time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(members, HasLen, 1)
+	c.Assert(members[0].ID, Equals, "a")
+	c.Assert(members[0].InFlight, Equals, 2)
+	c.Assert(members[0].Attributes[MemberHostAttribute], Not(Equals), "")
+
+	cancel()
+	<-done
+	members, err = GroupStatus(store, "workers", time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(members, HasLen, 0)
+}
+
+func (s *S) TestGroupStatusDropsStale(c *C) {
+	store := &MemoryMembershipStore{}
+	store.Heartbeat("g", Member{ID: "b", Heartbeat: time.Now()})
+	store.Heartbeat("g", Member{ID: "a", Heartbeat: time.Now().Add(-time.Hour)})
+	store.Heartbeat("g", Member{ID: "c", Heartbeat: time.Now()})
+	members, err := GroupStatus(store, "g", time.Minute)
+	c.Assert(err, IsNil)
+	c.Assert(members, HasLen, 2)
+	c.Assert(members[0].ID, Equals, "b")
+	c.Assert(members[1].ID, Equals, "c")
+}
@@ -0,0 +1,158 @@
+package sqs
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A SendGuard suppresses sending a body identical to one sent within the
+// last Window, such as a webhook delivered twice by its sender. Unlike FIFO
+// deduplication it works on standard queues and across queues, but only
+// within one process, unless Store is shared.
+//
+// The zero value is ready to use.
+type SendGuard struct {
+	// Window is how long a sent body suppresses its duplicates, default
+	// five minutes.
+	Window time.Duration
+	// MaxEntries bounds the number of remembered bodies, default 10000;
+	// the oldest are forgotten first.
+	MaxEntries int
+	// Store, if set, remembers the bodies instead of memory, so that
+	// duplicates are suppressed across restarts, or across processes
+	// sharing it. Store errors let bodies through. MaxEntries does not
+	// apply.
+	Store Storage
+
+	sent, suppressed int64
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	order   list.List // of *guardEntry, oldest first
+}
+
+type guardEntry struct {
+	sum [sha256.Size]byte
+	at  time.Time
+}
+
+// SendGuardStats counts the decisions of a SendGuard.
+type SendGuardStats struct {
+	Sent       int64
+	Suppressed int64
+}
+
+// Allow reports whether body may be sent, and if so remembers it. A caller
+// whose send then fails should call Forget so a retry is not suppressed.
+func (g *SendGuard) Allow(body string) bool {
+	return g.allow(sha256.Sum256([]byte(body)))
+}
+
+// allow implements Allow for the digest sum of a body or key.
+func (g *SendGuard) allow(sum [sha256.Size]byte) bool {
+	if g.Store != nil {
+		return g.allowStored(sum)
+	}
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.expire(now)
+	if _, ok := g.entries[sum]; ok {
+		atomic.AddInt64(&g.suppressed, 1)
+		return false
+	}
+	if g.entries == nil {
+		g.entries = make(map[[sha256.Size]byte]*list.Element)
+	}
+	g.entries[sum] = g.order.PushBack(&guardEntry{sum, now})
+	max := g.MaxEntries
+	if max <= 0 {
+		max = 10000
+	}
+	for g.order.Len() > max {
+		g.remove(g.order.Front())
+	}
+	atomic.AddInt64(&g.sent, 1)
+	return true
+}
+
+// Forget removes body from the guard.
+func (g *SendGuard) Forget(body string) {
+	g.forget(sha256.Sum256([]byte(body)))
+}
+
+// forget implements Forget for the digest sum of a body or key.
+func (g *SendGuard) forget(sum [sha256.Size]byte) {
+	if g.Store != nil {
+		if g.Store.Delete(guardKey(sum)) == nil {
+			atomic.AddInt64(&g.sent, -1)
+		}
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if e, ok := g.entries[sum]; ok {
+		g.remove(e)
+		atomic.AddInt64(&g.sent, -1)
+	}
+}
+
+// Send sends body to q unless it is a duplicate. It returns the message ID
+// and whether the message was sent.
+func (g *SendGuard) Send(q QueueSender, body string, opt *SendMessageOpt) (string, bool, error) {
+	if !g.Allow(body) {
+		return "", false, nil
+	}
+	id, err := q.SendMessageWithOpt(body, opt)
+	if err != nil {
+		g.Forget(body)
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+// Stats returns the number of sent and suppressed bodies.
+func (g *SendGuard) Stats() SendGuardStats {
+	return SendGuardStats{
+		Sent:       atomic.LoadInt64(&g.sent),
+		Suppressed: atomic.LoadInt64(&g.suppressed),
+	}
+}
+
+func (g *SendGuard) window() time.Duration {
+	if g.Window <= 0 {
+		return 5 * time.Minute
+	}
+	return g.Window
+}
+
+func guardKey(sum [sha256.Size]byte) string {
+	return "guard/" + hex.EncodeToString(sum[:])
+}
+
+// allowStored implements Allow with Store.
+func (g *SendGuard) allowStored(sum [sha256.Size]byte) bool {
+	if _, err := g.Store.Get(guardKey(sum)); err == nil {
+		atomic.AddInt64(&g.suppressed, 1)
+		return false
+	}
+	g.Store.Put(guardKey(sum), nil, g.window())
+	atomic.AddInt64(&g.sent, 1)
+	return true
+}
+
+func (g *SendGuard) expire(now time.Time) {
+	window := g.window()
+	for e := g.order.Front(); e != nil && now.Sub(e.Value.(*guardEntry).at) >= window; e = g.order.Front() {
+		g.remove(e)
+	}
+}
+
+func (g *SendGuard) remove(e *list.Element) {
+	delete(g.entries, e.Value.(*guardEntry).sum)
+	g.order.Remove(e)
+}
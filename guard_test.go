@@ -0,0 +1,37 @@
+package sqs
+
+import (
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestSendGuard(c *C) {
+	g := &SendGuard{Window: 50 * time.Millisecond, MaxEntries: 2}
+	c.Assert(g.Allow("a"), Equals, true)
+	c.Assert(g.Allow("a"), Equals, false)
+	c.Assert(g.Allow("b"), Equals, true)
+
+	g.Forget("b")
+	c.Assert(g.Allow("b"), Equals, true)
+
+	// "a" is evicted once more than MaxEntries bodies are remembered.
+	c.Assert(g.Allow("c"), Equals, true)
+	c.Assert(g.Allow("a"), Equals, true)
+
+	time.Sleep(60 * time.Millisecond)
+	c.Assert(g.Allow("c"), Equals, true)
+	c.Assert(g.Stats(), Equals, SendGuardStats{Sent: 5, Suppressed: 1})
+}
+
+func (s *S) TestSendGuardStore(c *C) {
+	store := &MemoryStorage{}
+	g1 := &SendGuard{Store: store}
+	g2 := &SendGuard{Store: store}
+	c.Assert(g1.Allow("hook"), Equals, true)
+	c.Assert(g2.Allow("hook"), Equals, false)
+	g1.Forget("hook")
+	c.Assert(g2.Allow("hook"), Equals, true)
+	c.Assert(g1.Stats(), Equals, SendGuardStats{Sent: 0, Suppressed: 0})
+	c.Assert(g2.Stats(), Equals, SendGuardStats{Sent: 1, Suppressed: 1})
+}
@@ -0,0 +1,95 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// A PingStatus classifies the failure of a Ping.
+type PingStatus int
+
+const (
+	// PingUnknown is any failure not classified otherwise; see
+	// PingError.Err.
+	PingUnknown PingStatus = iota
+	// PingAuth means SQS refused the credentials or denied the request.
+	PingAuth
+	// PingNetwork means SQS could not be reached, or did not answer in
+	// time.
+	PingNetwork
+	// PingNotFound means the queue does not exist.
+	PingNotFound
+	// PingThrottled means SQS throttled the request, retries included.
+	PingThrottled
+)
+
+func (s PingStatus) String() string {
+	switch s {
+	case PingUnknown:
+		return "unknown"
+	case PingAuth:
+		return "auth"
+	case PingNetwork:
+		return "network"
+	case PingNotFound:
+		return "not found"
+	case PingThrottled:
+		return "throttled"
+	}
+	return "invalid"
+}
+
+// A PingError reports a failed Ping.
+type PingError struct {
+	Status PingStatus
+	Err    error
+}
+
+func (e *PingError) Error() string {
+	return fmt.Sprintf("sqs: ping failed (%s): %s", e.Status, e.Err)
+}
+
+func (e *PingError) Unwrap() error {
+	return e.Err
+}
+
+// pingError classifies err, the failure of a Ping.
+func pingError(err error) error {
+	if err == nil {
+		return nil
+	}
+	status := PingUnknown
+	switch {
+	case IsAuthFailure(err), ErrorCode(err) == ErrCodeAccessDenied, ErrorCode(err) == ErrCodeAccessDeniedException:
+		status = PingAuth
+	case errors.Is(err, ErrQueueNotFound):
+		status = PingNotFound
+	case IsThrottled(err):
+		status = PingThrottled
+	case transportError(err), errors.Is(err, context.DeadlineExceeded):
+		status = PingNetwork
+	}
+	return &PingError{status, err}
+}
+
+// Ping checks that SQS can be reached with the credentials of the client,
+// with one cheap authenticated request, e.g. for the readiness probe of a
+// service. It returns nil, or a *PingError classifying the failure:
+//
+//	var pe *sqs.PingError
+//	if errors.As(client.Ping(ctx), &pe) && pe.Status == sqs.PingNetwork {
+//		...
+//	}
+func (sqs *SQS) Ping(ctx context.Context) error {
+	params := url.Values{"MaxResults": {"1"}}
+	return pingError(sqs.callContext(ctx, "ListQueues", "/", params, &listQueuesResponse{}))
+}
+
+// Ping is like SQS.Ping, but checks that the queue exists and can be
+// accessed.
+func (q *Queue) Ping(ctx context.Context) error {
+	_, err := q.WithContext(ctx).GetQueueAttributes(QueueArn)
+	return pingError(err)
+}
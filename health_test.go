@@ -0,0 +1,61 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "launchpad.net/gocheck"
+)
+
+func pingStatus(err error) PingStatus {
+	var pe *PingError
+	if !errors.As(err, &pe) {
+		return -1
+	}
+	return pe.Status
+}
+
+func (s *S) TestPing(c *C) {
+	ctx := context.Background()
+	sqs := NewLocal().SQS()
+	c.Assert(sqs.Ping(ctx), IsNil)
+	q, err := sqs.CreateQueue("ready", nil)
+	c.Assert(err, IsNil)
+	c.Assert(q.Ping(ctx), IsNil)
+
+	c.Assert(q.DeleteQueue(), IsNil)
+	err = q.Ping(ctx)
+	c.Assert(pingStatus(err), Equals, PingNotFound)
+	c.Assert(errors.Is(err, ErrQueueNotFound), Equals, true)
+	c.Assert(err, ErrorMatches, `sqs: ping failed \(not found\): .*`)
+}
+
+func (s *S) TestPingFailures(c *C) {
+	code := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code></Error></ErrorResponse>`, code)
+	}))
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	ctx := context.Background()
+
+	for _, t := range []struct {
+		code   string
+		status PingStatus
+	}{
+		{ErrCodeInvalidClientTokenId, PingAuth},
+		{ErrCodeAccessDenied, PingAuth},
+		{ErrCodeRequestThrottled, PingThrottled},
+		{ErrCodeInvalidAction, PingUnknown},
+	} {
+		code = t.code
+		c.Check(pingStatus(sqs.Ping(ctx)), Equals, t.status, Commentf(t.code))
+	}
+
+	srv.Close()
+	c.Assert(pingStatus(sqs.Ping(ctx)), Equals, PingNetwork)
+}
@@ -0,0 +1,72 @@
+package sqs
+
+import (
+	"context"
+	"time"
+)
+
+// HeartbeatOpt configures a visibility heartbeat.
+type HeartbeatOpt struct {
+	// VisibilityTimeout, in seconds, is the timeout the message is given
+	// on every beat; it defaults to 60.
+	VisibilityTimeout int
+	// Interval is the time between beats; it defaults to a third of the
+	// VisibilityTimeout so that one failed beat is tolerated.
+	Interval time.Duration
+	// MaxDuration, if positive, stops the heartbeat after that long, so a
+	// stuck handler eventually lets the message be redelivered.
+	MaxDuration time.Duration
+	// OnError, if set, is called with ChangeMessageVisibility errors.
+	OnError func(err error)
+}
+
+// A Heartbeat keeps a message invisible while it is processed by
+// periodically extending its visibility timeout in the background.
+type Heartbeat struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartHeartbeat starts extending the visibility timeout of m, beginning
+// after the first interval, until Stop is called or opt.MaxDuration
+// elapses. opt may be nil.
+//
+// Stop the heartbeat before deleting or releasing m, or the next beat may
+// make it invisible again.
+func (q *Queue) StartHeartbeat(m *Message, opt *HeartbeatOpt) *Heartbeat {
+	var o HeartbeatOpt
+	if opt != nil {
+		o = *opt
+	}
+	if o.VisibilityTimeout <= 0 {
+		o.VisibilityTimeout = 60
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Duration(o.VisibilityTimeout) * time.Second / 3
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if o.MaxDuration > 0 {
+		ctx, cancel = context.WithTimeout(q.Context(), o.MaxDuration)
+	} else {
+		ctx, cancel = context.WithCancel(q.Context())
+	}
+	h := &Heartbeat{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(h.done)
+		q := q.WithContext(ctx)
+		for sleepContext(ctx, o.Interval) {
+			err := q.ChangeMessageVisibility(m, o.VisibilityTimeout)
+			if err != nil && ctx.Err() == nil && o.OnError != nil {
+				o.OnError(err)
+			}
+		}
+	}()
+	return h
+}
+
+// Stop stops the heartbeat and waits for an ongoing beat to be abandoned.
+func (h *Heartbeat) Stop() {
+	h.cancel()
+	<-h.done
+}
@@ -0,0 +1,43 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestHeartbeat(c *C) {
+	var beats int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.FormValue("Action"), Equals, "ChangeMessageVisibility")
+		c.Check(r.FormValue("VisibilityTimeout"), Equals, "30")
+		atomic.AddInt32(&beats, 1)
+		fmt.Fprint(w, "<ChangeMessageVisibilityResponse/>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	q := &Queue{SQS: sqs, path: "/123/q"}
+	m := &Message{ReceiptHandle: "rh"}
+
+	h := q.StartHeartbeat(m, &HeartbeatOpt{VisibilityTimeout: 30, Interval: 10 * time.Millisecond})
+	time.Sleep(55 * time.Millisecond)
+	h.Stop()
+	// Let a beat abandoned by Stop reach the server.
+	time.Sleep(10 * time.Millisecond)
+	n := atomic.LoadInt32(&beats)
+	c.Assert(n >= 3, Equals, true)
+	time.Sleep(30 * time.Millisecond)
+	c.Assert(atomic.LoadInt32(&beats), Equals, n)
+
+	// MaxDuration stops the heartbeat on its own.
+	atomic.StoreInt32(&beats, 0)
+	h = q.StartHeartbeat(m, &HeartbeatOpt{VisibilityTimeout: 30, Interval: 10 * time.Millisecond, MaxDuration: 25 * time.Millisecond})
+	time.Sleep(60 * time.Millisecond)
+	c.Assert(atomic.LoadInt32(&beats) <= 2, Equals, true)
+	h.Stop()
+}
@@ -0,0 +1,83 @@
+package sqs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// An IdempotentSender sends a message at most once per deduplication key,
+// the digest of its body and of a key chosen by the caller, such as the ID
+// of the request that caused it, to suppress the double submits of
+// retried application code. On FIFO queues the key is sent as the
+// MessageDeduplicationId, and SQS drops duplicates sent within five
+// minutes; on standard queues Guard remembers the keys sent.
+//
+// The zero value is ready to use once Queue is set.
+type IdempotentSender struct {
+	Queue QueueSender
+	// FIFO makes the sender rely on the deduplication of a FIFO queue. It
+	// is implied by Queue names ending in ".fifo".
+	FIFO bool
+	// Guard remembers the keys sent to standard queues; it defaults to a
+	// SendGuard of the sender's own, with its default window and size.
+	Guard *SendGuard
+
+	guard SendGuard
+}
+
+// DeduplicationKey returns the deduplication key of body and key: the hex
+// SHA-256 digest of both, usable as a MessageDeduplicationId.
+func DeduplicationKey(body, key string) string {
+	sum := deduplicationSum(body, key)
+	return hex.EncodeToString(sum[:])
+}
+
+func deduplicationSum(body, key string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(body))
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// fifo reports whether the queue of s deduplicates messages itself.
+func (s *IdempotentSender) fifo() bool {
+	if s.FIFO {
+		return true
+	}
+	q, ok := s.Queue.(interface{ Name() string })
+	return ok && strings.HasSuffix(q.Name(), ".fifo")
+}
+
+// Send sends body unless a message with the same body and key was sent
+// already. It returns the message ID and whether the message was sent; on
+// FIFO queues, where SQS decides, it always reports it sent. opt may be
+// nil; its MessageDeduplicationId is replaced.
+func (s *IdempotentSender) Send(body, key string, opt *SendMessageOpt) (string, bool, error) {
+	sum := deduplicationSum(body, key)
+	if s.fifo() {
+		o := SendMessageOpt{}
+		if opt != nil {
+			o = *opt
+		}
+		o.MessageDeduplicationId = hex.EncodeToString(sum[:])
+		id, err := s.Queue.SendMessageWithOpt(body, &o)
+		return id, err == nil, err
+	}
+	g := s.Guard
+	if g == nil {
+		g = &s.guard
+	}
+	if !g.allow(sum) {
+		return "", false, nil
+	}
+	id, err := s.Queue.SendMessageWithOpt(body, opt)
+	if err != nil {
+		g.forget(sum)
+		return "", false, err
+	}
+	return id, true, nil
+}
@@ -0,0 +1,38 @@
+package sqs
+
+import (
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestIdempotentSender(c *C) {
+	sqs := NewLocal().SQS()
+	q, err := sqs.CreateQueue("orders", nil)
+	c.Assert(err, IsNil)
+	sender := &IdempotentSender{Queue: q}
+	id, sent, err := sender.Send("order 1", "req-1", nil)
+	c.Assert(err, IsNil)
+	c.Assert(sent, Equals, true)
+	c.Assert(id, Not(Equals), "")
+	_, sent, err = sender.Send("order 1", "req-1", nil)
+	c.Assert(err, IsNil)
+	c.Assert(sent, Equals, false)
+	_, sent, err = sender.Send("order 1", "req-2", nil)
+	c.Assert(err, IsNil)
+	c.Assert(sent, Equals, true)
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Visible, Equals, 2)
+
+	fifo, err := sqs.CreateQueue("orders.fifo", &CreateQueueOpt{FifoQueue: true})
+	c.Assert(err, IsNil)
+	sender = &IdempotentSender{Queue: fifo}
+	_, sent, err = sender.Send("order 1", "req-1", &SendMessageOpt{MessageGroupId: "customer-1"})
+	c.Assert(err, IsNil)
+	c.Assert(sent, Equals, true)
+	msgs, err := fifo.ReceiveMessages(&ReceiveMessageOpt{AttributeNames: []Attribute{All}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].SystemAttributes.MessageDeduplicationId, Equals, DeduplicationKey("order 1", "req-1"))
+	c.Assert(msgs[0].SystemAttributes.MessageGroupId, Equals, "customer-1")
+	c.Assert(DeduplicationKey("order 1", "req-1"), Not(Equals), DeduplicationKey("order 1", "req-2"))
+}
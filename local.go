@@ -0,0 +1,721 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalAccountId is the account owning the queues of a Local.
+const LocalAccountId = "000000000000"
+
+// A Local is an in-process SQS service for unit tests and local
+// development. It is a Doer answering the requests of the client returned
+// by SQS, so queues, consumers and every other part of the package work
+// against it unchanged, without an HTTP emulator. It is also an
+// http.Handler, to serve other clients; see the sqstest package.
+//
+// Local simulates visibility timeouts, delays, retention and long polling.
+// It delivers messages in the order they were sent, and numbers message
+// IDs and receipt handles sequentially, so that runs are reproducible;
+// set Now to control the clock.
+type Local struct {
+	// Now returns the current time; it defaults to time.Now. Long polls
+	// still wait in real time.
+	Now func() time.Time
+	// URL is the base of the queue URLs, default "http://local.invalid".
+	// Set it to the URL of the server serving l as an http.Handler.
+	URL string
+
+	mu     sync.Mutex
+	queues map[string]*localQueue
+	seq    int
+}
+
+type localQueue struct {
+	name     string
+	attrs    map[string]string
+	created  time.Time
+	modified time.Time
+	msgs     []*localMessage
+	handles  map[string]*localMessage
+	// notify is closed and replaced whenever a message may have become
+	// available, to wake up long polls.
+	notify chan struct{}
+}
+
+type localMessage struct {
+	m         Message
+	visibleAt time.Time
+}
+
+var localDefaults = map[string]string{
+	string(VisibilityTimeout):             "30",
+	string(DelaySeconds):                  "0",
+	string(MaximumMessageSize):            "262144",
+	string(MessageRetentionPeriod):        "345600",
+	string(ReceiveMessageWaitTimeSeconds): "0",
+}
+
+// NewLocal returns a Local without queues.
+func NewLocal() *Local {
+	return &Local{queues: make(map[string]*localQueue)}
+}
+
+// SQS returns a client of l.
+func (l *Local) SQS() *SQS {
+	return &SQS{Endpoint: l.url(), Client: l}
+}
+
+func (l *Local) url() string {
+	if l.URL != "" {
+		return l.URL
+	}
+	return "http://local.invalid"
+}
+
+func (l *Local) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// Do implements Doer.
+func (l *Local) Do(req *http.Request) (*http.Response, error) {
+	params := req.URL.Query()
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if form, err := url.ParseQuery(string(b)); err == nil {
+			for k, v := range form {
+				params[k] = v
+			}
+		}
+	}
+	action := params.Get("Action")
+	path_ := req.URL.Path
+	if u, err := url.Parse(params.Get("QueueUrl")); err == nil && u.Path != "" {
+		// Clients may send queue requests to the service endpoint.
+		path_ = u.Path
+	}
+	var body interface{}
+	var err *EmbeddedError
+	if action == "ReceiveMessage" {
+		body, err = l.receive(req.Context(), path_, params)
+		if body == nil && err == nil {
+			return nil, req.Context().Err()
+		}
+	} else {
+		l.mu.Lock()
+		body, err = l.do(action, path_, params)
+		l.mu.Unlock()
+	}
+	if err != nil {
+		return xmlError(req, err.Code, err.Message), nil
+	}
+	b, merr := xml.Marshal(body)
+	if merr != nil {
+		return nil, merr
+	}
+	return xmlResponse(req, http.StatusOK, b), nil
+}
+
+// ServeHTTP implements http.Handler.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp, err := l.Do(r)
+	if err != nil {
+		// The client went away during a long poll.
+		return
+	}
+	defer resp.Body.Close()
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func localError(code, format string, args ...interface{}) *EmbeddedError {
+	return &EmbeddedError{Type: "Sender", Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+type localEmptyResponse struct {
+	XMLName xml.Name
+}
+
+func (l *Local) do(action, path_ string, params url.Values) (interface{}, *EmbeddedError) {
+	empty := &localEmptyResponse{XMLName: xml.Name{Local: action + "Response"}}
+	switch action {
+	case "CreateQueue":
+		return l.createQueue(params)
+	case "GetQueueUrl":
+		name := params.Get("QueueName")
+		if owner := params.Get("QueueOwnerAWSAccountId"); l.queues[name] == nil || owner != "" && owner != LocalAccountId {
+			return nil, localError(ErrCodeNonExistentQueue, "queue %s does not exist", name)
+		}
+		return &getQueueUrlResponse{QueueUrl: l.queueURL(name)}, nil
+	case "ListQueues":
+		return l.listQueues(params)
+	}
+
+	q := l.queues[path.Base(path_)]
+	if q == nil || path.Dir(path_) != "/"+LocalAccountId {
+		return nil, localError(ErrCodeNonExistentQueue, "queue %s does not exist", path_)
+	}
+	now := l.now()
+	q.expire(now)
+	switch action {
+	case "DeleteQueue":
+		delete(l.queues, q.name)
+		q.wake()
+		return empty, nil
+	case "PurgeQueue":
+		q.msgs = nil
+		q.handles = make(map[string]*localMessage)
+		return empty, nil
+	case "GetQueueAttributes":
+		return q.attributes(params, now), nil
+	case "SetQueueAttributes":
+		for name, v := range localAttributes(params) {
+			q.attrs[name] = v
+		}
+		q.modified = now
+		return empty, nil
+	case "SendMessage":
+		m, err := l.send(q, "", params, now)
+		if err != nil {
+			return nil, err
+		}
+		return &sendMessageResponse{Id: m.Id, MD5OfMessageBody: m.MD5OfBody}, nil
+	case "SendMessageBatch":
+		resp := &SendMessageBatchResult{}
+		for i := 1; params.Get(fmt.Sprintf("SendMessageBatchRequestEntry.%d.Id", i)) != ""; i++ {
+			prefix := fmt.Sprintf("SendMessageBatchRequestEntry.%d.", i)
+			id := params.Get(prefix + "Id")
+			m, err := l.send(q, prefix, params, now)
+			if err != nil {
+				resp.Failed = append(resp.Failed, BatchResultErrorEntry{Id: id, Code: err.Code, Message: err.Message, SenderFault: true})
+				continue
+			}
+			resp.Successful = append(resp.Successful, SendMessageBatchResultEntry{Id: id, MessageId: m.Id, MD5OfMessageBody: m.MD5OfBody})
+		}
+		return struct {
+			XMLName xml.Name `xml:"SendMessageBatchResponse"`
+			*SendMessageBatchResult
+		}{SendMessageBatchResult: resp}, nil
+	case "DeleteMessage":
+		if !q.delete(params.Get("ReceiptHandle")) {
+			return nil, localError(ErrCodeReceiptHandleIsInvalid, "invalid receipt handle")
+		}
+		return empty, nil
+	case "DeleteMessageBatch":
+		resp := &xmlDeleteBatchResponse{}
+		for i := 1; params.Get(fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.Id", i)) != ""; i++ {
+			prefix := fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.", i)
+			id := params.Get(prefix + "Id")
+			if q.delete(params.Get(prefix + "ReceiptHandle")) {
+				resp.Successful = append(resp.Successful, DeleteMessageBatchResultEntry{Id: id})
+			} else {
+				resp.Failed = append(resp.Failed, BatchResultErrorEntry{Id: id, Code: ErrCodeReceiptHandleIsInvalid, SenderFault: true})
+			}
+		}
+		return resp, nil
+	case "ChangeMessageVisibility":
+		lm := q.handles[params.Get("ReceiptHandle")]
+		if lm == nil {
+			return nil, localError(ErrCodeReceiptHandleIsInvalid, "invalid receipt handle")
+		}
+		timeout, err := strconv.Atoi(params.Get("VisibilityTimeout"))
+		if err != nil || timeout < 0 || timeout > 43200 {
+			return nil, localError(ErrCodeInvalidParameterValue, "invalid visibility timeout %q", params.Get("VisibilityTimeout"))
+		}
+		lm.visibleAt = now.Add(time.Duration(timeout) * time.Second)
+		q.wake()
+		return empty, nil
+	}
+	return nil, localError(ErrCodeInvalidAction, "action %s is not supported", action)
+}
+
+// listQueues lists the queues in name order. Without MaxResults it
+// returns the first 1000, like SQS; with it, pages continue after the
+// name encoded in NextToken.
+func (l *Local) listQueues(params url.Values) (interface{}, *EmbeddedError) {
+	max := 1000
+	if v := params.Get("MaxResults"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 1000 {
+			return nil, localError(ErrCodeInvalidParameterValue, "invalid MaxResults %s", v)
+		}
+		max = n
+	}
+	var after string
+	if token := params.Get("NextToken"); token != "" {
+		b, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			return nil, localError(ErrCodeInvalidParameterValue, "invalid NextToken")
+		}
+		after = string(b)
+	}
+	var names []string
+	for name := range l.queues {
+		if strings.HasPrefix(name, params.Get("QueueNamePrefix")) && name > after {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	resp := &listQueuesResponse{}
+	for i, name := range names {
+		if i == max {
+			if params.Get("MaxResults") != "" {
+				resp.NextToken = base64.RawURLEncoding.EncodeToString([]byte(names[i-1]))
+			}
+			break
+		}
+		resp.Queues = append(resp.Queues, l.queueURL(name))
+	}
+	return resp, nil
+}
+
+func (l *Local) queueURL(name string) string {
+	return l.url() + "/" + LocalAccountId + "/" + name
+}
+
+// localAttributes returns the Attribute.N.Name/Value pairs of params.
+func localAttributes(params url.Values) map[string]string {
+	attrs := make(map[string]string)
+	for i := 1; params.Get(fmt.Sprintf("Attribute.%d.Name", i)) != ""; i++ {
+		prefix := fmt.Sprintf("Attribute.%d.", i)
+		attrs[params.Get(prefix+"Name")] = params.Get(prefix + "Value")
+	}
+	return attrs
+}
+
+func (l *Local) createQueue(params url.Values) (interface{}, *EmbeddedError) {
+	name := params.Get("QueueName")
+	if name == "" || len(name) > 80 {
+		return nil, localError(ErrCodeInvalidParameterValue, "invalid queue name %q", name)
+	}
+	attrs := localAttributes(params)
+	if q := l.queues[name]; q != nil {
+		for k, v := range attrs {
+			if q.attrs[k] != v {
+				return nil, localError(ErrCodeQueueAlreadyExists, "queue %s exists with different attributes", name)
+			}
+		}
+	} else {
+		now := l.now()
+		q = &localQueue{
+			name:     name,
+			attrs:    make(map[string]string),
+			created:  now,
+			modified: now,
+			handles:  make(map[string]*localMessage),
+			notify:   make(chan struct{}),
+		}
+		for k, v := range localDefaults {
+			q.attrs[k] = v
+		}
+		for k, v := range attrs {
+			q.attrs[k] = v
+		}
+		l.queues[name] = q
+	}
+	return &createQueuesResponse{QueueUrl: l.queueURL(name)}, nil
+}
+
+// send enqueues the message described by the parameters starting with
+// prefix.
+func (l *Local) send(q *localQueue, prefix string, params url.Values, now time.Time) (*Message, *EmbeddedError) {
+	body := params.Get(prefix + "MessageBody")
+	if body == "" {
+		return nil, localError(ErrCodeMissingParameter, "the request must contain the parameter MessageBody")
+	}
+	if max := q.int(MaximumMessageSize); len(body) > max {
+		return nil, localError(ErrCodeInvalidParameterValue, "message must be shorter than %d bytes", max)
+	}
+	delay := q.int(DelaySeconds)
+	if v := params.Get(prefix + "DelaySeconds"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 0 || d > MaxDelaySeconds {
+			return nil, localError(ErrCodeInvalidParameterValue, "invalid delay %q", v)
+		}
+		delay = d
+	}
+	attrs := make(MessageAttributes)
+	for i := 1; params.Get(fmt.Sprintf("%sMessageAttribute.%d.Name", prefix, i)) != ""; i++ {
+		p := fmt.Sprintf("%sMessageAttribute.%d.", prefix, i)
+		v := MessageAttributeValue{DataType: params.Get(p + "Value.DataType"), StringValue: params.Get(p + "Value.StringValue")}
+		if b := params.Get(p + "Value.BinaryValue"); b != "" {
+			var err error
+			if v.BinaryValue, err = base64.StdEncoding.DecodeString(b); err != nil {
+				return nil, localError(ErrCodeInvalidParameterValue, "invalid binary value of attribute %s", params.Get(p+"Name"))
+			}
+		}
+		attrs[params.Get(p+"Name")] = v
+	}
+
+	var traceHeader string
+	for i := 1; params.Get(fmt.Sprintf("%sMessageSystemAttribute.%d.Name", prefix, i)) != ""; i++ {
+		p := fmt.Sprintf("%sMessageSystemAttribute.%d.", prefix, i)
+		if name := params.Get(p + "Name"); name != string(AWSTraceHeader) {
+			return nil, localError(ErrCodeInvalidParameterValue, "invalid message system attribute %s", name)
+		}
+		traceHeader = params.Get(p + "Value.StringValue")
+	}
+
+	l.seq++
+	sum := md5.Sum([]byte(body))
+	lm := &localMessage{
+		m: Message{
+			Id:                fmt.Sprintf("00000000-0000-0000-0000-%012d", l.seq),
+			Body:              body,
+			MD5OfBody:         hex.EncodeToString(sum[:]),
+			MessageAttributes: attrs,
+			SystemAttributes: SystemAttributes{
+				SentTimestamp:  now,
+				SenderId:       LocalAccountId,
+				MessageGroupId: params.Get(prefix + "MessageGroupId"),
+
+				MessageDeduplicationId: params.Get(prefix + "MessageDeduplicationId"),
+				AWSTraceHeader:         traceHeader,
+			},
+		},
+		visibleAt: now.Add(time.Duration(delay) * time.Second),
+	}
+	q.msgs = append(q.msgs, lm)
+	q.wake()
+	return &lm.m, nil
+}
+
+// receive answers a ReceiveMessage request, long polling until a message
+// is available, the wait time elapsed or ctx is done. It returns a nil
+// response and error in the latter case.
+func (l *Local) receive(ctx context.Context, path_ string, params url.Values) (interface{}, *EmbeddedError) {
+	max := 1
+	if v := params.Get("MaxNumberOfMessages"); v != "" {
+		var err error
+		if max, err = strconv.Atoi(v); err != nil || max < 1 || max > 10 {
+			return nil, localError(ErrCodeInvalidParameterValue, "invalid MaxNumberOfMessages %q", v)
+		}
+	}
+	l.mu.Lock()
+	q := l.queues[path.Base(path_)]
+	if q == nil {
+		l.mu.Unlock()
+		return nil, localError(ErrCodeNonExistentQueue, "queue %s does not exist", path_)
+	}
+	wait := q.int(ReceiveMessageWaitTimeSeconds)
+	timeout := q.int(VisibilityTimeout)
+	l.mu.Unlock()
+	if v := params.Get("WaitTimeSeconds"); v != "" {
+		wait, _ = strconv.Atoi(v)
+	}
+	if v := params.Get("VisibilityTimeout"); v != "" {
+		timeout, _ = strconv.Atoi(v)
+	}
+	deadline := time.NewTimer(time.Duration(wait) * time.Second)
+	defer deadline.Stop()
+
+	for {
+		l.mu.Lock()
+		if l.queues[q.name] != q {
+			l.mu.Unlock()
+			return nil, localError(ErrCodeNonExistentQueue, "queue %s does not exist", path_)
+		}
+		now := l.now()
+		q.expire(now)
+		resp := &xmlReceiveResponse{}
+		next := time.Time{}
+		for _, lm := range q.msgs {
+			if len(resp.Messages) == max {
+				break
+			}
+			if now.Before(lm.visibleAt) {
+				if next.IsZero() || lm.visibleAt.Before(next) {
+					next = lm.visibleAt
+				}
+				continue
+			}
+			l.seq++
+			lm.m.ReceiptHandle = fmt.Sprintf("%s#%d", lm.m.Id, l.seq)
+			lm.m.SystemAttributes.ApproximateReceiveCount++
+			if lm.m.SystemAttributes.ApproximateFirstReceiveTimestamp.IsZero() {
+				lm.m.SystemAttributes.ApproximateFirstReceiveTimestamp = now
+			}
+			lm.visibleAt = now.Add(time.Duration(timeout) * time.Second)
+			q.handles[lm.m.ReceiptHandle] = lm
+			m := lm.m
+			m.MessageAttributes = filterMessageAttributes(m.MessageAttributes, params)
+			resp.Messages = append(resp.Messages, toXMLMessage(&m))
+		}
+		notify := q.notify
+		l.mu.Unlock()
+		if len(resp.Messages) > 0 || wait <= 0 {
+			return resp, nil
+		}
+
+		d := time.Duration(wait) * time.Second
+		if !next.IsZero() && next.Sub(now) < d {
+			d = next.Sub(now)
+		}
+		visible := time.NewTimer(d)
+		select {
+		case <-notify:
+		case <-visible.C:
+		case <-deadline.C:
+			visible.Stop()
+			return resp, nil
+		case <-ctx.Done():
+			visible.Stop()
+			return nil, nil
+		}
+		visible.Stop()
+	}
+}
+
+// filterMessageAttributes returns the attributes named by the
+// MessageAttributeName.N parameters.
+func filterMessageAttributes(attrs MessageAttributes, params url.Values) MessageAttributes {
+	filtered := make(MessageAttributes)
+	for i := 1; params.Get(fmt.Sprintf("MessageAttributeName.%d", i)) != ""; i++ {
+		name := params.Get(fmt.Sprintf("MessageAttributeName.%d", i))
+		for k, v := range attrs {
+			if name == "All" || name == ".*" || k == name ||
+				strings.HasSuffix(name, ".*") && strings.HasPrefix(k, strings.TrimSuffix(name, "*")) {
+				filtered[k] = v
+			}
+		}
+	}
+	return filtered
+}
+
+func (q *localQueue) int(name Attribute) int {
+	n, _ := strconv.Atoi(q.attrs[string(name)])
+	return n
+}
+
+// wake wakes up the long polls of q.
+func (q *localQueue) wake() {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// expire drops the messages older than the retention period.
+func (q *localQueue) expire(now time.Time) {
+	retention := time.Duration(q.int(MessageRetentionPeriod)) * time.Second
+	kept := q.msgs[:0]
+	for _, lm := range q.msgs {
+		if now.Sub(lm.m.SystemAttributes.SentTimestamp) < retention {
+			kept = append(kept, lm)
+		} else {
+			q.forget(lm)
+		}
+	}
+	q.msgs = kept
+}
+
+// delete deletes the message received with handle. Deleting a deleted
+// message succeeds, as it does with SQS.
+func (q *localQueue) delete(handle string) bool {
+	lm, ok := q.handles[handle]
+	if !ok {
+		return false
+	}
+	if lm == nil {
+		return true
+	}
+	for i, m := range q.msgs {
+		if m == lm {
+			q.msgs = append(q.msgs[:i], q.msgs[i+1:]...)
+			break
+		}
+	}
+	q.forget(lm)
+	return true
+}
+
+// forget keeps the handles of a removed message valid but unbound.
+func (q *localQueue) forget(lm *localMessage) {
+	for h, m := range q.handles {
+		if m == lm {
+			q.handles[h] = nil
+		}
+	}
+}
+
+func (q *localQueue) attributes(params url.Values, now time.Time) *QueueAttributes {
+	all := make(map[string]string)
+	for k, v := range q.attrs {
+		all[k] = v
+	}
+	visible, hidden, delayed := 0, 0, 0
+	for _, lm := range q.msgs {
+		switch {
+		case !now.Before(lm.visibleAt):
+			visible++
+		case lm.m.SystemAttributes.ApproximateReceiveCount > 0:
+			hidden++
+		default:
+			delayed++
+		}
+	}
+	all[string(ApproximateNumberOfMessages)] = strconv.Itoa(visible)
+	all[string(ApproximateNumberOfMessagesNotVisible)] = strconv.Itoa(hidden)
+	all[string(ApproximateNumberOfMessagesDelayed)] = strconv.Itoa(delayed)
+	all[string(CreatedTimestamp)] = strconv.FormatInt(q.created.Unix(), 10)
+	all[string(LastModifiedTimestamp)] = strconv.FormatInt(q.modified.Unix(), 10)
+	all[string(QueueArn)] = "arn:aws:sqs:local:" + LocalAccountId + ":" + q.name
+
+	names := make(map[string]bool)
+	for i := 1; params.Get(fmt.Sprintf("AttributeName.%d", i)) != ""; i++ {
+		names[params.Get(fmt.Sprintf("AttributeName.%d", i))] = true
+	}
+	resp := &QueueAttributes{}
+	for _, name := range sortedKeys(all) {
+		if names[string(All)] || names[name] {
+			resp.Attributes = append(resp.Attributes, struct {
+				Name  string
+				Value string
+			}{name, all[name]})
+		}
+	}
+	return resp
+}
+
+type xmlAttribute struct {
+	Name  string
+	Value string
+}
+
+type xmlMessageAttribute struct {
+	Name  string
+	Value struct {
+		DataType    string
+		StringValue string `xml:",omitempty"`
+		BinaryValue string `xml:",omitempty"`
+	}
+}
+
+type xmlMessage struct {
+	MessageId        string
+	ReceiptHandle    string
+	MD5OfBody        string
+	Body             string
+	Attribute        []xmlAttribute
+	MessageAttribute []xmlMessageAttribute
+}
+
+type xmlReceiveResponse struct {
+	XMLName  xml.Name     `xml:"ReceiveMessageResponse"`
+	Messages []xmlMessage `xml:"ReceiveMessageResult>Message"`
+}
+
+type xmlDeleteBatchResponse struct {
+	XMLName    xml.Name                        `xml:"DeleteMessageBatchResponse"`
+	Successful []DeleteMessageBatchResultEntry `xml:"DeleteMessageBatchResult>DeleteMessageBatchResultEntry"`
+	Failed     []BatchResultErrorEntry         `xml:"DeleteMessageBatchResult>BatchResultErrorEntry"`
+}
+
+// toXMLMessage returns m as encoded in ReceiveMessage responses.
+func toXMLMessage(m *Message) xmlMessage {
+	sum := md5.Sum([]byte(m.Body))
+	x := xmlMessage{
+		MessageId:     m.Id,
+		ReceiptHandle: m.ReceiptHandle,
+		MD5OfBody:     hex.EncodeToString(sum[:]),
+		Body:          m.Body,
+	}
+	attrs := make(map[string]string)
+	for name, v := range m.SystemAttributes.Raw {
+		attrs[name] = v
+	}
+	a := m.SystemAttributes
+	attrs[string(ApproximateReceiveCount)] = strconv.Itoa(a.ApproximateReceiveCount)
+	if !a.SentTimestamp.IsZero() {
+		attrs[string(SentTimestamp)] = strconv.FormatInt(a.SentTimestamp.UnixNano()/int64(time.Millisecond), 10)
+	}
+	if !a.ApproximateFirstReceiveTimestamp.IsZero() {
+		attrs[string(ApproximateFirstReceiveTimestamp)] = strconv.FormatInt(a.ApproximateFirstReceiveTimestamp.UnixNano()/int64(time.Millisecond), 10)
+	}
+	if a.SenderId != "" {
+		attrs[string(SenderId)] = a.SenderId
+	}
+	if a.MessageGroupId != "" {
+		attrs[string(MessageGroupId)] = a.MessageGroupId
+	}
+	if a.MessageDeduplicationId != "" {
+		attrs[string(MessageDeduplicationId)] = a.MessageDeduplicationId
+	}
+	if a.AWSTraceHeader != "" {
+		attrs[string(AWSTraceHeader)] = a.AWSTraceHeader
+	}
+	for _, name := range sortedKeys(attrs) {
+		x.Attribute = append(x.Attribute, xmlAttribute{name, attrs[name]})
+	}
+	names := make([]string, 0, len(m.MessageAttributes))
+	for name := range m.MessageAttributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := m.MessageAttributes[name]
+		a := xmlMessageAttribute{Name: name}
+		a.Value.DataType = v.DataType
+		if v.BinaryValue != nil {
+			a.Value.BinaryValue = base64.StdEncoding.EncodeToString(v.BinaryValue)
+		} else {
+			a.Value.StringValue = v.StringValue
+		}
+		x.MessageAttribute = append(x.MessageAttribute, a)
+	}
+	return x
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// xmlError returns a 400 response carrying an SQS error.
+func xmlError(req *http.Request, code, message string) *http.Response {
+	b, _ := xml.Marshal(struct {
+		XMLName xml.Name      `xml:"ErrorResponse"`
+		Error   EmbeddedError `xml:"Error"`
+	}{Error: EmbeddedError{Type: "Sender", Code: code, Message: message}})
+	return xmlResponse(req, http.StatusBadRequest, b)
+}
+
+func xmlResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/xml"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
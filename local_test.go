@@ -0,0 +1,283 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestLocal(c *C) {
+	now := time.Unix(1500000000, 0)
+	l := NewLocal()
+	l.Now = func() time.Time { return now }
+	sqs := l.SQS()
+
+	q, err := sqs.CreateQueue("jobs", &CreateQueueOpt{VisibilityTimeout: 10})
+	c.Assert(err, IsNil)
+	c.Assert(q.URL(), Equals, "http://local.invalid/000000000000/jobs")
+	_, err = sqs.CreateQueue("jobs", &CreateQueueOpt{VisibilityTimeout: 20})
+	c.Assert(err, ErrorMatches, ".*QueueAlreadyExists.*")
+	_, err = sqs.Queue("missing")
+	c.Assert(errors.Is(err, ErrQueueNotFound), Equals, true)
+
+	id, err := q.SendMessageWithOpt("first", &SendMessageOpt{
+		MessageAttributes: MessageAttributes{"kind": {DataType: "String", StringValue: "a"}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, "00000000-0000-0000-0000-000000000001")
+	_, err = q.SendMessageWithOpt("later", &SendMessageOpt{DelaySeconds: 5})
+	c.Assert(err, IsNil)
+
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10, MessageAttributeNames: []string{"All"}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "first")
+	kind, _ := msgs[0].GetString("kind")
+	c.Assert(kind, Equals, "a")
+
+	attrs, err := q.GetQueueAttributes(All)
+	c.Assert(err, IsNil)
+	c.Assert(attrs.ApproximateNumberOfMessages(), Equals, 0)
+	c.Assert(attrs.ApproximateNumberOfMessagesNotVisible(), Equals, 1)
+	c.Assert(attrs.VisibilityTimeout(), Equals, 10*time.Second)
+
+	now = now.Add(5 * time.Second)
+	msgs, err = q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "later")
+	c.Assert(q.DeleteMessage(msgs[0]), IsNil)
+
+	now = now.Add(5 * time.Second)
+	msgs, err = q.ReceiveMessages(&ReceiveMessageOpt{AttributeNames: []Attribute{All}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "first")
+	c.Assert(msgs[0].SystemAttributes.ApproximateReceiveCount, Equals, 2)
+	c.Assert(msgs[0].SystemAttributes.SentTimestamp.Equal(time.Unix(1500000000, 0)), Equals, true)
+	c.Assert(msgs[0].MessageAttributes, HasLen, 0)
+
+	c.Assert(q.ChangeMessageVisibility(msgs[0], 0), IsNil)
+	res, err := q.DeleteMessageBatch(msgs)
+	c.Assert(err, IsNil)
+	c.Assert(res.Failed, HasLen, 0)
+	c.Assert(q.DeleteMessage(&Message{ReceiptHandle: "bogus"}), ErrorMatches, ".*ReceiptHandleIsInvalid.*")
+
+	queues, err := sqs.ListQueues("")
+	c.Assert(err, IsNil)
+	c.Assert(queues, HasLen, 1)
+	c.Assert(q.DeleteQueue(), IsNil)
+	_, err = q.ReceiveMessages(nil)
+	c.Assert(errors.Is(err, ErrQueueNotFound), Equals, true)
+}
+
+func (s *S) TestListQueuesPages(c *C) {
+	sqs := NewLocal().SQS()
+	for _, name := range []string{"a1", "a2", "a3", "a4", "a5", "b1"} {
+		_, err := sqs.CreateQueue(name, nil)
+		c.Assert(err, IsNil)
+	}
+
+	page, err := sqs.ListQueuesPage(&ListQueuesOpt{NamePrefix: "a", MaxResults: 2})
+	c.Assert(err, IsNil)
+	c.Assert(page.Queues, HasLen, 2)
+	c.Assert(page.Queues[1].Name(), Equals, "a2")
+	c.Assert(page.NextToken, Not(Equals), "")
+	page, err = sqs.ListQueuesPage(&ListQueuesOpt{NamePrefix: "a", MaxResults: 2, NextToken: page.NextToken})
+	c.Assert(err, IsNil)
+	c.Assert(page.Queues[0].Name(), Equals, "a3")
+	_, err = sqs.ListQueuesPage(&ListQueuesOpt{MaxResults: 1001})
+	c.Assert(err, ErrorMatches, ".*InvalidParameterValue.*")
+
+	it := sqs.IterQueues("a")
+	it.opt.MaxResults = 2
+	var names []string
+	for it.Next() {
+		names = append(names, it.Queue().Name())
+	}
+	c.Assert(it.Err(), IsNil)
+	c.Assert(names, DeepEquals, []string{"a1", "a2", "a3", "a4", "a5"})
+
+	queues, err := sqs.ListQueuesAll("")
+	c.Assert(err, IsNil)
+	c.Assert(queues, HasLen, 6)
+
+	urls, err := sqs.ListQueueUrls("a")
+	c.Assert(err, IsNil)
+	c.Assert(urls, HasLen, 5)
+	c.Assert(QueueUrlName(urls[4]), Equals, "a5")
+}
+
+func (s *S) TestLocalListQueuesPages(c *C) {
+	sqs := NewLocal().SQS()
+	for i := 0; i < 1500; i++ {
+		_, err := sqs.CreateQueue(fmt.Sprintf("q%04d", i), nil)
+		c.Assert(err, IsNil)
+	}
+	var sizes []int
+	err := sqs.ListQueuesPages("q", func(urls []string) bool {
+		sizes = append(sizes, len(urls))
+		return true
+	})
+	c.Assert(err, IsNil)
+	c.Assert(sizes, DeepEquals, []int{1000, 500})
+
+	// Stopping early.
+	sizes = nil
+	err = sqs.ListQueuesPages("", func(urls []string) bool {
+		sizes = append(sizes, len(urls))
+		c.Assert(QueueUrlName(urls[0]), Equals, "q0000")
+		return false
+	})
+	c.Assert(err, IsNil)
+	c.Assert(sizes, DeepEquals, []int{1000})
+}
+
+func (s *S) TestLocalLongPoll(c *C) {
+	l := NewLocal()
+	q, err := l.SQS().CreateQueue("poll", nil)
+	c.Assert(err, IsNil)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.SendMessage("hello")
+	}()
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{WaitTimeSeconds: 5})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "hello")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = q.WithContext(ctx).ReceiveMessages(&ReceiveMessageOpt{WaitTimeSeconds: 5})
+	c.Assert(err, NotNil)
+	c.Assert(time.Since(start) < time.Second, Equals, true)
+}
+
+func (s *S) TestLocalConsumer(c *C) {
+	l := NewLocal()
+	q, err := l.SQS().CreateQueue("work", nil)
+	c.Assert(err, IsNil)
+	for _, body := range []string{"a", "b", "c"} {
+		_, err := q.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+	handled := make(chan string, 3)
+	consumer := &Consumer{
+		Queue: q,
+		Handler: HandlerFunc(func(m *Message) error {
+			handled <- m.Body
+			return nil
+		}),
+	}
+	consumer.Start()
+	var got []string
+	for i := 0; i < 3; i++ {
+		got = append(got, <-handled)
+	}
+	consumer.Stop()
+	c.Assert(got, HasLen, 3)
+	attrs, err := q.GetQueueAttributes(ApproximateNumberOfMessages, ApproximateNumberOfMessagesNotVisible)
+	c.Assert(err, IsNil)
+	c.Assert(attrs.ApproximateNumberOfMessages()+attrs.ApproximateNumberOfMessagesNotVisible(), Equals, 0)
+}
+
+// requestParams returns the parameters of req, in its URL or its body,
+// leaving the body to be read again.
+func requestParams(c *C, req *http.Request) url.Values {
+	params := req.URL.Query()
+	if req.Body == nil {
+		return params
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	form, err := url.ParseQuery(string(b))
+	c.Assert(err, IsNil)
+	for k, v := range form {
+		params[k] = v
+	}
+	return params
+}
+
+func (s *S) TestQueueUrlParameter(c *C) {
+	l := NewLocal()
+	sqs := l.SQS()
+	var params url.Values
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		params = requestParams(c, req)
+		return l.Do(req)
+	})
+	q, err := sqs.CreateQueue("addressed", nil)
+	c.Assert(err, IsNil)
+	c.Assert(params.Get("Version"), Equals, "2012-11-05")
+	c.Assert(params.Get("QueueUrl"), Equals, "")
+	_, err = q.SendMessage("hello")
+	c.Assert(err, IsNil)
+	c.Assert(params.Get("QueueUrl"), Equals, q.URL())
+
+	// Requests to the service endpoint are routed by their QueueUrl.
+	req, err := http.NewRequest("GET", "http://local.invalid/?Action=ReceiveMessage&QueueUrl="+url.QueryEscape(q.URL()), nil)
+	c.Assert(err, IsNil)
+	resp, err := l.Do(req)
+	c.Assert(err, IsNil)
+	c.Assert(resp.StatusCode, Equals, 200)
+	body, _ := ioutil.ReadAll(resp.Body)
+	c.Assert(string(body), Matches, "(.|\n)*hello(.|\n)*")
+}
+
+func (s *S) TestQueueOwnedByAndUrl(c *C) {
+	sqs := NewLocal().SQS()
+	q, err := sqs.CreateQueue("shared", nil)
+	c.Assert(err, IsNil)
+
+	owned, err := sqs.QueueOwnedBy("shared", LocalAccountId)
+	c.Assert(err, IsNil)
+	c.Assert(owned.URL(), Equals, q.URL())
+	_, err = sqs.QueueOwnedBy("shared", "111111111111")
+	c.Assert(IsNonExistentQueue(err), Equals, true)
+
+	byUrl, err := sqs.QueueByUrl("https://sqs.us-east-1.amazonaws.com/000000000000/shared")
+	c.Assert(err, IsNil)
+	c.Assert(byUrl.URL(), Equals, q.URL())
+	c.Assert(byUrl.AccountId(), Equals, LocalAccountId)
+	_, err = byUrl.SendMessage("hello")
+	c.Assert(err, IsNil)
+	byName, err := sqs.QueueByName(LocalAccountId, "shared")
+	c.Assert(err, IsNil)
+	c.Assert(byName.URL(), Equals, q.URL())
+	_, err = sqs.QueueByName("", "shared")
+	c.Assert(err, ErrorMatches, `sqs: invalid queue "shared" of account ""`)
+	for _, bad := range []string{"http://local.invalid/shared", "http://local.invalid//shared", "http://local.invalid/1/2/3", "%"} {
+		_, err = sqs.QueueByUrl(bad)
+		c.Check(err, ErrorMatches, "sqs: invalid queue URL .*")
+	}
+}
+
+func (s *S) TestQueueStats(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("stats", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessageWithOpt("later", &SendMessageOpt{DelaySeconds: 60})
+	c.Assert(err, IsNil)
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st, Equals, QueueStats{Delayed: 1})
+	c.Assert(st.Empty(), Equals, false)
+
+	_, err = q.SendMessage("now")
+	c.Assert(err, IsNil)
+	_, err = q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	st, err = q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st, Equals, QueueStats{InFlight: 1, Delayed: 1})
+	c.Assert(st.Pending(), Equals, 2)
+}
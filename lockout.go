@@ -0,0 +1,107 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// PurgeLockout is how long SQS refuses to purge a queue again after a
+// purge, and DeletedQueueLockout how long it refuses to create a queue
+// with the name of a deleted one.
+const (
+	PurgeLockout        = 60 * time.Second
+	DeletedQueueLockout = 60 * time.Second
+)
+
+var (
+	// ErrPurgeInProgress is matched, with errors.Is, by the errors of
+	// PurgeQueue during the PurgeLockout of a queue.
+	ErrPurgeInProgress = errors.New("sqs: purge in progress")
+	// ErrQueueDeletedRecently is matched by the errors of CreateQueue
+	// during the DeletedQueueLockout of a queue name.
+	ErrQueueDeletedRecently = errors.New("sqs: queue deleted recently")
+)
+
+// lockoutPoll is how often an operation is retried during a lockout whose
+// end is unknown, because it was started by another client.
+var lockoutPoll = 5 * time.Second
+
+// lockouts tracks the purges and queue deletions made by a client, so that
+// it knows when their lockouts end.
+type lockouts struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func (l *lockouts) start(key string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.until == nil {
+		l.until = make(map[string]time.Time)
+	}
+	l.until[key] = time.Now().Add(d)
+}
+
+// remaining returns how long the lockout of key lasts, if known.
+func (l *lockouts) remaining(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until, ok := l.until[key]
+	if !ok {
+		return 0
+	}
+	d := time.Until(until)
+	if d <= 0 {
+		delete(l.until, key)
+		return 0
+	}
+	return d
+}
+
+// awaitLockout waits out the lockout of key started by the client, if it
+// ends within LockoutWait.
+func (sqs *SQS) awaitLockout(ctx context.Context, key string) error {
+	if d := sqs.lockouts.remaining(key); d > 0 && d < sqs.LockoutWait {
+		if !sleepContext(ctx, d) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// awaitPurge waits out a purge of q started by the client, if it ends
+// within LockoutWait: the purge may delete the messages sent to q, or
+// received from it, until then.
+func (q *Queue) awaitPurge(ctx context.Context) error {
+	return q.SQS.awaitLockout(ctx, q.purgeKey())
+}
+
+// purgeKey is the key of the purge lockout of q.
+func (q *Queue) purgeKey() string {
+	return "purge:" + q.path
+}
+
+// waitLockout runs do, waiting out the lockout of key for up to
+// LockoutWait: before the first attempt if the lockout is known, and
+// between attempts failing with target otherwise.
+func (sqs *SQS) waitLockout(ctx context.Context, key string, target error, do func() error) error {
+	deadline := time.Now().Add(sqs.LockoutWait)
+	if err := sqs.awaitLockout(ctx, key); err != nil {
+		return err
+	}
+	for {
+		err := do()
+		if !errors.Is(err, target) {
+			return err
+		}
+		d := sqs.lockouts.remaining(key)
+		if d <= 0 {
+			d = lockoutPoll
+		}
+		if time.Now().Add(d).After(deadline) || !sleepContext(ctx, d) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestLockoutWait(c *C) {
+	defer func(d time.Duration) { lockoutPoll = d }(lockoutPoll)
+	lockoutPoll = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	refusals := map[string]int{"PurgeQueue": 2, "CreateQueue": 1}
+	codes := map[string]string{
+		"PurgeQueue":  "AWS.SimpleQueueService.PurgeQueueInProgress",
+		"CreateQueue": "AWS.SimpleQueueService.QueueDeletedRecently",
+	}
+	calls := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		action := r.FormValue("Action")
+		calls[action]++
+		if refusals[action] > 0 {
+			refusals[action]--
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "<ErrorResponse><Error><Code>%s</Code></Error></ErrorResponse>", codes[action])
+			return
+		}
+		fmt.Fprintf(w, "<%sResponse><CreateQueueResult><QueueUrl>http://sqs/123/q</QueueUrl></CreateQueueResult></%[1]sResponse>", action)
+	}))
+	defer srv.Close()
+
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	err := q.PurgeQueue()
+	c.Assert(errors.Is(err, ErrPurgeInProgress), Equals, true)
+
+	sqs.LockoutWait = time.Second
+	c.Assert(q.PurgeQueue(), IsNil)
+	c.Assert(calls["PurgeQueue"], Equals, 3)
+	_, err = sqs.CreateQueue("q", nil)
+	c.Assert(err, IsNil)
+	c.Assert(calls["CreateQueue"], Equals, 2)
+
+	// A purge made by the client is waited out without asking SQS.
+	sqs.lockouts.start("purge:/123/q", 20*time.Millisecond)
+	start := time.Now()
+	c.Assert(q.PurgeQueue(), IsNil)
+	c.Assert(time.Since(start) >= 20*time.Millisecond, Equals, true)
+	c.Assert(calls["PurgeQueue"], Equals, 4)
+}
+
+func (s *S) TestLockoutAwareTools(c *C) {
+	sqs := NewLocal().SQS()
+	sqs.LockoutWait = time.Second
+	src, err := sqs.CreateQueue("src", nil)
+	c.Assert(err, IsNil)
+	dst, err := sqs.CreateQueue("dst", nil)
+	c.Assert(err, IsNil)
+	for _, body := range []string{"one", "two"} {
+		_, err := src.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+	const lockout = 20 * time.Millisecond
+	ctx := context.Background()
+
+	// Messages are not moved into a queue the client is purging.
+	m, err := src.ReceiveMessage()
+	c.Assert(err, IsNil)
+	sqs.lockouts.start(dst.purgeKey(), lockout)
+	start := time.Now()
+	_, err = src.MoveMessage(m, dst, nil)
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(start) >= lockout, Equals, true)
+
+	sqs.lockouts.start(dst.purgeKey(), lockout)
+	start = time.Now()
+	res, err := src.Redrive(ctx, dst, nil)
+	c.Assert(err, IsNil)
+	c.Assert(res.Moved, Equals, 1)
+	c.Assert(time.Since(start) >= lockout, Equals, true)
+
+	// Nor drained from one.
+	sqs.lockouts.start(dst.purgeKey(), lockout)
+	start = time.Now()
+	var drained int
+	err = dst.Drain(ctx, func(msgs []Message) error {
+		drained += len(msgs)
+		return nil
+	}, &DrainOpt{UntilEmpty: true, ReceiveOpt: &ReceiveMessageOpt{WaitTimeSeconds: 1}})
+	c.Assert(err, IsNil)
+	c.Assert(drained, Equals, 2)
+	c.Assert(time.Since(start) >= lockout, Equals, true)
+
+	// Lockouts ending after LockoutWait are not waited out.
+	sqs.lockouts.start(dst.purgeKey(), time.Minute)
+	start = time.Now()
+	_, err = dst.SendMessage("three")
+	c.Assert(err, IsNil)
+	m, err = dst.ReceiveMessage()
+	c.Assert(err, IsNil)
+	_, err = dst.MoveMessage(m, src, nil)
+	c.Assert(err, IsNil)
+	c.Assert(time.Since(start) < time.Second, Equals, true)
+}
@@ -0,0 +1,78 @@
+package sqs
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// A LogEntry describes one HTTP request made by an SQS client.
+type LogEntry struct {
+	Action   string
+	Path     string
+	Duration time.Duration
+	// StatusCode is zero when no response was received.
+	StatusCode int
+	// RequestId is the ID SQS assigned to the request, to be quoted to AWS
+	// support.
+	RequestId string
+	Err       error
+}
+
+// String formats e as key=value pairs.
+func (e LogEntry) String() string {
+	s := fmt.Sprintf("action=%s path=%s duration=%s status=%d request_id=%s",
+		e.Action, e.Path, e.Duration, e.StatusCode, e.RequestId)
+	if e.Err != nil {
+		s += fmt.Sprintf(" error=%q", e.Err)
+	}
+	return s
+}
+
+// A Logger receives a debug-level entry for every request of an SQS client.
+// Entries carry no message bodies or credentials, so they are safe to log
+// in production.
+type Logger interface {
+	Debug(e LogEntry)
+}
+
+// The LoggerFunc type is an adapter to allow the use of an ordinary
+// function as a Logger.
+type LoggerFunc func(e LogEntry)
+
+// Debug calls f(e).
+func (f LoggerFunc) Debug(e LogEntry) {
+	f(e)
+}
+
+// StdLogger returns a Logger printing entries to l, or to the standard
+// logger if l is nil.
+func StdLogger(l *log.Logger) Logger {
+	return LoggerFunc(func(e LogEntry) {
+		if l == nil {
+			log.Print("sqs: ", e)
+		} else {
+			l.Print("sqs: ", e)
+		}
+	})
+}
+
+// requestId returns the ID of a request, as told by the response headers
+// or, failing them, by the error or the body of the response.
+func requestId(h http.Header, body []byte, err error) string {
+	if id := h.Get("X-Amzn-Requestid"); id != "" {
+		return id
+	}
+	var e *ErrorResponse
+	if errors.As(err, &e) {
+		return e.RequestId
+	}
+	var m struct {
+		RequestId string `xml:"ResponseMetadata>RequestId"`
+	}
+	xml.Unmarshal(body, &m)
+	return m.RequestId
+}
@@ -0,0 +1,50 @@
+package sqs
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestLogger(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("Action") {
+		case "DeleteMessage":
+			fmt.Fprint(w, "<DeleteMessageResponse><ResponseMetadata><RequestId>req-1</RequestId></ResponseMetadata></DeleteMessageResponse>")
+		default:
+			w.Header().Set("X-Amzn-RequestId", "req-2")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "<ErrorResponse><Error><Code>InvalidParameterValue</Code></Error><RequestId>req-3</RequestId></ErrorResponse>")
+		}
+	}))
+	defer srv.Close()
+
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	var entries []LogEntry
+	sqs.Logger = LoggerFunc(func(e LogEntry) { entries = append(entries, e) })
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	err := q.DeleteMessage(&Message{ReceiptHandle: "h"})
+	c.Assert(err, IsNil)
+	err = q.ChangeMessageVisibility(&Message{ReceiptHandle: "h"}, 10)
+	c.Assert(err, NotNil)
+
+	c.Assert(entries, HasLen, 2)
+	c.Assert(entries[0].Action, Equals, "DeleteMessage")
+	c.Assert(entries[0].Path, Equals, "/123/q")
+	c.Assert(entries[0].StatusCode, Equals, 200)
+	c.Assert(entries[0].RequestId, Equals, "req-1")
+	c.Assert(entries[0].Err, IsNil)
+	c.Assert(entries[1].StatusCode, Equals, 400)
+	c.Assert(entries[1].RequestId, Equals, "req-2")
+	c.Assert(entries[1].Err, NotNil)
+
+	var buf bytes.Buffer
+	StdLogger(log.New(&buf, "", 0)).Debug(LogEntry{Action: "SendMessage", Path: "/123/q", StatusCode: 200, RequestId: "r"})
+	c.Assert(buf.String(), Equals, "sqs: action=SendMessage path=/123/q duration=0s status=200 request_id=r\n")
+}
@@ -0,0 +1,187 @@
+package sqs
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Metrics receives the measurements of an SQS client. The client reports:
+//
+//	requests.<Action>         counter, every request
+//	errors.<Code>             counter, failed requests by error code, or
+//	                          "transport" for requests without a response
+//	messages.sent             counter
+//	messages.received         counter
+//	messages.deleted          counter
+//	latency.<Action>          timing, including ReceiveMessage long polls
+//	batch_size.<Action>       value, entries of batch requests
+//
+// With SQS.QueueLabels set, it also reports per queue:
+//
+//	requests.<Action>.<queue> counter, every request
+//	latency.<Action>.<queue>  timing
+//
+// where <queue> is the label QueueLabels gives the queue name.
+type Metrics interface {
+	Counter(name string, delta int64)
+	Timing(name string, d time.Duration)
+	Value(name string, v int64)
+}
+
+// NopMetrics discards every measurement. It is the default.
+type NopMetrics struct{}
+
+// Counter implements Metrics.
+func (NopMetrics) Counter(name string, delta int64) {}
+
+// Timing implements Metrics.
+func (NopMetrics) Timing(name string, d time.Duration) {}
+
+// Value implements Metrics.
+func (NopMetrics) Value(name string, v int64) {}
+
+// FuncMetrics calls its functions with every measurement; nil functions
+// are skipped. It adapts the client to any telemetry library.
+type FuncMetrics struct {
+	OnCounter func(name string, delta int64)
+	OnTiming  func(name string, d time.Duration)
+	OnValue   func(name string, v int64)
+}
+
+// Counter implements Metrics.
+func (m FuncMetrics) Counter(name string, delta int64) {
+	if m.OnCounter != nil {
+		m.OnCounter(name, delta)
+	}
+}
+
+// Timing implements Metrics.
+func (m FuncMetrics) Timing(name string, d time.Duration) {
+	if m.OnTiming != nil {
+		m.OnTiming(name, d)
+	}
+}
+
+// Value implements Metrics.
+func (m FuncMetrics) Value(name string, v int64) {
+	if m.OnValue != nil {
+		m.OnValue(name, v)
+	}
+}
+
+// ExpvarMetrics publishes measurements in an expvar.Map: counters under
+// their name, and timings and values as <name>.count and <name>.total,
+// timings in nanoseconds.
+type ExpvarMetrics struct {
+	m *expvar.Map
+}
+
+var expvarMu sync.Mutex
+
+// NewExpvarMetrics returns metrics published as the expvar variable name,
+// which is created if needed.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if m, ok := expvar.Get(name).(*expvar.Map); ok {
+		return &ExpvarMetrics{m}
+	}
+	return &ExpvarMetrics{expvar.NewMap(name)}
+}
+
+// Counter implements Metrics.
+func (m *ExpvarMetrics) Counter(name string, delta int64) {
+	m.m.Add(name, delta)
+}
+
+// Timing implements Metrics.
+func (m *ExpvarMetrics) Timing(name string, d time.Duration) {
+	m.Value(name, int64(d))
+}
+
+// Value implements Metrics.
+func (m *ExpvarMetrics) Value(name string, v int64) {
+	m.m.Add(name+".count", 1)
+	m.m.Add(name+".total", v)
+}
+
+// QueueLabels bounds the number of distinct queue labels in metrics and
+// SLO stats, so that accounts with thousands of queues do not explode
+// their cardinality. Allowed queues keep their name; the others share
+// Buckets labels chosen by a hash of their name, or the Other label.
+type QueueLabels struct {
+	// Allow lists the queue names reported under their own name.
+	Allow []string
+	// Buckets, if positive, is the number of "hash<N>" labels the other
+	// queues are spread over, so that a hot queue still stands out.
+	Buckets int
+	// Other labels the other queues when Buckets is zero, default
+	// "other".
+	Other string
+}
+
+// Label returns the label of the queue name. The account-level name ""
+// stays "".
+func (l *QueueLabels) Label(name string) string {
+	if name == "" {
+		return ""
+	}
+	for _, a := range l.Allow {
+		if a == name {
+			return name
+		}
+	}
+	if l.Buckets > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		return fmt.Sprintf("hash%d", h.Sum32()%uint32(l.Buckets))
+	}
+	if l.Other != "" {
+		return l.Other
+	}
+	return "other"
+}
+
+// recordMetrics reports the outcome of one request on the queue labelled
+// queue, "" if the request did not address a queue or QueueLabels is not
+// set.
+func (sqs *SQS) recordMetrics(action, queue string, d time.Duration, err error, resp interface{}) {
+	m := sqs.Metrics
+	if m == nil {
+		return
+	}
+	m.Counter("requests."+action, 1)
+	m.Timing("latency."+action, d)
+	if queue != "" {
+		m.Counter("requests."+action+"."+queue, 1)
+		m.Timing("latency."+action+"."+queue, d)
+	}
+	if err != nil {
+		code := "transport"
+		var e *ErrorResponse
+		if errors.As(err, &e) {
+			code = e.EmbeddedError.Code
+		}
+		m.Counter("errors."+code, 1)
+		return
+	}
+	switch r := resp.(type) {
+	case *receiveMessageResponse:
+		m.Counter("messages.received", int64(len(r.Messages)))
+	case *sendMessageResponse:
+		m.Counter("messages.sent", 1)
+	case *SendMessageBatchResult:
+		m.Counter("messages.sent", int64(len(r.Successful)))
+		m.Value("batch_size."+action, int64(len(r.Successful)+len(r.Failed)))
+	case *DeleteMessageBatchResult:
+		m.Counter("messages.deleted", int64(len(r.Successful)))
+		m.Value("batch_size."+action, int64(len(r.Successful)+len(r.Failed)))
+	}
+	if action == "DeleteMessage" {
+		m.Counter("messages.deleted", 1)
+	}
+}
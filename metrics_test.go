@@ -0,0 +1,90 @@
+package sqs
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestMetrics(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("Action") {
+		case "ReceiveMessage":
+			fmt.Fprint(w, "<ReceiveMessageResponse><ReceiveMessageResult><Message><MessageId>1</MessageId></Message><Message><MessageId>2</MessageId></Message></ReceiveMessageResult></ReceiveMessageResponse>")
+		case "DeleteMessage":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "<ErrorResponse><Error><Code>ReceiptHandleIsInvalid</Code></Error></ErrorResponse>")
+		}
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	counters := make(map[string]int64)
+	var timings []string
+	sqs.Metrics = FuncMetrics{
+		OnCounter: func(name string, delta int64) { counters[name] += delta },
+		OnTiming:  func(name string, d time.Duration) { timings = append(timings, name) },
+	}
+	q := &Queue{SQS: sqs, path: "/123/q"}
+	_, err := q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(q.DeleteMessage(&Message{ReceiptHandle: "rh"}), NotNil)
+
+	c.Assert(counters, DeepEquals, map[string]int64{
+		"requests.ReceiveMessage":       1,
+		"messages.received":             2,
+		"requests.DeleteMessage":        1,
+		"errors.ReceiptHandleIsInvalid": 1,
+	})
+	c.Assert(timings, DeepEquals, []string{"latency.ReceiveMessage", "latency.DeleteMessage"})
+}
+
+// expvarRuns numbers the expvar maps of TestExpvarMetrics, which are
+// global, so that the test can run more than once in a process.
+var expvarRuns int
+
+func (s *S) TestExpvarMetrics(c *C) {
+	expvarRuns++
+	name := fmt.Sprintf("%s_%d", c.TestName(), expvarRuns)
+	m := NewExpvarMetrics(name)
+	m.Counter("requests.SendMessage", 2)
+	m.Value("batch_size.SendMessageBatch", 7)
+	c.Assert(NewExpvarMetrics(name), DeepEquals, m)
+	v := expvar.Get(name).(*expvar.Map)
+	c.Assert(v.Get("requests.SendMessage").String(), Equals, "2")
+	c.Assert(v.Get("batch_size.SendMessageBatch.total").String(), Equals, "7")
+}
+
+func (s *S) TestQueueLabels(c *C) {
+	l := &QueueLabels{Allow: []string{"orders"}}
+	c.Assert(l.Label("orders"), Equals, "orders")
+	c.Assert(l.Label("tenant-1"), Equals, "other")
+	c.Assert(l.Label(""), Equals, "")
+	l.Buckets = 4
+	c.Assert(l.Label("tenant-1"), Matches, "hash[0-3]")
+	c.Assert(l.Label("tenant-1"), Equals, l.Label("tenant-1"))
+
+	sqs := NewLocal().SQS()
+	sqs.QueueLabels = &QueueLabels{Allow: []string{"orders"}, Other: "tenant"}
+	counters := make(map[string]int64)
+	sqs.Metrics = FuncMetrics{OnCounter: func(name string, delta int64) { counters[name] += delta }}
+	sqs.SLO = NewSLOTracker(SLO{})
+	for _, name := range []string{"orders", "tenant-1", "tenant-2"} {
+		q, err := sqs.CreateQueue(name, nil)
+		c.Assert(err, IsNil)
+		_, err = q.SendMessage("hi")
+		c.Assert(err, IsNil)
+	}
+	c.Assert(counters["requests.SendMessage"], Equals, int64(3))
+	c.Assert(counters["requests.SendMessage.orders"], Equals, int64(1))
+	c.Assert(counters["requests.SendMessage.tenant"], Equals, int64(2))
+	queues := make(map[string]bool)
+	for _, st := range sqs.SLO.Stats() {
+		queues[st.Queue] = true
+	}
+	c.Assert(queues, DeepEquals, map[string]bool{"": true, "orders": true, "tenant": true})
+}
@@ -0,0 +1,70 @@
+package sqs
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// A Doer performs HTTP requests. *http.Client implements it.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// The DoerFunc type is an adapter to allow the use of an ordinary function
+// as a Doer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// A Middleware wraps the Doer performing the signed requests of an SQS
+// client, to log, measure, mutate or fail them.
+type Middleware func(next Doer) Doer
+
+// Hooks are called at points of a request's life that middleware can't
+// observe. Every field is optional.
+type Hooks struct {
+	// BeforeSign is called with the parameters of every request before
+	// they are signed, and may change them. It is given a copy private to
+	// the request, never the parameters of the caller.
+	BeforeSign func(action string, params url.Values)
+	// AfterResponse is called with the outcome of every HTTP request,
+	// before its body is read.
+	AfterResponse func(action string, r *http.Response, err error)
+	// OnRetry is called before a throttled request, or a receive with a
+	// ReceiveRequestAttemptId, is retried, with the number of the
+	// upcoming attempt, starting at 1.
+	OnRetry func(action, path string, attempt int, err error)
+	// OnResponse is called with the exchange of every request once done,
+	// including failed ones, its response body read in full.
+	OnResponse func(x *Exchange)
+}
+
+// doer returns the client's Doer wrapped in its middleware, the first
+// middleware outermost.
+func (sqs *SQS) doer() Doer {
+	var d Doer = http.DefaultClient
+	if sqs.Client != nil {
+		d = sqs.Client
+	} else if sqs.Transport != nil {
+		d = sqs.httpClient()
+	}
+	for i := len(sqs.Middleware) - 1; i >= 0; i-- {
+		d = sqs.Middleware[i](d)
+	}
+	return d
+}
+
+type actionKey struct{}
+
+// RequestAction returns the action of a request made by an SQS client, for
+// middleware: its parameters are in the URL of GET requests, but in the
+// body of POST requests, see the Content-Type of the request.
+func RequestAction(req *http.Request) string {
+	if action, ok := req.Context().Value(actionKey{}).(string); ok {
+		return action
+	}
+	return req.URL.Query().Get("Action")
+}
@@ -0,0 +1,49 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestMiddleware(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.FormValue("Extra"), Equals, "yes")
+		fmt.Fprint(w, "<DeleteMessageResponse/>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+
+	var trace []string
+	mw := func(name string) Middleware {
+		return func(next Doer) Doer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				trace = append(trace, name)
+				return next.Do(req)
+			})
+		}
+	}
+	sqs.Middleware = []Middleware{mw("outer"), mw("inner")}
+	sqs.Hooks.BeforeSign = func(action string, params url.Values) {
+		params.Set("Extra", "yes")
+	}
+	sqs.Hooks.AfterResponse = func(action string, r *http.Response, err error) {
+		trace = append(trace, fmt.Sprintf("%s %d", action, r.StatusCode))
+	}
+	q := &Queue{SQS: sqs, path: "/123/q"}
+	c.Assert(q.DeleteMessage(&Message{ReceiptHandle: "rh"}), IsNil)
+	c.Assert(trace, DeepEquals, []string{"outer", "inner", "DeleteMessage 200"})
+
+	// Middleware can inject faults.
+	fault := errors.New("injected")
+	sqs.Middleware = []Middleware{func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) { return nil, fault })
+	}}
+	sqs.Hooks.AfterResponse = nil
+	c.Assert(errors.Is(q.DeleteMessage(&Message{ReceiptHandle: "rh"}), fault), Equals, true)
+}
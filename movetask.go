@@ -0,0 +1,131 @@
+package sqs
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The statuses of a message move task.
+const (
+	MoveTaskRunning    = "RUNNING"
+	MoveTaskCompleted  = "COMPLETED"
+	MoveTaskCancelling = "CANCELLING"
+	MoveTaskCancelled  = "CANCELLED"
+	MoveTaskFailed     = "FAILED"
+)
+
+// MessageMoveTaskOpt holds the options of StartMessageMoveTask.
+type MessageMoveTaskOpt struct {
+	// Destination receives the messages. If nil, each message goes back
+	// to the queue it was dead lettered from.
+	Destination *Queue
+	// MaxNumberOfMessagesPerSecond caps the rate of the move, up to 500;
+	// zero lets SQS pick it.
+	MaxNumberOfMessagesPerSecond int
+}
+
+// A MessageMoveTask is a move of messages out of a dead letter queue, run
+// by SQS.
+type MessageMoveTask struct {
+	// TaskHandle identifies the task to CancelMessageMoveTask; SQS only
+	// returns it for a running task.
+	TaskHandle     string
+	Status         string
+	SourceArn      string
+	DestinationArn string
+
+	MaxNumberOfMessagesPerSecond      int
+	ApproximateNumberOfMessagesMoved  int64
+	ApproximateNumberOfMessagesToMove int64
+	// FailureReason tells why a task failed.
+	FailureReason string
+	// StartedTimestamp is when the task started, in milliseconds since
+	// the epoch; see Started.
+	StartedTimestamp int64
+}
+
+// Started returns the time the task started.
+func (t *MessageMoveTask) Started() time.Time {
+	return time.Unix(0, t.StartedTimestamp*int64(time.Millisecond))
+}
+
+type startMessageMoveTaskResponse struct {
+	TaskHandle string `xml:"StartMessageMoveTaskResult>TaskHandle"`
+	ResponseMetadata
+}
+
+// StartMessageMoveTask starts moving the messages of q, a dead letter
+// queue, to their source queues or to opt.Destination, and returns the
+// handle of the task. SQS moves them asynchronously; follow the task with
+// ListMessageMoveTasks. opt may be nil.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_StartMessageMoveTask.html
+// for more details.
+func (q *Queue) StartMessageMoveTask(opt *MessageMoveTaskOpt) (string, error) {
+	params := url.Values{"SourceArn": {q.Arn()}}
+	if opt != nil {
+		if opt.Destination != nil {
+			params.Set("DestinationArn", opt.Destination.Arn())
+		}
+		if opt.MaxNumberOfMessagesPerSecond > 0 {
+			params.Set("MaxNumberOfMessagesPerSecond", strconv.Itoa(opt.MaxNumberOfMessagesPerSecond))
+		}
+	}
+	var resp startMessageMoveTaskResponse
+	if err := q.call("StartMessageMoveTask", "/", params, &resp); err != nil {
+		return "", err
+	}
+	return resp.TaskHandle, nil
+}
+
+type listMessageMoveTasksResponse struct {
+	Tasks []MessageMoveTask `xml:"ListMessageMoveTasksResult>ListMessageMoveTasksResultEntry"`
+	ResponseMetadata
+}
+
+func (r *listMessageMoveTasksResponse) decodeJSON(body []byte) error {
+	var doc struct {
+		Results []MessageMoveTask
+	}
+	err := json.Unmarshal(body, &doc)
+	r.Tasks = doc.Results
+	return err
+}
+
+// ListMessageMoveTasks returns the most recent message move tasks of q, a
+// dead letter queue, up to max, from 1 to 10; zero returns the last one.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_ListMessageMoveTasks.html
+// for more details.
+func (q *Queue) ListMessageMoveTasks(max int) ([]MessageMoveTask, error) {
+	params := url.Values{"SourceArn": {q.Arn()}}
+	if max > 0 {
+		params.Set("MaxResults", strconv.Itoa(max))
+	}
+	var resp listMessageMoveTasksResponse
+	if err := q.call("ListMessageMoveTasks", "/", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Tasks, nil
+}
+
+type cancelMessageMoveTaskResponse struct {
+	ApproximateNumberOfMessagesMoved int64 `xml:"CancelMessageMoveTaskResult>ApproximateNumberOfMessagesMoved"`
+	ResponseMetadata
+}
+
+// CancelMessageMoveTask cancels the running message move task identified
+// by taskHandle, and returns the approximate number of messages it moved.
+// The messages moved stay in their new queue.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_CancelMessageMoveTask.html
+// for more details.
+func (sqs *SQS) CancelMessageMoveTask(taskHandle string) (int64, error) {
+	var resp cancelMessageMoveTaskResponse
+	if err := sqs.call("CancelMessageMoveTask", "/", url.Values{"TaskHandle": {taskHandle}}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.ApproximateNumberOfMessagesMoved, nil
+}
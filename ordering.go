@@ -0,0 +1,134 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// OrderAnomalyKind is the kind of an OrderAnomaly.
+type OrderAnomalyKind int
+
+const (
+	// OrderRegression is a message received after a later message of its
+	// group: the earlier one was typically returned to the queue by an
+	// expired visibility timeout while the later one was handled.
+	OrderRegression OrderAnomalyKind = iota
+	// OrderRepeat is a message received again after it was received last
+	// in its group, because it was not deleted in time.
+	OrderRepeat
+)
+
+func (k OrderAnomalyKind) String() string {
+	switch k {
+	case OrderRegression:
+		return "regression"
+	case OrderRepeat:
+		return "repeat"
+	}
+	return fmt.Sprintf("OrderAnomalyKind(%d)", int(k))
+}
+
+// An OrderAnomaly reports a message of a FIFO queue received out of the
+// order of the sequence numbers of its group.
+type OrderAnomaly struct {
+	Kind    OrderAnomalyKind
+	Group   string
+	Message *Message
+	// Sequence is the sequence number of Message, and Last the highest
+	// sequence number received before in the group.
+	Sequence, Last string
+}
+
+func (a *OrderAnomaly) String() string {
+	return fmt.Sprintf("sqs: message %s of group %s received out of order (%s): sequence number %s, last %s",
+		a.Message.Id, a.Group, a.Kind, a.Sequence, a.Last)
+}
+
+// An OrderChecker verifies that the messages of a FIFO queue are received
+// in the order of their sequence numbers within each message group, to
+// debug consumers whose visibility timeouts expire before they are done.
+// It is a Transformer checking every message it decodes:
+//
+//	checker := &sqs.OrderChecker{OnAnomaly: func(a *sqs.OrderAnomaly) { log.Print(a) }}
+//	client.Transformers = append(client.Transformers, checker)
+//
+// Messages must be received with the MessageGroupId and SequenceNumber
+// system attributes, e.g. with AttributeNames set to All; others are not
+// checked. Groups are told apart by their ID only, so the checker of a
+// client receiving from several FIFO queues should only be used if their
+// group IDs differ.
+type OrderChecker struct {
+	// OnAnomaly, if set, is called with every anomaly found.
+	OnAnomaly func(a *OrderAnomaly)
+
+	mu        sync.Mutex
+	last      map[string]string // by group
+	anomalies int64
+}
+
+// Encode implements Transformer.
+func (c *OrderChecker) Encode(ctx context.Context, m *Message) error {
+	return nil
+}
+
+// Decode implements Transformer. It checks m, and never fails.
+func (c *OrderChecker) Decode(ctx context.Context, m *Message) error {
+	c.Check(m)
+	return nil
+}
+
+// Check records the receipt of m, and returns the anomaly found, if any.
+func (c *OrderChecker) Check(m *Message) *OrderAnomaly {
+	group, seq := m.SystemAttributes.Raw["MessageGroupId"], m.SystemAttributes.Raw["SequenceNumber"]
+	if group == "" || seq == "" {
+		return nil
+	}
+	c.mu.Lock()
+	if c.last == nil {
+		c.last = make(map[string]string)
+	}
+	last, seen := c.last[group]
+	var a *OrderAnomaly
+	switch cmp := compareSequence(seq, last); {
+	case !seen || cmp > 0:
+		c.last[group] = seq
+	case cmp == 0:
+		a = &OrderAnomaly{Kind: OrderRepeat}
+	default:
+		a = &OrderAnomaly{Kind: OrderRegression}
+	}
+	if a != nil {
+		a.Group, a.Message, a.Sequence, a.Last = group, m, seq, last
+		c.anomalies++
+	}
+	c.mu.Unlock()
+	if a != nil && c.OnAnomaly != nil {
+		c.OnAnomaly(a)
+	}
+	return a
+}
+
+// Anomalies returns the number of anomalies found so far.
+func (c *OrderChecker) Anomalies() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.anomalies
+}
+
+// compareSequence compares two sequence numbers, decimal numbers of up to
+// 128 bits without leading zeros, returning -1, 0 or 1.
+func compareSequence(a, b string) int {
+	switch {
+	case len(a) != len(b):
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package sqs
+
+import (
+	"context"
+
+	. "launchpad.net/gocheck"
+)
+
+func fifoMessage(id, group, seq string) *Message {
+	m := &Message{Id: id}
+	m.SystemAttributes.Raw = map[string]string{"MessageGroupId": group, "SequenceNumber": seq}
+	return m
+}
+
+func (s *S) TestOrderChecker(c *C) {
+	var found []string
+	checker := &OrderChecker{OnAnomaly: func(a *OrderAnomaly) {
+		found = append(found, a.Kind.String()+" "+a.Message.Id+" "+a.Last)
+	}}
+	for _, m := range []*Message{
+		fifoMessage("a1", "a", "18849496460467696128"),
+		fifoMessage("b1", "b", "18849496460467696129"),
+		fifoMessage("a2", "a", "118849496460467696130"),
+		fifoMessage("a2", "a", "118849496460467696130"),
+		fifoMessage("a1", "a", "18849496460467696128"),
+		fifoMessage("b2", "b", "18849496460467696131"),
+		{Id: "standard"},
+	} {
+		c.Assert(checker.Decode(context.Background(), m), IsNil)
+	}
+	c.Assert(found, DeepEquals, []string{
+		"repeat a2 118849496460467696130",
+		"regression a1 118849496460467696130",
+	})
+	c.Assert(checker.Anomalies(), Equals, int64(2))
+
+	a := checker.Check(fifoMessage("b1", "b", "18849496460467696129"))
+	c.Assert(a, NotNil)
+	c.Assert(a.String(), Equals, "sqs: message b1 of group b received out of order (regression): sequence number 18849496460467696129, last 18849496460467696131")
+}
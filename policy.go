@@ -0,0 +1,292 @@
+package sqs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PolicyVersion is the IAM policy language version used by generated
+// policy documents.
+const PolicyVersion = "2012-10-17"
+
+// A PolicyDocument is an IAM policy document: an identity policy, such as
+// those returned by MinimalPolicy, or the resource policy of a queue,
+// stored as JSON in its Policy attribute.
+type PolicyDocument struct {
+	Version   string            `json:"Version"`
+	Id        string            `json:"Id,omitempty"`
+	Statement []PolicyStatement `json:"Statement"`
+
+	// queueArn is the queue of the statements added by the Allow
+	// methods.
+	queueArn string
+}
+
+// A PolicyStatement is a single statement of an IAM policy document.
+// Principal only appears in resource policies.
+type PolicyStatement struct {
+	Sid       string           `json:"Sid,omitempty"`
+	Effect    string           `json:"Effect"`
+	Principal *PolicyPrincipal `json:"Principal,omitempty"`
+	Action    PolicyValues     `json:"Action"`
+	Resource  PolicyValues     `json:"Resource"`
+	// Condition maps condition operators, such as "ArnEquals", to the
+	// values of condition keys, such as "aws:SourceArn".
+	Condition map[string]map[string]PolicyValues `json:"Condition,omitempty"`
+}
+
+// PolicyValues are the values of a policy element, which IAM accepts as a
+// single string or as an array of them.
+type PolicyValues []string
+
+// UnmarshalJSON accepts a single string as well as an array.
+func (v *PolicyValues) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = PolicyValues{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(v))
+}
+
+// A PolicyPrincipal is the principal of a statement of a resource policy:
+// anyone if Any is set, or the given AWS accounts, roles and users, and
+// services (e.g. "sns.amazonaws.com").
+type PolicyPrincipal struct {
+	Any     bool
+	AWS     PolicyValues
+	Service PolicyValues
+}
+
+type policyPrincipal struct {
+	AWS     PolicyValues `json:",omitempty"`
+	Service PolicyValues `json:",omitempty"`
+}
+
+// MarshalJSON encodes Any as "*".
+func (p PolicyPrincipal) MarshalJSON() ([]byte, error) {
+	if p.Any {
+		return []byte(`"*"`), nil
+	}
+	return json.Marshal(policyPrincipal{AWS: p.AWS, Service: p.Service})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *PolicyPrincipal) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s != "*" {
+			return fmt.Errorf("sqs: invalid policy principal %q", s)
+		}
+		*p = PolicyPrincipal{Any: true}
+		return nil
+	}
+	var raw policyPrincipal
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*p = PolicyPrincipal{AWS: raw.AWS, Service: raw.Service}
+	return nil
+}
+
+// JSON returns the indented JSON encoding of the policy document.
+func (p *PolicyDocument) JSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// String returns the JSON encoding of the policy document, as stored in
+// the Policy queue attribute.
+func (p *PolicyDocument) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
+
+// ParsePolicy decodes a policy document, such as the value of a Policy
+// queue attribute.
+func ParsePolicy(s string) (*PolicyDocument, error) {
+	var p PolicyDocument
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate checks that the document has a version and statements, and
+// that each statement has an effect, actions and resources.
+func (p *PolicyDocument) Validate() error {
+	if p.Version == "" {
+		return errors.New("sqs: policy has no Version")
+	}
+	if len(p.Statement) == 0 {
+		return errors.New("sqs: policy has no Statement")
+	}
+	for i, st := range p.Statement {
+		switch {
+		case st.Effect != "Allow" && st.Effect != "Deny":
+			return fmt.Errorf("sqs: policy statement %d has Effect %q, want Allow or Deny", i, st.Effect)
+		case len(st.Action) == 0:
+			return fmt.Errorf("sqs: policy statement %d has no Action", i)
+		case len(st.Resource) == 0:
+			return fmt.Errorf("sqs: policy statement %d has no Resource", i)
+		case st.Principal != nil && !st.Principal.Any && len(st.Principal.AWS) == 0 && len(st.Principal.Service) == 0:
+			return fmt.Errorf("sqs: policy statement %d has an empty Principal", i)
+		}
+	}
+	return nil
+}
+
+// NewQueuePolicy returns an empty resource policy for q, to which the
+// Allow methods add statements:
+//
+//	p := NewQueuePolicy(q).AllowSendFrom("111122223333").AllowSNSTopic(topicArn)
+//	err := q.SetPolicy(p)
+func NewQueuePolicy(q *Queue) *PolicyDocument {
+	return &PolicyDocument{Version: PolicyVersion, Id: q.Arn() + "/Policy", queueArn: q.Arn()}
+}
+
+// AllowSendFrom allows the given AWS accounts to send messages to the
+// queue, and returns p.
+func (p *PolicyDocument) AllowSendFrom(accountIds ...string) *PolicyDocument {
+	p.Statement = append(p.Statement, PolicyStatement{
+		Effect:    "Allow",
+		Principal: &PolicyPrincipal{AWS: accountIds},
+		Action:    PolicyValues{"sqs:SendMessage"},
+		Resource:  PolicyValues{p.queueArn},
+	})
+	return p
+}
+
+// AllowSNSTopic allows the SNS topic topicArn to deliver its messages to
+// the queue, and returns p.
+func (p *PolicyDocument) AllowSNSTopic(topicArn string) *PolicyDocument {
+	return p.AllowService("sns.amazonaws.com", topicArn)
+}
+
+// AllowService allows the AWS service (e.g. "events.amazonaws.com") to
+// send messages to the queue on behalf of sourceArn, such as an
+// EventBridge rule or an S3 bucket, and returns p.
+func (p *PolicyDocument) AllowService(service, sourceArn string) *PolicyDocument {
+	p.Statement = append(p.Statement, PolicyStatement{
+		Effect:    "Allow",
+		Principal: &PolicyPrincipal{Service: PolicyValues{service}},
+		Action:    PolicyValues{"sqs:SendMessage"},
+		Resource:  PolicyValues{p.queueArn},
+		Condition: map[string]map[string]PolicyValues{
+			"ArnEquals": {"aws:SourceArn": {sourceArn}},
+		},
+	})
+	return p
+}
+
+// Policy returns the resource policy of the queue, or nil if it has none.
+func (q *Queue) Policy() (*PolicyDocument, error) {
+	attrs, err := q.GetQueueAttributes(Policy)
+	if err != nil {
+		return nil, err
+	}
+	v := attrs.Policy()
+	if v == "" {
+		return nil, nil
+	}
+	p, err := ParsePolicy(v)
+	if err != nil {
+		return nil, err
+	}
+	p.queueArn = q.Arn()
+	return p, nil
+}
+
+// SetPolicy validates p and sets it as the resource policy of the queue.
+// A nil policy removes it.
+func (q *Queue) SetPolicy(p *PolicyDocument) error {
+	v := ""
+	if p != nil {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		v = p.String()
+	}
+	return q.SetQueueAttributes(map[Attribute]string{Policy: v})
+}
+
+// A QueueUse declares the SQS API actions a service performs against a
+// queue, identified by ARN.
+type QueueUse struct {
+	Arn     string
+	Actions []string
+}
+
+// UseQueue returns a QueueUse of q for the given API actions, named as in
+// this package (e.g. "SendMessage", "ReceiveMessage").
+func UseQueue(q *Queue, actions ...string) QueueUse {
+	return QueueUse{Arn: q.Arn(), Actions: actions}
+}
+
+// iamActions maps API actions to the IAM action that authorizes them when
+// the two differ.
+var iamActions = map[string]string{
+	"SendMessageBatch":             "SendMessage",
+	"DeleteMessageBatch":           "DeleteMessage",
+	"ChangeMessageVisibilityBatch": "ChangeMessageVisibility",
+}
+
+// IAMAction returns the IAM action (e.g. "sqs:SendMessage") required to
+// call the given API action.
+func IAMAction(action string) string {
+	if a, ok := iamActions[action]; ok {
+		action = a
+	}
+	return "sqs:" + action
+}
+
+// MinimalPolicy returns the least-privilege IAM policy allowing exactly the
+// declared uses. Resources that need the same set of actions share a
+// statement, and the output is sorted so it is stable across runs.
+func MinimalPolicy(uses ...QueueUse) *PolicyDocument {
+	byArn := make(map[string]map[string]bool)
+	for _, u := range uses {
+		set := byArn[u.Arn]
+		if set == nil {
+			set = make(map[string]bool)
+			byArn[u.Arn] = set
+		}
+		for _, a := range u.Actions {
+			set[IAMAction(a)] = true
+		}
+	}
+
+	byActions := make(map[string][]string)
+	for arn, set := range byArn {
+		if len(set) == 0 {
+			continue
+		}
+		actions := make([]string, 0, len(set))
+		for a := range set {
+			actions = append(actions, a)
+		}
+		sort.Strings(actions)
+		key := strings.Join(actions, ",")
+		byActions[key] = append(byActions[key], arn)
+	}
+
+	keys := make([]string, 0, len(byActions))
+	for k := range byActions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	doc := &PolicyDocument{Version: PolicyVersion}
+	for _, k := range keys {
+		arns := byActions[k]
+		sort.Strings(arns)
+		doc.Statement = append(doc.Statement, PolicyStatement{
+			Effect:   "Allow",
+			Action:   PolicyValues(strings.Split(k, ",")),
+			Resource: arns,
+		})
+	}
+	return doc
+}
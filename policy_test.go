@@ -0,0 +1,112 @@
+package sqs
+
+import (
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestQueueArn(c *C) {
+	q := &Queue{SQS: s.sqs, path: "/123456789012/orders"}
+	c.Assert(q.AccountId(), Equals, "123456789012")
+	c.Assert(q.Arn(), Equals, "arn:aws:sqs:us-east-1:123456789012:orders")
+}
+
+func (s *S) TestMinimalPolicy(c *C) {
+	orders := &Queue{SQS: s.sqs, path: "/123456789012/orders"}
+	events := &Queue{SQS: s.sqs, path: "/123456789012/events"}
+	doc := MinimalPolicy(
+		UseQueue(orders, "SendMessageBatch", "SendMessage"),
+		UseQueue(events, "SendMessage"),
+		UseQueue(orders, "ReceiveMessage", "DeleteMessageBatch"),
+	)
+	c.Assert(doc.Version, Equals, PolicyVersion)
+	c.Assert(doc.Statement, DeepEquals, []PolicyStatement{
+		{
+			Effect:   "Allow",
+			Action:   []string{"sqs:DeleteMessage", "sqs:ReceiveMessage", "sqs:SendMessage"},
+			Resource: []string{orders.Arn()},
+		},
+		{
+			Effect:   "Allow",
+			Action:   []string{"sqs:SendMessage"},
+			Resource: []string{events.Arn()},
+		},
+	})
+}
+
+func (s *S) TestParsePolicy(c *C) {
+	p, err := ParsePolicy(`{
+		"Version": "2012-10-17",
+		"Statement": [{
+			"Effect": "Allow",
+			"Principal": "*",
+			"Action": "sqs:SendMessage",
+			"Resource": "arn:aws:sqs:us-east-1:123456789012:orders",
+			"Condition": {"ArnEquals": {"aws:SourceArn": "arn:aws:sns:us-east-1:123456789012:topic"}}
+		}, {
+			"Effect": "Deny",
+			"Principal": {"AWS": ["111122223333", "444455556666"]},
+			"Action": ["sqs:ReceiveMessage"],
+			"Resource": ["*"]
+		}]
+	}`)
+	c.Assert(err, IsNil)
+	c.Assert(p.Validate(), IsNil)
+	c.Assert(p.Statement[0].Principal, DeepEquals, &PolicyPrincipal{Any: true})
+	c.Assert(p.Statement[0].Action, DeepEquals, PolicyValues{"sqs:SendMessage"})
+	c.Assert(p.Statement[0].Condition["ArnEquals"]["aws:SourceArn"], DeepEquals, PolicyValues{"arn:aws:sns:us-east-1:123456789012:topic"})
+	c.Assert(p.Statement[1].Principal.AWS, DeepEquals, PolicyValues{"111122223333", "444455556666"})
+
+	again, err := ParsePolicy(p.String())
+	c.Assert(err, IsNil)
+	c.Assert(again, DeepEquals, p)
+
+	p.Statement[1].Effect = "Maybe"
+	c.Assert(p.Validate(), ErrorMatches, `sqs: policy statement 1 has Effect "Maybe", want Allow or Deny`)
+	_, err = ParsePolicy(`{"Statement": [{"Principal": "someone"}]}`)
+	c.Assert(err, ErrorMatches, `sqs: invalid policy principal "someone"`)
+}
+
+func (s *S) TestQueuePolicy(c *C) {
+	sqs := NewLocal().SQS()
+	q, err := sqs.CreateQueue("orders", nil)
+	c.Assert(err, IsNil)
+	p, err := q.Policy()
+	c.Assert(err, IsNil)
+	c.Assert(p, IsNil)
+
+	topic := "arn:aws:sns:us-east-1:123456789012:orders"
+	c.Assert(q.SetPolicy(NewQueuePolicy(q).AllowSendFrom("111122223333").AllowSNSTopic(topic)), IsNil)
+	p, err = q.Policy()
+	c.Assert(err, IsNil)
+	c.Assert(p.Statement, DeepEquals, []PolicyStatement{
+		{
+			Effect:    "Allow",
+			Principal: &PolicyPrincipal{AWS: PolicyValues{"111122223333"}},
+			Action:    PolicyValues{"sqs:SendMessage"},
+			Resource:  PolicyValues{q.Arn()},
+		},
+		{
+			Effect:    "Allow",
+			Principal: &PolicyPrincipal{Service: PolicyValues{"sns.amazonaws.com"}},
+			Action:    PolicyValues{"sqs:SendMessage"},
+			Resource:  PolicyValues{q.Arn()},
+			Condition: map[string]map[string]PolicyValues{"ArnEquals": {"aws:SourceArn": {topic}}},
+		},
+	})
+
+	// An empty policy is refused, a nil one removes the policy.
+	c.Assert(q.SetPolicy(NewQueuePolicy(q)), ErrorMatches, "sqs: policy has no Statement")
+	c.Assert(q.SetPolicy(nil), IsNil)
+	p, err = q.Policy()
+	c.Assert(err, IsNil)
+	c.Assert(p, IsNil)
+
+	dlq, err := sqs.CreateQueue("orders-dead", nil)
+	c.Assert(err, IsNil)
+	opt := (&CreateQueueOpt{}).WithDeadLetter(dlq.Arn(), 3).WithPolicy(NewQueuePolicy(q).AllowSendFrom("111122223333"))
+	jobs, err := sqs.CreateQueue("jobs", opt)
+	c.Assert(err, IsNil)
+	rp, err := jobs.RedrivePolicy()
+	c.Assert(err, IsNil)
+	c.Assert(rp.DeadLetterTargetArn, Equals, dlq.Arn())
+}
@@ -0,0 +1,192 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	minPollBackoff = 100 * time.Millisecond
+	maxPollBackoff = 10 * time.Second
+)
+
+// backoff paces receive loops after failed or empty receives, doubling the
+// pause each time up to maxPollBackoff.
+type backoff struct {
+	d time.Duration
+}
+
+func (b *backoff) reset() {
+	b.d = 0
+}
+
+// wait pauses for the next backoff interval. It returns false if ctx was
+// done first.
+func (b *backoff) wait(ctx context.Context) bool {
+	if b.d == 0 {
+		b.d = minPollBackoff
+	} else if b.d *= 2; b.d > maxPollBackoff {
+		b.d = maxPollBackoff
+	}
+	return sleepContext(ctx, b.d)
+}
+
+// sleepContext sleeps for d. It returns false if ctx was done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// receive performs one receive of a polling loop and paces the loop with b:
+// failed receives, and empty ones that returned before the requested long
+// poll elapsed, are followed by a backoff pause so loops don't spin.
+func receive(ctx context.Context, q *Queue, opt *ReceiveMessageOpt, b *backoff, onError func(error)) []*Message {
+	start := time.Now()
+	msgs, err := q.WithContext(ctx).ReceiveMessages(opt)
+	err = reportReceiveError(err, onError)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// Abandoned by the caller; not worth reporting.
+	case err != nil:
+		if onError != nil {
+			onError(err)
+		}
+		b.wait(ctx)
+	case len(msgs) > 0:
+		b.reset()
+	case opt.WaitTimeSeconds == 0 || time.Since(start) < time.Duration(opt.WaitTimeSeconds)*time.Second:
+		b.wait(ctx)
+	default:
+		b.reset()
+	}
+	return msgs
+}
+
+// Messages continuously receives from the queue and delivers the messages
+// over the returned channel until ctx is done, when both channels are
+// closed. Empty receives and errors are paced with a backoff. Receive
+// errors are delivered on the error channel, which holds one error: errors
+// occurring while it is full are dropped, so callers need not read it.
+//
+// opt may be nil; WaitTimeSeconds defaults to 20 for long polling.
+func (q *Queue) Messages(ctx context.Context, opt *ReceiveMessageOpt) (<-chan Message, <-chan error) {
+	o := ReceiveMessageOpt{WaitTimeSeconds: 20}
+	if opt != nil {
+		o = *opt
+		if o.WaitTimeSeconds == 0 {
+			o.WaitTimeSeconds = 20
+		}
+	}
+	msgs := make(chan Message)
+	errs := make(chan error, 1)
+	onError := func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	go func() {
+		defer close(errs)
+		defer close(msgs)
+		var b backoff
+		for ctx.Err() == nil {
+			for _, m := range receive(ctx, q, &o, &b, onError) {
+				select {
+				case msgs <- *m:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return msgs, errs
+}
+
+// DrainOpt configures Drain.
+type DrainOpt struct {
+	// ReceiveOpt configures the receives; MaxNumberOfMessages defaults
+	// to 10 and WaitTimeSeconds to 20, as Drain always long polls.
+	ReceiveOpt *ReceiveMessageOpt
+	// UntilEmpty returns once a receive comes back empty, to process the
+	// backlog of a queue, rather than once ctx is done.
+	UntilEmpty bool
+	// OnError, if set, is called with receive and delete errors. Failed
+	// receives are retried with a backoff; messages failing to be
+	// deleted are delivered again.
+	OnError func(err error)
+}
+
+// Drain receives batches of messages and passes each batch to fn, deleting
+// the batch once fn returns nil, for bulk processing with batch rather than
+// per-message semantics. It returns when ctx is done, or when fn returns an
+// error, which it returns; the messages of that batch are delivered again
+// once their visibility timeout expires. opt may be nil.
+func (q *Queue) Drain(ctx context.Context, fn func(msgs []Message) error, opt *DrainOpt) error {
+	if opt == nil {
+		opt = &DrainOpt{}
+	}
+	o := ReceiveMessageOpt{MaxNumberOfMessages: MaxBatchSize, WaitTimeSeconds: 20}
+	if opt.ReceiveOpt != nil {
+		o = *opt.ReceiveOpt
+		if o.MaxNumberOfMessages == 0 {
+			o.MaxNumberOfMessages = MaxBatchSize
+		}
+		if o.WaitTimeSeconds == 0 {
+			o.WaitTimeSeconds = 20
+		}
+	}
+	onError := func(err error) {
+		if opt.OnError != nil {
+			opt.OnError(err)
+		}
+	}
+	if err := q.awaitPurge(ctx); err != nil {
+		return err
+	}
+	var b backoff
+	for ctx.Err() == nil {
+		polled := time.Now()
+		msgs, err := q.WithContext(ctx).ReceiveMessages(&o)
+		if err := reportReceiveError(err, onError); err != nil {
+			if ctx.Err() == nil {
+				onError(err)
+				b.wait(ctx)
+			}
+			continue
+		}
+		if len(msgs) == 0 {
+			switch {
+			case opt.UntilEmpty && err == nil:
+				return nil
+			case time.Since(polled) < time.Duration(o.WaitTimeSeconds)*time.Second:
+				// The endpoint did not long-poll; don't spin on it.
+				b.wait(ctx)
+			default:
+				b.reset()
+			}
+			continue
+		}
+		b.reset()
+		batch := make([]Message, len(msgs))
+		for i, m := range msgs {
+			batch[i] = *m
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		res, err := q.DeleteMessageBatch(msgs)
+		if err != nil {
+			onError(err)
+		} else if err := res.Err(); err != nil {
+			onError(fmt.Errorf("sqs: deleting messages drained from %s: %w", q.Name(), err))
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,133 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestMessages(c *C) {
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n == 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "<ErrorResponse><Error><Code>InternalError</Code></Error></ErrorResponse>")
+			return
+		}
+		fmt.Fprintf(w, "<ReceiveMessageResponse><ReceiveMessageResult><Message><MessageId>%d</MessageId><Body>m%[1]d</Body></Message></ReceiveMessageResult></ReceiveMessageResponse>", n)
+	}))
+	defer srv.Close()
+
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs, errs := q.Messages(ctx, nil)
+	c.Assert((<-msgs).Body, Equals, "m1")
+	c.Assert((<-msgs).Body, Equals, "m3")
+	c.Assert(<-errs, ErrorMatches, ".*InternalError.*")
+	cancel()
+	for range msgs {
+	}
+	for range errs {
+	}
+}
+
+func (s *S) TestMessagesLongPolls(c *C) {
+	var mu sync.Mutex
+	var params []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		params = append(params, r.Form.Get("MaxNumberOfMessages")+" "+r.Form.Get("WaitTimeSeconds"))
+		mu.Unlock()
+		fmt.Fprint(w, "<ReceiveMessageResponse><ReceiveMessageResult><Message><MessageId>1</MessageId><Body>m</Body></Message></ReceiveMessageResult></ReceiveMessageResponse>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgs, errs := q.Messages(ctx, &ReceiveMessageOpt{MaxNumberOfMessages: 10})
+	c.Assert((<-msgs).Body, Equals, "m")
+	cancel()
+	for range msgs {
+	}
+	for range errs {
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(params[0], Equals, "10 20")
+}
+
+func (s *S) TestDrain(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("etl", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 25; i++ {
+		_, err := q.SendMessage(fmt.Sprint(i))
+		c.Assert(err, IsNil)
+	}
+	var sizes []int
+	seen := make(map[string]bool)
+	err = q.Drain(context.Background(), func(msgs []Message) error {
+		sizes = append(sizes, len(msgs))
+		for _, m := range msgs {
+			seen[m.Body] = true
+		}
+		return nil
+	}, &DrainOpt{UntilEmpty: true, ReceiveOpt: &ReceiveMessageOpt{WaitTimeSeconds: 1}})
+	c.Assert(err, IsNil)
+	c.Assert(sizes, DeepEquals, []int{10, 10, 5})
+	c.Assert(seen, HasLen, 25)
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Empty(), Equals, true)
+
+	// A failed batch stops the drain and is left in flight.
+	_, err = q.SendMessage("bad")
+	c.Assert(err, IsNil)
+	err = q.Drain(context.Background(), func(msgs []Message) error {
+		return fmt.Errorf("loading %d rows: failed", len(msgs))
+	}, nil)
+	c.Assert(err, ErrorMatches, "loading 1 rows: failed")
+	st, err = q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.InFlight, Equals, 1)
+}
+
+func (s *S) TestDrainLongPolls(c *C) {
+	var mu sync.Mutex
+	var waits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		waits = append(waits, r.Form.Get("WaitTimeSeconds"))
+		mu.Unlock()
+		fmt.Fprint(w, "<ReceiveMessageResponse><ReceiveMessageResult/></ReceiveMessageResponse>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	// Receives long poll even when ReceiveOpt is given, and are paced
+	// when the endpoint answers at once.
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	err := q.Drain(ctx, func([]Message) error { return nil }, &DrainOpt{ReceiveOpt: &ReceiveMessageOpt{VisibilityTimeout: 30}})
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	mu.Lock()
+	defer mu.Unlock()
+	c.Assert(len(waits) > 0 && len(waits) < 10, Equals, true, Commentf("%d receives", len(waits)))
+	for _, w := range waits {
+		c.Assert(w, Equals, "20")
+	}
+}
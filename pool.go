@@ -0,0 +1,122 @@
+package sqs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A ReceiverPool runs several long-poll loops on a queue and delivers the
+// messages they receive over one bounded channel. Receivers only ask for
+// as many messages as the channel has room for, so that messages are
+// never received, and hidden from other consumers, while nothing can take
+// them: when the channel is full, receivers pause until it drains. Any
+// number of workers can read the channel, each taking the next message
+// as soon as it is free, whichever receiver got it.
+type ReceiverPool struct {
+	Queue *Queue
+	// Receivers is the number of polling loops; it defaults to 1.
+	Receivers int
+	// Capacity is the capacity of the channel; it defaults to a batch per
+	// receiver.
+	Capacity int
+	// ReceiveOpt holds the options of the receives. WaitTimeSeconds
+	// defaults to 20 for long polling, and MaxNumberOfMessages, the
+	// batch size, to 10.
+	ReceiveOpt *ReceiveMessageOpt
+	// ThrottleInterval is how long receivers pause when the channel is
+	// full before checking again; it defaults to 100ms.
+	ThrottleInterval time.Duration
+	// OnError, if set, is called with receive errors.
+	OnError func(err error)
+
+	mu        sync.Mutex
+	reserved  int
+	throttled int64
+}
+
+// Run starts the receivers and returns the channel of the messages they
+// receive, which is closed once ctx is done and they returned. Messages
+// left in the channel can still be read after it is closed.
+func (p *ReceiverPool) Run(ctx context.Context) <-chan *Message {
+	o := ReceiveMessageOpt{WaitTimeSeconds: 20}
+	if p.ReceiveOpt != nil {
+		o = *p.ReceiveOpt
+	}
+	if o.MaxNumberOfMessages <= 0 || o.MaxNumberOfMessages > 10 {
+		o.MaxNumberOfMessages = 10
+	}
+	n := p.Receivers
+	if n <= 0 {
+		n = 1
+	}
+	size := p.Capacity
+	if size <= 0 {
+		size = n * o.MaxNumberOfMessages
+	}
+	out := make(chan *Message, size)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.poll(ctx, o, out)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Throttled returns the number of times receivers paused for want of room
+// in the channel; a steadily increasing count means the workers reading
+// it are the bottleneck.
+func (p *ReceiverPool) Throttled() int64 {
+	return atomic.LoadInt64(&p.throttled)
+}
+
+func (p *ReceiverPool) poll(ctx context.Context, o ReceiveMessageOpt, out chan *Message) {
+	interval := p.ThrottleInterval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	var b backoff
+	for ctx.Err() == nil {
+		n := p.reserve(cap(out), len(out), o.MaxNumberOfMessages)
+		if n == 0 {
+			atomic.AddInt64(&p.throttled, 1)
+			sleepContext(ctx, interval)
+			continue
+		}
+		opt := o
+		opt.MaxNumberOfMessages = n
+		// The reservation guarantees room for the messages, so sends
+		// never block.
+		for _, m := range receive(ctx, p.Queue, &opt, &b, p.OnError) {
+			out <- m
+		}
+		p.mu.Lock()
+		p.reserved -= n
+		p.mu.Unlock()
+	}
+}
+
+// reserve reserves room in the channel for up to max messages, given its
+// capacity and length, and returns the number reserved. The length only
+// decreases outside of reservations, so the room is never overestimated.
+func (p *ReceiverPool) reserve(capacity, length, max int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := capacity - length - p.reserved
+	if n > max {
+		n = max
+	}
+	if n <= 0 {
+		return 0
+	}
+	p.reserved += n
+	return n
+}
@@ -0,0 +1,48 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestReceiverPool(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 30; i++ {
+		_, err := q.SendMessage(fmt.Sprint(i))
+		c.Assert(err, IsNil)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &ReceiverPool{
+		Queue:            q,
+		Receivers:        3,
+		Capacity:         4,
+		ReceiveOpt:       &ReceiveMessageOpt{WaitTimeSeconds: 1, MaxNumberOfMessages: 10},
+		ThrottleInterval: 5 * time.Millisecond,
+	}
+	msgs := p.Run(ctx)
+	seen := make(map[string]bool)
+	for len(seen) < 30 {
+		m := <-msgs
+		c.Assert(seen[m.Body], Equals, false)
+		seen[m.Body] = true
+		// A slow worker: the messages received are those handled,
+		// this one, and at most a full channel.
+		time.Sleep(2 * time.Millisecond)
+		st, err := q.Stats()
+		c.Assert(err, IsNil)
+		c.Assert(st.InFlight <= 1+p.Capacity, Equals, true, Commentf("%d in flight", st.InFlight))
+		c.Assert(q.DeleteMessage(m), IsNil)
+	}
+	c.Assert(p.Throttled() > 0, Equals, true)
+
+	cancel()
+	for m := range msgs {
+		c.Fatalf("unexpected message %s", m.Body)
+	}
+}
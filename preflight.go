@@ -0,0 +1,146 @@
+package sqs
+
+import (
+	"net/url"
+	"strings"
+)
+
+// A PreflightStatus reports the outcome of probing a single permission.
+type PreflightStatus int
+
+const (
+	// PreflightAllowed means the request was authorized.
+	PreflightAllowed PreflightStatus = iota
+	// PreflightDenied means SQS answered with AccessDenied.
+	PreflightDenied
+	// PreflightUnknown means the probe failed for an unrelated reason,
+	// e.g. a network error; see PreflightResult.Err.
+	PreflightUnknown
+	// PreflightUnchecked means the action cannot be probed without side
+	// effects, so it was not attempted.
+	PreflightUnchecked
+)
+
+func (s PreflightStatus) String() string {
+	switch s {
+	case PreflightAllowed:
+		return "allowed"
+	case PreflightDenied:
+		return "denied"
+	case PreflightUnknown:
+		return "unknown"
+	case PreflightUnchecked:
+		return "unchecked"
+	}
+	return "invalid"
+}
+
+// A PreflightResult is the outcome of probing one action against one
+// resource.
+type PreflightResult struct {
+	Action   string // IAM action, e.g. "sqs:SendMessage"
+	Resource string // queue ARN, or "*" for account-level actions
+	Status   PreflightStatus
+	Err      error
+}
+
+// A PreflightReport collects the results of a permission preflight.
+type PreflightReport struct {
+	Results []PreflightResult
+}
+
+// Missing returns a description ("sqs:SendMessage on arn:...") of every
+// permission that was denied.
+func (r *PreflightReport) Missing() []string {
+	var missing []string
+	for _, res := range r.Results {
+		if res.Status == PreflightDenied {
+			missing = append(missing, res.Action+" on "+res.Resource)
+		}
+	}
+	return missing
+}
+
+// Err returns a *MissingPermissionsError if any permission was denied, and
+// nil otherwise.
+func (r *PreflightReport) Err() error {
+	if missing := r.Missing(); len(missing) > 0 {
+		return &MissingPermissionsError{Missing: missing}
+	}
+	return nil
+}
+
+// MissingPermissionsError lists the IAM permissions a preflight found to be
+// missing.
+type MissingPermissionsError struct {
+	Missing []string
+}
+
+func (e *MissingPermissionsError) Error() string {
+	return "sqs: missing IAM permissions: " + strings.Join(e.Missing, ", ")
+}
+
+// preflightProbes maps actions to parameters that SQS rejects during
+// validation, which happens only after authorization. A validation error
+// therefore proves the permission without changing any state.
+var preflightProbes = map[string]url.Values{
+	"GetQueueAttributes":      {"AttributeName.1": {"QueueArn"}},
+	"SendMessage":             {"MessageBody": {""}},
+	"ReceiveMessage":          {"MaxNumberOfMessages": {"0"}},
+	"DeleteMessage":           {"ReceiptHandle": {"preflight"}},
+	"ChangeMessageVisibility": {"ReceiptHandle": {"preflight"}, "VisibilityTimeout": {"0"}},
+	"SetQueueAttributes":      {"Attribute.1.Name": {"VisibilityTimeout"}, "Attribute.1.Value": {"-1"}},
+}
+
+// Preflight checks that the client holds the IAM permissions required to
+// perform the given API actions on q, without sending, receiving, or
+// modifying anything. Actions that cannot be probed safely, such as
+// DeleteQueue, are reported as PreflightUnchecked.
+func (q *Queue) Preflight(actions ...string) *PreflightReport {
+	report := &PreflightReport{}
+	seen := make(map[string]bool)
+	for _, action := range actions {
+		iam := IAMAction(action)
+		if seen[iam] {
+			continue
+		}
+		seen[iam] = true
+		res := PreflightResult{Action: iam, Resource: q.Arn()}
+		base := strings.TrimPrefix(iam, "sqs:")
+		switch {
+		case base == "ListQueues":
+			res.Resource = "*"
+			_, err := q.SQS.ListQueues(q.Name())
+			res.Status, res.Err = preflightStatus(err)
+		case preflightProbes[base] != nil:
+			params := url.Values{}
+			for k, v := range preflightProbes[base] {
+				params[k] = v
+			}
+			var resp ResponseMetadata
+			err := q.call(base, q.path, params, &resp)
+			res.Status, res.Err = preflightStatus(err)
+		default:
+			res.Status = PreflightUnchecked
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report
+}
+
+// preflightStatus maps the error of a probe to a status. Only validation
+// errors, returned after authorization, prove a permission: other failures,
+// such as a missing queue, throttling or server errors, mean the probe
+// never got that far.
+func preflightStatus(err error) (PreflightStatus, error) {
+	if err == nil {
+		return PreflightAllowed, nil
+	}
+	switch ErrorCode(err) {
+	case ErrCodeAccessDenied, ErrCodeAccessDeniedException:
+		return PreflightDenied, err
+	case ErrCodeInvalidParameterValue, ErrCodeMissingParameter, ErrCodeInvalidAttributeValue, ErrCodeReceiptHandleIsInvalid:
+		return PreflightAllowed, nil
+	}
+	return PreflightUnknown, err
+}
@@ -0,0 +1,44 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestPreflightStatus(c *C) {
+	apiError := func(code string) error {
+		return &ErrorResponse{StatusCode: 400, EmbeddedError: EmbeddedError{Code: code}}
+	}
+	for _, t := range []struct {
+		err    error
+		status PreflightStatus
+	}{
+		{nil, PreflightAllowed},
+		{apiError(ErrCodeInvalidParameterValue), PreflightAllowed},
+		{apiError(ErrCodeMissingParameter), PreflightAllowed},
+		{apiError(ErrCodeInvalidAttributeValue), PreflightAllowed},
+		{apiError(ErrCodeReceiptHandleIsInvalid), PreflightAllowed},
+		{fmt.Errorf("probing: %w", apiError(ErrCodeMissingParameter)), PreflightAllowed},
+		{apiError(ErrCodeAccessDenied), PreflightDenied},
+		{apiError(ErrCodeAccessDeniedException), PreflightDenied},
+		{fmt.Errorf("probing: %w", apiError(ErrCodeAccessDenied)), PreflightDenied},
+		{apiError(ErrCodeInvalidClientTokenId), PreflightUnknown},
+		{apiError(ErrCodeSignatureDoesNotMatch), PreflightUnknown},
+		{apiError(ErrCodeNonExistentQueue), PreflightUnknown},
+		{apiError(ErrCodeRequestThrottled), PreflightUnknown},
+		{apiError("InternalError"), PreflightUnknown},
+		{context.DeadlineExceeded, PreflightUnknown},
+		{errors.New("connection refused"), PreflightUnknown},
+	} {
+		status, err := preflightStatus(t.err)
+		c.Check(status, Equals, t.status, Commentf("%v", t.err))
+		if status == PreflightAllowed {
+			c.Check(err, IsNil)
+		} else {
+			c.Check(err, Equals, t.err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A PriorityConsumer consumes several queues by priority, which SQS does
+// not support within a queue: each receive goes to the first of Queues
+// holding messages, so that higher priority queues are drained first.
+// Messages are handled and settled like by a Consumer.
+//
+// Queues are polled without long polling, one after the other, and the
+// consumer backs off while all of them are empty.
+type PriorityConsumer struct {
+	// Queues lists the queues by decreasing priority.
+	Queues  []*Queue
+	Handler Handler
+
+	// Weights, if set, holds the share of the receives of each queue,
+	// e.g. 6, 3 and 1: each receive starts with the queue due by
+	// weighted round robin, falling back to the others by priority when
+	// it is empty. By default ordering is strict, and lower priority
+	// queues starve while higher priority ones have a backlog.
+	Weights []int
+
+	// Concurrency, BatchSize, VisibilityTimeout, RetryDelay, RetryPolicy,
+	// MessageAttributeNames, AttributeNames and OnError are those of a
+	// Consumer.
+	Concurrency           int
+	BatchSize             int
+	VisibilityTimeout     int
+	RetryDelay            int
+	RetryPolicy           RetryPolicy
+	MessageAttributeNames []string
+	AttributeNames        []Attribute
+	OnError               func(err error)
+
+	once      sync.Once
+	consumers []*Consumer // by queue
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// init sets up a consumer per queue, settling its messages.
+func (p *PriorityConsumer) init() {
+	p.consumers = make([]*Consumer, len(p.Queues))
+	for i, q := range p.Queues {
+		p.consumers[i] = &Consumer{
+			Queue:                 q,
+			Handler:               p.Handler,
+			BatchSize:             p.BatchSize,
+			VisibilityTimeout:     p.VisibilityTimeout,
+			RetryDelay:            p.RetryDelay,
+			RetryPolicy:           p.RetryPolicy,
+			MessageAttributeNames: p.MessageAttributeNames,
+			AttributeNames:        p.AttributeNames,
+			OnError:               p.OnError,
+		}
+	}
+}
+
+// Run consumes messages until ctx is done, then waits for the messages in
+// flight to be handled.
+func (p *PriorityConsumer) Run(ctx context.Context) error {
+	if len(p.Queues) == 0 {
+		return errors.New("sqs: priority consumer without queues")
+	}
+	if p.Weights != nil && len(p.Weights) != len(p.Queues) {
+		return errors.New("sqs: priority consumer needs one weight per queue")
+	}
+	p.once.Do(p.init)
+	n := p.Concurrency
+	if n <= 0 {
+		n = 1
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// work receives and handles messages until ctx is done.
+func (p *PriorityConsumer) work(ctx context.Context) {
+	opts := make([]*ReceiveMessageOpt, len(p.consumers))
+	for i, c := range p.consumers {
+		opts[i] = c.receiveOpt()
+		opts[i].WaitTimeSeconds = 0
+	}
+	var b backoff
+	current := make([]int, len(p.consumers))
+	for ctx.Err() == nil {
+		received := false
+		for _, i := range p.order(current) {
+			c := p.consumers[i]
+			msgs, err := c.Queue.WithContext(ctx).ReceiveMessages(opts[i])
+			if err = reportReceiveError(err, c.onError); err != nil {
+				if ctx.Err() == nil {
+					c.onError(err)
+				}
+				continue
+			}
+			if len(msgs) == 0 {
+				continue
+			}
+			c.accept(msgs)
+			c.track(msgs)
+			c.handleAll(msgs, false)
+			received = true
+			break
+		}
+		if received {
+			b.reset()
+		} else {
+			b.wait(ctx)
+		}
+	}
+}
+
+// order returns the indexes of the queues in the order of the next
+// receive. With Weights, current holds the state of the smooth weighted
+// round robin electing the first queue.
+func (p *PriorityConsumer) order(current []int) []int {
+	order := make([]int, 0, len(current))
+	first := -1
+	if p.Weights != nil {
+		total := 0
+		for i, w := range p.Weights {
+			if w <= 0 {
+				continue
+			}
+			current[i] += w
+			total += w
+			if first < 0 || current[i] > current[first] {
+				first = i
+			}
+		}
+		if first >= 0 {
+			current[first] -= total
+			order = append(order, first)
+		}
+	}
+	for i := range current {
+		if i != first {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// Report returns the counts of the consumer so far, over all its queues.
+func (p *PriorityConsumer) Report() ConsumerReport {
+	var r ConsumerReport
+	for _, qr := range p.Reports() {
+		r.Received += qr.Received
+		r.Processed += qr.Processed
+		r.Acked += qr.Acked
+		r.Nacked += qr.Nacked
+		r.Held += qr.Held
+		r.InFlight += qr.InFlight
+		r.Released += qr.Released
+		r.DeadLettered += qr.DeadLettered
+		r.Duplicates += qr.Duplicates
+		r.InFlightBytes += qr.InFlightBytes
+	}
+	return r
+}
+
+// Reports returns the counts of the consumer so far by queue, in the
+// order of Queues.
+func (p *PriorityConsumer) Reports() []ConsumerReport {
+	p.once.Do(p.init)
+	reports := make([]ConsumerReport, len(p.consumers))
+	for i, c := range p.consumers {
+		reports[i] = c.Report()
+	}
+	return reports
+}
+
+// Start runs the consumer in the background until Stop is called.
+func (p *PriorityConsumer) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	p.cancel, p.done = cancel, done
+	go func() {
+		if err := p.Run(ctx); err != nil && ctx.Err() == nil && p.OnError != nil {
+			p.OnError(err)
+		}
+		close(done)
+	}()
+}
+
+// Stop stops receiving, waits for the messages in flight to be handled and
+// returns the report of the consumer.
+func (p *PriorityConsumer) Stop() ConsumerReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+		p.cancel, p.done = nil, nil
+	}
+	return p.Report()
+}
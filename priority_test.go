@@ -0,0 +1,80 @@
+package sqs
+
+import (
+	"context"
+
+	. "launchpad.net/gocheck"
+)
+
+// consumePriority sends bodies to the queues of p, then runs p until every
+// message was handled and returns the bodies in the order handled.
+func consumePriority(c *C, p *PriorityConsumer, bodies [][]string) []string {
+	total := 0
+	for i, q := range p.Queues {
+		for _, body := range bodies[i] {
+			_, err := q.SendMessage(body)
+			c.Assert(err, IsNil)
+			total++
+		}
+	}
+	handled := make(chan string, total)
+	p.Handler = HandlerFunc(func(m *Message) error { handled <- m.Body; return nil })
+	p.Start()
+	var got []string
+	for len(got) < total {
+		got = append(got, <-handled)
+	}
+	report := p.Stop()
+	c.Assert(report.Acked, Equals, int64(total))
+	c.Assert(report.Clean(), Equals, true)
+	return got
+}
+
+func (s *S) TestPriorityConsumerStrict(c *C) {
+	sqs := NewLocal().SQS()
+	high, err := sqs.CreateQueue("high", nil)
+	c.Assert(err, IsNil)
+	low, err := sqs.CreateQueue("low", nil)
+	c.Assert(err, IsNil)
+	p := &PriorityConsumer{Queues: []*Queue{high, low}}
+	got := consumePriority(c, p, [][]string{{"h1", "h2", "h3"}, {"l1", "l2"}})
+	c.Assert(got, DeepEquals, []string{"h1", "h2", "h3", "l1", "l2"})
+	reports := p.Reports()
+	c.Assert(reports[0].Acked, Equals, int64(3))
+	c.Assert(reports[1].Acked, Equals, int64(2))
+}
+
+func (s *S) TestPriorityConsumerWeights(c *C) {
+	sqs := NewLocal().SQS()
+	high, err := sqs.CreateQueue("high", nil)
+	c.Assert(err, IsNil)
+	low, err := sqs.CreateQueue("low", nil)
+	c.Assert(err, IsNil)
+	p := &PriorityConsumer{Queues: []*Queue{high, low}, Weights: []int{2, 1}}
+	got := consumePriority(c, p, [][]string{{"h1", "h2", "h3"}, {"l1", "l2", "l3"}})
+	c.Assert(got, DeepEquals, []string{"h1", "l1", "h2", "h3", "l2", "l3"})
+}
+
+func (s *S) TestPriorityConsumerOrder(c *C) {
+	p := &PriorityConsumer{Weights: []int{5, 1, 0}}
+	current := make([]int, 3)
+	firsts := make(map[int]int)
+	for i := 0; i < 6; i++ {
+		order := p.order(current)
+		c.Assert(order, HasLen, 3)
+		firsts[order[0]]++
+	}
+	c.Assert(firsts, DeepEquals, map[int]int{0: 5, 1: 1})
+
+	p.Weights = nil
+	c.Assert(p.order(current), DeepEquals, []int{0, 1, 2})
+}
+
+func (s *S) TestPriorityConsumerInvalid(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("high", nil)
+	c.Assert(err, IsNil)
+	p := &PriorityConsumer{}
+	c.Assert(p.Run(context.Background()), ErrorMatches, "sqs: priority consumer without queues")
+	p = &PriorityConsumer{Queues: []*Queue{q}, Weights: []int{1, 2}}
+	c.Assert(p.Run(context.Background()), ErrorMatches, "sqs: priority consumer needs one weight per queue")
+}
@@ -0,0 +1,401 @@
+package sqs
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Protocol is the wire format a client speaks: how request parameters
+// are carried and responses encoded.
+type Protocol interface {
+	// prepare readies req to carry params for action, and returns the
+	// method to sign and the parameters to pass to the signer.
+	prepare(req *http.Request, method, action string, params url.Values) (string, url.Values, error)
+	// finish adds the parameters returned by the signer to req.
+	finish(req *http.Request, method string, signed url.Values)
+	// decode decodes the body of a successful response into resp.
+	decode(body io.Reader, resp interface{}) error
+	// decodeError decodes the body of a failed response into e.
+	decodeError(r *http.Response, body []byte, e *ErrorResponse) error
+}
+
+var (
+	// QueryProtocol sends parameters as a query string, in the URL or the
+	// body of POST requests, and decodes XML responses. It is the
+	// default.
+	QueryProtocol Protocol = queryProtocol{}
+	// JSONProtocol sends parameters as an AWS JSON 1.0 document and
+	// decodes JSON responses, which is cheaper than XML. Error codes are
+	// those of the query protocol.
+	//
+	// SQS only accepts JSON requests signed with SignatureV4;
+	// SignatureV2 adds its parameters to the URL, which suits backends
+	// that do not check them. Local speaks the query protocol only.
+	JSONProtocol Protocol = jsonProtocol{}
+)
+
+// protocol returns the protocol of the client.
+func (sqs *SQS) protocol() Protocol {
+	if sqs.Protocol == nil {
+		return QueryProtocol
+	}
+	return sqs.Protocol
+}
+
+type queryProtocol struct{}
+
+// formContentType is the content type of the parameters of POST requests
+// of the query protocol.
+const formContentType = "application/x-www-form-urlencoded"
+
+func (queryProtocol) prepare(req *http.Request, method, action string, params url.Values) (string, url.Values, error) {
+	return method, params, nil
+}
+
+func (queryProtocol) finish(req *http.Request, method string, signed url.Values) {
+	encoded := signed.Encode()
+	if method == "POST" {
+		req.Header.Set("Content-Type", formContentType)
+		req.Body = ioutil.NopCloser(strings.NewReader(encoded))
+		req.ContentLength = int64(len(encoded))
+	} else {
+		req.URL.RawQuery = encoded
+	}
+}
+
+func (queryProtocol) decode(body io.Reader, resp interface{}) error {
+	if fd, ok := resp.(fastXMLDecoder); ok {
+		return decodeFast(body, fd, func(b []byte) error { return xml.Unmarshal(b, resp) })
+	}
+	return xml.NewDecoder(body).Decode(resp)
+}
+
+func (queryProtocol) decodeError(r *http.Response, body []byte, e *ErrorResponse) error {
+	return xml.Unmarshal(body, e)
+}
+
+type jsonProtocol struct{}
+
+// jsonContentType is the content type of AWS JSON 1.0 documents.
+const jsonContentType = "application/x-amz-json-1.0"
+
+func (jsonProtocol) prepare(req *http.Request, method, action string, params url.Values) (string, url.Values, error) {
+	body, err := json.Marshal(jsonParams(params))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Method = "POST"
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", jsonContentType)
+	req.Header.Set("X-Amz-Target", "AmazonSQS."+action)
+	// Ask for the error codes of the query protocol, which ErrorResponse
+	// and the ErrCode constants use.
+	req.Header.Set("X-Amzn-Query-Mode", "true")
+	return "POST", url.Values{}, nil
+}
+
+func (jsonProtocol) finish(req *http.Request, method string, signed url.Values) {
+	if len(signed) > 0 {
+		req.URL.RawQuery = signed.Encode()
+	}
+}
+
+func (jsonProtocol) decode(body io.Reader, resp interface{}) error {
+	b, err := ioutil.ReadAll(body)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	if r, ok := resp.(jsonResponse); ok {
+		return r.decodeJSON(b)
+	}
+	return json.Unmarshal(b, resp)
+}
+
+func (jsonProtocol) decodeError(r *http.Response, body []byte, e *ErrorResponse) error {
+	var doc struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"` // also matches Message
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return err
+		}
+	}
+	e.EmbeddedError.Code = doc.Type[strings.LastIndex(doc.Type, "#")+1:]
+	e.EmbeddedError.Message = doc.Message
+	e.EmbeddedError.Type = "Sender"
+	if r.StatusCode >= 500 {
+		e.EmbeddedError.Type = "Receiver"
+	}
+	if q := r.Header.Get("X-Amzn-Query-Error"); q != "" {
+		parts := strings.SplitN(q, ";", 2)
+		e.EmbeddedError.Code = parts[0]
+		if len(parts) == 2 && parts[1] != "" {
+			e.EmbeddedError.Type = parts[1]
+		}
+	}
+	e.RequestId = r.Header.Get("X-Amzn-Requestid")
+	return nil
+}
+
+// jsonNumbers are the request parameters sent as JSON numbers.
+var jsonNumbers = map[string]bool{
+	"DelaySeconds":                 true,
+	"MaxNumberOfMessages":          true,
+	"MaxNumberOfMessagesPerSecond": true,
+	"MaxResults":                   true,
+	"VisibilityTimeout":            true,
+	"WaitTimeSeconds":              true,
+}
+
+// jsonLists renames the flattened list parameters of the query protocol
+// to their JSON members.
+var jsonLists = map[string]string{
+	"AttributeName":        "AttributeNames",
+	"MessageAttributeName": "MessageAttributeNames",
+	"TagKey":               "TagKeys",
+	"AWSAccountId":         "AWSAccountIds",
+	"ActionName":           "Actions",
+}
+
+// A paramTree holds query parameters split on their dots, e.g.
+// Tag.1.Key as {"Tag": {"1": {"Key": value}}}.
+type paramTree map[string]interface{}
+
+// jsonParams converts query parameters to the members of a JSON request,
+// leaving out those the JSON protocol carries in headers.
+func jsonParams(params url.Values) map[string]interface{} {
+	tree := paramTree{}
+	for k, v := range params {
+		switch k {
+		case "Action", "Version", "Timestamp":
+			continue
+		}
+		tree.set(strings.Split(k, "."), v[0])
+	}
+	return tree.json()
+}
+
+func (t paramTree) set(path []string, v string) {
+	if len(path) == 1 {
+		t[path[0]] = v
+		return
+	}
+	child, ok := t[path[0]].(paramTree)
+	if !ok {
+		child = paramTree{}
+		t[path[0]] = child
+	}
+	child.set(path[1:], v)
+}
+
+// items returns the members of a flattened list, by index.
+func (t paramTree) items() []interface{} {
+	idx := make([]int, 0, len(t))
+	for k := range t {
+		i, err := strconv.Atoi(k)
+		if err != nil {
+			return nil
+		}
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	items := make([]interface{}, len(idx))
+	for n, i := range idx {
+		items[n] = t[strconv.Itoa(i)]
+	}
+	return items
+}
+
+func (t paramTree) json() map[string]interface{} {
+	doc := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		switch v := v.(type) {
+		case string:
+			if n, err := strconv.Atoi(v); err == nil && jsonNumbers[k] {
+				doc[k] = n
+			} else {
+				doc[k] = v
+			}
+		case paramTree:
+			name, member := v.member(k)
+			doc[name] = member
+		}
+	}
+	return doc
+}
+
+// member converts the subtree t of parameter k to a JSON member.
+func (t paramTree) member(k string) (string, interface{}) {
+	items := t.items()
+	if items == nil {
+		return k, t.json()
+	}
+	str := func(v interface{}, key string) string {
+		if m, ok := v.(paramTree); ok {
+			s, _ := m[key].(string)
+			return s
+		}
+		return ""
+	}
+	switch {
+	case k == "Attribute":
+		m := make(map[string]string, len(items))
+		for _, it := range items {
+			m[str(it, "Name")] = str(it, "Value")
+		}
+		return "Attributes", m
+	case k == "Tag":
+		m := make(map[string]string, len(items))
+		for _, it := range items {
+			m[str(it, "Key")] = str(it, "Value")
+		}
+		return "Tags", m
+	case k == "MessageAttribute", k == "MessageSystemAttribute":
+		m := make(map[string]interface{}, len(items))
+		for _, it := range items {
+			if it, ok := it.(paramTree); ok {
+				value, _ := it["Value"].(paramTree)
+				m[str(it, "Name")] = value.json()
+			}
+		}
+		return k + "s", m
+	case strings.HasSuffix(k, "RequestEntry"):
+		entries := make([]interface{}, 0, len(items))
+		for _, it := range items {
+			if it, ok := it.(paramTree); ok {
+				entries = append(entries, it.json())
+			}
+		}
+		return "Entries", entries
+	}
+	list := make([]interface{}, len(items))
+	for i, it := range items {
+		list[i] = it
+		if t, ok := it.(paramTree); ok {
+			list[i] = t.json()
+		}
+	}
+	if name, ok := jsonLists[k]; ok {
+		return name, list
+	}
+	return k + "s", list
+}
+
+// A jsonResponse decodes itself from a JSON response whose members differ
+// from its XML elements.
+type jsonResponse interface {
+	decodeJSON(body []byte) error
+}
+
+func (r *listQueuesResponse) decodeJSON(body []byte) error {
+	var doc struct {
+		QueueUrls []string
+		NextToken string
+	}
+	err := json.Unmarshal(body, &doc)
+	r.Queues, r.NextToken = doc.QueueUrls, doc.NextToken
+	return err
+}
+
+func (r *listDeadLetterSourceQueuesResponse) decodeJSON(body []byte) error {
+	var doc struct {
+		QueueUrls []string `json:"queueUrls"`
+	}
+	err := json.Unmarshal(body, &doc)
+	r.Queues = doc.QueueUrls
+	return err
+}
+
+func (a *QueueAttributes) decodeJSON(body []byte) error {
+	var doc struct {
+		Attributes map[string]string
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(doc.Attributes))
+	for name := range doc.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a.Attributes = append(a.Attributes, struct {
+			Name  string
+			Value string
+		}{name, doc.Attributes[name]})
+	}
+	return nil
+}
+
+func (r *listQueueTagsResponse) decodeJSON(body []byte) error {
+	var doc struct {
+		Tags map[string]string
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(doc.Tags))
+	for k := range doc.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.Tags = append(r.Tags, struct {
+			Key   string
+			Value string
+		}{k, doc.Tags[k]})
+	}
+	return nil
+}
+
+func (r *sendMessageResponse) decodeJSON(body []byte) error {
+	var doc struct {
+		MessageId        string
+		MD5OfMessageBody string
+	}
+	err := json.Unmarshal(body, &doc)
+	r.Id, r.MD5OfMessageBody = doc.MessageId, doc.MD5OfMessageBody
+	return err
+}
+
+func (r *receiveMessageResponse) decodeJSON(body []byte) error {
+	var doc struct {
+		Messages []struct {
+			MessageId         string
+			ReceiptHandle     string
+			MD5OfBody         string
+			Body              string
+			Attributes        map[string]string
+			MessageAttributes MessageAttributes
+		}
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	for _, jm := range doc.Messages {
+		m := &Message{
+			Id:                jm.MessageId,
+			Body:              jm.Body,
+			ReceiptHandle:     jm.ReceiptHandle,
+			MD5OfBody:         jm.MD5OfBody,
+			MessageAttributes: jm.MessageAttributes,
+		}
+		for name, value := range jm.Attributes {
+			if err := m.SystemAttributes.set(name, value); err != nil {
+				return err
+			}
+		}
+		r.Messages = append(r.Messages, m)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestJSONParams(c *C) {
+	params := url.Values{
+		"Action":                            {"SendMessageBatch"},
+		"QueueUrl":                          {"http://sqs/123/q"},
+		"SendMessageBatchRequestEntry.1.Id": {"a"},
+		"SendMessageBatchRequestEntry.1.MessageBody":                          {"one"},
+		"SendMessageBatchRequestEntry.1.DelaySeconds":                         {"5"},
+		"SendMessageBatchRequestEntry.1.MessageAttribute.1.Name":              {"kind"},
+		"SendMessageBatchRequestEntry.1.MessageAttribute.1.Value.DataType":    {"String"},
+		"SendMessageBatchRequestEntry.1.MessageAttribute.1.Value.StringValue": {"order"},
+		"SendMessageBatchRequestEntry.2.Id":                                   {"b"},
+		"SendMessageBatchRequestEntry.2.MessageBody":                          {"two"},
+	}
+	b, err := json.Marshal(jsonParams(params))
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"Entries":[{"DelaySeconds":5,"Id":"a","MessageAttributes":{"kind":{"DataType":"String","StringValue":"order"}},"MessageBody":"one"},{"Id":"b","MessageBody":"two"}],"QueueUrl":"http://sqs/123/q"}`)
+
+	params = url.Values{
+		"MaxNumberOfMessages": {"10"},
+		"AttributeName.1":     {"All"},
+		"Attribute.1.Name":    {"DelaySeconds"},
+		"Attribute.1.Value":   {"10"},
+		"Tag.1.Key":           {"team"},
+		"Tag.1.Value":         {"ops"},
+	}
+	b, err = json.Marshal(jsonParams(params))
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"AttributeNames":["All"],"Attributes":{"DelaySeconds":"10"},"MaxNumberOfMessages":10,"Tags":{"team":"ops"}}`)
+}
+
+func (s *S) TestQueryMethods(c *C) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.ParseForm(), IsNil)
+		action := r.Form.Get("Action")
+		methods = append(methods, r.Method+" "+action)
+		if r.Method == "POST" {
+			c.Check(r.Header.Get("Content-Type"), Equals, "application/x-www-form-urlencoded")
+			c.Check(r.URL.RawQuery, Equals, "")
+		}
+		switch action {
+		case "SendMessage":
+			c.Check(r.PostForm.Get("MessageBody"), Equals, "a & b")
+			fmt.Fprint(w, `<SendMessageResponse><SendMessageResult><MessageId>id</MessageId></SendMessageResult></SendMessageResponse>`)
+		default:
+			fmt.Fprintf(w, `<%sResponse></%sResponse>`, action, action)
+		}
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	var actions []string
+	sqs.Middleware = []Middleware{func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			actions = append(actions, RequestAction(req))
+			return next.Do(req)
+		})
+	}}
+	sqs.DisableChecksums = true
+	q, err := sqs.QueueByArn("arn:aws:sqs:us-east-1:123456789012:jobs")
+	c.Assert(err, IsNil)
+
+	_, err = q.SendMessage("a & b")
+	c.Assert(err, IsNil)
+	_, err = q.ReceiveMessage()
+	c.Assert(err, IsNil)
+	c.Assert(methods, DeepEquals, []string{"POST SendMessage", "GET ReceiveMessage"})
+	c.Assert(actions, DeepEquals, []string{"SendMessage", "ReceiveMessage"})
+}
+
+func (s *S) TestJSONProtocol(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.Header.Get("Content-Type"), Equals, "application/x-amz-json-1.0")
+		body, _ := ioutil.ReadAll(r.Body)
+		var req map[string]interface{}
+		c.Check(json.Unmarshal(body, &req), IsNil)
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSQS.ReceiveMessage":
+			c.Check(req["MaxNumberOfMessages"], Equals, 2.0)
+			fmt.Fprint(w, `{"Messages":[{"MessageId":"id1","ReceiptHandle":"rh1","MD5OfBody":"49f68a5c8493ec2c0bf489821c21fc3b","Body":"hi",`+
+				`"Attributes":{"ApproximateReceiveCount":"3"},"MessageAttributes":{"blob":{"DataType":"Binary","BinaryValue":"AQI="}}}]}`)
+		case "AmazonSQS.SendMessage":
+			c.Check(req["MessageBody"], Equals, "hi")
+			fmt.Fprint(w, `{"MessageId":"id2","MD5OfMessageBody":"49f68a5c8493ec2c0bf489821c21fc3b"}`)
+		case "AmazonSQS.GetQueueAttributes":
+			fmt.Fprint(w, `{"Attributes":{"ApproximateNumberOfMessages":"7"}}`)
+		case "AmazonSQS.DeleteQueue":
+			w.Header().Set("X-Amzn-Query-Error", "AWS.SimpleQueueService.NonExistentQueue;Sender")
+			w.Header().Set("X-Amzn-Requestid", "req-1")
+			w.WriteHeader(400)
+			fmt.Fprint(w, `{"__type":"com.amazonaws.sqs#QueueDoesNotExist","message":"The specified queue does not exist."}`)
+		default:
+			w.WriteHeader(400)
+		}
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	sqs.Protocol = JSONProtocol
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 2})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "hi")
+	c.Assert(msgs[0].SystemAttributes.ApproximateReceiveCount, Equals, 3)
+	c.Assert(msgs[0].MessageAttributes["blob"].BinaryValue, DeepEquals, []byte{1, 2})
+
+	id, err := q.SendMessage("hi")
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, "id2")
+
+	attrs, err := q.GetQueueAttributes(ApproximateNumberOfMessages)
+	c.Assert(err, IsNil)
+	c.Assert(attrs.ApproximateNumberOfMessages(), Equals, 7)
+
+	err = q.DeleteQueue()
+	c.Assert(IsNonExistentQueue(err), Equals, true)
+	c.Assert(err.(*ErrorResponse).RequestId, Equals, "req-1")
+	c.Assert(err.(*ErrorResponse).EmbeddedError.Message, Equals, "The specified queue does not exist.")
+}
+
+func (s *S) TestJSONMessageMoveTasks(c *C) {
+	var resp listMessageMoveTasksResponse
+	body := `{"Results":[{"TaskHandle":"task-1","Status":"RUNNING","SourceArn":"arn:aws:sqs:us-east-1:123456789012:jobs-dead",` +
+		`"ApproximateNumberOfMessagesMoved":20,"ApproximateNumberOfMessagesToMove":100,"StartedTimestamp":1700000000000}]}`
+	c.Assert(jsonProtocol{}.decode(strings.NewReader(body), &resp), IsNil)
+	c.Assert(resp.Tasks, HasLen, 1)
+	c.Assert(resp.Tasks[0].TaskHandle, Equals, "task-1")
+	c.Assert(resp.Tasks[0].ApproximateNumberOfMessagesMoved, Equals, int64(20))
+	c.Assert(resp.Tasks[0].Started().Unix(), Equals, int64(1700000000))
+
+	b, err := json.Marshal(jsonParams(url.Values{"SourceArn": {"arn"}, "MaxNumberOfMessagesPerSecond": {"50"}}))
+	c.Assert(err, IsNil)
+	c.Assert(string(b), Equals, `{"MaxNumberOfMessagesPerSecond":50,"SourceArn":"arn"}`)
+}
+
+func (s *S) TestJSONSystemAttributes(c *C) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSQS.SendMessage":
+			fmt.Fprint(w, `{"MessageId":"id1"}`)
+		case "AmazonSQS.SendMessageBatch":
+			fmt.Fprint(w, `{"Successful":[{"Id":"a","MessageId":"id2"}]}`)
+		}
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	sqs.Protocol = JSONProtocol
+	sqs.DisableChecksums = true
+	q := &Queue{SQS: sqs, path: "/123/q"}
+
+	_, err := q.SendMessageWithOpt("hi", &SendMessageOpt{AWSTraceHeader: "Root=1"})
+	c.Assert(err, IsNil)
+	_, err = q.SendMessageBatch([]SendMessageBatchEntry{{Id: "a", Body: "hi", AWSTraceHeader: "Root=2"}})
+	c.Assert(err, IsNil)
+	c.Assert(bodies, DeepEquals, []string{
+		`{"MessageBody":"hi","MessageSystemAttributes":{"AWSTraceHeader":{"DataType":"String","StringValue":"Root=1"}},"QueueUrl":"` + srv.URL + `/123/q"}`,
+		`{"Entries":[{"Id":"a","MessageBody":"hi","MessageSystemAttributes":{"AWSTraceHeader":{"DataType":"String","StringValue":"Root=2"}}}],"QueueUrl":"` + srv.URL + `/123/q"}`,
+	})
+}
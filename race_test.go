@@ -0,0 +1,141 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	. "launchpad.net/gocheck"
+)
+
+// Run with -race.
+func (s *S) TestConcurrentSendReceive(c *C) {
+	sqs := NewLocal().SQS()
+	sqs.Metrics = NewExpvarMetrics("sqs_race_test")
+	sqs.RateLimiter = NewRateLimiter(0, 0)
+	sqs.CircuitBreaker = &CircuitBreaker{}
+	sqs.SLO = NewSLOTracker(SLO{})
+	q, err := sqs.CreateQueue("race", nil)
+	c.Assert(err, IsNil)
+
+	const senders, perSender = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < perSender; j++ {
+				if _, err := q.SendMessage(fmt.Sprintf("%d-%d", i, j)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(i)
+	}
+	var received int64
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt64(&received) < senders*perSender {
+				msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10, WaitTimeSeconds: 1})
+				if err != nil {
+					errs <- err
+					return
+				}
+				for _, m := range msgs {
+					if err := q.DeleteMessage(m); err != nil {
+						errs <- err
+						return
+					}
+					mu.Lock()
+					if !seen[m.Body] {
+						seen[m.Body] = true
+						atomic.AddInt64(&received, 1)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		c.Fatal(err)
+	}
+	c.Assert(seen, HasLen, senders*perSender)
+}
+
+func (s *S) TestRetryDoesNotReuseSignedParams(c *C) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "<ErrorResponse><Error><Code>OverLimit</Code></Error></ErrorResponse>")
+			return
+		}
+		fmt.Fprint(w, "<GetQueueAttributesResponse></GetQueueAttributesResponse>")
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	sqs.MaxRetries = 1
+	var signatures []string
+	sqs.Hooks.BeforeSign = func(action string, params url.Values) {
+		signatures = append(signatures, params.Get("Signature"))
+	}
+	params := url.Values{"AttributeName.1": {"All"}}
+	var resp QueueAttributes
+	c.Assert(sqs.call("GetQueueAttributes", "/123/q", params, &resp), IsNil)
+	c.Assert(calls, Equals, int32(2))
+	c.Assert(params, DeepEquals, url.Values{"AttributeName.1": {"All"}})
+	c.Assert(signatures, DeepEquals, []string{"", ""})
+}
+
+func (s *S) TestNewRequestLeavesParamsUntouched(c *C) {
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Hooks.BeforeSign = func(action string, params url.Values) {
+		params.Set("Extra", "1")
+	}
+	params := url.Values{"QueueName": {"q"}}
+	want := url.Values{"QueueName": {"q"}}
+
+	req, err := sqs.newRequest(context.Background(), "GET", "GetQueueUrl", "http://sqs.invalid/", params)
+	c.Assert(err, IsNil)
+	c.Assert(params, DeepEquals, want)
+	query := req.URL.Query()
+	c.Assert(query.Get("Action"), Equals, "GetQueueUrl")
+	c.Assert(query.Get("Extra"), Equals, "1")
+	c.Assert(query.Get("Signature"), Not(Equals), "")
+
+	req, err = sqs.newRequest(context.Background(), "POST", "GetQueueUrl", "http://sqs.invalid/", params)
+	c.Assert(err, IsNil)
+	c.Assert(params, DeepEquals, want)
+	c.Assert(req.URL.RawQuery, Equals, "")
+	b, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	form, err := url.ParseQuery(string(b))
+	c.Assert(err, IsNil)
+	c.Assert(form.Get("QueueName"), Equals, "q")
+	c.Assert(form.Get("Signature"), Not(Equals), "")
+
+	// Run with -race: goroutines may share one parameter map.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sqs.newRequest(context.Background(), "GET", "GetQueueUrl", "http://sqs.invalid/", params)
+		}()
+	}
+	wg.Wait()
+	c.Assert(params, DeepEquals, want)
+}
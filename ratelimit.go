@@ -0,0 +1,105 @@
+package sqs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A RateLimiter caps the requests per second a client issues, globally and
+// per action, with token buckets. Requests over the limit wait for a token
+// rather than fail, which smooths the bursts of scaled-out consumers and
+// batch flushes before they turn into throttling. A RateLimiter may be
+// shared by several clients.
+type RateLimiter struct {
+	mu      sync.Mutex
+	global  *tokenBucket
+	actions map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token at now and returns how long to wait before it may
+// be used. The tokens may go negative, queueing later callers behind it.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(rate)
+		if burst < 1 {
+			burst = 1
+		}
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// NewRateLimiter returns a limiter allowing rate requests per second, with
+// bursts of up to burst requests; burst defaults to rate, at least 1. A
+// rate of zero imposes no global limit, for limiters with only per-action
+// limits.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{global: newTokenBucket(rate, burst), actions: make(map[string]*tokenBucket)}
+}
+
+// Limit additionally caps the requests of action, e.g. "SendMessageBatch",
+// and returns l. Requests of action count towards both limits.
+func (l *RateLimiter) Limit(action string, rate float64, burst int) *RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b := newTokenBucket(rate, burst); b != nil {
+		l.actions[action] = b
+	} else {
+		delete(l.actions, action)
+	}
+	return l
+}
+
+// Wait blocks until a request of action may be issued, or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context, action string) error {
+	now := time.Now()
+	l.mu.Lock()
+	var d time.Duration
+	var reserved []*tokenBucket
+	for _, b := range []*tokenBucket{l.global, l.actions[action]} {
+		if b == nil {
+			continue
+		}
+		reserved = append(reserved, b)
+		if w := b.reserve(now); w > d {
+			d = w
+		}
+	}
+	l.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	if sleepContext(ctx, d) {
+		return nil
+	}
+	// Give the tokens back so requests that are still waiting move up.
+	l.mu.Lock()
+	for _, b := range reserved {
+		b.tokens++
+	}
+	l.mu.Unlock()
+	return ctx.Err()
+}
@@ -0,0 +1,46 @@
+package sqs
+
+import (
+	"context"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestRateLimiter(c *C) {
+	l := NewRateLimiter(0, 0).Limit("SendMessage", 100, 2)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		c.Assert(l.Wait(ctx, "SendMessage"), IsNil)
+	}
+	// The burst of 2 passes at once, the other 4 at 100 per second.
+	elapsed := time.Since(start)
+	c.Assert(elapsed >= 35*time.Millisecond, Equals, true, Commentf("%s", elapsed))
+
+	// Other actions are not limited.
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		c.Assert(l.Wait(ctx, "ReceiveMessage"), IsNil)
+	}
+	c.Assert(time.Since(start) < 10*time.Millisecond, Equals, true)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	c.Assert(l.Wait(cctx, "SendMessage"), Equals, context.Canceled)
+}
+
+func (s *S) TestRateLimitedClient(c *C) {
+	sqs := NewLocal().SQS()
+	sqs.RateLimiter = NewRateLimiter(50, 1)
+	q, err := sqs.CreateQueue("q", nil)
+	c.Assert(err, IsNil)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err = q.SendMessage("hi")
+		c.Assert(err, IsNil)
+	}
+	// Four requests: the first passes, the other three wait 20ms each.
+	elapsed := time.Since(start)
+	c.Assert(elapsed >= 50*time.Millisecond, Equals, true, Commentf("%s", elapsed))
+}
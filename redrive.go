@@ -0,0 +1,118 @@
+package sqs
+
+import "context"
+
+// RedriveOpt configures Redrive.
+type RedriveOpt struct {
+	// Max is the number of messages moved, at most; zero moves every
+	// message.
+	Max int
+	// Rate caps the messages moved per second; zero does not limit it.
+	Rate float64
+	// Filter, when set, selects the messages to move. The others stay in
+	// the queue.
+	Filter func(m *Message) bool
+	// DryRun counts the messages that would be moved without moving them.
+	DryRun bool
+	// Annotation is recorded on the moved messages, with its Action
+	// defaulting to "redrive"; see MoveMessage.
+	Annotation Annotation
+	// VisibilityTimeout hides the messages seen during the redrive, so
+	// that each is considered once, in seconds; default 60. Messages left
+	// in the queue are made visible again once the redrive is done.
+	VisibilityTimeout int
+}
+
+// RedriveResult counts the messages of a redrive.
+type RedriveResult struct {
+	Moved   int // or would have been, with DryRun
+	Skipped int // by Filter
+}
+
+// Redrive moves the messages of q, typically a dead letter queue, back to
+// dst with MoveMessage, stopping once q appears empty, opt.Max messages were
+// moved or ctx is done. Each message is considered once.
+//
+// It returns the counts so far along with any error.
+func (q *Queue) Redrive(ctx context.Context, dst *Queue, opt *RedriveOpt) (*RedriveResult, error) {
+	if opt == nil {
+		opt = &RedriveOpt{}
+	}
+	note := opt.Annotation
+	if note.Action == "" {
+		note.Action = "redrive"
+	}
+	timeout := opt.VisibilityTimeout
+	if timeout <= 0 {
+		timeout = 60
+	}
+	var limiter *RateLimiter
+	if opt.Rate > 0 {
+		limiter = NewRateLimiter(opt.Rate, 1)
+	}
+	res := &RedriveResult{}
+	if err := dst.awaitPurge(ctx); err != nil {
+		return res, err
+	}
+	var left []*Message
+	seen := make(map[string]bool)
+	err := func() error {
+		for idle := 0; idle < 3; {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			n := 10
+			if opt.Max > 0 && opt.Max-res.Moved < n {
+				n = opt.Max - res.Moved
+			}
+			msgs, err := q.WithContext(ctx).ReceiveMessages(&ReceiveMessageOpt{
+				MaxNumberOfMessages:   n,
+				VisibilityTimeout:     timeout,
+				MessageAttributeNames: []string{"All"},
+				AttributeNames:        []Attribute{All},
+			})
+			if err != nil {
+				return err
+			}
+			idle++
+			for _, m := range msgs {
+				if seen[m.Id] {
+					continue
+				}
+				seen[m.Id] = true
+				idle = 0
+				switch {
+				case opt.Max > 0 && res.Moved == opt.Max:
+					left = append(left, m)
+				case opt.Filter != nil && !opt.Filter(m):
+					res.Skipped++
+					left = append(left, m)
+				case opt.DryRun:
+					res.Moved++
+					left = append(left, m)
+				default:
+					if limiter != nil {
+						if err := limiter.Wait(ctx, ""); err != nil {
+							return err
+						}
+					}
+					if _, err := q.MoveMessage(m, dst, &note); err != nil {
+						return err
+					}
+					res.Moved++
+				}
+			}
+			if opt.Max > 0 && res.Moved == opt.Max {
+				return nil
+			}
+		}
+		return nil
+	}()
+	// Release the messages left behind, even if ctx is done.
+	for _, m := range left {
+		if e := q.ChangeMessageVisibility(m, 0); e != nil && err == nil {
+			err = e
+		}
+	}
+	return res, err
+}
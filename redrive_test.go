@@ -0,0 +1,50 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestRedrive(c *C) {
+	sqs := NewLocal().SQS()
+	dlq, err := sqs.CreateQueue("jobs-dlq", nil)
+	c.Assert(err, IsNil)
+	jobs, err := sqs.CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 5; i++ {
+		_, err := dlq.SendMessageWithOpt(fmt.Sprintf("m%d", i), &SendMessageOpt{
+			MessageAttributes: MessageAttributes{"tenant": {DataType: "String", StringValue: fmt.Sprint(i % 2)}},
+		})
+		c.Assert(err, IsNil)
+	}
+	odd := func(m *Message) bool { return m.MessageAttributes["tenant"].StringValue == "1" }
+
+	res, err := dlq.Redrive(context.Background(), jobs, &RedriveOpt{Filter: odd, DryRun: true})
+	c.Assert(err, IsNil)
+	c.Assert(*res, Equals, RedriveResult{Moved: 2, Skipped: 3})
+
+	res, err = dlq.Redrive(context.Background(), jobs, &RedriveOpt{
+		Filter:     odd,
+		Max:        1,
+		Annotation: Annotation{By: "oncall", Reason: "bug fixed"},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(res.Moved, Equals, 1)
+
+	res, err = dlq.Redrive(context.Background(), jobs, &RedriveOpt{Rate: 1000})
+	c.Assert(err, IsNil)
+	c.Assert(*res, Equals, RedriveResult{Moved: 4})
+
+	msgs, err := jobs.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10, MessageAttributeNames: []string{"All"}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 5)
+	for _, m := range msgs {
+		as, err := Annotations(m)
+		c.Assert(err, IsNil)
+		c.Assert(as, HasLen, 1)
+		c.Assert(as[0].Action, Equals, "redrive")
+		c.Assert(as[0].Source, Equals, "jobs-dlq")
+	}
+}
@@ -0,0 +1,212 @@
+package sqs
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WriteMessageDump writes msgs to w as JSON lines, one message per line,
+// to be replayed with NewReplay.
+func WriteMessageDump(w io.Writer, msgs []*Message) error {
+	enc := json.NewEncoder(w)
+	for _, m := range msgs {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadMessageDump reads messages written by WriteMessageDump.
+func ReadMessageDump(r io.Reader) ([]*Message, error) {
+	var msgs []*Message
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var m Message
+		err := dec.Decode(&m)
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sqs: invalid message dump: %s", err)
+		}
+		msgs = append(msgs, &m)
+	}
+}
+
+// A Replay serves recorded messages as if they were a queue, so that a
+// Consumer, or any other code using the package, can be run offline
+// against production traffic with the same Handler. It is a Doer that
+// answers the requests of the queue returned by Queue in-process, with
+// receive, delete and visibility semantics like those of SQS.
+type Replay struct {
+	queue *Queue
+
+	mu      sync.Mutex
+	entries []*replayEntry
+	handles map[string]*replayEntry
+	left    int
+	deleted []*Message
+	seq     int
+	drained chan struct{}
+}
+
+type replayEntry struct {
+	m         *Message
+	visibleAt time.Time
+	deleted   bool
+}
+
+// NewReplay returns a replay of msgs, delivered in order.
+func NewReplay(msgs []*Message) *Replay {
+	r := &Replay{handles: make(map[string]*replayEntry), left: len(msgs), drained: make(chan struct{})}
+	for _, m := range msgs {
+		m2 := *m
+		r.entries = append(r.entries, &replayEntry{m: &m2})
+	}
+	if r.left == 0 {
+		close(r.drained)
+	}
+	sqs := &SQS{Endpoint: "http://replay.invalid", Client: r}
+	r.queue = &Queue{SQS: sqs, path: "/000000000000/replay"}
+	return r
+}
+
+// Queue returns the queue served by r. Its client may be configured
+// further, e.g. with Transformers, before use.
+func (r *Replay) Queue() *Queue {
+	return r.queue
+}
+
+// Drained returns a channel closed once every message was deleted.
+func (r *Replay) Drained() <-chan struct{} {
+	return r.drained
+}
+
+// Deleted returns the messages deleted so far, in order.
+func (r *Replay) Deleted() []*Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Message(nil), r.deleted...)
+}
+
+// Do implements Doer.
+func (r *Replay) Do(req *http.Request) (*http.Response, error) {
+	params := req.URL.Query()
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if form, err := url.ParseQuery(string(b)); err == nil {
+			for k, v := range form {
+				params[k] = v
+			}
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var body interface{}
+	switch action := params.Get("Action"); action {
+	case "ReceiveMessage":
+		body = r.receive(params)
+	case "DeleteMessage":
+		if !r.settle(params.Get("ReceiptHandle"), -1) {
+			return xmlError(req, ErrCodeReceiptHandleIsInvalid, ""), nil
+		}
+		body = struct {
+			XMLName xml.Name `xml:"DeleteMessageResponse"`
+		}{}
+	case "DeleteMessageBatch":
+		body = r.deleteBatch(params)
+	case "ChangeMessageVisibility":
+		timeout, _ := strconv.Atoi(params.Get("VisibilityTimeout"))
+		if !r.settle(params.Get("ReceiptHandle"), timeout) {
+			return xmlError(req, ErrCodeReceiptHandleIsInvalid, ""), nil
+		}
+		body = struct {
+			XMLName xml.Name `xml:"ChangeMessageVisibilityResponse"`
+		}{}
+	default:
+		return xmlError(req, ErrCodeInvalidAction, ""), nil
+	}
+	b, err := xml.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return xmlResponse(req, http.StatusOK, b), nil
+}
+
+// receive delivers up to MaxNumberOfMessages visible messages.
+func (r *Replay) receive(params url.Values) *xmlReceiveResponse {
+	max, _ := strconv.Atoi(params.Get("MaxNumberOfMessages"))
+	if max <= 0 {
+		max = 1
+	}
+	timeout := 30
+	if v := params.Get("VisibilityTimeout"); v != "" {
+		timeout, _ = strconv.Atoi(v)
+	}
+	now := time.Now()
+	resp := &xmlReceiveResponse{}
+	for _, e := range r.entries {
+		if len(resp.Messages) == max {
+			break
+		}
+		if e.deleted || now.Before(e.visibleAt) {
+			continue
+		}
+		r.seq++
+		e.m.ReceiptHandle = fmt.Sprintf("%s#%d", e.m.Id, r.seq)
+		e.m.SystemAttributes.ApproximateReceiveCount++
+		e.visibleAt = now.Add(time.Duration(timeout) * time.Second)
+		r.handles[e.m.ReceiptHandle] = e
+		resp.Messages = append(resp.Messages, toXMLMessage(e.m))
+	}
+	return resp
+}
+
+// settle deletes the message with the given receipt handle or, with a
+// timeout of zero or more, changes its visibility. It reports whether the
+// handle was valid.
+func (r *Replay) settle(handle string, timeout int) bool {
+	e, ok := r.handles[handle]
+	if !ok || e.deleted {
+		return false
+	}
+	if timeout >= 0 {
+		e.visibleAt = time.Now().Add(time.Duration(timeout) * time.Second)
+		return true
+	}
+	e.deleted = true
+	r.deleted = append(r.deleted, e.m)
+	if r.left--; r.left == 0 {
+		close(r.drained)
+	}
+	return true
+}
+
+func (r *Replay) deleteBatch(params url.Values) *xmlDeleteBatchResponse {
+	resp := &xmlDeleteBatchResponse{}
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.", i)
+		id := params.Get(prefix + "Id")
+		if id == "" {
+			return resp
+		}
+		if r.settle(params.Get(prefix+"ReceiptHandle"), -1) {
+			resp.Successful = append(resp.Successful, DeleteMessageBatchResultEntry{Id: id})
+		} else {
+			resp.Failed = append(resp.Failed, BatchResultErrorEntry{Id: id, Code: ErrCodeReceiptHandleIsInvalid, SenderFault: true})
+		}
+	}
+}
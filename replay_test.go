@@ -0,0 +1,66 @@
+package sqs
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestMessageDumpRoundTrip(c *C) {
+	msgs := []*Message{
+		{Id: "a", Body: "one", MessageAttributes: MessageAttributes{"k": {DataType: "String", StringValue: "v"}}},
+		{Id: "b", Body: "two"},
+	}
+	var buf bytes.Buffer
+	c.Assert(WriteMessageDump(&buf, msgs), IsNil)
+	got, err := ReadMessageDump(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(got, DeepEquals, msgs)
+
+	_, err = ReadMessageDump(bytes.NewBufferString("{"))
+	c.Assert(err, ErrorMatches, "sqs: invalid message dump: .*")
+}
+
+func (s *S) TestReplayConsumer(c *C) {
+	r := NewReplay([]*Message{
+		{Id: "a", Body: "one", MessageAttributes: MessageAttributes{"k": {DataType: "String", StringValue: "v"}}},
+		{Id: "b", Body: "fail once"},
+		{Id: "c", Body: "three"},
+	})
+	var mu sync.Mutex
+	failed := false
+	var attrs []string
+	consumer := &Consumer{
+		Queue:                 r.Queue(),
+		MessageAttributeNames: []string{"All"},
+		Handler: HandlerFunc(func(m *Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if v, err := m.GetString("k"); err == nil {
+				attrs = append(attrs, v)
+			}
+			if m.Body == "fail once" && !failed {
+				failed = true
+				return errors.New("failed")
+			}
+			return nil
+		}),
+	}
+	consumer.Start()
+	select {
+	case <-r.Drained():
+	case <-time.After(5 * time.Second):
+		c.Fatal("replay not drained")
+	}
+	consumer.Stop()
+
+	counts := make(map[string]int)
+	for _, m := range r.Deleted() {
+		counts[m.Id] = m.SystemAttributes.ApproximateReceiveCount
+	}
+	c.Assert(counts, DeepEquals, map[string]int{"a": 1, "b": 2, "c": 1})
+	c.Assert(attrs, DeepEquals, []string{"v"})
+}
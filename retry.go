@@ -0,0 +1,75 @@
+package sqs
+
+import "time"
+
+// A RetryPolicy decides what becomes of a message whose handler failed:
+// when it is redelivered, or whether it is given up on.
+type RetryPolicy interface {
+	// Retry returns the decision for m, which failed with err. m carries
+	// its ApproximateReceiveCount.
+	Retry(m *Message, err error) RetryDecision
+}
+
+// A RetryDecision is the outcome of a RetryPolicy.
+type RetryDecision struct {
+	// Delay is how long the message stays invisible before it is
+	// redelivered, rounded up to the second.
+	Delay time.Duration
+	// DeadLetterQueue, if set, receives the message, which is deleted
+	// from its queue instead of being redelivered.
+	DeadLetterQueue *Queue
+}
+
+// RetryPolicyFunc adapts a function to the RetryPolicy interface.
+type RetryPolicyFunc func(m *Message, err error) RetryDecision
+
+// Retry implements RetryPolicy.
+func (f RetryPolicyFunc) Retry(m *Message, err error) RetryDecision {
+	return f(m, err)
+}
+
+// ExponentialBackoff is a RetryPolicy redelivering a message received n
+// times after Base * 2^(n-1), up to Max, and moving it to DeadLetterQueue
+// once it was received MaxReceives times.
+//
+// Unlike a RedrivePolicy, which counts the receives of messages that were
+// never handled too, such as those of a crashed consumer, it only moves
+// messages whose handler failed, and records the error in an Annotation.
+type ExponentialBackoff struct {
+	// Base is the delay after the first failure; it defaults to one
+	// second.
+	Base time.Duration
+	// Max caps the delay; it defaults to MaxVisibilityTimeout.
+	Max time.Duration
+
+	// MaxReceives, if positive and DeadLetterQueue is set, is the number
+	// of receives after which a failed message is dead lettered.
+	MaxReceives     int
+	DeadLetterQueue *Queue
+}
+
+// Retry implements RetryPolicy.
+func (b *ExponentialBackoff) Retry(m *Message, err error) RetryDecision {
+	n := m.SystemAttributes.ApproximateReceiveCount
+	if n < 1 {
+		n = 1
+	}
+	if b.DeadLetterQueue != nil && b.MaxReceives > 0 && n >= b.MaxReceives {
+		return RetryDecision{DeadLetterQueue: b.DeadLetterQueue}
+	}
+	base, max := b.Base, b.Max
+	if base <= 0 {
+		base = time.Second
+	}
+	if max <= 0 || max > MaxVisibilityTimeout {
+		max = MaxVisibilityTimeout
+	}
+	d := base
+	for i := 1; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return RetryDecision{Delay: d}
+}
@@ -0,0 +1,70 @@
+package sqs
+
+import (
+	"errors"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestExponentialBackoff(c *C) {
+	b := &ExponentialBackoff{Base: 10 * time.Second, Max: time.Minute}
+	var delays []time.Duration
+	for n := 0; n <= 5; n++ {
+		m := &Message{SystemAttributes: SystemAttributes{ApproximateReceiveCount: n}}
+		delays = append(delays, b.Retry(m, nil).Delay)
+	}
+	c.Assert(delays, DeepEquals, []time.Duration{10 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute})
+
+	dlq := &Queue{path: "/" + LocalAccountId + "/dead"}
+	b.MaxReceives, b.DeadLetterQueue = 3, dlq
+	m := &Message{SystemAttributes: SystemAttributes{ApproximateReceiveCount: 3}}
+	c.Assert(b.Retry(m, nil), DeepEquals, RetryDecision{DeadLetterQueue: dlq})
+}
+
+func (s *S) TestConsumerRetryPolicy(c *C) {
+	l := NewLocal()
+	sqs := l.SQS()
+	q, err := sqs.CreateQueue("backoff", nil)
+	c.Assert(err, IsNil)
+	dlq, err := sqs.CreateQueue("backoff-dead", nil)
+	c.Assert(err, IsNil)
+	q.SendMessage("poison")
+
+	// Record the delays of the backoff, but redeliver at once.
+	backoff := &ExponentialBackoff{Base: 30 * time.Second, MaxReceives: 3, DeadLetterQueue: dlq}
+	delays := make(chan time.Duration, 10)
+	consumer := &Consumer{
+		Queue: q,
+		Handler: HandlerFunc(func(m *Message) error {
+			return errors.New("cannot parse")
+		}),
+		RetryPolicy: RetryPolicyFunc(func(m *Message, err error) RetryDecision {
+			d := backoff.Retry(m, err)
+			delays <- d.Delay
+			d.Delay = 0
+			return d
+		}),
+	}
+	consumer.Start()
+	var msgs []*Message
+	for i := 0; i < 100 && len(msgs) == 0; i++ {
+		msgs, err = dlq.ReceiveMessages(&ReceiveMessageOpt{MessageAttributeNames: []string{"All"}})
+		c.Assert(err, IsNil)
+		time.Sleep(10 * time.Millisecond)
+	}
+	r := consumer.Stop()
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "poison")
+	notes, err := Annotations(msgs[0])
+	c.Assert(err, IsNil)
+	c.Assert(notes, HasLen, 1)
+	c.Assert(notes[0].Action, Equals, "dead letter")
+	c.Assert(notes[0].Reason, Equals, "cannot parse")
+	c.Assert([]time.Duration{<-delays, <-delays, <-delays}, DeepEquals, []time.Duration{30 * time.Second, time.Minute, 0})
+	c.Assert(r.Nacked, Equals, int64(2))
+	c.Assert(r.DeadLettered, Equals, int64(1))
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Empty(), Equals, true)
+}
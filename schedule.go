@@ -0,0 +1,98 @@
+package sqs
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeliverAtAttribute is the message attribute holding the delivery time,
+// in RFC 3339 format, of a message scheduled by a Scheduler beyond
+// MaxDelaySeconds.
+const DeliverAtAttribute = "deliver-at"
+
+// A Scheduler sends messages to Queue for delivery at a given time, however
+// far, while SQS delays messages by MaxDelaySeconds at most: messages due
+// later are sent with the longest delay and their delivery time in
+// DeliverAtAttribute, then sent again by the Handler of the scheduler
+// until due. It needs standard queues, FIFO queues not supporting delays
+// per message.
+type Scheduler struct {
+	Queue *Queue
+	// Now returns the current time; it defaults to time.Now.
+	Now func() time.Time
+}
+
+func (s *Scheduler) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// SendAt sends a message delivered at time at, or at once if at is past.
+// The DelaySeconds of opt is ignored; opt may be nil. It returns the ID of
+// the message sent, which changes each time the message is sent again.
+func (s *Scheduler) SendAt(body string, at time.Time, opt *SendMessageOpt) (string, error) {
+	var o SendMessageOpt
+	if opt != nil {
+		o = *opt
+	}
+	return s.send(body, at, o)
+}
+
+// send sends a message delivered at time at with the options o, its delay
+// set up to MaxDelaySeconds.
+func (s *Scheduler) send(body string, at time.Time, o SendMessageOpt) (string, error) {
+	left := at.Sub(s.now())
+	o.DelaySeconds = 0
+	if left > 0 {
+		o.DelaySeconds = visibilitySeconds(left)
+	}
+	attrs := make(MessageAttributes, len(o.MessageAttributes)+1)
+	for name, v := range o.MessageAttributes {
+		if name != DeliverAtAttribute {
+			attrs[name] = v
+		}
+	}
+	if o.DelaySeconds > MaxDelaySeconds {
+		o.DelaySeconds = MaxDelaySeconds
+		attrs[DeliverAtAttribute] = MessageAttributeValue{
+			DataType:    "String",
+			StringValue: at.UTC().Format(time.RFC3339Nano),
+		}
+	}
+	o.MessageAttributes = attrs
+	return s.Queue.SendMessageWithOpt(body, &o)
+}
+
+// Handler returns a Handler passing the messages that are due to h, without
+// their DeliverAtAttribute, and sending the others again for later
+// delivery. The Consumer using it must receive DeliverAtAttribute, as it
+// does by default, and counts each message sent again as Acked.
+func (s *Scheduler) Handler(h Handler) Handler {
+	return HandlerFunc(func(m *Message) error {
+		v, ok := m.MessageAttributes[DeliverAtAttribute]
+		if !ok {
+			return h.HandleMessage(m)
+		}
+		at, err := time.Parse(time.RFC3339Nano, v.StringValue)
+		if err != nil {
+			return fmt.Errorf("sqs: %s attribute of message %s: %w", DeliverAtAttribute, m.Id, err)
+		}
+		if at.After(s.now()) {
+			_, err := s.send(m.Body, at, SendMessageOpt{
+				MessageAttributes: m.MessageAttributes,
+				AWSTraceHeader:    m.SystemAttributes.AWSTraceHeader,
+			})
+			return err
+		}
+		due := *m
+		due.MessageAttributes = make(MessageAttributes, len(m.MessageAttributes)-1)
+		for name, v := range m.MessageAttributes {
+			if name != DeliverAtAttribute {
+				due.MessageAttributes[name] = v
+			}
+		}
+		return h.HandleMessage(&due)
+	})
+}
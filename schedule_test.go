@@ -0,0 +1,72 @@
+package sqs
+
+import (
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestSchedulerSendAt(c *C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLocal()
+	l.Now = func() time.Time { return now }
+	q, err := l.SQS().CreateQueue("reminders", nil)
+	c.Assert(err, IsNil)
+	sched := &Scheduler{Queue: q, Now: l.Now}
+
+	var handled []*Message
+	handler := sched.Handler(HandlerFunc(func(m *Message) error {
+		handled = append(handled, m)
+		return nil
+	}))
+	// consume handles the messages visible now, like a Consumer.
+	consume := func() int {
+		msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{
+			MaxNumberOfMessages:   10,
+			MessageAttributeNames: []string{"All"},
+		})
+		c.Assert(err, IsNil)
+		for _, m := range msgs {
+			c.Assert(handler.HandleMessage(m), IsNil)
+			c.Assert(q.DeleteMessage(m), IsNil)
+		}
+		return len(msgs)
+	}
+
+	_, err = sched.SendAt("soon", now.Add(time.Minute), nil)
+	c.Assert(err, IsNil)
+	_, err = sched.SendAt("tomorrow", now.Add(24*time.Hour), &SendMessageOpt{
+		MessageAttributes: MessageAttributes{"user": {DataType: "String", StringValue: "ann"}},
+	})
+	c.Assert(err, IsNil)
+	c.Assert(consume(), Equals, 0)
+
+	now = now.Add(time.Minute)
+	c.Assert(consume(), Equals, 1)
+	c.Assert(handled, HasLen, 1)
+	c.Assert(handled[0].Body, Equals, "soon")
+
+	hops := 0
+	for len(handled) < 2 {
+		now = now.Add(MaxDelaySeconds * time.Second)
+		hops += consume()
+	}
+	// One receive per MaxDelaySeconds, the last one delivering it.
+	c.Assert(hops, Equals, 24*3600/MaxDelaySeconds)
+	c.Assert(handled[1].Body, Equals, "tomorrow")
+	c.Assert(handled[1].MessageAttributes, DeepEquals, MessageAttributes{
+		"user": {DataType: "String", StringValue: "ann"},
+	})
+	st, err := q.Stats()
+	c.Assert(err, IsNil)
+	c.Assert(st.Visible+st.InFlight+st.Delayed, Equals, 0)
+}
+
+func (s *S) TestSchedulerInvalidDeliverAt(c *C) {
+	sched := &Scheduler{}
+	handler := sched.Handler(HandlerFunc(func(m *Message) error { return nil }))
+	err := handler.HandleMessage(&Message{Id: "42", MessageAttributes: MessageAttributes{
+		DeliverAtAttribute: {DataType: "String", StringValue: "tomorrow"},
+	}})
+	c.Assert(err, ErrorMatches, `sqs: deliver-at attribute of message 42: .*`)
+}
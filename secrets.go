@@ -0,0 +1,290 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/librato/goamz-aws/aws"
+)
+
+// Well-known secret names looked up by AuthFromSecrets.
+const (
+	SecretAccessKeyId     = "AWS_ACCESS_KEY_ID"
+	SecretSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+)
+
+// ErrSecretNotFound is returned by a SecretsProvider that has no value for
+// the requested name.
+var ErrSecretNotFound = errors.New("sqs: secret not found")
+
+// A SecretsProvider supplies named secrets, such as AWS credentials or
+// client-side encryption keys, on demand. Implementations must be safe for
+// concurrent use.
+type SecretsProvider interface {
+	Secret(name string) ([]byte, error)
+}
+
+// The SecretsFunc type is an adapter to allow the use of an ordinary
+// function, such as a Vault client lookup, as a SecretsProvider.
+type SecretsFunc func(name string) ([]byte, error)
+
+// Secret calls f(name).
+func (f SecretsFunc) Secret(name string) ([]byte, error) {
+	return f(name)
+}
+
+// EnvSecrets reads secrets from environment variables named Prefix+name.
+type EnvSecrets struct {
+	Prefix string
+}
+
+// Secret returns the value of the environment variable Prefix+name.
+func (e EnvSecrets) Secret(name string) ([]byte, error) {
+	v, ok := os.LookupEnv(e.Prefix + name)
+	if !ok || v == "" {
+		return nil, ErrSecretNotFound
+	}
+	return []byte(v), nil
+}
+
+// FileSecrets reads each secret from a file named after it in Dir, the
+// layout used by Kubernetes and Docker secret mounts. Trailing newlines are
+// stripped.
+type FileSecrets struct {
+	Dir string
+}
+
+// Secret returns the contents of the file Dir/name.
+func (f FileSecrets) Secret(name string) ([]byte, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return nil, ErrSecretNotFound
+	}
+	b, err := ioutil.ReadFile(filepath.Join(f.Dir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrSecretNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b, "\r\n"), nil
+}
+
+// AuthFromSecrets builds AWS credentials from the SecretAccessKeyId and
+// SecretSecretAccessKey secrets of p.
+func AuthFromSecrets(p SecretsProvider) (aws.Auth, error) {
+	var auth aws.Auth
+	key, err := p.Secret(SecretAccessKeyId)
+	if err != nil {
+		return auth, err
+	}
+	secret, err := p.Secret(SecretSecretAccessKey)
+	if err != nil {
+		return auth, err
+	}
+	auth.AccessKey = string(key)
+	auth.SecretKey = string(secret)
+	return auth, nil
+}
+
+// NewWithSecrets creates a new SQS whose credentials are read from p. If p
+// is a *RotatingSecrets, the client picks up rotated credentials for
+// subsequent requests without being recreated; both keys are swapped at
+// once, never pairing a new access key with an old secret key.
+func NewWithSecrets(p SecretsProvider, region aws.Region) (*SQS, error) {
+	auth, err := AuthFromSecrets(p)
+	if err != nil {
+		return nil, err
+	}
+	sqs := New(auth, region)
+	if r, ok := p.(*RotatingSecrets); ok {
+		update := func(string, []byte) {
+			if auth, err := AuthFromSecrets(r); err == nil {
+				sqs.setAuth(auth)
+			}
+		}
+		r.OnRotate(SecretAccessKeyId, update)
+		r.OnRotate(SecretSecretAccessKey, update)
+	}
+	return sqs, nil
+}
+
+// SecretKeys is a KeyProvider, like StaticKeys, whose master keys are the
+// secrets of Secrets named Prefix plus the key ID, each a base64-encoded
+// 256-bit key. New data keys are wrapped with the master key named Current;
+// older keys are kept in the provider to decrypt messages sent before a
+// rotation.
+type SecretKeys struct {
+	Secrets SecretsProvider
+	Prefix  string
+	Current string
+}
+
+// master returns the static keys holding the master key keyId.
+func (k *SecretKeys) master(keyId string) (*StaticKeys, error) {
+	v, err := k.Secrets.Secret(k.Prefix + keyId)
+	if err != nil {
+		return nil, fmt.Errorf("sqs: master key %q: %s", keyId, err)
+	}
+	key, err := base64.StdEncoding.DecodeString(string(v))
+	if err != nil {
+		return nil, fmt.Errorf("sqs: master key %q: %s", keyId, err)
+	}
+	return &StaticKeys{Current: keyId, Keys: map[string][]byte{keyId: key}}, nil
+}
+
+// GenerateDataKey implements KeyProvider.
+func (k *SecretKeys) GenerateDataKey(ctx context.Context) (string, []byte, []byte, error) {
+	keys, err := k.master(k.Current)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return keys.GenerateDataKey(ctx)
+}
+
+// DecryptDataKey implements KeyProvider.
+func (k *SecretKeys) DecryptDataKey(ctx context.Context, keyId string, encrypted []byte) ([]byte, error) {
+	keys, err := k.master(keyId)
+	if err != nil {
+		return nil, err
+	}
+	return keys.DecryptDataKey(ctx, keyId, encrypted)
+}
+
+// RotatingSecrets caches the secrets of an underlying provider and
+// periodically re-reads them, invoking the callbacks registered with
+// OnRotate whenever a value changes.
+type RotatingSecrets struct {
+	Provider SecretsProvider
+	Interval time.Duration
+
+	mu       sync.Mutex
+	cache    map[string][]byte
+	watchers map[string][]func(name string, value []byte)
+	stop     chan struct{}
+}
+
+// NewRotatingSecrets returns a RotatingSecrets that refreshes the secrets of
+// p every interval once started.
+func NewRotatingSecrets(p SecretsProvider, interval time.Duration) *RotatingSecrets {
+	return &RotatingSecrets{Provider: p, Interval: interval}
+}
+
+// Secret returns the cached value of name, reading it from the underlying
+// provider on first use.
+func (r *RotatingSecrets) Secret(name string) ([]byte, error) {
+	r.mu.Lock()
+	v, ok := r.cache[name]
+	r.mu.Unlock()
+	if ok {
+		return v, nil
+	}
+	v, err := r.Provider.Secret(name)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	if r.cache == nil {
+		r.cache = make(map[string][]byte)
+	}
+	r.cache[name] = v
+	r.mu.Unlock()
+	return v, nil
+}
+
+// OnRotate registers fn to be called with the new value whenever the secret
+// name changes.
+func (r *RotatingSecrets) OnRotate(name string, fn func(name string, value []byte)) {
+	r.mu.Lock()
+	if r.watchers == nil {
+		r.watchers = make(map[string][]func(string, []byte))
+	}
+	r.watchers[name] = append(r.watchers[name], fn)
+	r.mu.Unlock()
+}
+
+// Refresh re-reads every cached secret and fires the rotation callbacks of
+// those whose value changed. The new values replace the cached ones
+// together, before any callback runs, so that related secrets such as an
+// access key pair rotate as one. If a lookup fails, Refresh returns its
+// error and every secret keeps its previous value.
+func (r *RotatingSecrets) Refresh() error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.cache))
+	for name := range r.cache {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	values := make([][]byte, len(names))
+	for i, name := range names {
+		v, err := r.Provider.Secret(name)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	type rotation struct {
+		name     string
+		value    []byte
+		watchers []func(string, []byte)
+	}
+	var rotated []rotation
+	r.mu.Lock()
+	for i, name := range names {
+		if !bytes.Equal(r.cache[name], values[i]) {
+			rotated = append(rotated, rotation{name, values[i], r.watchers[name]})
+		}
+		r.cache[name] = values[i]
+	}
+	r.mu.Unlock()
+	for _, rot := range rotated {
+		for _, fn := range rot.watchers {
+			fn(rot.name, rot.value)
+		}
+	}
+	return nil
+}
+
+// Start begins refreshing secrets every Interval in the background.
+func (r *RotatingSecrets) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil || r.Interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	go func() {
+		t := time.NewTicker(r.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				r.Refresh()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends background refreshing started by Start.
+func (r *RotatingSecrets) Stop() {
+	r.mu.Lock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.mu.Unlock()
+}
@@ -0,0 +1,122 @@
+package sqs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/librato/goamz-aws/aws"
+	. "launchpad.net/gocheck"
+)
+
+// secretsMap is a SecretsProvider whose secrets can be changed under test.
+type secretsMap struct {
+	mu      sync.Mutex
+	secrets map[string]string
+	err     error
+}
+
+func (m *secretsMap) Secret(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	v, ok := m.secrets[name]
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	return []byte(v), nil
+}
+
+func (m *secretsMap) set(name, value string) {
+	m.mu.Lock()
+	m.secrets[name] = value
+	m.mu.Unlock()
+}
+
+func (s *S) TestNewWithSecretsRotation(c *C) {
+	p := &secretsMap{secrets: map[string]string{
+		SecretAccessKeyId:     "key1",
+		SecretSecretAccessKey: "secret1",
+	}}
+	r := NewRotatingSecrets(p, 0)
+	sqs, err := NewWithSecrets(r, s.sqs.Region)
+	c.Assert(err, IsNil)
+	c.Assert(sqs.Auth, Equals, aws.Auth{AccessKey: "key1", SecretKey: "secret1"})
+
+	// Every callback sees both keys of the rotation.
+	var seen []aws.Auth
+	record := func(string, []byte) {
+		auth, err := AuthFromSecrets(r)
+		c.Check(err, IsNil)
+		seen = append(seen, auth)
+	}
+	r.OnRotate(SecretAccessKeyId, record)
+	r.OnRotate(SecretSecretAccessKey, record)
+	p.set(SecretAccessKeyId, "key2")
+	p.set(SecretSecretAccessKey, "secret2")
+	c.Assert(r.Refresh(), IsNil)
+	rotated := aws.Auth{AccessKey: "key2", SecretKey: "secret2"}
+	c.Assert(seen, DeepEquals, []aws.Auth{rotated, rotated})
+	c.Assert(sqs.Auth, Equals, rotated)
+
+	// A failed lookup rotates neither key.
+	p.set(SecretAccessKeyId, "key3")
+	p.err = errors.New("vault sealed")
+	c.Assert(r.Refresh(), ErrorMatches, "vault sealed")
+	p.err = nil
+	c.Assert(sqs.Auth, Equals, rotated)
+	key, err := r.Secret(SecretAccessKeyId)
+	c.Assert(err, IsNil)
+	c.Assert(string(key), Equals, "key2")
+}
+
+func (s *S) TestFileSecrets(c *C) {
+	dir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(dir, SecretAccessKeyId), []byte("key\n"), 0600), IsNil)
+	f := FileSecrets{Dir: dir}
+	v, err := f.Secret(SecretAccessKeyId)
+	c.Assert(err, IsNil)
+	c.Assert(string(v), Equals, "key")
+	_, err = f.Secret(SecretSecretAccessKey)
+	c.Assert(err, Equals, ErrSecretNotFound)
+	_, err = f.Secret("../" + SecretAccessKeyId)
+	c.Assert(err, Equals, ErrSecretNotFound)
+}
+
+func (s *S) TestSecretKeys(c *C) {
+	master := func(b byte) string {
+		return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+	}
+	p := &secretsMap{secrets: map[string]string{"master/k1": master(1)}}
+	keys := &SecretKeys{Secrets: p, Prefix: "master/", Current: "k1"}
+	sqs := NewLocal().SQS()
+	sqs.Transformers = []Transformer{&Encryption{Keys: keys}}
+	q, err := sqs.CreateQueue("secret", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("top secret")
+	c.Assert(err, IsNil)
+
+	// Rotating the master key keeps older messages readable while the old
+	// key stays in the provider.
+	p.set("master/k2", master(2))
+	keys.Current = "k2"
+	_, err = q.SendMessage("still secret")
+	c.Assert(err, IsNil)
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10})
+	c.Assert(err, IsNil)
+	var bodies []string
+	for _, m := range msgs {
+		bodies = append(bodies, m.Body)
+	}
+	c.Assert(strings.Join(bodies, ","), Equals, "top secret,still secret")
+
+	keys.Current = "k3"
+	_, err = q.SendMessage("lost")
+	c.Assert(err, ErrorMatches, `sqs: generating data key: sqs: master key "k3": sqs: secret not found`)
+}
@@ -1,38 +1,240 @@
 package sqs
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"github.com/librato/goamz-aws/aws"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 )
 
+// A Signer authenticates the requests of a client, adding the parameters
+// or headers its backend expects. Signers must be safe for concurrent use.
+type Signer interface {
+	// Sign signs a request of method to path with creds. params are the
+	// request parameters, private to the request, and header its
+	// headers, including Host.
+	Sign(creds Credentials, method, path string, params url.Values, header http.Header) error
+}
+
+// SignerFunc adapts a function to the Signer interface.
+type SignerFunc func(creds Credentials, method, path string, params url.Values, header http.Header) error
+
+// Sign implements Signer.
+func (f SignerFunc) Sign(creds Credentials, method, path string, params url.Values, header http.Header) error {
+	return f(creds, method, path, params, header)
+}
+
+var (
+	// SignatureV2 signs requests with AWS Signature Version 2, adding
+	// the security token of temporary credentials. It is the default.
+	SignatureV2 Signer = signatureV2{}
+	// SignatureV4 signs requests with AWS Signature Version 4, in the
+	// Authorization header, for the region of the client. It is the only
+	// signature SQS accepts for JSONProtocol requests.
+	SignatureV4 Signer = signatureV4{}
+	// NoSigner leaves requests unsigned, for SQS-compatible backends
+	// without authentication such as ElasticMQ. No credentials are
+	// needed.
+	NoSigner Signer = noSigner{}
+)
+
+// A requestSigner signs finished requests, headers and body included,
+// rather than their parameters.
+type requestSigner interface {
+	signRequest(creds Credentials, region string, req *http.Request, now time.Time) error
+}
+
+type signatureV2 struct{}
+
+func (signatureV2) Sign(creds Credentials, method, path string, params url.Values, header http.Header) error {
+	if creds.SecurityToken != "" {
+		params.Set("SecurityToken", creds.SecurityToken)
+	}
+	sign(creds.auth(), method, path, params, header)
+	return nil
+}
+
+type noSigner struct{}
+
+func (noSigner) Sign(Credentials, string, string, url.Values, http.Header) error {
+	return nil
+}
+
 func sign(auth aws.Auth, method, path string, params url.Values, headers http.Header) {
 	params.Set("AWSAccessKeyId", auth.AccessKey)
 	params.Set("SignatureMethod", "HmacSHA256")
 	params.Set("SignatureVersion", "2")
 
-	var sarray []string
-	for k, v := range params {
-		for _, vi := range v {
-			sarray = append(sarray, aws.Encode(k)+"="+aws.Encode(vi))
+	// The string to sign is written straight to the hash, with the
+	// canonical query built in a pooled buffer. Parameters are sorted by
+	// encoded name, as SQS does, so that AttributeName.1 precedes
+	// AttributeName.10.
+	type param struct{ encoded, name string }
+	keys := make([]param, 0, len(params))
+	for k := range params {
+		keys = append(keys, param{aws.Encode(k), k})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].encoded < keys[j].encoded })
+	buf := getBuffer()
+	defer putBuffer(buf)
+	for _, k := range keys {
+		ek := k.encoded
+		vs := params[k.name]
+		if len(vs) > 1 {
+			vs = append([]string(nil), vs...)
+			sort.Strings(vs)
+		}
+		for _, v := range vs {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(ek)
+			buf.WriteByte('=')
+			buf.WriteString(aws.Encode(v))
 		}
 	}
-	sort.StringSlice(sarray).Sort()
-	joined := strings.Join(sarray, "&")
 
-	host := headers.Get("Host")
-	payload := strings.Join([]string{method, host, path, joined}, "\n")
-	/*println("stringtosign")
-	println(payload)
-	println()*/
 	hash := hmac.New(sha256.New, []byte(auth.SecretKey))
-	hash.Write([]byte(payload))
-	signature := make([]byte, base64.StdEncoding.EncodedLen(hash.Size()))
-	base64.StdEncoding.Encode(signature, hash.Sum(nil))
-	params.Set("Signature", string(signature))
+	for _, s := range [...]string{method, headers.Get("Host"), path} {
+		hash.Write([]byte(s))
+		hash.Write([]byte{'\n'})
+	}
+	hash.Write(buf.Bytes())
+	var sum [sha256.Size]byte
+	params.Set("Signature", base64.StdEncoding.EncodeToString(hash.Sum(sum[:0])))
+}
+
+type signatureV4 struct{}
+
+// Sign implements Signer for callers outside of a client, which sign
+// query protocol requests: params are the query of GET requests and the
+// form body of POST requests. The region is read from the Host header,
+// e.g. sqs.eu-west-1.amazonaws.com, and defaults to us-east-1.
+func (signatureV4) Sign(creds Credentials, method, path string, params url.Values, header http.Header) error {
+	host := header.Get("Host")
+	req := &http.Request{Method: method, URL: &url.URL{Host: host, Path: path}, Header: header}
+	if method == "POST" {
+		req.Body = ioutil.NopCloser(strings.NewReader(params.Encode()))
+	} else {
+		req.URL.RawQuery = params.Encode()
+	}
+	region := "us-east-1"
+	if parts := strings.Split(host, "."); len(parts) == 4 && parts[0] == "sqs" {
+		region = parts[1]
+	}
+	return signatureV4{}.signRequest(creds, region, req, time.Now())
+}
+
+func (signatureV4) signRequest(creds Credentials, region string, req *http.Request, now time.Time) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	signV4(creds, region, "sqs", req, body, now)
+	return nil
+}
+
+// signV4 adds the Authorization header of AWS Signature Version 4 to req,
+// whose body is body, for service in region.
+func signV4(creds Credentials, region, service string, req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	if creds.SecurityToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SecurityToken)
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	// Sign the host and the headers describing the payload or the AWS
+	// request, so that the headers set by the transport do not count.
+	headers := map[string]string{"host": host}
+	for name, v := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payload := sha256.Sum256(body)
+	canonical := strings.Join([]string{
+		req.Method,
+		path,
+		v4Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	hashed := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := []byte("AWS4" + creds.SecretKey)
+	for _, s := range []string{date, region, service, "aws4_request", toSign} {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(s))
+		key = h.Sum(nil)
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(key))
+}
+
+// v4Query returns the canonical query string of Signature Version 4: the
+// parameters sorted and escaped as in RFC 3986.
+func v4Query(q url.Values) string {
+	var pairs []string
+	for k, vs := range q {
+		for _, v := range vs {
+			pairs = append(pairs, v4Escape(k)+"="+v4Escape(v))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// v4Escape escapes every byte of s but the unreserved characters of RFC
+// 3986.
+func v4Escape(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&15])
+		}
+	}
+	return b.String()
 }
@@ -0,0 +1,76 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestSigners(c *C) {
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.SetCredentialsProvider(CredentialsFunc(func() (Credentials, error) {
+		return Credentials{AccessKey: "temp", SecretKey: "secret", SecurityToken: "token"}, nil
+	}))
+	req, err := sqs.newRequest(context.Background(), "GET", "ListQueues", "http://sqs.invalid/", nil)
+	c.Assert(err, IsNil)
+	params := req.URL.Query()
+	c.Assert(params.Get("AWSAccessKeyId"), Equals, "temp")
+	c.Assert(params.Get("SecurityToken"), Equals, "token")
+	c.Assert(params.Get("Signature"), Not(Equals), "")
+
+	// NoSigner needs no credentials.
+	sqs.SetCredentialsProvider(CredentialsFunc(func() (Credentials, error) {
+		return Credentials{}, errors.New("no credentials here")
+	}))
+	sqs.Signer = NoSigner
+	req, err = sqs.newRequest(context.Background(), "GET", "ListQueues", "http://sqs.invalid/", nil)
+	c.Assert(err, IsNil)
+	params = req.URL.Query()
+	c.Assert(params.Get("Action"), Equals, "ListQueues")
+	c.Assert(params.Get("AWSAccessKeyId"), Equals, "")
+	c.Assert(params.Get("Signature"), Equals, "")
+
+	sqs.Signer = SignerFunc(func(creds Credentials, method, path string, params url.Values, header http.Header) error {
+		header.Set("Authorization", "Bearer "+creds.SecretKey)
+		return nil
+	})
+	_, err = sqs.newRequest(context.Background(), "GET", "ListQueues", "http://sqs.invalid/", nil)
+	c.Assert(err, ErrorMatches, "no credentials here")
+	sqs.SetCredentialsProvider(StaticCredentials(Credentials{SecretKey: "gateway-key"}))
+	req, err = sqs.newRequest(context.Background(), "GET", "ListQueues", "http://sqs.invalid/", nil)
+	c.Assert(err, IsNil)
+	c.Assert(req.Header.Get("Authorization"), Equals, "Bearer gateway-key")
+}
+
+func (s *S) TestSignatureV4(c *C) {
+	// The example of the AWS documentation.
+	req, err := http.NewRequest("GET", "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	c.Assert(err, IsNil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds := Credentials{AccessKey: "AKIDEXAMPLE", SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(creds, "us-east-1", "iam", req, nil, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	c.Assert(req.Header.Get("Authorization"), Equals, "AWS4-HMAC-SHA256 "+
+		"Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7")
+
+	// Clients sign the finished request, body included.
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Signer = SignatureV4
+	sqs.Protocol = JSONProtocol
+	sqs.SetCredentials(Credentials{AccessKey: "temp", SecretKey: "secret", SecurityToken: "token"})
+	req, err = sqs.newRequest(context.Background(), "POST", "ListQueues", "https://sqs.us-east-1.amazonaws.com/", url.Values{"QueueNamePrefix": {"jobs"}})
+	c.Assert(err, IsNil)
+	c.Assert(req.URL.RawQuery, Equals, "")
+	c.Assert(req.Header.Get("X-Amz-Security-Token"), Equals, "token")
+	c.Assert(req.Header.Get("Authorization"), Matches, `AWS4-HMAC-SHA256 Credential=temp/\d{8}/`+s.sqs.Region.Name+
+		`/sqs/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target, Signature=[0-9a-f]{64}`)
+	body, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, IsNil)
+	c.Assert(string(body), Equals, `{"QueueNamePrefix":"jobs"}`)
+}
@@ -0,0 +1,176 @@
+package sqs
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// An SLO is a service level objective for queue operations as observed by
+// the client.
+type SLO struct {
+	// SuccessTarget is the fraction of requests that must succeed, e.g.
+	// 0.999.
+	SuccessTarget float64
+	// LatencyThreshold is the duration above which a request counts as
+	// slow, and LatencyTarget the fraction of requests that must be
+	// faster.
+	LatencyThreshold time.Duration
+	LatencyTarget    float64
+	// Window is the sliding window the objectives are evaluated over,
+	// tracked at a resolution of Buckets slots. They default to one hour
+	// and 60 buckets; a Window shorter than Buckets nanoseconds is raised
+	// to it.
+	Window  time.Duration
+	Buckets int
+}
+
+// SLOStats summarizes one action on one queue over the SLO window.
+type SLOStats struct {
+	Action string
+	Queue  string // empty for account-level actions such as ListQueues
+
+	Requests int64
+	Failures int64
+	Slow     int64
+
+	SuccessRate       float64
+	LatencyCompliance float64
+	// BurnRate is how fast the error budget is consumed: 1 means exactly
+	// on budget, above 1 means the objective will be missed if the rate
+	// persists.
+	BurnRate float64
+	// BudgetRemaining is the fraction of the window's error budget left;
+	// it goes negative once the objective is breached.
+	BudgetRemaining float64
+	// Met reports whether both the success and latency objectives are
+	// currently met.
+	Met bool
+}
+
+// An SLOTracker records the outcome of every request made by an SQS client
+// whose SLO field points to it.
+type SLOTracker struct {
+	slo SLO
+	now func() time.Time
+
+	mu     sync.Mutex
+	series map[sloKey]*sloSeries
+}
+
+type sloKey struct {
+	action, queue string
+}
+
+type sloBucket struct {
+	epoch                    int64
+	requests, failures, slow int64
+}
+
+type sloSeries struct {
+	buckets []sloBucket
+}
+
+// NewSLOTracker returns a tracker evaluating slo.
+func NewSLOTracker(slo SLO) *SLOTracker {
+	if slo.Window <= 0 {
+		slo.Window = time.Hour
+	}
+	if slo.Buckets <= 0 {
+		slo.Buckets = 60
+	}
+	if slo.Window < time.Duration(slo.Buckets) {
+		slo.Window = time.Duration(slo.Buckets)
+	}
+	return &SLOTracker{slo: slo, now: time.Now, series: make(map[sloKey]*sloSeries)}
+}
+
+func (t *SLOTracker) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(t.slo.Window/time.Duration(t.slo.Buckets))
+}
+
+// Record accounts for one request. Transport errors, 5xx responses and
+// throttling count as failures; other client errors, such as a missing
+// queue, do not burn the error budget.
+func (t *SLOTracker) Record(action, queue string, d time.Duration, err error) {
+	now := t.now()
+	epoch := t.epoch(now)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	k := sloKey{action, queue}
+	s := t.series[k]
+	if s == nil {
+		s = &sloSeries{buckets: make([]sloBucket, t.slo.Buckets)}
+		t.series[k] = s
+	}
+	b := &s.buckets[epoch%int64(len(s.buckets))]
+	if b.epoch != epoch {
+		*b = sloBucket{epoch: epoch}
+	}
+	b.requests++
+	if sloFailure(err) {
+		b.failures++
+	}
+	if t.slo.LatencyThreshold > 0 && d > t.slo.LatencyThreshold {
+		b.slow++
+	}
+}
+
+// Stats returns a snapshot of every tracked action and queue, sorted by
+// queue and action.
+func (t *SLOTracker) Stats() []SLOStats {
+	epoch := t.epoch(t.now())
+	oldest := epoch - int64(t.slo.Buckets) + 1
+	t.mu.Lock()
+	stats := make([]SLOStats, 0, len(t.series))
+	for k, s := range t.series {
+		st := SLOStats{Action: k.action, Queue: k.queue}
+		for _, b := range s.buckets {
+			if b.epoch >= oldest && b.epoch <= epoch {
+				st.Requests += b.requests
+				st.Failures += b.failures
+				st.Slow += b.slow
+			}
+		}
+		if st.Requests == 0 {
+			continue
+		}
+		t.evaluate(&st)
+		stats = append(stats, st)
+	}
+	t.mu.Unlock()
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Queue != stats[j].Queue {
+			return stats[i].Queue < stats[j].Queue
+		}
+		return stats[i].Action < stats[j].Action
+	})
+	return stats
+}
+
+func (t *SLOTracker) evaluate(st *SLOStats) {
+	n := float64(st.Requests)
+	st.SuccessRate = 1 - float64(st.Failures)/n
+	st.LatencyCompliance = 1 - float64(st.Slow)/n
+	if budget := 1 - t.slo.SuccessTarget; budget > 0 {
+		st.BurnRate = (1 - st.SuccessRate) / budget
+		st.BudgetRemaining = 1 - float64(st.Failures)/(budget*n)
+	}
+	st.Met = st.SuccessRate >= t.slo.SuccessTarget && st.LatencyCompliance >= t.slo.LatencyTarget
+}
+
+func sloFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		return true
+	}
+	if e.StatusCode >= 500 {
+		return true
+	}
+	_, throttled := throttleHint(err)
+	return throttled
+}
@@ -0,0 +1,37 @@
+package sqs
+
+import (
+	"errors"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestSLOTracker(c *C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	t := NewSLOTracker(SLO{SuccessTarget: 0.9, LatencyThreshold: time.Second, Window: time.Minute, Buckets: 6})
+	t.now = func() time.Time { return now }
+
+	for i := 0; i < 18; i++ {
+		t.Record("SendMessage", "orders", 10*time.Millisecond, nil)
+	}
+	t.Record("SendMessage", "orders", 2*time.Second, errors.New("connection reset"))
+	t.Record("SendMessage", "orders", time.Millisecond, &ErrorResponse{StatusCode: 400})
+
+	stats := t.Stats()
+	c.Assert(stats, HasLen, 1)
+	c.Check(stats[0].Requests, Equals, int64(20))
+	c.Check(stats[0].Failures, Equals, int64(1))
+	c.Check(stats[0].Slow, Equals, int64(1))
+	c.Check(stats[0].SuccessRate, Equals, 0.95)
+	c.Check(stats[0].BurnRate > 0.49 && stats[0].BurnRate < 0.51, Equals, true)
+
+	now = now.Add(2 * time.Minute)
+	c.Assert(t.Stats(), HasLen, 0)
+}
+
+func (s *S) TestSLOTrackerShortWindow(c *C) {
+	t := NewSLOTracker(SLO{SuccessTarget: 0.9, Window: 10, Buckets: 60})
+	t.Record("SendMessage", "orders", time.Millisecond, nil)
+	c.Assert(t.slo.Window, Equals, time.Duration(60))
+}
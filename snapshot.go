@@ -0,0 +1,172 @@
+package sqs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// A Snapshot records the messages seen in a queue at one point in time, so
+// that the state of a queue can be compared across an incident. Snapshots
+// are stored as JSON.
+type Snapshot struct {
+	Queue    string          `json:"queue"`
+	At       time.Time       `json:"at"`
+	Messages []SnapshotEntry `json:"messages"`
+}
+
+// A SnapshotEntry identifies one message of a Snapshot.
+type SnapshotEntry struct {
+	MessageId    string    `json:"message_id"`
+	BodyHash     string    `json:"body_sha256"`
+	ReceiveCount int       `json:"receive_count,omitempty"`
+	Sent         time.Time `json:"sent,omitempty"`
+}
+
+// NewSnapshot returns a snapshot of msgs taken now. Messages received with
+// their system attributes also record when they were sent and how often
+// they were received.
+func NewSnapshot(queue string, msgs []*Message) *Snapshot {
+	s := &Snapshot{Queue: queue, At: time.Now().UTC(), Messages: make([]SnapshotEntry, 0, len(msgs))}
+	for _, m := range msgs {
+		sum := sha256.Sum256([]byte(m.Body))
+		s.Messages = append(s.Messages, SnapshotEntry{
+			MessageId:    m.Id,
+			BodyHash:     hex.EncodeToString(sum[:]),
+			ReceiveCount: m.SystemAttributes.ApproximateReceiveCount,
+			Sent:         m.SystemAttributes.SentTimestamp,
+		})
+	}
+	return s
+}
+
+// ReadSnapshot decodes a snapshot written by Snapshot.Write.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Write encodes s to w as JSON.
+func (s *Snapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Snapshot peeks at up to max messages of the queue and returns a snapshot
+// of them. See Peek for how peeking affects the messages.
+func (q *Queue) Snapshot(max int) (*Snapshot, error) {
+	msgs, err := q.Peek(max)
+	if err != nil {
+		return nil, err
+	}
+	return NewSnapshot(q.Name(), msgs), nil
+}
+
+// Peek returns up to max distinct messages of the queue without hiding
+// them from consumers: they are received with a zero visibility timeout
+// until max messages were seen or three receives in a row turn up nothing
+// new. SQS samples its servers on every receive, so Peek may miss messages
+// of large queues.
+//
+// Peeking is a receive: it increments the receive count of the messages,
+// and can move them to a dead letter queue.
+func (q *Queue) Peek(max int) ([]*Message, error) {
+	var msgs []*Message
+	seen := make(map[string]bool)
+	for idle := 0; len(msgs) < max && idle < 3; {
+		n := max - len(msgs)
+		if n > 10 {
+			n = 10
+		}
+		batch, err := q.ReceiveMessages(&ReceiveMessageOpt{
+			MaxNumberOfMessages:   n,
+			VisibilityTimeout:     VisibilityZero,
+			MessageAttributeNames: []string{"All"},
+			AttributeNames:        []Attribute{All},
+		})
+		if err != nil {
+			return nil, err
+		}
+		idle++
+		for _, m := range batch {
+			if !seen[m.Id] && len(msgs) < max {
+				seen[m.Id] = true
+				msgs = append(msgs, m)
+				idle = 0
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// A SnapshotDiff is the difference between two snapshots of a queue.
+type SnapshotDiff struct {
+	// Added holds the messages only present in the later snapshot.
+	Added []SnapshotEntry
+	// Processed holds the messages only present in the earlier snapshot,
+	// which were deleted in between.
+	Processed []SnapshotEntry
+	// Stuck holds the messages present in both snapshots.
+	Stuck []SnapshotEntry
+	// Requeued holds the messages of the later snapshot whose body matches
+	// a processed message of the earlier one, such as messages that were
+	// redriven or resent under a new ID.
+	Requeued []SnapshotEntry
+}
+
+// DiffSnapshots compares two snapshots of a queue, matching messages by ID
+// and then by body hash. Entries are sorted by sent time and ID.
+func DiffSnapshots(before, after *Snapshot) *SnapshotDiff {
+	d := &SnapshotDiff{}
+	ids := make(map[string]bool)
+	for _, e := range after.Messages {
+		ids[e.MessageId] = true
+	}
+	gone := make(map[string][]SnapshotEntry)
+	for _, e := range before.Messages {
+		if ids[e.MessageId] {
+			continue
+		}
+		gone[e.BodyHash] = append(gone[e.BodyHash], e)
+	}
+	stuck := make(map[string]bool)
+	for _, e := range before.Messages {
+		if ids[e.MessageId] {
+			stuck[e.MessageId] = true
+		}
+	}
+	for _, e := range after.Messages {
+		switch {
+		case stuck[e.MessageId]:
+			d.Stuck = append(d.Stuck, e)
+		case len(gone[e.BodyHash]) > 0:
+			gone[e.BodyHash] = gone[e.BodyHash][1:]
+			d.Requeued = append(d.Requeued, e)
+		default:
+			d.Added = append(d.Added, e)
+		}
+	}
+	for _, es := range gone {
+		d.Processed = append(d.Processed, es...)
+	}
+	for _, es := range [][]SnapshotEntry{d.Added, d.Processed, d.Stuck, d.Requeued} {
+		sortEntries(es)
+	}
+	return d
+}
+
+func sortEntries(es []SnapshotEntry) {
+	sort.Slice(es, func(i, j int) bool {
+		if !es[i].Sent.Equal(es[j].Sent) {
+			return es[i].Sent.Before(es[j].Sent)
+		}
+		return es[i].MessageId < es[j].MessageId
+	})
+}
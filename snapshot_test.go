@@ -0,0 +1,39 @@
+package sqs
+
+import (
+	"bytes"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestDiffSnapshots(c *C) {
+	before := NewSnapshot("q", []*Message{
+		{Id: "1", Body: "a"},
+		{Id: "2", Body: "b"},
+		{Id: "3", Body: "c"},
+	})
+	after := NewSnapshot("q", []*Message{
+		{Id: "2", Body: "b"},
+		{Id: "4", Body: "c"},
+		{Id: "5", Body: "d"},
+	})
+
+	var buf bytes.Buffer
+	c.Assert(before.Write(&buf), IsNil)
+	before, err := ReadSnapshot(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(before.Messages, HasLen, 3)
+
+	ids := func(es []SnapshotEntry) []string {
+		var ids []string
+		for _, e := range es {
+			ids = append(ids, e.MessageId)
+		}
+		return ids
+	}
+	d := DiffSnapshots(before, after)
+	c.Assert(ids(d.Added), DeepEquals, []string{"5"})
+	c.Assert(ids(d.Processed), DeepEquals, []string{"1"})
+	c.Assert(ids(d.Stuck), DeepEquals, []string{"2"})
+	c.Assert(ids(d.Requeued), DeepEquals, []string{"4"})
+}
@@ -0,0 +1,214 @@
+package sqs
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// An SNSNotification is the envelope of a message delivered to a queue by
+// an SNS subscription without raw message delivery.
+type SNSNotification struct {
+	Type      string
+	MessageId string
+	TopicArn  string
+	Subject   string
+	// Message is the body published to the topic.
+	Message string
+	// Timestamp is when the notification was published, as formatted
+	// by SNS, e.g. "2012-04-25T21:49:25.719Z".
+	Timestamp string
+
+	SignatureVersion string
+	Signature        string
+	SigningCertURL   string
+	UnsubscribeURL   string
+
+	MessageAttributes map[string]struct {
+		Type  string
+		Value string
+	}
+}
+
+// stringToSign returns the fields of n covered by its signature.
+func (n *SNSNotification) stringToSign() string {
+	var b strings.Builder
+	field := func(name, value string) {
+		b.WriteString(name + "\n" + value + "\n")
+	}
+	field("Message", n.Message)
+	field("MessageId", n.MessageId)
+	if n.Subject != "" {
+		field("Subject", n.Subject)
+	}
+	field("Timestamp", n.Timestamp)
+	field("TopicArn", n.TopicArn)
+	field("Type", n.Type)
+	return b.String()
+}
+
+// SNSUnwrap is a Transformer unwrapping the messages delivered by SNS
+// subscriptions: handlers get the body published to the topic, and its
+// message attributes, while the envelope is kept in the SNS field of the
+// message. Other messages, including those of subscriptions with raw
+// message delivery, are left untouched. Set it on the client of a Consumer
+// to unwrap the messages it handles:
+//
+//	sqs.Transformers = append(sqs.Transformers, &SNSUnwrap{Verify: true})
+//
+// Sending through a client with SNSUnwrap leaves messages untouched.
+type SNSUnwrap struct {
+	// Verify checks the signature of every notification, failing the
+	// receive with a *DecodeError for forged ones.
+	Verify bool
+	// Client fetches the signing certificates; it defaults to
+	// http.DefaultClient.
+	Client *http.Client
+	// Certificate, if set, returns the signing certificate at certURL,
+	// instead of fetching it with Client after checking that certURL is
+	// an HTTPS URL of an SNS endpoint.
+	Certificate func(certURL string) (*x509.Certificate, error)
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate // by URL
+}
+
+// Encode implements Transformer.
+func (u *SNSUnwrap) Encode(ctx context.Context, m *Message) error {
+	return nil
+}
+
+// Decode implements Transformer.
+func (u *SNSUnwrap) Decode(ctx context.Context, m *Message) error {
+	if !strings.HasPrefix(strings.TrimSpace(m.Body), "{") {
+		return nil
+	}
+	var n SNSNotification
+	if err := json.Unmarshal([]byte(m.Body), &n); err != nil || n.Type != "Notification" || n.TopicArn == "" {
+		return nil
+	}
+	if u.Verify {
+		if err := u.verify(&n); err != nil {
+			return err
+		}
+	}
+	attrs := make(MessageAttributes, len(m.MessageAttributes)+len(n.MessageAttributes))
+	for name, v := range m.MessageAttributes {
+		attrs[name] = v
+	}
+	for name, v := range n.MessageAttributes {
+		value := MessageAttributeValue{DataType: v.Type, StringValue: v.Value}
+		if v.Type == "Binary" {
+			b, err := base64.StdEncoding.DecodeString(v.Value)
+			if err != nil {
+				return fmt.Errorf("sqs: binary attribute %s of SNS message %s: %w", name, n.MessageId, err)
+			}
+			value = MessageAttributeValue{DataType: v.Type, BinaryValue: b}
+		}
+		attrs[name] = value
+	}
+	m.Body = n.Message
+	m.MessageAttributes = attrs
+	m.SNS = &n
+	return nil
+}
+
+// verify checks the signature of n.
+func (u *SNSUnwrap) verify(n *SNSNotification) error {
+	var hash crypto.Hash
+	var sum []byte
+	switch n.SignatureVersion {
+	case "1":
+		h := sha1.Sum([]byte(n.stringToSign()))
+		hash, sum = crypto.SHA1, h[:]
+	case "2":
+		h := sha256.Sum256([]byte(n.stringToSign()))
+		hash, sum = crypto.SHA256, h[:]
+	default:
+		return fmt.Errorf("sqs: unsupported signature version %q of SNS message %s", n.SignatureVersion, n.MessageId)
+	}
+	sig, err := base64.StdEncoding.DecodeString(n.Signature)
+	if err != nil {
+		return fmt.Errorf("sqs: signature of SNS message %s: %w", n.MessageId, err)
+	}
+	cert, err := u.certificate(n.SigningCertURL)
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("sqs: signing certificate %s of SNS message %s is not RSA", n.SigningCertURL, n.MessageId)
+	}
+	if err := rsa.VerifyPKCS1v15(key, hash, sum, sig); err != nil {
+		return fmt.Errorf("sqs: invalid signature of SNS message %s", n.MessageId)
+	}
+	return nil
+}
+
+// certificate returns the certificate at certURL, fetched once.
+func (u *SNSUnwrap) certificate(certURL string) (*x509.Certificate, error) {
+	u.mu.Lock()
+	cert, ok := u.certs[certURL]
+	u.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+	var err error
+	if u.Certificate != nil {
+		cert, err = u.Certificate(certURL)
+	} else {
+		cert, err = u.fetch(certURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.mu.Lock()
+	if u.certs == nil {
+		u.certs = make(map[string]*x509.Certificate)
+	}
+	u.certs[certURL] = cert
+	u.mu.Unlock()
+	return cert, nil
+}
+
+// fetch downloads the PEM certificate at certURL from SNS.
+func (u *SNSUnwrap) fetch(certURL string) (*x509.Certificate, error) {
+	cu, err := url.Parse(certURL)
+	if err != nil || cu.Scheme != "https" || !strings.HasPrefix(cu.Hostname(), "sns.") || !strings.HasSuffix(cu.Hostname(), ".amazonaws.com") {
+		return nil, fmt.Errorf("sqs: SNS signing certificate URL %q is not an SNS endpoint", certURL)
+	}
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	r, err := client.Get(certURL)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sqs: fetching SNS signing certificate %s: %s", certURL, r.Status)
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("sqs: SNS signing certificate is not PEM encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
@@ -0,0 +1,112 @@
+package sqs
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+// snsNotification returns the body of a notification signed with key.
+func snsNotification(c *C, key *rsa.PrivateKey, message string) string {
+	n := &SNSNotification{
+		Type:             "Notification",
+		MessageId:        "sns-1",
+		TopicArn:         "arn:aws:sns:us-east-1:123456789012:orders",
+		Subject:          "order created",
+		Message:          message,
+		Timestamp:        "2024-01-02T03:04:05.678Z",
+		SignatureVersion: "2",
+		SigningCertURL:   "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem",
+	}
+	n.MessageAttributes = map[string]struct {
+		Type  string
+		Value string
+	}{
+		"kind": {"String", "order"},
+		"blob": {"Binary", "AQI="},
+	}
+	sum := sha256.Sum256([]byte(n.stringToSign()))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	c.Assert(err, IsNil)
+	n.Signature = base64.StdEncoding.EncodeToString(sig)
+	b, err := json.Marshal(n)
+	c.Assert(err, IsNil)
+	return string(b)
+}
+
+func (s *S) TestSNSUnwrap(c *C) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, IsNil)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+	var fetched int
+	unwrap := &SNSUnwrap{Verify: true, Certificate: func(certURL string) (*x509.Certificate, error) {
+		fetched++
+		return cert, nil
+	}}
+
+	sqs := NewLocal().SQS()
+	sqs.Transformers = []Transformer{unwrap}
+	q, err := sqs.CreateQueue("orders", nil)
+	c.Assert(err, IsNil)
+
+	body := snsNotification(c, key, `{"id":42}`)
+	_, err = q.SendMessage(body)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage(body)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage(`{"id":43}`) // raw message delivery
+	c.Assert(err, IsNil)
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 3)
+	m := msgs[0]
+	c.Assert(m.Body, Equals, `{"id":42}`)
+	c.Assert(m.SNS, NotNil)
+	c.Assert(m.SNS.Subject, Equals, "order created")
+	c.Assert(m.SNS.TopicArn, Equals, "arn:aws:sns:us-east-1:123456789012:orders")
+	c.Assert(m.SNS.MessageId, Equals, "sns-1")
+	c.Assert(m.MessageAttributes["kind"], DeepEquals, MessageAttributeValue{DataType: "String", StringValue: "order"})
+	c.Assert(m.MessageAttributes["blob"].BinaryValue, DeepEquals, []byte{1, 2})
+	c.Assert(msgs[2].Body, Equals, `{"id":43}`)
+	c.Assert(msgs[2].SNS, IsNil)
+	c.Assert(fetched, Equals, 1)
+	for _, m := range msgs {
+		c.Assert(q.DeleteMessage(m), IsNil)
+	}
+
+	var n SNSNotification
+	c.Assert(json.Unmarshal([]byte(body), &n), IsNil)
+	n.Message = `{"id":666}`
+	forged, err := json.Marshal(&n)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage(string(forged))
+	c.Assert(err, IsNil)
+	_, err = q.ReceiveMessages(nil)
+	var de *DecodeError
+	c.Assert(errors.As(err, &de), Equals, true)
+	c.Assert(err, ErrorMatches, ".*invalid signature of SNS message sns-1")
+
+	unwrap = &SNSUnwrap{Verify: true}
+	n.SigningCertURL = "https://attacker.example.com/cert.pem"
+	_, err = unwrap.certificate(n.SigningCertURL)
+	c.Assert(err, ErrorMatches, `sqs: SNS signing certificate URL .* is not an SNS endpoint`)
+}
@@ -0,0 +1,312 @@
+package sqs
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Chaos injects the faults of a distributed queue into the requests of a
+// client of Local, for soak testing consumers. Its rates are
+// probabilities between 0 and 1.
+type Chaos struct {
+	// Throttle is the rate of requests answered with a throttling error.
+	Throttle float64
+	// Duplicate is the rate of received messages delivered a second
+	// time, by a later receive.
+	Duplicate float64
+	// Expire is the rate of received messages whose visibility timeout
+	// expires at once, so they are redelivered while being handled.
+	Expire float64
+	// Reorder is the rate of messages held back for a second when
+	// received, so they are delivered after later ones.
+	Reorder float64
+	// Seed seeds the random choices of faults.
+	Seed int64
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	dups  []xmlMessage
+	stats ChaosStats
+}
+
+// ChaosStats counts the faults injected by Chaos.
+type ChaosStats struct {
+	Throttled  int
+	Duplicated int
+	Expired    int
+	Reordered  int
+}
+
+// Stats returns the faults injected so far.
+func (c *Chaos) Stats() ChaosStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *Chaos) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if c.rand == nil {
+		c.rand = rand.New(rand.NewSource(c.Seed))
+	}
+	return c.rand.Float64() < rate
+}
+
+// Middleware returns a Middleware injecting the faults of c.
+func (c *Chaos) Middleware(next Doer) Doer {
+	return DoerFunc(func(req *http.Request) (*http.Response, error) {
+		c.mu.Lock()
+		throttle := c.roll(c.Throttle)
+		if throttle {
+			c.stats.Throttled++
+		}
+		c.mu.Unlock()
+		if throttle {
+			return xmlError(req, "RequestThrottled", "injected by Chaos"), nil
+		}
+		r, err := next.Do(req)
+		if err != nil || r.StatusCode != http.StatusOK || RequestAction(req) != "ReceiveMessage" {
+			return r, err
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var resp xmlReceiveResponse
+		if err := xml.Unmarshal(b, &resp); err != nil {
+			return nil, err
+		}
+		resp.Messages = c.mangle(req, next, resp.Messages)
+		if b, err = xml.Marshal(&resp); err != nil {
+			return nil, err
+		}
+		return xmlResponse(req, http.StatusOK, b), nil
+	})
+}
+
+// mangle injects faults into the messages of a receive.
+func (c *Chaos) mangle(req *http.Request, next Doer, msgs []xmlMessage) []xmlMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kept := msgs[:0]
+	for _, m := range msgs {
+		switch {
+		case c.roll(c.Reorder):
+			c.stats.Reordered++
+			c.hide(req, next, m.ReceiptHandle, 1)
+			continue
+		case c.roll(c.Expire):
+			c.stats.Expired++
+			c.hide(req, next, m.ReceiptHandle, 0)
+		}
+		if c.roll(c.Duplicate) {
+			c.stats.Duplicated++
+			c.dups = append(c.dups, m)
+		} else if len(c.dups) > 0 {
+			kept = append(kept, c.dups[0])
+			c.dups = c.dups[1:]
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// hide changes the visibility timeout of a received message.
+func (c *Chaos) hide(req *http.Request, next Doer, handle string, timeout int) {
+	u := *req.URL
+	u.RawQuery = url.Values{
+		"Action":            {"ChangeMessageVisibility"},
+		"ReceiptHandle":     {handle},
+		"VisibilityTimeout": {strconv.Itoa(timeout)},
+	}.Encode()
+	hreq, err := http.NewRequestWithContext(req.Context(), "GET", u.String(), nil)
+	if err != nil {
+		return
+	}
+	if r, err := next.Do(hreq); err == nil {
+		r.Body.Close()
+	}
+}
+
+// SoakOpt configures Soak.
+type SoakOpt struct {
+	// Messages is the number of messages sent, 1000 by default.
+	Messages int
+	// Rate, if positive, paces the sends at that many messages per
+	// second, so that runs can last for hours; otherwise every message is
+	// sent at once.
+	Rate float64
+	// Duration bounds the run; it defaults to a minute.
+	Duration time.Duration
+	// VisibilityTimeout is the visibility timeout of the queue, in
+	// seconds, one by default so lost acknowledgements are retried fast.
+	VisibilityTimeout int
+	// Body returns the body of message i, by default "soak-<i>".
+	Body func(i int) string
+	// Chaos, if set, injects faults into the run.
+	Chaos *Chaos
+	// MaxDuplicates bounds the number of extra successful handlings of a
+	// message, 5 by default.
+	MaxDuplicates int
+}
+
+// A SoakReport is the outcome of a soak test.
+type SoakReport struct {
+	Sent    int
+	Elapsed time.Duration
+	// Handled counts the successful handlings of every message by ID.
+	Handled map[string]int
+	// Calls is the number of handler calls, failed or not.
+	Calls int
+	// Lost lists the messages never handled successfully, and Excess
+	// those handled more often than MaxDuplicates allows, sorted.
+	Lost   []string
+	Excess []string
+	Chaos  ChaosStats
+}
+
+// Duplicates returns the number of extra successful handlings.
+func (r *SoakReport) Duplicates() int {
+	n := 0
+	for _, h := range r.Handled {
+		if h > 1 {
+			n += h - 1
+		}
+	}
+	return n
+}
+
+func (r *SoakReport) String() string {
+	return fmt.Sprintf("sent=%d calls=%d duplicates=%d lost=%d excess=%d elapsed=%s chaos=%+v",
+		r.Sent, r.Calls, r.Duplicates(), len(r.Lost), len(r.Excess), r.Elapsed, r.Chaos)
+}
+
+// Soak certifies the handler of c against the delivery semantics of SQS:
+// it runs c against a Local queue, optionally through Chaos, until every
+// message sent was handled successfully or the run's duration elapsed.
+// It fails if a message was lost or handled more often than allowed.
+//
+// Soak sets the Queue of c and wraps its Handler.
+func Soak(ctx context.Context, c *Consumer, opt *SoakOpt) (*SoakReport, error) {
+	if opt == nil {
+		opt = &SoakOpt{}
+	}
+	n := opt.Messages
+	if n <= 0 {
+		n = 1000
+	}
+	duration := opt.Duration
+	if duration <= 0 {
+		duration = time.Minute
+	}
+	timeout := opt.VisibilityTimeout
+	if timeout <= 0 {
+		timeout = 1
+	}
+	body := opt.Body
+	if body == nil {
+		body = func(i int) string { return "soak-" + strconv.Itoa(i) }
+	}
+	maxDups := opt.MaxDuplicates
+	if maxDups <= 0 {
+		maxDups = 5
+	}
+
+	local := NewLocal()
+	client := local.SQS()
+	q, err := client.CreateQueue("soak", &CreateQueueOpt{VisibilityTimeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	if opt.Chaos != nil {
+		client.Middleware = append(client.Middleware, opt.Chaos.Middleware)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	report := &SoakReport{Handled: make(map[string]int)}
+	var mu sync.Mutex
+	done := make(chan struct{})
+	handled := 0
+	// finished closes done once every message was sent and handled.
+	finished := func() {
+		if report.Sent == n && handled == n {
+			close(done)
+		}
+	}
+	h := c.Handler
+	c.Queue = q
+	c.Handler = HandlerFunc(func(m *Message) error {
+		err := h.HandleMessage(m)
+		mu.Lock()
+		defer mu.Unlock()
+		report.Calls++
+		if err == nil {
+			if report.Handled[m.Id]++; report.Handled[m.Id] == 1 {
+				handled++
+				finished()
+			}
+		}
+		return err
+	})
+
+	start := time.Now()
+	c.Start()
+	// Messages are sent through a client without chaos, so every send
+	// counts.
+	sender := &Queue{SQS: &SQS{Endpoint: client.Endpoint, Client: local}, path: q.path}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		id, err := sender.SendMessage(body(i))
+		if err != nil {
+			c.Stop()
+			return nil, err
+		}
+		mu.Lock()
+		if _, ok := report.Handled[id]; !ok {
+			report.Handled[id] = 0
+		}
+		report.Sent++
+		finished()
+		mu.Unlock()
+		if opt.Rate > 0 {
+			sleepContext(ctx, time.Duration(float64(time.Second)/opt.Rate))
+		}
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	c.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	report.Elapsed = time.Since(start)
+	if opt.Chaos != nil {
+		report.Chaos = opt.Chaos.Stats()
+	}
+	for id, h := range report.Handled {
+		if h == 0 {
+			report.Lost = append(report.Lost, id)
+		} else if h-1 > maxDups {
+			report.Excess = append(report.Excess, id)
+		}
+	}
+	sort.Strings(report.Lost)
+	sort.Strings(report.Excess)
+	if len(report.Lost) > 0 || len(report.Excess) > 0 {
+		return report, fmt.Errorf("sqs: soak test failed: %s", report)
+	}
+	return report, nil
+}
@@ -0,0 +1,61 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestSoak(c *C) {
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	consumer := &Consumer{
+		Concurrency: 4,
+		Handler: HandlerFunc(func(m *Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if !failed[m.Body] {
+				failed[m.Body] = true
+				return errors.New("first attempt fails")
+			}
+			return nil
+		}),
+	}
+	chaos := &Chaos{Duplicate: 0.2, Expire: 0.1, Reorder: 0.05, Seed: 1}
+	report, err := Soak(context.Background(), consumer, &SoakOpt{Messages: 50, Duration: 20 * time.Second, Chaos: chaos})
+	c.Assert(err, IsNil)
+	c.Assert(report.Sent, Equals, 50)
+	c.Assert(report.Handled, HasLen, 50)
+	c.Assert(report.Lost, HasLen, 0)
+	c.Assert(report.Calls >= 100, Equals, true)
+	c.Assert(report.Chaos.Duplicated > 0, Equals, true)
+	c.Assert(report.Chaos.Expired > 0, Equals, true)
+}
+
+func (s *S) TestSoakLoss(c *C) {
+	consumer := &Consumer{
+		RetryDelay: 1,
+		Handler: HandlerFunc(func(m *Message) error {
+			if m.Body == "soak-3" {
+				return errors.New("never handled")
+			}
+			return nil
+		}),
+	}
+	report, err := Soak(context.Background(), consumer, &SoakOpt{Messages: 5, Duration: 300 * time.Millisecond})
+	c.Assert(err, ErrorMatches, "sqs: soak test failed: .*lost=1.*")
+	c.Assert(report.Lost, HasLen, 1)
+	c.Assert(report.Handled[report.Lost[0]], Equals, 0)
+}
+
+func (s *S) TestChaosThrottle(c *C) {
+	chaos := &Chaos{Throttle: 1}
+	sqs := NewLocal().SQS()
+	sqs.Middleware = []Middleware{chaos.Middleware}
+	_, err := sqs.CreateQueue("q", nil)
+	c.Assert(err, ErrorMatches, ".*RequestThrottled.*")
+	c.Assert(chaos.Stats().Throttled, Equals, 1)
+}
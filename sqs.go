@@ -10,30 +10,155 @@
 package sqs
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/librato/goamz-aws/aws"
 )
 
 // The SQS type encapsulates operations with a specific SQS region.
+//
+// An SQS, and the Queues derived from it, are safe for concurrent use by
+// multiple goroutines once configured: its fields must not be changed
+// while requests are in flight, except through methods such as
+// SetCredentialsProvider.
 type SQS struct {
 	aws.Auth
 	aws.Region
 	private byte // Reserve the right of using private data.
+
+	// Endpoint, when set, overrides the endpoint derived from Region,
+	// e.g. "http://localhost:9324" for ElasticMQ or the DNS name of a VPC
+	// interface endpoint.
+	Endpoint string
+
+	// SLO, when set, records the outcome and latency of every request.
+	SLO *SLOTracker
+	// Metrics, when set, receives request and message measurements.
+	Metrics Metrics
+	// QueueLabels, when set, relabels the queues of SLO stats and adds
+	// per-queue request metrics under a bounded set of labels.
+	QueueLabels *QueueLabels
+
+	// MaxRetries is the number of times a throttled request is retried
+	// once its queue's cooldown has elapsed. Throttling always starts a
+	// cooldown, shared by every request to the same queue, whether or not
+	// the request is retried. It also bounds the retries of receives
+	// with a ReceiveRequestAttemptId that failed on the network.
+	MaxRetries int
+	// RateLimiter, when set, holds back requests over its limits, so that
+	// they are throttled on the client rather than by SQS.
+	RateLimiter *RateLimiter
+	// CircuitBreaker, when set, fails requests fast after persistent
+	// failures of the service.
+	CircuitBreaker *CircuitBreaker
+
+	// DisableChecksums turns off the verification of the MD5 digests SQS
+	// returns for sent and received message bodies.
+	DisableChecksums bool
+	// DisableValidation turns off the checks of message sizes and
+	// characters, attribute names, batch sizes, queue names, delays and
+	// timeouts against the limits of SQS, which otherwise fail requests
+	// with a *ValidationError before they are sent.
+	DisableValidation bool
+
+	// Signer authenticates the requests; it defaults to SignatureV2. Use
+	// SignatureV4 with JSONProtocol, and NoSigner, or a custom Signer, for
+	// SQS-compatible backends with no or different authentication.
+	Signer Signer
+
+	// Protocol is the wire format of the requests and responses; it
+	// defaults to QueryProtocol. See JSONProtocol.
+	Protocol Protocol
+	// Capabilities, when set, typically by Probe, are those of the
+	// endpoint; requests it does not support fail early.
+	Capabilities *Capabilities
+	// Timeouts, when set, bound the requests by action.
+	Timeouts *Timeouts
+
+	// Client performs the HTTP requests; it defaults to
+	// http.DefaultClient, or to a client of its own when Transport is
+	// set. Middleware wraps it, the first outermost, and Hooks observe
+	// every request.
+	Client     Doer
+	Middleware []Middleware
+	Hooks      Hooks
+	// Transport, when set and Client is not, tunes the connections of the
+	// client; see TransportOpt. It is read once, by the first request.
+	Transport *TransportOpt
+	// Logger, when set, logs every request at debug level.
+	Logger Logger
+	// Debug captures every request and its response, attaching them to
+	// the errors returned; see ExchangeOf. It holds response bodies in
+	// memory, and is meant for troubleshooting.
+	Debug bool
+
+	// Transformers rewrite the messages sent and received, for instance
+	// to compress their bodies; see Transformer.
+	Transformers []Transformer
+
+	// LockoutWait is how long PurgeQueue and CreateQueue wait out the
+	// lockouts following a purge or the deletion of a queue, retrying
+	// rather than failing with ErrPurgeInProgress or
+	// ErrQueueDeletedRecently. Lockouts started by the client itself are
+	// waited out before the first attempt, and purges started by the
+	// client before Drain, MoveMessage and Redrive use the queue. It
+	// defaults to zero, not waiting.
+	LockoutWait time.Duration
+
+	authMu     sync.RWMutex
+	creds      *RefreshingCredentials
+	queueCreds map[string]*RefreshingCredentials // by queue ARN prefix
+	cooldowns  cooldowns
+	lockouts   lockouts
+
+	transportOnce   sync.Once
+	transportClient *http.Client
 }
 
-// The Queue type encapsulates operations with an SQS queue.
+// The Queue type encapsulates operations with an SQS queue. It is safe for
+// concurrent use like its SQS.
 type Queue struct {
 	*SQS
-	path string
+	path     string
+	ctx      context.Context
+	defaults *QueueOpt
+}
+
+// WithContext returns a shallow copy of q whose requests are bound to ctx:
+// they are abandoned, and return ctx's error, once ctx is done.
+func (q *Queue) WithContext(ctx context.Context) *Queue {
+	q2 := *q
+	q2.ctx = ctx
+	return &q2
+}
+
+// Context returns the context of q's requests, which defaults to the
+// background context.
+func (q *Queue) Context() context.Context {
+	if q.ctx != nil {
+		return q.ctx
+	}
+	return context.Background()
+}
+
+func (q *Queue) call(action, path string, params url.Values, resp interface{}) error {
+	return q.SQS.callContext(q.Context(), action, path, params, resp)
 }
 
 // An Attribute specifies which attribute of a message to set or receive.
@@ -43,6 +168,7 @@ const (
 	All                                   Attribute = "All"
 	ApproximateNumberOfMessages           Attribute = "ApproximateNumberOfMessages"
 	ApproximateNumberOfMessagesNotVisible Attribute = "ApproximateNumberOfMessagesNotVisible"
+	ApproximateNumberOfMessagesDelayed    Attribute = "ApproximateNumberOfMessagesDelayed"
 	VisibilityTimeout                     Attribute = "VisibilityTimeout"
 	CreatedTimestamp                      Attribute = "CreatedTimestamp"
 	LastModifiedTimestamp                 Attribute = "LastModifiedTimestamp"
@@ -50,72 +176,357 @@ const (
 	MaximumMessageSize                    Attribute = "MaximumMessageSize"
 	MessageRetentionPeriod                Attribute = "MessageRetentionPeriod"
 	QueueArn                              Attribute = "QueueArn"
+	RedrivePolicyAttribute                Attribute = "RedrivePolicy"
+	DelaySeconds                          Attribute = "DelaySeconds"
+	ReceiveMessageWaitTimeSeconds         Attribute = "ReceiveMessageWaitTimeSeconds"
+	KmsMasterKeyId                        Attribute = "KmsMasterKeyId"
+	KmsDataKeyReusePeriodSeconds          Attribute = "KmsDataKeyReusePeriodSeconds"
+	SqsManagedSseEnabled                  Attribute = "SqsManagedSseEnabled"
+	FifoQueue                             Attribute = "FifoQueue"
+	ContentBasedDeduplication             Attribute = "ContentBasedDeduplication"
+
+	// Message system attributes.
+	SentTimestamp                    Attribute = "SentTimestamp"
+	ApproximateFirstReceiveTimestamp Attribute = "ApproximateFirstReceiveTimestamp"
+	ApproximateReceiveCount          Attribute = "ApproximateReceiveCount"
+	SenderId                         Attribute = "SenderId"
+	MessageGroupId                   Attribute = "MessageGroupId"
+	MessageDeduplicationId           Attribute = "MessageDeduplicationId"
+	SequenceNumber                   Attribute = "SequenceNumber"
+	AWSTraceHeader                   Attribute = "AWSTraceHeader"
 )
 
 // New creates a new SQS.
 func New(auth aws.Auth, region aws.Region) *SQS {
-	return &SQS{auth, region, 0}
+	return &SQS{Auth: auth, Region: region}
+}
+
+// setAuth replaces the credentials used for subsequent requests.
+func (sqs *SQS) setAuth(auth aws.Auth) {
+	sqs.authMu.Lock()
+	sqs.Auth = auth
+	sqs.authMu.Unlock()
 }
 
 type ResponseMetadata struct {
 	RequestId string
 }
 
+// Queue returns the queue with the given name, looking up its URL with
+// GetQueueUrl. The returned error matches ErrQueueNotFound if there is no
+// such queue.
 func (sqs *SQS) Queue(name string) (*Queue, error) {
-	qs, err := sqs.ListQueues(name)
+	u, err := sqs.GetQueueUrl(name, nil)
 	if err != nil {
 		return nil, err
 	}
-	for _, q := range qs {
-		if q.Name() == name {
-			return q, nil
-		}
+	return sqs.queueFromUrl(u)
+}
+
+// QueueOwnedBy returns the queue with the given name owned by the account
+// accountId, looking up its URL with GetQueueUrl. The account must have
+// granted access to the queue, or its requests be signed with credentials
+// of the account; see SetQueueCredentialsProvider.
+func (sqs *SQS) QueueOwnedBy(name, accountId string) (*Queue, error) {
+	u, err := sqs.GetQueueUrl(name, &GetQueueUrlOpt{QueueOwnerAWSAccountId: accountId})
+	if err != nil {
+		return nil, err
 	}
-	// TODO: return error
-	return nil, nil
+	return sqs.queueFromUrl(u)
+}
+
+// QueueByUrl returns the queue with the given URL, such as one returned
+// by GetQueueUrl or read from configuration, without a request. Requests
+// are sent to the client's endpoint, whatever the host of the URL.
+func (sqs *SQS) QueueByUrl(rawurl string) (*Queue, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("sqs: invalid queue URL %q", rawurl)
+	}
+	if parts := strings.Split(u.Path, "/"); len(parts) != 3 || parts[0] != "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("sqs: invalid queue URL %q", rawurl)
+	}
+	return &Queue{SQS: sqs, path: u.Path}, nil
+}
+
+// queueFromUrl returns the Queue addressed by the given queue URL.
+func (sqs *SQS) queueFromUrl(rawurl string) (*Queue, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return &Queue{SQS: sqs, path: u.Path}, nil
+}
+
+type GetQueueUrlOpt struct {
+	// QueueOwnerAWSAccountId selects a queue owned by another account.
+	QueueOwnerAWSAccountId string
+}
+
+type getQueueUrlResponse struct {
+	QueueUrl string `xml:"GetQueueUrlResult>QueueUrl"`
+	ResponseMetadata
+}
+
+// GetQueueUrl returns the URL of an existing queue.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_GetQueueUrl.html
+// for more details.
+func (sqs *SQS) GetQueueUrl(name string, opt *GetQueueUrlOpt) (string, error) {
+	params := url.Values{
+		"QueueName": []string{name},
+	}
+	if opt != nil && opt.QueueOwnerAWSAccountId != "" {
+		params.Set("QueueOwnerAWSAccountId", opt.QueueOwnerAWSAccountId)
+	}
+	var resp getQueueUrlResponse
+	if err := sqs.call("GetQueueUrl", "/", params, &resp); err != nil {
+		return "", err
+	}
+	return resp.QueueUrl, nil
 }
 
 type listQueuesResponse struct {
-	Queues []string `xml:"ListQueuesResult>QueueUrl"`
+	Queues    []string `xml:"ListQueuesResult>QueueUrl"`
+	NextToken string   `xml:"ListQueuesResult>NextToken"`
 	ResponseMetadata
 }
 
-// ListQueues returns a list of your queues.
+// ListQueues returns a list of your queues. SQS returns at most 1000
+// queues; use ListQueuesAll or QueueIterator to list them all.
 //
 // See http://goo.gl/q1ue9 for more details.
 func (sqs *SQS) ListQueues(namePrefix string) ([]*Queue, error) {
-	params := url.Values{}
-	if namePrefix != "" {
-		params.Set("QueueNamePrefix", namePrefix)
+	page, err := sqs.ListQueuesPage(&ListQueuesOpt{NamePrefix: namePrefix})
+	if err != nil {
+		return nil, err
 	}
-	var resp listQueuesResponse
-	if err := sqs.get("ListQueues", "/", params, &resp); err != nil {
+	return page.Queues, nil
+}
+
+type ListQueuesOpt struct {
+	// NamePrefix selects the queues whose name starts with it.
+	NamePrefix string
+	// MaxResults is the number of queues per page, up to 1000. When set,
+	// pages end with a NextToken if more queues follow.
+	MaxResults int
+	// NextToken continues the listing after a previous page.
+	NextToken string
+}
+
+// A ListQueuesPage is one page of a queue listing.
+type ListQueuesPage struct {
+	Queues []*Queue
+	// NextToken, if not empty, requests the next page.
+	NextToken string
+}
+
+// ListQueuesPage returns one page of your queues, for callers paging
+// themselves. opt may be nil.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_ListQueues.html
+// for more details.
+func (sqs *SQS) ListQueuesPage(opt *ListQueuesOpt) (*ListQueuesPage, error) {
+	resp, err := sqs.listQueues(opt)
+	if err != nil {
 		return nil, err
 	}
-	queues := make([]*Queue, len(resp.Queues))
+	page := &ListQueuesPage{Queues: make([]*Queue, len(resp.Queues)), NextToken: resp.NextToken}
 	for i, queue := range resp.Queues {
-		u, err := url.Parse(queue)
+		q, err := sqs.queueFromUrl(queue)
 		if err != nil {
 			return nil, err
 		}
-		queues[i] = &Queue{sqs, u.Path}
+		page.Queues[i] = q
 	}
-	return queues, nil
+	return page, nil
 }
 
-func (sqs *SQS) newRequest(method, action, url_ string, params url.Values) (*http.Request, error) {
-	req, err := http.NewRequest("GET", url_, nil)
+// listQueues performs one ListQueues call.
+func (sqs *SQS) listQueues(opt *ListQueuesOpt) (*listQueuesResponse, error) {
+	params := url.Values{}
+	if opt != nil {
+		if opt.NamePrefix != "" {
+			params.Set("QueueNamePrefix", opt.NamePrefix)
+		}
+		if opt.MaxResults > 0 {
+			params.Set("MaxResults", strconv.Itoa(opt.MaxResults))
+		}
+		if opt.NextToken != "" {
+			params.Set("NextToken", opt.NextToken)
+		}
+	}
+	var resp listQueuesResponse
+	if err := sqs.call("ListQueues", "/", params, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListQueuesPages calls fn with the URLs of each page of the queues whose
+// name starts with namePrefix, until fn returns false or the listing
+// ends. Unlike ListQueuesPage, it builds no Queue, and it holds one page
+// at a time, for tooling that only needs names and for accounts with very
+// many queues; QueueUrlName returns the name of a queue URL.
+func (sqs *SQS) ListQueuesPages(namePrefix string, fn func(urls []string) bool) error {
+	opt := &ListQueuesOpt{NamePrefix: namePrefix, MaxResults: 1000}
+	for {
+		resp, err := sqs.listQueues(opt)
+		if err != nil {
+			return err
+		}
+		if !fn(resp.Queues) || resp.NextToken == "" {
+			return nil
+		}
+		opt.NextToken = resp.NextToken
+	}
+}
+
+// ListQueueUrls returns the URLs of every queue whose name starts with
+// namePrefix, following the pages of the listing.
+func (sqs *SQS) ListQueueUrls(namePrefix string) ([]string, error) {
+	var urls []string
+	err := sqs.ListQueuesPages(namePrefix, func(page []string) bool {
+		urls = append(urls, page...)
+		return true
+	})
+	return urls, err
+}
+
+// QueueUrlName returns the name of the queue with the given URL.
+func QueueUrlName(rawurl string) string {
+	return path.Base(rawurl)
+}
+
+// ListQueuesAll returns every queue whose name starts with namePrefix,
+// following the pages of the listing.
+func (sqs *SQS) ListQueuesAll(namePrefix string) ([]*Queue, error) {
+	var queues []*Queue
+	it := sqs.IterQueues(namePrefix)
+	for it.Next() {
+		queues = append(queues, it.Queue())
+	}
+	return queues, it.Err()
+}
+
+// A QueueIterator lists queues one at a time, fetching the pages of the
+// listing as needed:
+//
+//	it := sqs.IterQueues("jobs-")
+//	for it.Next() {
+//		q := it.Queue()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type QueueIterator struct {
+	sqs   *SQS
+	opt   ListQueuesOpt
+	page  []*Queue
+	queue *Queue
+	done  bool
+	err   error
+}
+
+// IterQueues returns an iterator over the queues whose name starts with
+// namePrefix.
+func (sqs *SQS) IterQueues(namePrefix string) *QueueIterator {
+	return &QueueIterator{sqs: sqs, opt: ListQueuesOpt{NamePrefix: namePrefix, MaxResults: 1000}}
+}
+
+// Next advances to the next queue and reports whether there is one.
+func (it *QueueIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			it.queue = nil
+			return false
+		}
+		page, err := it.sqs.ListQueuesPage(&it.opt)
+		if err != nil {
+			it.err = err
+			continue
+		}
+		it.page = page.Queues
+		it.opt.NextToken = page.NextToken
+		it.done = page.NextToken == ""
+	}
+	it.queue, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Queue returns the current queue.
+func (it *QueueIterator) Queue() *Queue {
+	return it.queue
+}
+
+// Err returns the error that ended the iteration, if any.
+func (it *QueueIterator) Err() error {
+	return it.err
+}
+
+// APIVersion is the version of the SQS API spoken by the client.
+const APIVersion = "2012-11-05"
+
+// newRequest returns a signed request of action, with params encoded in
+// its URL or, for POST, its body. Requests to a queue are sent to its URL
+// and carry it as the QueueUrl parameter. params is not modified, so that
+// callers may reuse it across retries and goroutines.
+func (sqs *SQS) newRequest(ctx context.Context, method, action, url_ string, params url.Values) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, actionKey{}, action), method, url_, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	params["Action"] = []string{action}
-	params["Timestamp"] = []string{time.Now().UTC().Format(time.RFC3339)}
-	params["Version"] = []string{"2009-02-01"}
+	signer := sqs.Signer
+	if signer == nil {
+		signer = SignatureV2
+	}
+	var creds Credentials
+	if signer != NoSigner {
+		if creds, err = sqs.credentials(req.URL.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	signed := make(url.Values, len(params)+8)
+	for k, v := range params {
+		signed[k] = append([]string(nil), v...)
+	}
+	signed["Action"] = []string{action}
+	signed["Timestamp"] = []string{time.Now().UTC().Format(time.RFC3339)}
+	signed["Version"] = []string{APIVersion}
+	if queueName(req.URL.Path) != "" {
+		signed["QueueUrl"] = []string{url_}
+	}
 
 	req.Header.Set("Host", req.Host)
 
-	sign(sqs.Auth, method, req.URL.Path, params, req.Header)
+	if sqs.Hooks.BeforeSign != nil {
+		sqs.Hooks.BeforeSign(action, signed)
+	}
+	proto := sqs.protocol()
+	if proto == JSONProtocol {
+		if err := sqs.require(FeatureJSONProtocol); err != nil {
+			return nil, err
+		}
+	}
+	method, signed, err = proto.prepare(req, method, action, signed)
+	if err != nil {
+		return nil, err
+	}
+	rs, whole := signer.(requestSigner)
+	if !whole {
+		if err := signer.Sign(creds, method, req.URL.Path, signed, req.Header); err != nil {
+			return nil, err
+		}
+	}
+	proto.finish(req, method, signed)
+	if whole {
+		if err := rs.signRequest(creds, sqs.Region.Name, req, time.Now()); err != nil {
+			return nil, err
+		}
+	}
 	return req, nil
 }
 
@@ -129,8 +540,14 @@ type EmbeddedError struct {
 type ErrorResponse struct {
 	StatusCode    int           // HTTP status code (200, 403, ...)
 	StatusMsg     string        // HTTP status message ("Service Unavailable", "Bad Request", ...)
-	EmbeddedError EmbeddedError `"xml:"Error"`
+	EmbeddedError EmbeddedError `xml:"Error"`
 	RequestId     string        // A unique ID for this request
+
+	// RetryAfter is the delay the service asked for before retrying,
+	// taken from the Retry-After header of throttling responses.
+	RetryAfter time.Duration `xml:"-"`
+	// Exchange is the request and response of the error, with SQS.Debug.
+	Exchange *Exchange `xml:"-"`
 }
 
 func (e ErrorResponse) Error() string {
@@ -143,76 +560,279 @@ func (e ErrorResponse) Error() string {
 		e.EmbeddedError.Message)
 }
 
-func buildError(r *http.Response) error {
+// ErrQueueNotFound is matched, with errors.Is, by the errors returned by
+// Queue, GetQueueUrl and every queue operation when the queue does not
+// exist.
+var ErrQueueNotFound = errors.New("sqs: queue not found")
+
+// Is reports whether e matches target. Responses for a queue that does not
+// exist match ErrQueueNotFound, those refusing an operation during a
+// lockout match ErrPurgeInProgress or ErrQueueDeletedRecently, and those
+// refusing a receipt handle ErrReceiptHandleInvalid or
+// ErrMessageNotInflight.
+func (e ErrorResponse) Is(target error) bool {
+	switch target {
+	case ErrQueueNotFound:
+		switch e.EmbeddedError.Code {
+		case ErrCodeNonExistentQueue, ErrCodeQueueDoesNotExist:
+			return true
+		}
+	case ErrPurgeInProgress:
+		return e.EmbeddedError.Code == ErrCodePurgeQueueInProgress
+	case ErrQueueDeletedRecently:
+		return e.EmbeddedError.Code == ErrCodeQueueDeletedRecently
+	case ErrReceiptHandleInvalid:
+		return e.EmbeddedError.Code == ErrCodeReceiptHandleIsInvalid
+	case ErrMessageNotInflight:
+		return e.EmbeddedError.Code == ErrCodeMessageNotInflight
+	}
+	return false
+}
+
+func buildError(action string, proto Protocol, r *http.Response) error {
 	sqsError := ErrorResponse{}
 	sqsError.StatusCode = r.StatusCode
 	sqsError.StatusMsg = r.Status
-	body, ioErr := ioutil.ReadAll(r.Body)
-	if ioErr != nil {
-		return fmt.Errorf("Could not read error response body: %s", ioErr)
+	sqsError.RetryAfter = parseRetryAfter(r.Header.Get("Retry-After"))
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("sqs: %s: reading %s error response%s: %w", action, r.Status, requestIdSuffix(r.Header), err)
 	}
-	if xmlErr := xml.Unmarshal(body, &sqsError); xmlErr != nil {
-		return fmt.Errorf("Could not unmarshal error response body from xml: %s", xmlErr)
+	if err := proto.decodeError(r, body, &sqsError); err != nil {
+		return fmt.Errorf("sqs: %s: decoding %s error response%s: %w", action, r.Status, requestIdSuffix(r.Header), err)
 	}
 	return &sqsError
 }
 
-func (sqs *SQS) doRequest(req *http.Request, resp interface{}) error {
-	/*dump, _ := http.DumpRequest(req, true)
-	println("req DUMP:\n", string(dump))*/
+// requestIdSuffix returns the request ID of a response header for error
+// messages, or "" if the response carries none.
+func requestIdSuffix(h http.Header) string {
+	if id := h.Get("X-Amzn-Requestid"); id != "" {
+		return " (request " + id + ")"
+	}
+	return ""
+}
+
+// A bodyReader remembers the read error of a response body, so that a body
+// truncated by a network failure is reported as such rather than as a
+// decoding error.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
 
-	r, err := http.DefaultClient.Do(req)
+func (sqs *SQS) doRequest(action string, req *http.Request, resp interface{}) (err error) {
+	start := time.Now()
+	var x *Exchange
+	if sqs.capturing() {
+		x = newExchange(action, req)
+		defer func() { err = sqs.exchangeDone(x, start, err) }()
+	}
+	r, err := sqs.doer().Do(req)
+	if sqs.Hooks.AfterResponse != nil {
+		sqs.Hooks.AfterResponse(action, r, err)
+	}
+	var body []byte
+	if sqs.Logger != nil {
+		defer func() {
+			e := LogEntry{Action: action, Path: req.URL.Path, Duration: time.Since(start), Err: err}
+			if r != nil {
+				e.StatusCode = r.StatusCode
+				e.RequestId = requestId(r.Header, body, err)
+			}
+			sqs.Logger.Debug(e)
+		}()
+	}
 	if err != nil {
 		return err
 	}
 
 	defer r.Body.Close()
-	/*str, _ := http.DumpResponse(r, true)
-	fmt.Printf("response text: %s\n", str)
-	fmt.Printf("response struct: %+v\n", resp)*/
+	if x != nil {
+		defer x.capture(r)()
+	}
+	proto := sqs.protocol()
 	if r.StatusCode != 200 {
-		return buildError(r)
+		return buildError(action, proto, r)
+	}
+	src := io.Reader(r.Body)
+	if sqs.Logger != nil {
+		// Keep the body for the request ID of the log entry.
+		var buf bytes.Buffer
+		src = io.TeeReader(src, &buf)
+		defer func() { body = buf.Bytes() }()
 	}
-	body, _ := ioutil.ReadAll(r.Body)
-	return xml.Unmarshal(body, resp)
+	br := &bodyReader{r: src}
+	if err := proto.decode(br, resp); err != nil {
+		if br.err != nil {
+			return fmt.Errorf("sqs: %s: reading response%s: %w", action, requestIdSuffix(r.Header), br.err)
+		}
+		return fmt.Errorf("sqs: %s: decoding response%s: %w", action, requestIdSuffix(r.Header), err)
+	}
+	return nil
 }
 
-func (sqs *SQS) post(action, path string, params url.Values, body []byte, resp interface{}) error {
-	endpoint := strings.Replace(sqs.Region.EC2Endpoint, "ec2", "sqs", 1) + path
-	req, err := sqs.newRequest("POST", action, endpoint, params)
-	if err != nil {
-		return err
+// endpoint returns the base URL requests are sent to.
+func (sqs *SQS) endpoint() string {
+	if sqs.Endpoint != "" {
+		return strings.TrimRight(sqs.Endpoint, "/")
+	}
+	return strings.Replace(sqs.Region.EC2Endpoint, "ec2", "sqs", 1)
+}
+
+// postActions are the actions sent as POST requests: those changing
+// state, whose parameters, such as message bodies and queue policies, are
+// too large or too sensitive for URLs, which are limited in length and
+// commonly logged. Other actions are sent as GET requests.
+var postActions = map[string]bool{
+	"AddPermission":                true,
+	"CancelMessageMoveTask":        true,
+	"ChangeMessageVisibility":      true,
+	"ChangeMessageVisibilityBatch": true,
+	"CreateQueue":                  true,
+	"DeleteMessage":                true,
+	"DeleteMessageBatch":           true,
+	"DeleteQueue":                  true,
+	"PurgeQueue":                   true,
+	"RemovePermission":             true,
+	"SendMessage":                  true,
+	"SendMessageBatch":             true,
+	"SetQueueAttributes":           true,
+	"StartMessageMoveTask":         true,
+	"TagQueue":                     true,
+	"UntagQueue":                   true,
+}
+
+// actionMethod returns the HTTP method of the requests of action.
+func actionMethod(action string) string {
+	if postActions[action] {
+		return "POST"
 	}
-	req.Header.Set("Content-Type", "x-www-form-urlencoded")
+	return "GET"
+}
 
-	encodedParams := params.Encode()
-	req.Body = ioutil.NopCloser(strings.NewReader(encodedParams))
-	req.ContentLength = int64(len(encodedParams))
+func (sqs *SQS) call(action, path string, params url.Values, resp interface{}) error {
+	return sqs.callContext(context.Background(), action, path, params, resp)
+}
+
+func (sqs *SQS) callContext(ctx context.Context, action, path string, params url.Values, resp interface{}) error {
+	return sqs.callMethod(ctx, actionMethod(action), action, path, params, resp)
+}
 
-	return sqs.doRequest(req, resp)
+// callMethod performs action with an HTTP request of the given method,
+// retried while throttled.
+func (sqs *SQS) callMethod(ctx context.Context, method, action, path string, params url.Values, resp interface{}) error {
+	return sqs.retry(ctx, action, path, func() error {
+		ctx, cancel, timeout := sqs.withTimeout(ctx, action, params)
+		defer cancel()
+		endpoint := sqs.endpoint() + path
+		req, err := sqs.newRequest(ctx, method, action, endpoint, params)
+		if err != nil {
+			return err
+		}
+		return timeoutError(ctx, action, timeout, sqs.send(action, path, req, resp))
+	})
 }
 
-func (sqs *SQS) get(action, path string, params url.Values, resp interface{}) error {
-	if params == nil {
-		params = url.Values{}
+// send performs req, once RateLimiter and CircuitBreaker allow it, and
+// records its outcome.
+func (sqs *SQS) send(action, path_ string, req *http.Request, resp interface{}) error {
+	if sqs.RateLimiter != nil {
+		if err := sqs.RateLimiter.Wait(req.Context(), action); err != nil {
+			return err
+		}
 	}
-	endpoint := strings.Replace(sqs.Region.EC2Endpoint, "ec2", "sqs", 1) + path
-	req, err := sqs.newRequest("GET", action, endpoint, params)
-	if err != nil {
-		return err
+	if sqs.CircuitBreaker != nil {
+		if err := sqs.CircuitBreaker.Allow(); err != nil {
+			return err
+		}
 	}
-
-	if len(params) > 0 {
-		req.URL.RawQuery = params.Encode()
+	start := time.Now()
+	err := sqs.doRequest(action, req, resp)
+	if sqs.CircuitBreaker != nil {
+		sqs.CircuitBreaker.Record(err)
 	}
+	d := time.Since(start)
+	queue := queueName(path_)
+	if sqs.QueueLabels != nil {
+		queue = sqs.QueueLabels.Label(queue)
+	}
+	if sqs.SLO != nil {
+		sqs.SLO.Record(action, queue, d, err)
+	}
+	if sqs.QueueLabels == nil {
+		queue = ""
+	}
+	sqs.recordMetrics(action, queue, d, err, resp)
+	return err
+}
 
-	return sqs.doRequest(req, resp)
+// queueName returns the name of the queue addressed by path_, or "" for the
+// account-level path.
+func queueName(path_ string) string {
+	if path_ == "" || path_ == "/" {
+		return ""
+	}
+	return path.Base(path_)
 }
 
 func (q *Queue) Name() string {
 	return path.Base(q.path)
 }
 
+// AccountId returns the AWS account ID that owns the queue.
+func (q *Queue) AccountId() string {
+	return path.Base(path.Dir(q.path))
+}
+
+// Arn returns the queue's Amazon Resource Name.
+func (q *Queue) Arn() string {
+	return q.SQS.arn(q.path)
+}
+
+// arn returns the ARN of the queue addressed by path_.
+func (sqs *SQS) arn(path_ string) string {
+	return "arn:aws:sqs:" + sqs.Region.Name + ":" + path.Base(path.Dir(path_)) + ":" + path.Base(path_)
+}
+
+// QueueByArn returns the queue with the given ARN, in the region of the
+// client, without a request. It addresses the queues of other accounts,
+// whose requests can be signed with SetQueueCredentialsProvider.
+func (sqs *SQS) QueueByArn(arn string) (*Queue, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sqs" || parts[4] == "" || parts[5] == "" {
+		return nil, fmt.Errorf("sqs: invalid queue ARN %q", arn)
+	}
+	if parts[3] != sqs.Region.Name {
+		return nil, fmt.Errorf("sqs: queue %s is not in region %s", arn, sqs.Region.Name)
+	}
+	return sqs.QueueByName(parts[4], parts[5])
+}
+
+// QueueByName returns the queue with the given name owned by the account
+// accountId, in the region of the client, without a request. Services
+// that know their queue need neither a GetQueueUrl round trip at startup
+// nor the permission to make it.
+func (sqs *SQS) QueueByName(accountId, name string) (*Queue, error) {
+	if accountId == "" || name == "" || strings.Contains(accountId+name, "/") {
+		return nil, fmt.Errorf("sqs: invalid queue %q of account %q", name, accountId)
+	}
+	return &Queue{SQS: sqs, path: "/" + accountId + "/" + name}, nil
+}
+
+// URL returns the queue's URL.
+func (q *Queue) URL() string {
+	return q.endpoint() + q.path
+}
+
 // AddPermission adds a permission to a queue for a specific principal.
 //
 // See http://goo.gl/vG4CP for more details.
@@ -221,15 +841,121 @@ func (q *Queue) AddPermission() error {
 }
 
 // ChangeMessageVisibility changes the visibility timeout of a specified message
-// in a queue to a new value.
+// in a queue to a new value, in seconds. A timeout of zero makes the message
+// immediately available for redelivery.
 //
 // See http://goo.gl/tORrh for more details.
-func (q *Queue) ChangeMessageVisibility() error {
-	return nil
+func (q *Queue) ChangeMessageVisibility(m *Message, visibilityTimeout int) error {
+	if !q.DisableValidation {
+		if err := validateVisibilityTimeout(visibilityTimeout); err != nil {
+			return err
+		}
+	}
+	params := url.Values{}
+	params.Set("ReceiptHandle", q.receiptHandle(m.ReceiptHandle))
+	params.Set("VisibilityTimeout", strconv.Itoa(visibilityTimeout))
+	var resp ResponseMetadata
+	return q.call("ChangeMessageVisibility", q.path, params, &resp)
 }
 
+// CreateQueueOpt holds the attributes of a new queue. Zero fields are left
+// to the service defaults; Attributes can set any attribute explicitly,
+// including to zero, and takes precedence over the typed fields.
 type CreateQueueOpt struct {
+	// Deprecated: use VisibilityTimeout.
 	DefaultVisibilityTimeout int
+
+	// VisibilityTimeout is the default visibility timeout of received
+	// messages, in seconds.
+	VisibilityTimeout int
+	// DelaySeconds postpones the delivery of every new message by up to
+	// MaxDelaySeconds.
+	DelaySeconds int
+	// MaximumMessageSize is the largest message body accepted, in bytes.
+	MaximumMessageSize int
+	// MessageRetentionPeriod is how long messages are kept, in seconds.
+	MessageRetentionPeriod int
+	// ReceiveMessageWaitTimeSeconds is the default long-polling wait time.
+	ReceiveMessageWaitTimeSeconds int
+
+	Policy        string
+	RedrivePolicy *RedrivePolicy
+
+	// KmsMasterKeyId enables server-side encryption with a KMS key
+	// (SSE-KMS), and KmsDataKeyReusePeriodSeconds sets how long SQS
+	// reuses a data key before calling KMS again. SqsManagedSseEnabled
+	// enables encryption with keys managed by SQS (SSE-SQS) instead.
+	KmsMasterKeyId               string
+	KmsDataKeyReusePeriodSeconds int
+	SqsManagedSseEnabled         bool
+
+	// FifoQueue creates a FIFO queue, whose name must end in ".fifo".
+	FifoQueue                 bool
+	ContentBasedDeduplication bool
+
+	Attributes map[Attribute]string
+}
+
+// attributes returns the queue attributes set in opt.
+func (opt *CreateQueueOpt) attributes() map[Attribute]string {
+	attrs := make(map[Attribute]string)
+	setInt := func(name Attribute, v int) {
+		if v > 0 {
+			attrs[name] = strconv.Itoa(v)
+		}
+	}
+	setInt(VisibilityTimeout, opt.DefaultVisibilityTimeout)
+	setInt(VisibilityTimeout, opt.VisibilityTimeout)
+	setInt(DelaySeconds, opt.DelaySeconds)
+	setInt(MaximumMessageSize, opt.MaximumMessageSize)
+	setInt(MessageRetentionPeriod, opt.MessageRetentionPeriod)
+	setInt(ReceiveMessageWaitTimeSeconds, opt.ReceiveMessageWaitTimeSeconds)
+	if opt.Policy != "" {
+		attrs[Policy] = opt.Policy
+	}
+	if opt.RedrivePolicy != nil {
+		attrs[RedrivePolicyAttribute] = opt.RedrivePolicy.String()
+	}
+	if opt.KmsMasterKeyId != "" {
+		attrs[KmsMasterKeyId] = opt.KmsMasterKeyId
+	}
+	setInt(KmsDataKeyReusePeriodSeconds, opt.KmsDataKeyReusePeriodSeconds)
+	if opt.SqsManagedSseEnabled {
+		attrs[SqsManagedSseEnabled] = "true"
+	}
+	if opt.FifoQueue {
+		attrs[FifoQueue] = "true"
+	}
+	if opt.ContentBasedDeduplication {
+		attrs[ContentBasedDeduplication] = "true"
+	}
+	for name, v := range opt.Attributes {
+		attrs[name] = v
+	}
+	return attrs
+}
+
+// WithDeadLetter sets the redrive policy of opt to move messages received
+// maxReceiveCount times to the dead letter queue arn, and returns opt.
+func (opt *CreateQueueOpt) WithDeadLetter(arn string, maxReceiveCount int) *CreateQueueOpt {
+	opt.RedrivePolicy = &RedrivePolicy{DeadLetterTargetArn: arn, MaxReceiveCount: maxReceiveCount}
+	return opt
+}
+
+// WithPolicy sets the resource policy of opt to p, and returns opt.
+func (opt *CreateQueueOpt) WithPolicy(p *PolicyDocument) *CreateQueueOpt {
+	opt.Policy = p.String()
+	return opt
+}
+
+// MaxDelaySeconds is the longest delivery delay SQS supports.
+const MaxDelaySeconds = 900
+
+func validateDelay(d int) error {
+	if d < 0 || d > MaxDelaySeconds {
+		return invalid("DelaySeconds", "DelaySeconds must be between 0 and %d, got %d", MaxDelaySeconds, d)
+	}
+	return nil
 }
 
 type createQueuesResponse struct {
@@ -241,22 +967,44 @@ type createQueuesResponse struct {
 //
 // See http://goo.gl/EwNUK for more details.
 func (sqs *SQS) CreateQueue(name string, opt *CreateQueueOpt) (*Queue, error) {
+	if !sqs.DisableValidation {
+		if err := validateQueueName(name); err != nil {
+			return nil, err
+		}
+	}
 	params := url.Values{
 		"QueueName": []string{name},
 	}
 	if opt != nil {
-		dvt := strconv.Itoa(opt.DefaultVisibilityTimeout)
-		params["DefaultVisibilityTimeout"] = []string{dvt}
+		if !sqs.DisableValidation {
+			if err := validateDelay(opt.DelaySeconds); err != nil {
+				return nil, err
+			}
+		}
+		if opt.RedrivePolicy != nil {
+			if err := opt.RedrivePolicy.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		attrs := opt.attributes()
+		if attrs[FifoQueue] == "true" {
+			if !strings.HasSuffix(name, ".fifo") {
+				return nil, invalid("QueueName", "FIFO queue name %q must end in .fifo", name)
+			}
+			if err := sqs.require(FeatureFIFO); err != nil {
+				return nil, err
+			}
+		}
+		encodeAttributes(params, attrs)
 	}
 	var resp createQueuesResponse
-	if err := sqs.get("CreateQueue", "/", params, &resp); err != nil {
-		return nil, err
-	}
-	u, err := url.Parse(resp.QueueUrl)
+	err := sqs.waitLockout(context.Background(), "create:"+name, ErrQueueDeletedRecently, func() error {
+		return sqs.call("CreateQueue", "/", params, &resp)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &Queue{sqs, u.Path}, nil
+	return sqs.queueFromUrl(resp.QueueUrl)
 }
 
 // DeleteQueue deletes a queue.
@@ -265,64 +1013,562 @@ func (sqs *SQS) CreateQueue(name string, opt *CreateQueueOpt) (*Queue, error) {
 func (q *Queue) DeleteQueue() error {
 	params := url.Values{}
 	var resp ResponseMetadata
-	if err := q.SQS.get("DeleteQueue", q.path, params, &resp); err != nil {
+	if err := q.call("DeleteQueue", q.path, params, &resp); err != nil {
 		return err
 	}
+	q.lockouts.start("create:"+q.Name(), DeletedQueueLockout)
 	return nil
 }
 
+// PurgeQueue deletes every message in the queue. SQS allows one purge per
+// queue every PurgeLockout; see SQS.LockoutWait.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_PurgeQueue.html
+// for more details.
+func (q *Queue) PurgeQueue() error {
+	var resp ResponseMetadata
+	err := q.waitLockout(q.Context(), q.purgeKey(), ErrPurgeInProgress, func() error {
+		return q.call("PurgeQueue", q.path, url.Values{}, &resp)
+	})
+	if err == nil {
+		q.lockouts.start(q.purgeKey(), PurgeLockout)
+	}
+	return err
+}
+
 // DeleteMessage deletes a message from the queue.
 //
 // See http://goo.gl/t8jnk for more details.
 func (q *Queue) DeleteMessage(m *Message) error {
 	var resp interface{}
 	params := url.Values{}
-	params.Set("ReceiptHandle", m.ReceiptHandle)
-	if err := q.get("DeleteMessage", q.path, params, &resp); err != nil {
+	params.Set("ReceiptHandle", q.receiptHandle(m.ReceiptHandle))
+	if err := q.call("DeleteMessage", q.path, params, &resp); err != nil {
 		return err
 	}
-	return nil
+	return q.deleted(m.ReceiptHandle)
+}
+
+// DeleteMessageByHandle deletes the message with the given receipt
+// handle, for architectures passing handles rather than messages between
+// processes. Its error matches ErrReceiptHandleInvalid when the handle is
+// stale, e.g. of a message already deleted.
+func (q *Queue) DeleteMessageByHandle(handle string) error {
+	return q.DeleteMessage(&Message{ReceiptHandle: handle})
+}
+
+type DeleteMessageBatchResultEntry struct {
+	Id string
+}
+
+type DeleteMessageBatchResult struct {
+	Successful []DeleteMessageBatchResultEntry `xml:"DeleteMessageBatchResult>DeleteMessageBatchResultEntry"`
+	Failed     []BatchResultErrorEntry         `xml:"DeleteMessageBatchResult>BatchResultErrorEntry"`
+	ResponseMetadata
+}
+
+// DeleteMessageBatch deletes up to 10 messages from the queue in one
+// request. The entries of the result are identified by the index of their
+// message in msgs. Entries can fail individually; they are reported in the
+// result's Failed list, see its Err and FailedMessages methods. Only a
+// failure of the whole request returns an error, as does a failure to
+// release what a HandleTransformer holds for a deleted message, in which
+// case the result is returned too.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_DeleteMessageBatch.html
+// for more details.
+func (q *Queue) DeleteMessageBatch(msgs []*Message) (*DeleteMessageBatchResult, error) {
+	if !q.DisableValidation {
+		if err := validateBatchSize(len(msgs)); err != nil {
+			return nil, err
+		}
+	}
+	params := url.Values{}
+	for i, m := range msgs {
+		prefix := fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.", i+1)
+		params.Set(prefix+"Id", strconv.Itoa(i))
+		params.Set(prefix+"ReceiptHandle", q.receiptHandle(m.ReceiptHandle))
+	}
+	var resp DeleteMessageBatchResult
+	if err := q.call("DeleteMessageBatch", q.path, params, &resp); err != nil {
+		return nil, err
+	}
+	for _, e := range resp.Successful {
+		i, err := strconv.Atoi(e.Id)
+		if err != nil || i < 0 || i >= len(msgs) {
+			continue
+		}
+		if err := q.deleted(msgs[i].ReceiptHandle); err != nil {
+			return &resp, err
+		}
+	}
+	return &resp, nil
 }
 
 type QueueAttributes struct {
 	Attributes []struct {
 		Name  string
 		Value string
-	}
+	} `xml:"GetQueueAttributesResult>Attribute"`
 	ResponseMetadata
 }
 
+// get returns the value of the named attribute, if present.
+func (a *QueueAttributes) get(name Attribute) (string, bool) {
+	for _, attr := range a.Attributes {
+		if attr.Name == string(name) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+func (a *QueueAttributes) int(name Attribute) int {
+	v, _ := a.get(name)
+	n, _ := strconv.Atoi(v)
+	return n
+}
+
+func (a *QueueAttributes) seconds(name Attribute) time.Duration {
+	return time.Duration(a.int(name)) * time.Second
+}
+
+func (a *QueueAttributes) timestamp(name Attribute) time.Time {
+	v, ok := a.get(name)
+	if !ok {
+		return time.Time{}
+	}
+	n, _ := strconv.ParseInt(v, 10, 64)
+	return time.Unix(n, 0)
+}
+
+// The typed accessors below return the zero value when the attribute was
+// not requested.
+
+// ApproximateNumberOfMessages returns the number of messages available for
+// retrieval.
+func (a *QueueAttributes) ApproximateNumberOfMessages() int {
+	return a.int(ApproximateNumberOfMessages)
+}
+
+// ApproximateNumberOfMessagesNotVisible returns the number of messages in
+// flight.
+func (a *QueueAttributes) ApproximateNumberOfMessagesNotVisible() int {
+	return a.int(ApproximateNumberOfMessagesNotVisible)
+}
+
+// ApproximateNumberOfMessagesDelayed returns the number of messages not
+// yet available because of a delivery delay.
+func (a *QueueAttributes) ApproximateNumberOfMessagesDelayed() int {
+	return a.int(ApproximateNumberOfMessagesDelayed)
+}
+
+// VisibilityTimeout returns the default visibility timeout of the queue.
+func (a *QueueAttributes) VisibilityTimeout() time.Duration {
+	return a.seconds(VisibilityTimeout)
+}
+
+// DelaySeconds returns the default delivery delay of the queue.
+func (a *QueueAttributes) DelaySeconds() time.Duration {
+	return a.seconds(DelaySeconds)
+}
+
+// MessageRetentionPeriod returns how long the queue keeps messages.
+func (a *QueueAttributes) MessageRetentionPeriod() time.Duration {
+	return a.seconds(MessageRetentionPeriod)
+}
+
+// ReceiveMessageWaitTime returns the default long-polling wait time.
+func (a *QueueAttributes) ReceiveMessageWaitTime() time.Duration {
+	return a.seconds(ReceiveMessageWaitTimeSeconds)
+}
+
+// MaximumMessageSize returns the largest message body accepted, in bytes.
+func (a *QueueAttributes) MaximumMessageSize() int {
+	return a.int(MaximumMessageSize)
+}
+
+// CreatedTimestamp returns when the queue was created.
+func (a *QueueAttributes) CreatedTimestamp() time.Time {
+	return a.timestamp(CreatedTimestamp)
+}
+
+// LastModifiedTimestamp returns when the queue attributes last changed.
+func (a *QueueAttributes) LastModifiedTimestamp() time.Time {
+	return a.timestamp(LastModifiedTimestamp)
+}
+
+// Arn returns the Amazon resource name of the queue.
+func (a *QueueAttributes) Arn() string {
+	v, _ := a.get(QueueArn)
+	return v
+}
+
+// Policy returns the access policy of the queue.
+func (a *QueueAttributes) Policy() string {
+	v, _ := a.get(Policy)
+	return v
+}
+
+// KmsMasterKeyId returns the KMS key encrypting the queue with SSE-KMS, or
+// "" if it is not.
+func (a *QueueAttributes) KmsMasterKeyId() string {
+	v, _ := a.get(KmsMasterKeyId)
+	return v
+}
+
+// KmsDataKeyReusePeriod returns how long SQS reuses a data key under
+// SSE-KMS.
+func (a *QueueAttributes) KmsDataKeyReusePeriod() time.Duration {
+	return a.seconds(KmsDataKeyReusePeriodSeconds)
+}
+
+// SqsManagedSseEnabled reports whether the queue is encrypted with keys
+// managed by SQS.
+func (a *QueueAttributes) SqsManagedSseEnabled() bool {
+	v, _ := a.get(SqsManagedSseEnabled)
+	return v == "true"
+}
+
+// Encrypted reports whether the queue is encrypted at rest, with either
+// SSE-KMS or SSE-SQS.
+func (a *QueueAttributes) Encrypted() bool {
+	return a.KmsMasterKeyId() != "" || a.SqsManagedSseEnabled()
+}
+
+// QueueStats are the approximate message counts of a queue.
+type QueueStats struct {
+	Visible  int // available for retrieval
+	InFlight int // received and not yet deleted or visible again
+	Delayed  int // not yet available because of a delivery delay
+}
+
+// Pending returns the number of messages not yet deleted, including those
+// delayed.
+func (st QueueStats) Pending() int {
+	return st.Visible + st.InFlight + st.Delayed
+}
+
+// Empty reports whether the queue holds no message at all. A queue with
+// delayed messages only is not empty, although receives return nothing.
+func (st QueueStats) Empty() bool {
+	return st.Pending() == 0
+}
+
+// Stats returns the approximate message counts of the queue.
+func (q *Queue) Stats() (QueueStats, error) {
+	attrs, err := q.GetQueueAttributes(ApproximateNumberOfMessages, ApproximateNumberOfMessagesNotVisible, ApproximateNumberOfMessagesDelayed)
+	if err != nil {
+		return QueueStats{}, err
+	}
+	return QueueStats{
+		Visible:  attrs.ApproximateNumberOfMessages(),
+		InFlight: attrs.ApproximateNumberOfMessagesNotVisible(),
+		Delayed:  attrs.ApproximateNumberOfMessagesDelayed(),
+	}, nil
+}
+
 // GetQueueAttributes returns one or all attributes of a queue.
 //
 // See http://goo.gl/X01zD for more details.
 func (q *Queue) GetQueueAttributes(attrs ...Attribute) (*QueueAttributes, error) {
 	params := url.Values{}
 	for i, attr := range attrs {
-		key := fmt.Sprintf("Attribute.%d", i)
-		params[key] = []string{string(attr)}
+		params.Set(fmt.Sprintf("AttributeName.%d", i+1), string(attr))
 	}
 	var resp QueueAttributes
-	if err := q.get("GetQueueAttributes", q.path, params, &resp); err != nil {
+	if err := q.call("GetQueueAttributes", q.path, params, &resp); err != nil {
 		return nil, err
 	}
 	return &resp, nil
 }
 
 type Message struct {
-	Id            string `xml:"ReceiveMessageResult>Message>MessageId"`
-	Body          string `xml:"ReceiveMessageResult>Message>Body"`
-	ReceiptHandle string `xml:"ReceiveMessageResult>Message>ReceiptHandle"`
+	Id            string `xml:"MessageId"`
+	Body          string `xml:"Body"`
+	ReceiptHandle string `xml:"ReceiptHandle"`
+	MD5OfBody     string `xml:"MD5OfBody"`
+
+	// MessageAttributes holds the message's custom attributes, if they
+	// were requested with ReceiveMessageOpt.MessageAttributeNames.
+	MessageAttributes MessageAttributes `xml:"MessageAttribute"`
+	// SystemAttributes holds the attributes SQS maintains for the message,
+	// if they were requested with ReceiveMessageOpt.AttributeNames.
+	SystemAttributes SystemAttributes `xml:"Attribute"`
+	// SNS holds the envelope of a message delivered by SNS, once
+	// unwrapped by the SNSUnwrap transformer.
+	SNS *SNSNotification `xml:"-" json:",omitempty"`
+
+	ctx context.Context
+}
+
+// Context returns the context the message was produced in, as extracted
+// by the Tracing transformer, or the background context.
+func (m *Message) Context() context.Context {
+	if m.ctx != nil {
+		return m.ctx
+	}
+	return context.Background()
+}
+
+// WithContext returns a shallow copy of m with its context changed to ctx.
+func (m *Message) WithContext(ctx context.Context) *Message {
+	m2 := *m
+	m2.ctx = ctx
+	return &m2
+}
+
+// SystemAttributes are the per-message attributes maintained by SQS.
+type SystemAttributes struct {
+	SentTimestamp                    time.Time
+	ApproximateFirstReceiveTimestamp time.Time
+	ApproximateReceiveCount          int
+	SenderId                         string
+
+	// MessageGroupId, MessageDeduplicationId and SequenceNumber are set
+	// on the messages of FIFO queues.
+	MessageGroupId         string
+	MessageDeduplicationId string
+	SequenceNumber         string
+
+	// AWSTraceHeader is the X-Ray trace header the message was sent
+	// with. Unlike the other system attributes, it is set by senders.
+	AWSTraceHeader string
+
+	// Raw holds every returned attribute, including those without a
+	// typed field, by name.
+	Raw map[string]string
+}
+
+// UnmarshalXML decodes one Attribute element of a message.
+func (a *SystemAttributes) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var attr struct {
+		Name  string
+		Value string
+	}
+	if err := d.DecodeElement(&attr, &start); err != nil {
+		return err
+	}
+	return a.set(attr.Name, attr.Value)
+}
+
+// set sets the attribute name to value.
+func (a *SystemAttributes) set(name, value string) error {
+	if a.Raw == nil {
+		a.Raw = make(map[string]string)
+	}
+	a.Raw[name] = value
+	var err error
+	switch Attribute(name) {
+	case SentTimestamp:
+		a.SentTimestamp, err = parseEpochMillis(value)
+	case ApproximateFirstReceiveTimestamp:
+		a.ApproximateFirstReceiveTimestamp, err = parseEpochMillis(value)
+	case ApproximateReceiveCount:
+		a.ApproximateReceiveCount, err = strconv.Atoi(value)
+	case SenderId:
+		a.SenderId = value
+	case MessageGroupId:
+		a.MessageGroupId = value
+	case MessageDeduplicationId:
+		a.MessageDeduplicationId = value
+	case SequenceNumber:
+		a.SequenceNumber = value
+	case AWSTraceHeader:
+		a.AWSTraceHeader = value
+	}
+	if err != nil {
+		return fmt.Errorf("sqs: invalid %s attribute %q", name, value)
+	}
+	return nil
+}
+
+// parseEpochMillis parses a timestamp in milliseconds since the epoch.
+func parseEpochMillis(s string) (time.Time, error) {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond)), nil
+}
+
+// A MessageAttributeValue is the typed value of a custom message attribute.
+// DataType is "String", "Number" or "Binary", optionally followed by a
+// custom type label ("Number.float").
+type MessageAttributeValue struct {
+	DataType    string
+	StringValue string
+	BinaryValue []byte
+}
+
+// MessageAttributes maps custom attribute names to their values.
+type MessageAttributes map[string]MessageAttributeValue
+
+// UnmarshalXML decodes one MessageAttribute element into the map.
+func (a *MessageAttributes) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var attr struct {
+		Name  string
+		Value struct {
+			DataType    string
+			StringValue string
+			BinaryValue string
+		}
+	}
+	if err := d.DecodeElement(&attr, &start); err != nil {
+		return err
+	}
+	v := MessageAttributeValue{DataType: attr.Value.DataType, StringValue: attr.Value.StringValue}
+	if attr.Value.BinaryValue != "" {
+		b, err := base64.StdEncoding.DecodeString(attr.Value.BinaryValue)
+		if err != nil {
+			return fmt.Errorf("sqs: invalid binary value for message attribute %q: %s", attr.Name, err)
+		}
+		v.BinaryValue = b
+	}
+	if *a == nil {
+		*a = make(MessageAttributes)
+	}
+	(*a)[attr.Name] = v
+	return nil
+}
+
+// VisibilityZero requests a visibility timeout of zero seconds, leaving
+// received messages immediately visible to other consumers.
+const VisibilityZero = -1
+
+type ReceiveMessageOpt struct {
+	// MaxNumberOfMessages is the maximum number of messages to return,
+	// from 1 to 10. It defaults to 1.
+	MaxNumberOfMessages int
+	// VisibilityTimeout overrides the queue's visibility timeout for the
+	// received messages, in seconds, when positive. Use VisibilityZero to
+	// peek at messages without hiding them.
+	VisibilityTimeout int
+	// WaitTimeSeconds enables long polling for up to 20 seconds.
+	WaitTimeSeconds int
+	// MessageAttributeNames lists the custom message attributes to return;
+	// "All" returns every attribute.
+	MessageAttributeNames []string
+	// AttributeNames lists the system attributes to return, such as
+	// SentTimestamp or ApproximateReceiveCount; All returns every one.
+	AttributeNames []Attribute
+	// ReceiveRequestAttemptId identifies a receive from a FIFO queue:
+	// for five minutes, receives with the same attempt ID return the
+	// same messages, as long as they were not deleted or changed, instead
+	// of losing them to their visibility timeout when a response never
+	// arrives. When empty, receives from queues whose name ends in
+	// ".fifo" generate one, which the client uses to retry those failing
+	// on the network up to MaxRetries times.
+	ReceiveRequestAttemptId string
+}
+
+type receiveMessageResponse struct {
+	Messages []*Message `xml:"ReceiveMessageResult>Message"`
+	ResponseMetadata
 }
 
 // ReceiveMessage retrieves one or more messages from the queue.
 //
 // See http://goo.gl/8RLI4 for more details.
 func (q *Queue) ReceiveMessage() (*Message, error) {
-	var resp Message
-	if err := q.get("ReceiveMessage", q.path, nil, &resp); err != nil {
+	msgs, err := q.ReceiveMessages(nil)
+	if err != nil {
 		return nil, err
 	}
-	return &resp, nil
+	if len(msgs) == 0 {
+		return &Message{}, nil
+	}
+	return msgs[0], nil
+}
+
+// ReceiveMessages retrieves up to opt.MaxNumberOfMessages messages from the
+// queue. It returns an empty slice when no message is available. Messages
+// failing their MD5 check or their decoding are reported by a
+// *ReceiveError, returned along with the other messages.
+//
+// See http://goo.gl/8RLI4 for more details.
+func (q *Queue) ReceiveMessages(opt *ReceiveMessageOpt) ([]*Message, error) {
+	opt = q.receiveOpt(opt)
+	if !q.DisableValidation {
+		if err := validateReceive(opt); err != nil {
+			return nil, err
+		}
+	}
+	params := url.Values{}
+	if opt.MaxNumberOfMessages > 0 {
+		params.Set("MaxNumberOfMessages", strconv.Itoa(opt.MaxNumberOfMessages))
+	}
+	switch {
+	case opt.VisibilityTimeout == VisibilityZero:
+		params.Set("VisibilityTimeout", "0")
+	case opt.VisibilityTimeout > 0:
+		params.Set("VisibilityTimeout", strconv.Itoa(opt.VisibilityTimeout))
+	}
+	if opt.WaitTimeSeconds > 0 {
+		params.Set("WaitTimeSeconds", strconv.Itoa(opt.WaitTimeSeconds))
+	}
+	for i, name := range q.attributeNames(opt.MessageAttributeNames) {
+		params.Set(fmt.Sprintf("MessageAttributeName.%d", i+1), name)
+	}
+	for i, name := range q.systemAttributeNames(opt.AttributeNames) {
+		params.Set(fmt.Sprintf("AttributeName.%d", i+1), string(name))
+	}
+	attemptId := opt.ReceiveRequestAttemptId
+	if attemptId == "" && strings.HasSuffix(q.Name(), ".fifo") {
+		id, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+		attemptId = id
+	}
+	if attemptId != "" {
+		params.Set("ReceiveRequestAttemptId", attemptId)
+	}
+	var resp receiveMessageResponse
+	err := q.call("ReceiveMessage", q.path, params, &resp)
+	// Retrying with the same attempt ID returns the messages of a
+	// response that was lost.
+	for attempt := 1; err != nil && attemptId != "" && attempt <= q.SQS.MaxRetries && transportError(err) && q.Context().Err() == nil; attempt++ {
+		if q.SQS.Hooks.OnRetry != nil {
+			q.SQS.Hooks.OnRetry("ReceiveMessage", q.path, attempt, err)
+		}
+		resp = receiveMessageResponse{}
+		err = q.call("ReceiveMessage", q.path, params, &resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A message failing its checks must not take the others down with
+	// it: they would stay hidden until their visibility timeout expires.
+	msgs := make([]*Message, 0, len(resp.Messages))
+	var failed []*DecodeError
+	for _, m := range resp.Messages {
+		if err := q.verifyMD5(m.Id, m.Body, m.MD5OfBody); err != nil {
+			failed = append(failed, &DecodeError{Message: m, Err: err})
+			continue
+		}
+		if err := q.decode(m); err != nil {
+			var de *DecodeError
+			if !errors.As(err, &de) {
+				de = &DecodeError{Message: m, Err: err}
+			}
+			failed = append(failed, de)
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	if len(failed) > 0 {
+		return msgs, &ReceiveError{Received: len(resp.Messages), Failed: failed}
+	}
+	return msgs, nil
+}
+
+// transportError reports whether err is a failure to get a response, such
+// as a connection reset or a timeout, rather than an error of SQS.
+func transportError(err error) bool {
+	var ue *url.Error
+	var ne net.Error
+	return errors.As(err, &ue) || errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF)
 }
 
 // RemovePermission removes a permission from a queue for a specific principal.
@@ -333,28 +1579,243 @@ func (q *Queue) RemovePermission() error {
 }
 
 type sendMessageResponse struct {
-	Id string `xml:"SendMessageResult>MessageId"`
+	Id               string `xml:"SendMessageResult>MessageId"`
+	MD5OfMessageBody string `xml:"SendMessageResult>MD5OfMessageBody"`
 	ResponseMetadata
 }
 
+type SendMessageOpt struct {
+	// MessageAttributes are custom attributes delivered with the message.
+	MessageAttributes MessageAttributes
+	// DelaySeconds postpones the delivery of the message by up to
+	// MaxDelaySeconds, overriding the queue's default delay.
+	DelaySeconds int
+	// MessageGroupId is the group of a message sent to a FIFO queue, which
+	// delivers the messages of a group in order.
+	MessageGroupId string
+	// MessageDeduplicationId identifies a message sent to a FIFO queue:
+	// messages with the same ID sent within five minutes are accepted but
+	// not delivered again.
+	MessageDeduplicationId string
+	// AWSTraceHeader is the X-Ray trace header of the message, sent as a
+	// message system attribute rather than a custom one, so that X-Ray
+	// follows the trace through the queue.
+	AWSTraceHeader string
+}
+
 // SendMessage delivers a message to the specified queue.
 // It returns the sent message's ID.
 //
 // See http://goo.gl/ThjJG for more details.
 func (q *Queue) SendMessage(body string) (string, error) {
-	params := url.Values{
-		"MessageBody": []string{body},
+	return q.SendMessageWithOpt(body, nil)
+}
+
+// SendMessageWithOpt delivers a message with the given options to the
+// specified queue. It returns the sent message's ID.
+//
+// See http://goo.gl/ThjJG for more details.
+func (q *Queue) SendMessageWithOpt(body string, opt *SendMessageOpt) (string, error) {
+	m := &Message{Body: body}
+	params := url.Values{}
+	delay := 0
+	if opt != nil {
+		delay = opt.DelaySeconds
+	}
+	if delay = q.sendDelay(delay); delay != 0 {
+		if !q.DisableValidation {
+			if err := validateDelay(delay); err != nil {
+				return "", err
+			}
+		}
+		params.Set("DelaySeconds", strconv.Itoa(delay))
+	}
+	if opt != nil {
+		m.SystemAttributes.AWSTraceHeader = opt.AWSTraceHeader
+		if opt.MessageGroupId != "" {
+			params.Set("MessageGroupId", opt.MessageGroupId)
+		}
+		if opt.MessageDeduplicationId != "" {
+			params.Set("MessageDeduplicationId", opt.MessageDeduplicationId)
+		}
+		m.MessageAttributes = opt.MessageAttributes
+	}
+	if err := q.encode(m); err != nil {
+		return "", err
+	}
+	if !q.DisableValidation {
+		if err := validateMessage(m); err != nil {
+			return "", err
+		}
 	}
+	params.Set("MessageBody", m.Body)
+	encodeMessageAttributes(params, "", m.MessageAttributes)
+	encodeSystemAttributes(params, "", &m.SystemAttributes)
 	var resp sendMessageResponse
-	if err := q.get("SendMessage", q.path, params, &resp); err != nil {
+	if err := q.call("SendMessage", q.path, params, &resp); err != nil {
+		return "", err
+	}
+	if err := q.verifyMD5(resp.Id, m.Body, resp.MD5OfMessageBody); err != nil {
 		return "", err
 	}
 	return resp.Id, nil
 }
 
-// SetQueueAttributes sets one attribute of a queue.
+// A SendMessageBatchEntry is one message of a SendMessageBatch request.
+// Id identifies the entry in the result and must be unique in the batch.
+type SendMessageBatchEntry struct {
+	Id                string
+	Body              string
+	DelaySeconds      int
+	MessageAttributes MessageAttributes
+	// MessageGroupId and MessageDeduplicationId are as in SendMessageOpt.
+	MessageGroupId         string
+	MessageDeduplicationId string
+	AWSTraceHeader         string
+}
+
+// A BatchResultErrorEntry reports the failure of one entry of a batch
+// request.
+type BatchResultErrorEntry struct {
+	Id          string
+	Code        string
+	Message     string
+	SenderFault bool
+}
+
+func (e *BatchResultErrorEntry) Error() string {
+	return fmt.Sprintf("sqs: batch entry %s failed: %s: %s", e.Id, e.Code, e.Message)
+}
+
+type SendMessageBatchResultEntry struct {
+	Id               string
+	MessageId        string
+	MD5OfMessageBody string
+}
+
+type SendMessageBatchResult struct {
+	Successful []SendMessageBatchResultEntry `xml:"SendMessageBatchResult>SendMessageBatchResultEntry"`
+	Failed     []BatchResultErrorEntry       `xml:"SendMessageBatchResult>BatchResultErrorEntry"`
+	ResponseMetadata
+}
+
+// SendMessageBatch delivers up to 10 messages to the queue in one request.
+// Entries can fail individually; they are reported in the result's Failed
+// list, see its Err and FailedEntries methods, and only a failure of the
+// whole request returns an error.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SendMessageBatch.html
+// for more details.
+func (q *Queue) SendMessageBatch(entries []SendMessageBatchEntry) (*SendMessageBatchResult, error) {
+	if !q.DisableValidation {
+		ids := make([]string, len(entries))
+		for i, e := range entries {
+			ids[i] = e.Id
+		}
+		if err := validateBatch(ids); err != nil {
+			return nil, err
+		}
+	}
+	params := url.Values{}
+	bodies := make(map[string]string, len(entries))
+	for i, e := range entries {
+		e.DelaySeconds = q.sendDelay(e.DelaySeconds)
+		if !q.DisableValidation {
+			if err := validateDelay(e.DelaySeconds); err != nil {
+				return nil, err
+			}
+		}
+		m := &Message{Body: e.Body, MessageAttributes: e.MessageAttributes}
+		m.SystemAttributes.AWSTraceHeader = e.AWSTraceHeader
+		if err := q.encode(m); err != nil {
+			return nil, err
+		}
+		if !q.DisableValidation {
+			if err := validateMessage(m); err != nil {
+				return nil, inEntry(e.Id, err)
+			}
+		}
+		bodies[e.Id] = m.Body
+		prefix := fmt.Sprintf("SendMessageBatchRequestEntry.%d.", i+1)
+		params.Set(prefix+"Id", e.Id)
+		params.Set(prefix+"MessageBody", m.Body)
+		if e.DelaySeconds > 0 {
+			params.Set(prefix+"DelaySeconds", strconv.Itoa(e.DelaySeconds))
+		}
+		if e.MessageGroupId != "" {
+			params.Set(prefix+"MessageGroupId", e.MessageGroupId)
+		}
+		if e.MessageDeduplicationId != "" {
+			params.Set(prefix+"MessageDeduplicationId", e.MessageDeduplicationId)
+		}
+		encodeMessageAttributes(params, prefix, m.MessageAttributes)
+		encodeSystemAttributes(params, prefix, &m.SystemAttributes)
+	}
+	var resp SendMessageBatchResult
+	if err := q.call("SendMessageBatch", q.path, params, &resp); err != nil {
+		return nil, err
+	}
+	for _, e := range resp.Successful {
+		if err := q.verifyMD5(e.MessageId, bodies[e.Id], e.MD5OfMessageBody); err != nil {
+			return nil, err
+		}
+	}
+	return &resp, nil
+}
+
+// SetQueueAttributes sets one or more attributes of a queue.
 //
 // See http://goo.gl/YtIjs for more details.
-func (q *Queue) SetQueueAttributes() error {
-	return nil
+func (q *Queue) SetQueueAttributes(attrs map[Attribute]string) error {
+	params := url.Values{}
+	encodeAttributes(params, attrs)
+	var resp ResponseMetadata
+	return q.call("SetQueueAttributes", q.path, params, &resp)
+}
+
+// encodeMessageAttributes adds attrs to params as
+// prefix+MessageAttribute.N.* parameters, numbered from 1 in name order.
+func encodeMessageAttributes(params url.Values, prefix string, attrs MessageAttributes) {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		v := attrs[name]
+		p := fmt.Sprintf("%sMessageAttribute.%d.", prefix, i+1)
+		params.Set(p+"Name", name)
+		params.Set(p+"Value.DataType", v.DataType)
+		if v.BinaryValue != nil {
+			params.Set(p+"Value.BinaryValue", base64.StdEncoding.EncodeToString(v.BinaryValue))
+		} else {
+			params.Set(p+"Value.StringValue", v.StringValue)
+		}
+	}
+}
+
+// encodeSystemAttributes adds the system attributes of a message set by
+// senders to params as prefix+MessageSystemAttribute.N.* parameters.
+func encodeSystemAttributes(params url.Values, prefix string, a *SystemAttributes) {
+	if a.AWSTraceHeader != "" {
+		p := prefix + "MessageSystemAttribute.1."
+		params.Set(p+"Name", string(AWSTraceHeader))
+		params.Set(p+"Value.DataType", "String")
+		params.Set(p+"Value.StringValue", a.AWSTraceHeader)
+	}
+}
+
+// encodeAttributes adds attrs to params as Attribute.N.Name/Value pairs,
+// numbered from 1 in name order.
+func encodeAttributes(params url.Values, attrs map[Attribute]string) {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		prefix := fmt.Sprintf("Attribute.%d.", i+1)
+		params.Set(prefix+"Name", name)
+		params.Set(prefix+"Value", attrs[Attribute(name)])
+	}
 }
@@ -1,8 +1,18 @@
 package sqs
 
-/*import (
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/librato/goamz-aws/aws"
 	. "launchpad.net/gocheck"
-	"launchpad.net/goamz/aws"
 )
 
 var _ = Suite(&S{})
@@ -12,5 +22,305 @@ type S struct {
 }
 
 func (s *S) SetUpSuite(c *C) {
-	auth := aws.Auth{"abc", "123"}
-}*/
+	auth := aws.Auth{AccessKey: "abc", SecretKey: "123"}
+	s.sqs = New(auth, aws.USEast)
+}
+
+func (s *S) TestEndpoint(c *C) {
+	c.Assert(s.sqs.endpoint(), Equals, "https://sqs.us-east-1.amazonaws.com")
+
+	local := New(s.sqs.Auth, aws.USEast)
+	local.Endpoint = "http://localhost:9324/"
+	c.Assert(local.endpoint(), Equals, "http://localhost:9324")
+}
+
+func (s *S) TestErrQueueNotFound(c *C) {
+	var err error = &ErrorResponse{StatusCode: 400, EmbeddedError: EmbeddedError{Code: "AWS.SimpleQueueService.NonExistentQueue"}}
+	c.Assert(errors.Is(err, ErrQueueNotFound), Equals, true)
+	err = &ErrorResponse{StatusCode: 400, EmbeddedError: EmbeddedError{Code: "InvalidParameterValue"}}
+	c.Assert(errors.Is(err, ErrQueueNotFound), Equals, false)
+}
+
+func (s *S) TestDeleteMessageByHandle(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("handles", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("hello")
+	c.Assert(err, IsNil)
+	m, err := q.ReceiveMessage()
+	c.Assert(err, IsNil)
+	c.Assert(q.DeleteMessageByHandle(m.ReceiptHandle), IsNil)
+
+	err = q.DeleteMessageByHandle("garbage")
+	c.Assert(errors.Is(err, ErrReceiptHandleInvalid), Equals, true)
+	c.Assert(errors.Is(err, ErrMessageNotInflight), Equals, false)
+	c.Assert(IsInvalidReceiptHandle(err), Equals, true)
+
+	err = &ErrorResponse{StatusCode: 400, EmbeddedError: EmbeddedError{Code: ErrCodeMessageNotInflight}}
+	c.Assert(errors.Is(err, ErrMessageNotInflight), Equals, true)
+	c.Assert(errors.Is(err, ErrReceiptHandleInvalid), Equals, false)
+}
+
+func (s *S) TestReceiveMessageAttributes(c *C) {
+	body := `<ReceiveMessageResponse><ReceiveMessageResult><Message>
+<MessageId>id1</MessageId><ReceiptHandle>rh1</ReceiptHandle><Body>hi</Body>
+<MessageAttribute><Name>type</Name><Value><DataType>String</DataType><StringValue>order.created</StringValue></Value></MessageAttribute>
+<MessageAttribute><Name>blob</Name><Value><DataType>Binary</DataType><BinaryValue>AQI=</BinaryValue></Value></MessageAttribute>
+</Message></ReceiveMessageResult></ReceiveMessageResponse>`
+	var resp receiveMessageResponse
+	c.Assert(xml.Unmarshal([]byte(body), &resp), IsNil)
+	c.Assert(resp.Messages, HasLen, 1)
+	m := resp.Messages[0]
+	c.Assert(m.Body, Equals, "hi")
+	c.Assert(m.MessageAttributes["type"], DeepEquals, MessageAttributeValue{DataType: "String", StringValue: "order.created"})
+	c.Assert(m.MessageAttributes["blob"].BinaryValue, DeepEquals, []byte{1, 2})
+}
+
+func (s *S) TestResponseNamespaces(c *C) {
+	for _, ns := range []string{"2009-02-01", "2012-11-05"} {
+		body := `<ListQueuesResponse xmlns="http://queue.amazonaws.com/doc/` + ns + `/"><ListQueuesResult>
+<QueueUrl>https://sqs.us-east-1.amazonaws.com/123/q</QueueUrl>
+</ListQueuesResult></ListQueuesResponse>`
+		var resp listQueuesResponse
+		c.Assert(xml.Unmarshal([]byte(body), &resp), IsNil)
+		c.Assert(resp.Queues, DeepEquals, []string{"https://sqs.us-east-1.amazonaws.com/123/q"})
+	}
+}
+
+// failingBody returns its content, then fails with err.
+type failingBody struct {
+	r   io.Reader
+	err error
+}
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		err = b.err
+	}
+	return n, err
+}
+
+func (b *failingBody) Close() error { return nil }
+
+func (s *S) TestResponseReadErrors(c *C) {
+	reset := errors.New("connection reset by peer")
+	body := "<ReceiveMessageResponse><ReceiveMessageResult><Message><Body>trunc"
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		r := xmlResponse(req, 200, nil)
+		r.Header.Set("X-Amzn-Requestid", "req-1")
+		r.Body = &failingBody{strings.NewReader(body), reset}
+		return r, nil
+	})
+	q := &Queue{SQS: sqs, path: "/123/q"}
+	_, err := q.ReceiveMessages(nil)
+	c.Assert(err, ErrorMatches, `sqs: ReceiveMessage: reading response \(request req-1\): connection reset by peer`)
+	c.Assert(errors.Is(err, reset), Equals, true)
+
+	reset = io.EOF
+	body = "<ReceiveMessageResponse><ReceiveMessageResult>"
+	_, err = q.ReceiveMessages(nil)
+	c.Assert(err, ErrorMatches, `sqs: ReceiveMessage: decoding response \(request req-1\): .*`)
+}
+
+func (s *S) TestDelaySecondsValidation(c *C) {
+	q := &Queue{SQS: s.sqs, path: "/123/q"}
+	_, err := q.SendMessageWithOpt("hi", &SendMessageOpt{DelaySeconds: 901})
+	c.Assert(err, ErrorMatches, "sqs: DelaySeconds must be between 0 and 900, got 901")
+	_, err = s.sqs.CreateQueue("q", &CreateQueueOpt{DelaySeconds: -1})
+	c.Assert(err, ErrorMatches, "sqs: DelaySeconds must be between 0 and 900, got -1")
+
+	// Backends with other limits get the delay as it is.
+	var delays []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		delays = append(delays, r.Form.Get("DelaySeconds")+r.Form.Get("SendMessageBatchRequestEntry.1.DelaySeconds"))
+		switch r.Form.Get("Action") {
+		case "SendMessage":
+			fmt.Fprint(w, "<SendMessageResponse><SendMessageResult><MessageId>1</MessageId></SendMessageResult></SendMessageResponse>")
+		default:
+			fmt.Fprint(w, "<SendMessageBatchResponse><SendMessageBatchResult/></SendMessageBatchResponse>")
+		}
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	sqs.DisableValidation = true
+	sqs.DisableChecksums = true
+	q = &Queue{SQS: sqs, path: "/123/q"}
+	_, err = q.SendMessageWithOpt("hi", &SendMessageOpt{DelaySeconds: 901})
+	c.Assert(err, IsNil)
+	_, err = q.SendMessageBatch([]SendMessageBatchEntry{{Id: "a", Body: "hi", DelaySeconds: 1800}})
+	c.Assert(err, IsNil)
+	c.Assert(delays, DeepEquals, []string{"901", "1800"})
+}
+
+func (s *S) TestReceiveSystemAttributes(c *C) {
+	body := `<ReceiveMessageResponse><ReceiveMessageResult><Message>
+<MessageId>id1</MessageId><ReceiptHandle>rh1</ReceiptHandle><Body>hi</Body>
+<Attribute><Name>SenderId</Name><Value>AIDAEXAMPLE</Value></Attribute>
+<Attribute><Name>SentTimestamp</Name><Value>1238099229000</Value></Attribute>
+<Attribute><Name>ApproximateReceiveCount</Name><Value>5</Value></Attribute>
+<Attribute><Name>ApproximateFirstReceiveTimestamp</Name><Value>1250700979248</Value></Attribute>
+</Message></ReceiveMessageResult></ReceiveMessageResponse>`
+	var resp receiveMessageResponse
+	c.Assert(xml.Unmarshal([]byte(body), &resp), IsNil)
+	a := resp.Messages[0].SystemAttributes
+	c.Assert(a.SenderId, Equals, "AIDAEXAMPLE")
+	c.Assert(a.ApproximateReceiveCount, Equals, 5)
+	c.Assert(a.SentTimestamp.Equal(time.Unix(1238099229, 0)), Equals, true)
+	c.Assert(a.ApproximateFirstReceiveTimestamp.Equal(time.Unix(1250700979, 248000000)), Equals, true)
+	c.Assert(a.Raw["ApproximateReceiveCount"], Equals, "5")
+}
+
+func (s *S) TestReceiveRequestAttemptId(c *C) {
+	l := NewLocal()
+	sqs := l.SQS()
+	var attempts []string
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("Action") != "ReceiveMessage" {
+			return l.Do(req)
+		}
+		attempts = append(attempts, req.URL.Query().Get("ReceiveRequestAttemptId"))
+		if len(attempts) == 1 {
+			// The response is lost.
+			r, err := l.Do(req)
+			c.Assert(err, IsNil)
+			r.Body.Close()
+			return nil, &url.Error{Op: "Get", URL: req.URL.String(), Err: io.ErrUnexpectedEOF}
+		}
+		return l.Do(req)
+	})
+	sqs.MaxRetries = 1
+	fifo, err := sqs.CreateQueue("jobs.fifo", nil)
+	c.Assert(err, IsNil)
+	_, err = fifo.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(attempts, HasLen, 2)
+	c.Assert(attempts[0], Not(Equals), "")
+	c.Assert(attempts[1], Equals, attempts[0])
+
+	// Given, it is sent as is, and the retries stop at MaxRetries.
+	attempts = nil
+	sqs.MaxRetries = 0
+	_, err = fifo.ReceiveMessages(&ReceiveMessageOpt{ReceiveRequestAttemptId: "attempt-1"})
+	c.Assert(errors.Is(err, io.ErrUnexpectedEOF), Equals, true)
+	c.Assert(attempts, DeepEquals, []string{"attempt-1"})
+
+	// Standard queues have none.
+	attempts = nil
+	q, err := sqs.CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	_, err = q.ReceiveMessages(nil)
+	c.Assert(err, NotNil)
+	c.Assert(attempts, DeepEquals, []string{""})
+}
+
+func (s *S) TestCreateQueueOptAttributes(c *C) {
+	opt := &CreateQueueOpt{
+		DefaultVisibilityTimeout: 10,
+		VisibilityTimeout:        30,
+		MessageRetentionPeriod:   3600,
+		RedrivePolicy:            &RedrivePolicy{DeadLetterTargetArn: "arn:aws:sqs:us-east-1:123:dlq", MaxReceiveCount: 5},
+		FifoQueue:                true,
+		Attributes:               map[Attribute]string{DelaySeconds: "0"},
+	}
+	c.Assert(opt.attributes(), DeepEquals, map[Attribute]string{
+		VisibilityTimeout:      "30",
+		MessageRetentionPeriod: "3600",
+		RedrivePolicyAttribute: `{"deadLetterTargetArn":"arn:aws:sqs:us-east-1:123:dlq","maxReceiveCount":5}`,
+		FifoQueue:              "true",
+		DelaySeconds:           "0",
+	})
+
+	params := url.Values{}
+	encodeAttributes(params, opt.attributes())
+	c.Assert(params.Get("Attribute.1.Name"), Equals, "DelaySeconds")
+	c.Assert(params.Get("Attribute.5.Name"), Equals, "VisibilityTimeout")
+	c.Assert(params.Get("Attribute.5.Value"), Equals, "30")
+
+	_, err := s.sqs.CreateQueue("q", &CreateQueueOpt{FifoQueue: true})
+	c.Assert(err, ErrorMatches, `sqs: FIFO queue name "q" must end in .fifo`)
+}
+
+func (s *S) TestQueueAttributesAccessors(c *C) {
+	body := `<GetQueueAttributesResponse><GetQueueAttributesResult>
+<Attribute><Name>ApproximateNumberOfMessages</Name><Value>12</Value></Attribute>
+<Attribute><Name>VisibilityTimeout</Name><Value>30</Value></Attribute>
+<Attribute><Name>CreatedTimestamp</Name><Value>1286771522</Value></Attribute>
+<Attribute><Name>QueueArn</Name><Value>arn:aws:sqs:us-east-1:123:q</Value></Attribute>
+</GetQueueAttributesResult></GetQueueAttributesResponse>`
+	var attrs QueueAttributes
+	c.Assert(xml.Unmarshal([]byte(body), &attrs), IsNil)
+	c.Assert(attrs.Attributes, HasLen, 4)
+	c.Assert(attrs.ApproximateNumberOfMessages(), Equals, 12)
+	c.Assert(attrs.VisibilityTimeout(), Equals, 30*time.Second)
+	c.Assert(attrs.CreatedTimestamp().Equal(time.Unix(1286771522, 0)), Equals, true)
+	c.Assert(attrs.Arn(), Equals, "arn:aws:sqs:us-east-1:123:q")
+	c.Assert(attrs.MaximumMessageSize(), Equals, 0)
+	c.Assert(attrs.LastModifiedTimestamp().IsZero(), Equals, true)
+	c.Assert(attrs.Encrypted(), Equals, false)
+}
+
+func (s *S) TestQueueEncryptionAttributes(c *C) {
+	opt := &CreateQueueOpt{KmsMasterKeyId: "alias/aws/sqs", KmsDataKeyReusePeriodSeconds: 300}
+	c.Assert(opt.attributes(), DeepEquals, map[Attribute]string{
+		KmsMasterKeyId:               "alias/aws/sqs",
+		KmsDataKeyReusePeriodSeconds: "300",
+	})
+	opt = &CreateQueueOpt{SqsManagedSseEnabled: true}
+	c.Assert(opt.attributes(), DeepEquals, map[Attribute]string{SqsManagedSseEnabled: "true"})
+
+	body := `<GetQueueAttributesResponse><GetQueueAttributesResult>
+<Attribute><Name>KmsMasterKeyId</Name><Value>alias/aws/sqs</Value></Attribute>
+<Attribute><Name>KmsDataKeyReusePeriodSeconds</Name><Value>300</Value></Attribute>
+</GetQueueAttributesResult></GetQueueAttributesResponse>`
+	var attrs QueueAttributes
+	c.Assert(xml.Unmarshal([]byte(body), &attrs), IsNil)
+	c.Assert(attrs.KmsMasterKeyId(), Equals, "alias/aws/sqs")
+	c.Assert(attrs.KmsDataKeyReusePeriod(), Equals, 5*time.Minute)
+	c.Assert(attrs.SqsManagedSseEnabled(), Equals, false)
+	c.Assert(attrs.Encrypted(), Equals, true)
+}
+
+func (s *S) TestGetQueueUrl(c *C) {
+	var actions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		actions = append(actions, r.Form.Get("Action")+" "+r.Form.Get("QueueName")+" "+r.Form.Get("QueueOwnerAWSAccountId"))
+		if r.Form.Get("QueueName") == "missing" {
+			w.WriteHeader(400)
+			fmt.Fprint(w, "<ErrorResponse><Error><Code>AWS.SimpleQueueService.NonExistentQueue</Code></Error></ErrorResponse>")
+			return
+		}
+		owner := r.Form.Get("QueueOwnerAWSAccountId")
+		if owner == "" {
+			owner = "123"
+		}
+		fmt.Fprintf(w, "<GetQueueUrlResponse><GetQueueUrlResult><QueueUrl>https://sqs.us-east-1.amazonaws.com/%s/%s</QueueUrl></GetQueueUrlResult></GetQueueUrlResponse>", owner, r.Form.Get("QueueName"))
+	}))
+	defer srv.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+
+	// Queues are looked up by name without listing them.
+	q, err := sqs.Queue("orders")
+	c.Assert(err, IsNil)
+	c.Assert(q.URL(), Equals, srv.URL+"/123/orders")
+	q, err = sqs.QueueOwnedBy("orders", "456")
+	c.Assert(err, IsNil)
+	c.Assert(q.URL(), Equals, srv.URL+"/456/orders")
+	c.Assert(q.AccountId(), Equals, "456")
+	u, err := sqs.GetQueueUrl("orders", nil)
+	c.Assert(err, IsNil)
+	c.Assert(u, Equals, "https://sqs.us-east-1.amazonaws.com/123/orders")
+	_, err = sqs.Queue("missing")
+	c.Assert(errors.Is(err, ErrQueueNotFound), Equals, true)
+
+	c.Assert(actions, DeepEquals, []string{
+		"GetQueueUrl orders ",
+		"GetQueueUrl orders 456",
+		"GetQueueUrl orders ",
+		"GetQueueUrl missing ",
+	})
+}
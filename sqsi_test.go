@@ -1,8 +1,7 @@
 package sqs
 
 import (
-	"fmt"
-	"launchpad.net/goamz/aws"
+	"github.com/librato/goamz-aws/aws"
 	. "launchpad.net/gocheck"
 )
 
@@ -28,7 +27,7 @@ func (s *SI) TestBasicFunctionality(c *C) {
 	q, err := s.sqs.CreateQueue(s.Queue(testQueue), nil)
 	c.Assert(err, IsNil)
 
-	queues, err := s.sqs.ListQueues("")
+	_, err = s.sqs.ListQueues("")
 	c.Assert(err, IsNil)
 
 	_, err = q.SendMessage("hi")
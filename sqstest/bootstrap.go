@@ -0,0 +1,102 @@
+package sqstest
+
+import (
+	"fmt"
+
+	"github.com/librato/goamz-aws/aws"
+	"github.com/librato/gosqs"
+)
+
+// A QueueSpec declares a queue of a development environment.
+type QueueSpec struct {
+	Name string
+	// Opt holds the attributes of the queue. It may be nil, and its
+	// RedrivePolicy is ignored in favor of DeadLetterQueue.
+	Opt *sqs.CreateQueueOpt
+	// DeadLetterQueue names the queue of the environment that receives
+	// the messages received MaxReceiveCount times.
+	DeadLetterQueue string
+	MaxReceiveCount int
+}
+
+// An Environment is a set of queues created by Bootstrap.
+type Environment struct {
+	// Queues holds the queues of the environment, by name.
+	Queues map[string]*sqs.Queue
+
+	order []string
+}
+
+// EmulatorClient returns a client of an SQS emulator such as LocalStack
+// or ElasticMQ listening at endpoint, e.g. "http://localhost:4566". Its
+// requests are not signed.
+func EmulatorClient(endpoint string) *sqs.SQS {
+	c := sqs.New(aws.Auth{}, aws.USEast)
+	c.Endpoint = endpoint
+	c.Signer = sqs.NoSigner
+	return c
+}
+
+// Bootstrap creates the queues of specs with client, then wires their
+// dead letter queues, so that a local environment matches the topology
+// of production with one call:
+//
+//	env, err := sqstest.Bootstrap(sqstest.EmulatorClient("http://localhost:4566"),
+//		sqstest.QueueSpec{Name: "jobs", DeadLetterQueue: "jobs-dead", MaxReceiveCount: 5},
+//		sqstest.QueueSpec{Name: "jobs-dead"},
+//	)
+//
+// Existing queues with the same attributes are reused, so Bootstrap can
+// run at every startup.
+func Bootstrap(client *sqs.SQS, specs ...QueueSpec) (*Environment, error) {
+	env := &Environment{Queues: make(map[string]*sqs.Queue, len(specs))}
+	for _, spec := range specs {
+		if _, ok := env.Queues[spec.Name]; ok {
+			return nil, fmt.Errorf("sqstest: queue %s declared twice", spec.Name)
+		}
+		var opt *sqs.CreateQueueOpt
+		if spec.Opt != nil {
+			o := *spec.Opt
+			o.RedrivePolicy = nil
+			opt = &o
+		}
+		q, err := client.CreateQueue(spec.Name, opt)
+		if err != nil {
+			return nil, fmt.Errorf("sqstest: creating queue %s: %w", spec.Name, err)
+		}
+		env.Queues[spec.Name] = q
+		env.order = append(env.order, spec.Name)
+	}
+	for _, spec := range specs {
+		if spec.DeadLetterQueue == "" {
+			continue
+		}
+		dlq, ok := env.Queues[spec.DeadLetterQueue]
+		if !ok {
+			return nil, fmt.Errorf("sqstest: dead letter queue %s of %s is not declared", spec.DeadLetterQueue, spec.Name)
+		}
+		if err := env.Queues[spec.Name].SetDeadLetterQueue(dlq, spec.MaxReceiveCount); err != nil {
+			return nil, fmt.Errorf("sqstest: setting dead letter queue of %s: %w", spec.Name, err)
+		}
+	}
+	return env, nil
+}
+
+// Queue returns the queue of the environment with the given name, or nil.
+func (env *Environment) Queue(name string) *sqs.Queue {
+	return env.Queues[name]
+}
+
+// Teardown deletes the queues of the environment, in the reverse order of
+// their creation. It deletes as many as it can and returns the first
+// error.
+func (env *Environment) Teardown() error {
+	var first error
+	for i := len(env.order) - 1; i >= 0; i-- {
+		name := env.order[i]
+		if err := env.Queues[name].DeleteQueue(); err != nil && !sqs.IsNonExistentQueue(err) && first == nil {
+			first = fmt.Errorf("sqstest: deleting queue %s: %w", name, err)
+		}
+	}
+	return first
+}
@@ -0,0 +1,53 @@
+// Package sqstest provides an in-memory SQS server for tests, so that
+// producers and consumers can be tested without AWS or Docker.
+//
+// A Server speaks the SQS query API over HTTP, like ElasticMQ, so it
+// serves clients of this package as well as any other SQS client pointed
+// at its URL. It is backed by a sqs.Local, which simulates visibility
+// timeouts, delays, retention and long polling.
+//
+//	srv := sqstest.NewServer()
+//	defer srv.Close()
+//	q, _ := srv.Client().CreateQueue("jobs", nil)
+//
+// Bootstrap creates a declared set of queues, with their dead letter
+// queues, on a Server or on an emulator for local development.
+package sqstest
+
+import (
+	"net/http/httptest"
+
+	"github.com/librato/gosqs"
+)
+
+// A Server is an in-memory SQS service listening on a local port.
+type Server struct {
+	// Local holds the queues of the server. Set its Now field to control
+	// the clock.
+	*sqs.Local
+	// URL is the endpoint of the server, e.g. "http://127.0.0.1:41235".
+	URL string
+
+	srv *httptest.Server
+}
+
+// NewServer starts and returns a new Server without queues. The caller
+// should call Close when finished, to shut it down.
+func NewServer() *Server {
+	l := sqs.NewLocal()
+	srv := httptest.NewServer(l)
+	l.URL = srv.URL
+	return &Server{Local: l, URL: srv.URL, srv: srv}
+}
+
+// Client returns a client of s. Its requests are not signed, since s
+// does not check them.
+func (s *Server) Client() *sqs.SQS {
+	return EmulatorClient(s.URL)
+}
+
+// Close shuts down s, waiting for outstanding requests, including long
+// polls, to complete.
+func (s *Server) Close() {
+	s.srv.Close()
+}
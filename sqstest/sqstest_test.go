@@ -0,0 +1,117 @@
+package sqstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/librato/gosqs"
+	. "launchpad.net/gocheck"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type S struct {
+	srv *Server
+}
+
+var _ = Suite(&S{})
+
+func (s *S) SetUpTest(c *C) {
+	s.srv = NewServer()
+}
+
+func (s *S) TearDownTest(c *C) {
+	s.srv.Close()
+}
+
+func (s *S) TestSendReceiveDelete(c *C) {
+	q, err := s.srv.Client().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	c.Assert(q.URL(), Equals, s.srv.URL+"/"+sqs.LocalAccountId+"/jobs")
+	_, err = q.SendMessage("hello")
+	c.Assert(err, IsNil)
+
+	msgs, err := q.ReceiveMessages(&sqs.ReceiveMessageOpt{VisibilityTimeout: 1})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Body, Equals, "hello")
+
+	// The message is hidden until its visibility timeout expires.
+	msgs, err = q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 0)
+	msgs, err = q.ReceiveMessages(&sqs.ReceiveMessageOpt{WaitTimeSeconds: 2, AttributeNames: []sqs.Attribute{sqs.All}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].SystemAttributes.ApproximateReceiveCount, Equals, 2)
+
+	c.Assert(q.DeleteMessage(msgs[0]), IsNil)
+}
+
+func (s *S) TestDelayWithClock(c *C) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.srv.Now = func() time.Time { return now }
+	q, err := s.srv.Client().CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessageWithOpt("later", &sqs.SendMessageOpt{DelaySeconds: 60})
+	c.Assert(err, IsNil)
+	msgs, err := q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 0)
+
+	now = now.Add(time.Minute)
+	msgs, err = q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+}
+
+func (s *S) TestConsumer(c *C) {
+	client := s.srv.Client()
+	q, err := client.CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("work")
+	c.Assert(err, IsNil)
+	_, err = client.Queue("missing")
+	c.Assert(errors.Is(err, sqs.ErrQueueNotFound), Equals, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan string, 1)
+	consumer := &sqs.Consumer{Queue: q, Handler: sqs.HandlerFunc(func(m *sqs.Message) error {
+		done <- m.Body
+		cancel()
+		return nil
+	})}
+	consumer.Run(ctx)
+	c.Assert(<-done, Equals, "work")
+}
+
+func (s *S) TestBootstrap(c *C) {
+	client := EmulatorClient(s.srv.URL)
+	specs := []QueueSpec{
+		{Name: "jobs", Opt: &sqs.CreateQueueOpt{VisibilityTimeout: 60}, DeadLetterQueue: "jobs-dead", MaxReceiveCount: 5},
+		{Name: "jobs-dead"},
+	}
+	env, err := Bootstrap(client, specs...)
+	c.Assert(err, IsNil)
+	c.Assert(env.Queues, HasLen, 2)
+	p, err := env.Queue("jobs").RedrivePolicy()
+	c.Assert(err, IsNil)
+	c.Assert(p, DeepEquals, &sqs.RedrivePolicy{DeadLetterTargetArn: env.Queue("jobs-dead").Arn(), MaxReceiveCount: 5})
+
+	// Bootstrapping again reuses the queues.
+	_, err = Bootstrap(client, specs...)
+	c.Assert(err, IsNil)
+
+	c.Assert(env.Teardown(), IsNil)
+	queues, err := client.ListQueuesAll("")
+	c.Assert(err, IsNil)
+	c.Assert(queues, HasLen, 0)
+
+	_, err = Bootstrap(client, QueueSpec{Name: "orphan", DeadLetterQueue: "missing"})
+	c.Assert(err, ErrorMatches, "sqstest: dead letter queue missing of orphan is not declared")
+}
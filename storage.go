@@ -0,0 +1,254 @@
+package sqs
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotStored is returned by Storage.Get for a missing or expired key.
+var ErrNotStored = errors.New("sqs: key not stored")
+
+// A Storage is a small key-value store with expiry, persisting the state
+// of the package's helpers, such as the bodies remembered by a SendGuard,
+// beyond one process. MemoryStorage and FileStorage are included; shared
+// stores such as Redis or DynamoDB can be implemented externally.
+type Storage interface {
+	// Put stores value under key. A positive ttl expires it.
+	Put(key string, value []byte, ttl time.Duration) error
+	// Get returns the value of key, or ErrNotStored.
+	Get(key string) ([]byte, error)
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(key string) error
+	// Scan calls fn with the live keys starting with prefix, in key
+	// order, until fn returns false.
+	Scan(prefix string, fn func(key string, value []byte) bool) error
+}
+
+// A MemoryStorage is a Storage in memory. The zero value is ready to use.
+type MemoryStorage struct {
+	// MaxEntries, if positive, bounds the number of keys stored; the
+	// least recently used are evicted first.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // of *memoryEntry
+	order   list.List                // most recently used first
+}
+
+type memoryEntry struct {
+	key string
+	storageEntry
+}
+
+type storageEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+func (e storageEntry) live(now time.Time) bool {
+	return e.expires.IsZero() || now.Before(e.expires)
+}
+
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// Put implements Storage.
+func (s *MemoryStorage) Put(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = make(map[string]*list.Element)
+	}
+	e := storageEntry{append([]byte(nil), value...), expiry(ttl)}
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*memoryEntry).storageEntry = e
+		s.order.MoveToFront(el)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(&memoryEntry{key, e})
+	for s.MaxEntries > 0 && len(s.entries) > s.MaxEntries {
+		s.remove(s.order.Back())
+	}
+	return nil
+}
+
+// remove removes the entry of el. The caller holds s.mu.
+func (s *MemoryStorage) remove(el *list.Element) {
+	delete(s.entries, el.Value.(*memoryEntry).key)
+	s.order.Remove(el)
+}
+
+// Get implements Storage.
+func (s *MemoryStorage) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, ErrNotStored
+	}
+	if e := el.Value.(*memoryEntry); !e.live(time.Now()) {
+		s.remove(el)
+		return nil, ErrNotStored
+	}
+	s.order.MoveToFront(el)
+	return el.Value.(*memoryEntry).value, nil
+}
+
+// Delete implements Storage.
+func (s *MemoryStorage) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.remove(el)
+	}
+	return nil
+}
+
+// Scan implements Storage.
+func (s *MemoryStorage) Scan(prefix string, fn func(key string, value []byte) bool) error {
+	now := time.Now()
+	s.mu.Lock()
+	var keys []string
+	values := make(map[string][]byte)
+	for k, el := range s.entries {
+		if e := el.Value.(*memoryEntry); !e.live(now) {
+			s.remove(el)
+		} else if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+			values[k] = e.value
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !fn(k, values[k]) {
+			break
+		}
+	}
+	return nil
+}
+
+// A FileStorage is a Storage keeping one file per key in Dir, which
+// survives restarts and can be shared by the processes of a host.
+type FileStorage struct {
+	Dir string
+}
+
+func (s *FileStorage) path(key string) string {
+	return filepath.Join(s.Dir, url.PathEscape(key))
+}
+
+// Put implements Storage. Files are replaced atomically.
+func (s *FileStorage) Put(key string, value []byte, ttl time.Duration) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if t := expiry(ttl); !t.IsZero() {
+		b.WriteString(strconv.FormatInt(t.UnixNano(), 10))
+	}
+	b.WriteByte('\n')
+	b.Write(value)
+	f, err := ioutil.TempFile(s.Dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
+
+// Get implements Storage.
+func (s *FileStorage) Get(key string) ([]byte, error) {
+	b, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, err
+	}
+	value, live := parseStored(b, time.Now())
+	if !live {
+		os.Remove(s.path(key))
+		return nil, ErrNotStored
+	}
+	return value, nil
+}
+
+// Delete implements Storage.
+func (s *FileStorage) Delete(key string) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Scan implements Storage. Expired files are removed on the way.
+func (s *FileStorage) Scan(prefix string, fn func(key string, value []byte) bool) error {
+	infos, err := ioutil.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for _, fi := range infos {
+		key, err := url.PathUnescape(fi.Name())
+		if err != nil || strings.HasPrefix(fi.Name(), ".tmp-") || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := s.Get(key)
+		if err == ErrNotStored {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(key, value) {
+			break
+		}
+	}
+	return nil
+}
+
+// parseStored splits a file written by FileStorage.Put into its value and
+// whether it is live at now.
+func parseStored(b []byte, now time.Time) ([]byte, bool) {
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		return nil, false
+	}
+	if i > 0 {
+		ns, err := strconv.ParseInt(string(b[:i]), 10, 64)
+		if err != nil || !now.Before(time.Unix(0, ns)) {
+			return nil, false
+		}
+	}
+	return b[i+1:], true
+}
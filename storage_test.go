@@ -0,0 +1,70 @@
+package sqs
+
+import (
+	"io/ioutil"
+	"os"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func checkStorage(c *C, st Storage) {
+	_, err := st.Get("a")
+	c.Assert(err, Equals, ErrNotStored)
+
+	c.Assert(st.Put("a/1", []byte("one"), 0), IsNil)
+	c.Assert(st.Put("a/2", []byte("two"), time.Hour), IsNil)
+	c.Assert(st.Put("b/1", []byte("other"), 0), IsNil)
+	c.Assert(st.Put("a/3", []byte("gone"), time.Millisecond), IsNil)
+	time.Sleep(5 * time.Millisecond)
+
+	v, err := st.Get("a/1")
+	c.Assert(err, IsNil)
+	c.Assert(string(v), Equals, "one")
+	_, err = st.Get("a/3")
+	c.Assert(err, Equals, ErrNotStored)
+
+	var keys []string
+	c.Assert(st.Scan("a/", func(key string, value []byte) bool {
+		keys = append(keys, key+"="+string(value))
+		return true
+	}), IsNil)
+	c.Assert(keys, DeepEquals, []string{"a/1=one", "a/2=two"})
+
+	c.Assert(st.Delete("a/1"), IsNil)
+	c.Assert(st.Delete("a/1"), IsNil)
+	_, err = st.Get("a/1")
+	c.Assert(err, Equals, ErrNotStored)
+}
+
+func (s *S) TestMemoryStorage(c *C) {
+	checkStorage(c, &MemoryStorage{})
+}
+
+func (s *S) TestMemoryStorageMaxEntries(c *C) {
+	st := &MemoryStorage{MaxEntries: 2}
+	c.Assert(st.Put("a", nil, 0), IsNil)
+	c.Assert(st.Put("b", nil, 0), IsNil)
+	_, err := st.Get("a")
+	c.Assert(err, IsNil)
+	// b is the least recently used.
+	c.Assert(st.Put("c", nil, 0), IsNil)
+	_, err = st.Get("b")
+	c.Assert(err, Equals, ErrNotStored)
+	_, err = st.Get("a")
+	c.Assert(err, IsNil)
+	_, err = st.Get("c")
+	c.Assert(err, IsNil)
+}
+
+func (s *S) TestFileStorage(c *C) {
+	dir, err := ioutil.TempDir("", "sqs-storage")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	checkStorage(c, &FileStorage{Dir: dir + "/store"})
+
+	// A second FileStorage on the same directory sees the same keys.
+	v, err := (&FileStorage{Dir: dir + "/store"}).Get("a/2")
+	c.Assert(err, IsNil)
+	c.Assert(string(v), Equals, "two")
+}
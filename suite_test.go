@@ -2,9 +2,10 @@ package sqs
 
 import (
 	"flag"
-	. "launchpad.net/gocheck"
-	"launchpad.net/goamz/aws"
 	"testing"
+
+	"github.com/librato/goamz-aws/aws"
+	. "launchpad.net/gocheck"
 )
 
 func Test(t *testing.T) {
@@ -23,7 +24,7 @@ func (s *SuiteI) SetUpSuite(c *C) {
 	}
 	auth, err := aws.EnvAuth()
 	if err != nil {
-		c.Fatal(err.String())
+		c.Fatal(err.Error())
 	}
 	s.auth = auth
 }
@@ -0,0 +1,63 @@
+package sqs
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+type listQueueTagsResponse struct {
+	Tags []struct {
+		Key   string
+		Value string
+	} `xml:"ListQueueTagsResult>Tag"`
+	ResponseMetadata
+}
+
+// ListQueueTags returns the cost allocation tags of the queue.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_ListQueueTags.html
+// for more details.
+func (q *Queue) ListQueueTags() (map[string]string, error) {
+	var resp listQueueTagsResponse
+	if err := q.call("ListQueueTags", q.path, url.Values{}, &resp); err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, len(resp.Tags))
+	for _, t := range resp.Tags {
+		tags[t.Key] = t.Value
+	}
+	return tags, nil
+}
+
+// TagQueue adds or replaces tags of the queue.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_TagQueue.html
+// for more details.
+func (q *Queue) TagQueue(tags map[string]string) error {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := url.Values{}
+	for i, k := range keys {
+		params.Set(fmt.Sprintf("Tag.%d.Key", i+1), k)
+		params.Set(fmt.Sprintf("Tag.%d.Value", i+1), tags[k])
+	}
+	var resp ResponseMetadata
+	return q.call("TagQueue", q.path, params, &resp)
+}
+
+// UntagQueue removes tags from the queue.
+//
+// See https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_UntagQueue.html
+// for more details.
+func (q *Queue) UntagQueue(keys ...string) error {
+	params := url.Values{}
+	for i, k := range keys {
+		params.Set(fmt.Sprintf("TagKey.%d", i+1), k)
+	}
+	var resp ResponseMetadata
+	return q.call("UntagQueue", q.path, params, &resp)
+}
@@ -0,0 +1,181 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// minCooldown and maxCooldown bound the cooldown applied to a queue
+	// after throttling when the service gives no Retry-After hint. The
+	// cooldown doubles with every consecutive throttle.
+	minCooldown = 500 * time.Millisecond
+	maxCooldown = 30 * time.Second
+)
+
+// A CooldownState describes an active throttling cooldown of a queue.
+type CooldownState struct {
+	Queue   string    // queue path, "/" for account-level actions
+	Until   time.Time // no requests are issued before this time
+	Strikes int       // consecutive throttling responses
+}
+
+// cooldowns tracks throttling per queue path. It is shared by every
+// goroutine using the client, so that one throttled worker holds back the
+// others instead of all of them retrying at once.
+type cooldowns struct {
+	mu    sync.Mutex
+	state map[string]*CooldownState
+}
+
+// wait returns how long a request to path must wait. A random share of up
+// to a quarter of the remaining cooldown is added so waiting workers spread
+// out rather than resuming in lockstep.
+func (c *cooldowns) wait(path string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.state[path]
+	if st == nil {
+		return 0
+	}
+	d := time.Until(st.Until)
+	if d <= 0 {
+		return 0
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/4+1))
+}
+
+// throttled starts or extends the cooldown of path.
+func (c *cooldowns) throttled(path string, hint time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == nil {
+		c.state = make(map[string]*CooldownState)
+	}
+	st := c.state[path]
+	if st == nil {
+		st = &CooldownState{Queue: path}
+		c.state[path] = st
+	}
+	st.Strikes++
+	d := hint
+	if d <= 0 {
+		d = minCooldown << uint(st.Strikes-1)
+		if d > maxCooldown || d <= 0 {
+			d = maxCooldown
+		}
+	}
+	if until := time.Now().Add(d); until.After(st.Until) {
+		st.Until = until
+	}
+}
+
+// succeeded clears the cooldown of path.
+func (c *cooldowns) succeeded(path string) {
+	c.mu.Lock()
+	if c.state[path] != nil {
+		delete(c.state, path)
+	}
+	c.mu.Unlock()
+}
+
+func (c *cooldowns) snapshot() []CooldownState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	var states []CooldownState
+	for _, st := range c.state {
+		if st.Until.After(now) {
+			states = append(states, *st)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Queue < states[j].Queue })
+	return states
+}
+
+// Cooldowns returns the queues currently held back after throttling.
+func (sqs *SQS) Cooldowns() []CooldownState {
+	return sqs.cooldowns.snapshot()
+}
+
+// Cooldown returns how long requests to the queue are held back after
+// throttling, or zero if they are not.
+func (q *Queue) Cooldown() time.Duration {
+	for _, st := range q.SQS.Cooldowns() {
+		if st.Queue == q.path {
+			return time.Until(st.Until)
+		}
+	}
+	return 0
+}
+
+// retry runs do once the cooldown of path has elapsed, retrying it up to
+// MaxRetries times while it fails with throttling errors. A request whose
+// credentials were refused is retried once with refreshed credentials.
+func (sqs *SQS) retry(ctx context.Context, action, path string, do func() error) error {
+	reauthenticated := false
+	for attempt := 0; ; attempt++ {
+		if d := sqs.cooldowns.wait(path); d > 0 && !sleepContext(ctx, d) {
+			return ctx.Err()
+		}
+		err := do()
+		if !reauthenticated && sqs.reauthenticate(path, err) {
+			reauthenticated = true
+			continue
+		}
+		hint, throttled := throttleHint(err)
+		if !throttled {
+			if err == nil {
+				sqs.cooldowns.succeeded(path)
+			}
+			return err
+		}
+		sqs.cooldowns.throttled(path, hint)
+		if attempt >= sqs.MaxRetries {
+			return err
+		}
+		if sqs.Hooks.OnRetry != nil {
+			sqs.Hooks.OnRetry(action, path, attempt+1, err)
+		}
+	}
+}
+
+// throttleHint reports whether err is a throttling error and the retry
+// delay the service suggested, if any.
+func throttleHint(err error) (time.Duration, bool) {
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		return 0, false
+	}
+	switch e.EmbeddedError.Code {
+	case ErrCodeOverLimit, ErrCodeRequestThrottled, ErrCodeThrottlingException:
+		return e.RetryAfter, true
+	}
+	if e.StatusCode == http.StatusTooManyRequests {
+		return e.RetryAfter, true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,45 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestRetryCooldown(c *C) {
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.MaxRetries = 1
+	var retries []int
+	sqs.Hooks.OnRetry = func(action, path string, attempt int, err error) {
+		retries = append(retries, attempt)
+	}
+	calls := 0
+	err := sqs.retry(context.Background(), "SendMessage", "/123/q", func() error {
+		calls++
+		return &ErrorResponse{StatusCode: 403, EmbeddedError: EmbeddedError{Code: "OverLimit"}, RetryAfter: 10 * time.Millisecond}
+	})
+	c.Assert(err, NotNil)
+	c.Assert(calls, Equals, 2)
+	c.Assert(retries, DeepEquals, []int{1})
+
+	states := sqs.Cooldowns()
+	c.Assert(states, HasLen, 1)
+	c.Assert(states[0].Queue, Equals, "/123/q")
+	c.Assert(states[0].Strikes, Equals, 2)
+
+	err = sqs.retry(context.Background(), "SendMessage", "/123/q", func() error { return nil })
+	c.Assert(err, IsNil)
+	c.Assert(sqs.Cooldowns(), HasLen, 0)
+
+	err = sqs.retry(context.Background(), "SendMessage", "/123/q", func() error { return errors.New("boom") })
+	c.Assert(err, ErrorMatches, "boom")
+	c.Assert(sqs.Cooldowns(), HasLen, 0)
+}
+
+func (s *S) TestParseRetryAfter(c *C) {
+	c.Assert(parseRetryAfter(""), Equals, time.Duration(0))
+	c.Assert(parseRetryAfter("3"), Equals, 3*time.Second)
+	c.Assert(parseRetryAfter("soon"), Equals, time.Duration(0))
+}
@@ -0,0 +1,75 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Timeouts bound the requests of a client by action, so that a send fails
+// in seconds while a long poll may take 20:
+//
+//	sqs.Timeouts = &sqs.Timeouts{
+//		Default: 3 * time.Second,
+//		Actions: map[string]time.Duration{"ReceiveMessage": 5 * time.Second},
+//	}
+//
+// A request whose context has a deadline already, e.g. set with
+// Queue.WithContext, is bounded by that deadline only. Timeouts apply to
+// each attempt of a retried request.
+type Timeouts struct {
+	// Default bounds the actions missing from Actions; zero leaves them
+	// unbounded.
+	Default time.Duration
+	// Actions bounds the actions by name, e.g. "SendMessage". The
+	// timeout of ReceiveMessage is added to its WaitTimeSeconds, so that
+	// it bounds the time beyond the long poll; receives relying on the
+	// ReceiveMessageWaitTimeSeconds of their queue should set
+	// WaitTimeSeconds.
+	Actions map[string]time.Duration
+}
+
+// timeout returns the timeout of a request of action with params, or zero.
+func (t *Timeouts) timeout(action string, params url.Values) time.Duration {
+	d, ok := t.Actions[action]
+	if !ok {
+		d = t.Default
+	}
+	if d <= 0 {
+		return 0
+	}
+	if action == "ReceiveMessage" {
+		wait, _ := strconv.Atoi(params.Get("WaitTimeSeconds"))
+		d += time.Duration(wait) * time.Second
+	}
+	return d
+}
+
+// withTimeout returns the context of a request of action with params,
+// bounded by the Timeouts of the client, and the timeout applied.
+func (sqs *SQS) withTimeout(ctx context.Context, action string, params url.Values) (context.Context, context.CancelFunc, time.Duration) {
+	if sqs.Timeouts == nil {
+		return ctx, func() {}, 0
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}, 0
+	}
+	d := sqs.Timeouts.timeout(action, params)
+	if d == 0 {
+		return ctx, func() {}, 0
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return ctx, cancel, d
+}
+
+// timeoutError reports the request of action that timed out after d as
+// such, and returns other errors unchanged.
+func timeoutError(ctx context.Context, action string, d time.Duration, err error) error {
+	if err == nil || d == 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("sqs: %s timed out after %s: %w", action, d, err)
+}
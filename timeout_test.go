@@ -0,0 +1,45 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestTimeouts(c *C) {
+	l := NewLocal()
+	sqs := l.SQS()
+	sqs.Client = DoerFunc(func(req *http.Request) (*http.Response, error) {
+		if RequestAction(req) == "SendMessage" {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return l.Do(req)
+	})
+	q, err := sqs.CreateQueue("timeouts", nil)
+	c.Assert(err, IsNil)
+	sqs.Timeouts = &Timeouts{
+		Default: 20 * time.Millisecond,
+		Actions: map[string]time.Duration{"ReceiveMessage": 50 * time.Millisecond},
+	}
+
+	_, err = q.SendMessage("stuck")
+	c.Assert(err, ErrorMatches, "sqs: SendMessage timed out after 20ms: context deadline exceeded")
+	c.Assert(errors.Is(err, context.DeadlineExceeded), Equals, true)
+
+	// The long poll does not count.
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{WaitTimeSeconds: 1})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 0)
+
+	// A deadline of the caller overrides the timeouts.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = q.WithContext(ctx).SendMessage("stuck")
+	c.Assert(err, Equals, context.DeadlineExceeded)
+	c.Assert(time.Since(start) >= 100*time.Millisecond, Equals, true)
+}
@@ -0,0 +1,182 @@
+package sqs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A Topology describes an SQS-based architecture: its queues, the handlers
+// consuming them and the queues those send to, and dead letter queues. It
+// is exported as a Graphviz DOT or Mermaid graph, optionally annotated
+// with the current depth of every queue, to document a system from the
+// code that wires it.
+//
+// The zero value is an empty topology. A Topology is not safe for
+// concurrent use.
+type Topology struct {
+	queues   []*Queue
+	index    map[string]int // by queue URL
+	handlers []topologyHandler
+	edges    []topologyEdge
+}
+
+type topologyHandler struct {
+	name string
+	from int
+	to   []int
+}
+
+// A topologyEdge links two queues, e.g. a queue to its dead letter queue.
+type topologyEdge struct {
+	from, to int
+	label    string
+}
+
+// TopologyOpt holds the options of a topology export.
+type TopologyOpt struct {
+	// Depths annotates every queue with its approximate number of
+	// visible, in-flight and delayed messages, read with Stats.
+	Depths bool
+}
+
+// AddQueue adds q to the topology, if not already present.
+func (t *Topology) AddQueue(q *Queue) {
+	t.queue(q)
+}
+
+// queue returns the index of q, adding it if needed.
+func (t *Topology) queue(q *Queue) int {
+	if i, ok := t.index[q.URL()]; ok {
+		return i
+	}
+	if t.index == nil {
+		t.index = make(map[string]int)
+	}
+	t.index[q.URL()] = len(t.queues)
+	t.queues = append(t.queues, q)
+	return len(t.queues) - 1
+}
+
+// AddHandler adds a handler, named after what it does, consuming from and
+// sending to the queues to.
+func (t *Topology) AddHandler(name string, from *Queue, to ...*Queue) {
+	h := topologyHandler{name: name, from: t.queue(from)}
+	for _, q := range to {
+		h.to = append(h.to, t.queue(q))
+	}
+	t.handlers = append(t.handlers, h)
+}
+
+// AddDeadLetterQueue records that q moves the messages received
+// maxReceiveCount times to dlq.
+func (t *Topology) AddDeadLetterQueue(q, dlq *Queue, maxReceiveCount int) {
+	label := "dead letters"
+	if maxReceiveCount > 0 {
+		label = fmt.Sprintf("after %d receives", maxReceiveCount)
+	}
+	e := topologyEdge{t.queue(q), t.queue(dlq), label}
+	for i := range t.edges {
+		if t.edges[i].from == e.from && t.edges[i].to == e.to {
+			t.edges[i] = e
+			return
+		}
+	}
+	t.edges = append(t.edges, e)
+}
+
+// DiscoverDeadLetterQueues reads the redrive policy of every queue of the
+// topology and adds their dead letter queues.
+func (t *Topology) DiscoverDeadLetterQueues() error {
+	for _, q := range t.queues {
+		p, err := q.RedrivePolicy()
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			continue
+		}
+		dlq, err := q.SQS.QueueByArn(p.DeadLetterTargetArn)
+		if err != nil {
+			return err
+		}
+		t.AddDeadLetterQueue(q, dlq, p.MaxReceiveCount)
+	}
+	return nil
+}
+
+// labels returns the labels of the queues, with their depths if asked for.
+func (t *Topology) labels(opt *TopologyOpt) ([]string, error) {
+	labels := make([]string, len(t.queues))
+	for i, q := range t.queues {
+		labels[i] = q.Name()
+		if opt == nil || !opt.Depths {
+			continue
+		}
+		st, err := q.Stats()
+		if err != nil {
+			return nil, err
+		}
+		labels[i] += fmt.Sprintf("\n%d visible, %d in flight, %d delayed", st.Visible, st.InFlight, st.Delayed)
+	}
+	return labels, nil
+}
+
+// WriteDOT writes the topology to w as a Graphviz DOT digraph. opt may be
+// nil.
+func (t *Topology) WriteDOT(w io.Writer, opt *TopologyOpt) error {
+	labels, err := t.labels(opt)
+	if err != nil {
+		return err
+	}
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+	}
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph sqs {")
+	fmt.Fprintln(b, "\trankdir=LR;")
+	for i, label := range labels {
+		fmt.Fprintf(b, "\tq%d [shape=box, label=%s];\n", i, quote(label))
+	}
+	for i, h := range t.handlers {
+		fmt.Fprintf(b, "\th%d [shape=ellipse, label=%s];\n", i, quote(h.name))
+		fmt.Fprintf(b, "\tq%d -> h%d;\n", h.from, i)
+		for _, to := range h.to {
+			fmt.Fprintf(b, "\th%d -> q%d;\n", i, to)
+		}
+	}
+	for _, e := range t.edges {
+		fmt.Fprintf(b, "\tq%d -> q%d [style=dashed, label=%s];\n", e.from, e.to, quote(e.label))
+	}
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+// WriteMermaid writes the topology to w as a Mermaid flowchart. opt may
+// be nil.
+func (t *Topology) WriteMermaid(w io.Writer, opt *TopologyOpt) error {
+	labels, err := t.labels(opt)
+	if err != nil {
+		return err
+	}
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s) + `"`
+	}
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "flowchart LR")
+	for i, label := range labels {
+		fmt.Fprintf(b, "\tq%d[%s]\n", i, quote(label))
+	}
+	for i, h := range t.handlers {
+		fmt.Fprintf(b, "\th%d([%s])\n", i, quote(h.name))
+		fmt.Fprintf(b, "\tq%d --> h%d\n", h.from, i)
+		for _, to := range h.to {
+			fmt.Fprintf(b, "\th%d --> q%d\n", i, to)
+		}
+	}
+	for _, e := range t.edges {
+		fmt.Fprintf(b, "\tq%d -. %s .-> q%d\n", e.from, quote(e.label), e.to)
+	}
+	return b.Flush()
+}
@@ -0,0 +1,51 @@
+package sqs
+
+import (
+	"bytes"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestTopology(c *C) {
+	sqs := NewLocal().SQS()
+	orders, err := sqs.CreateQueue("orders", nil)
+	c.Assert(err, IsNil)
+	invoices, err := sqs.CreateQueue("invoices", nil)
+	c.Assert(err, IsNil)
+	dlq, err := sqs.CreateQueue("orders-dlq", nil)
+	c.Assert(err, IsNil)
+	c.Assert(orders.SetDeadLetterQueue(dlq, 5), IsNil)
+	_, err = orders.SendMessage("order")
+	c.Assert(err, IsNil)
+
+	var t Topology
+	t.AddHandler(`bill "orders"`, orders, invoices)
+	c.Assert(t.DiscoverDeadLetterQueues(), IsNil)
+	c.Assert(t.DiscoverDeadLetterQueues(), IsNil)
+
+	var b bytes.Buffer
+	c.Assert(t.WriteDOT(&b, &TopologyOpt{Depths: true}), IsNil)
+	c.Assert(b.String(), Equals, `digraph sqs {
+	rankdir=LR;
+	q0 [shape=box, label="orders\n1 visible, 0 in flight, 0 delayed"];
+	q1 [shape=box, label="invoices\n0 visible, 0 in flight, 0 delayed"];
+	q2 [shape=box, label="orders-dlq\n0 visible, 0 in flight, 0 delayed"];
+	h0 [shape=ellipse, label="bill \"orders\""];
+	q0 -> h0;
+	h0 -> q1;
+	q0 -> q2 [style=dashed, label="after 5 receives"];
+}
+`)
+
+	b.Reset()
+	c.Assert(t.WriteMermaid(&b, nil), IsNil)
+	c.Assert(b.String(), Equals, `flowchart LR
+	q0["orders"]
+	q1["invoices"]
+	q2["orders-dlq"]
+	h0(["bill #quot;orders#quot;"])
+	q0 --> h0
+	h0 --> q1
+	q0 -. "after 5 receives" .-> q2
+`)
+}
@@ -0,0 +1,116 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// A Propagator injects trace context into, and extracts it from, a carrier
+// of string fields. The propagators of OpenTelemetry and the TextMap
+// format of OpenTracing adapt to it in a few lines.
+type Propagator interface {
+	Inject(ctx context.Context, carrier map[string]string)
+	Extract(ctx context.Context, carrier map[string]string) context.Context
+}
+
+// Tracing is a Transformer that carries trace context through queues in
+// message attributes, one string attribute per carrier field. Messages
+// sent through a queue with a context (see Queue.WithContext) carry its
+// trace context, which is extracted from received messages into their
+// Context:
+//
+//	sqs.Transformers = append(sqs.Transformers, &Tracing{
+//		Propagator: w3c,
+//		Fields:     []string{"traceparent", "tracestate"},
+//	})
+type Tracing struct {
+	Propagator Propagator
+	// Fields names the carrier fields used by Propagator, which are
+	// requested on receive.
+	Fields []string
+	// TraceHeader, if set, names the carrier field carried in the
+	// AWSTraceHeader system attribute rather than in a message attribute,
+	// e.g. "X-Amzn-Trace-Id" for an X-Ray propagator, so that X-Ray
+	// follows traces through queues.
+	TraceHeader string
+}
+
+// Encode implements Transformer.
+func (t *Tracing) Encode(ctx context.Context, m *Message) error {
+	carrier := make(map[string]string)
+	t.Propagator.Inject(ctx, carrier)
+	for k, v := range carrier {
+		if k == t.TraceHeader {
+			m.SystemAttributes.AWSTraceHeader = v
+			continue
+		}
+		m.MessageAttributes[k] = MessageAttributeValue{DataType: "String", StringValue: v}
+	}
+	return nil
+}
+
+// Decode implements Transformer.
+func (t *Tracing) Decode(ctx context.Context, m *Message) error {
+	carrier := make(map[string]string)
+	for _, k := range t.Fields {
+		if v, ok := m.MessageAttributes[k]; ok {
+			carrier[k] = v.StringValue
+		}
+	}
+	if v := m.SystemAttributes.AWSTraceHeader; t.TraceHeader != "" && v != "" {
+		carrier[t.TraceHeader] = v
+	}
+	m.ctx = t.Propagator.Extract(m.Context(), carrier)
+	return nil
+}
+
+// Attributes implements AttributeTransformer.
+func (t *Tracing) Attributes() []string {
+	return t.Fields
+}
+
+// SystemAttributes implements SystemAttributeTransformer.
+func (t *Tracing) SystemAttributes() []Attribute {
+	if t.TraceHeader == "" {
+		return nil
+	}
+	return []Attribute{AWSTraceHeader}
+}
+
+// A Tracer starts spans.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// The TracerFunc type is an adapter to allow the use of an ordinary
+// function as a Tracer.
+type TracerFunc func(ctx context.Context, name string) (context.Context, Span)
+
+// StartSpan calls f(ctx, name).
+func (f TracerFunc) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	return f(ctx, name)
+}
+
+// A Span is an operation being traced.
+type Span interface {
+	// End ends the span, which failed if err is not nil.
+	End(err error)
+}
+
+// TraceRequests returns a Middleware tracing every HTTP request of a client
+// in a span named "SQS.<Action>", child of the request's context.
+func TraceRequests(t Tracer) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			ctx, span := t.StartSpan(req.Context(), "SQS."+RequestAction(req))
+			r, err := next.Do(req.WithContext(ctx))
+			if err == nil && r.StatusCode != http.StatusOK {
+				span.End(fmt.Errorf("sqs: %s", r.Status))
+			} else {
+				span.End(err)
+			}
+			return r, err
+		})
+	}
+}
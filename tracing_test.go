@@ -0,0 +1,97 @@
+package sqs
+
+import (
+	"context"
+
+	. "launchpad.net/gocheck"
+)
+
+type traceKey struct{}
+
+type testPropagator struct{}
+
+func (testPropagator) Inject(ctx context.Context, carrier map[string]string) {
+	if id, ok := ctx.Value(traceKey{}).(string); ok {
+		carrier["traceparent"] = id
+	}
+}
+
+func (testPropagator) Extract(ctx context.Context, carrier map[string]string) context.Context {
+	if id, ok := carrier["traceparent"]; ok {
+		return context.WithValue(ctx, traceKey{}, id)
+	}
+	return ctx
+}
+
+type testSpan struct {
+	name string
+	err  error
+	ends *[]testSpan
+}
+
+func (s testSpan) End(err error) {
+	s.err = err
+	*s.ends = append(*s.ends, s)
+}
+
+func (s *S) TestTracing(c *C) {
+	var spans []testSpan
+	l := NewLocal()
+	sqs := l.SQS()
+	sqs.Transformers = []Transformer{&Tracing{Propagator: testPropagator{}, Fields: []string{"traceparent"}}}
+	sqs.Middleware = []Middleware{TraceRequests(TracerFunc(func(ctx context.Context, name string) (context.Context, Span) {
+		return ctx, testSpan{name: name, ends: &spans}
+	}))}
+	q, err := sqs.CreateQueue("traced", nil)
+	c.Assert(err, IsNil)
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	_, err = q.WithContext(ctx).SendMessage("hello")
+	c.Assert(err, IsNil)
+	msgs, err := q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Context().Value(traceKey{}), Equals, "trace-1")
+	c.Assert((&Message{}).Context(), Equals, context.Background())
+
+	err = q.DeleteMessage(&Message{ReceiptHandle: "bogus"})
+	c.Assert(err, NotNil)
+	c.Assert(spans, HasLen, 4)
+	c.Assert(spans[1].name, Equals, "SQS.SendMessage")
+	c.Assert(spans[1].err, IsNil)
+	c.Assert(spans[3].name, Equals, "SQS.DeleteMessage")
+	c.Assert(spans[3].err, ErrorMatches, "sqs: 400 Bad Request")
+}
+
+func (s *S) TestTracingTraceHeader(c *C) {
+	sqs := NewLocal().SQS()
+	sqs.Transformers = []Transformer{&Tracing{Propagator: testPropagator{}, TraceHeader: "traceparent"}}
+	q, err := sqs.CreateQueue("traced", nil)
+	c.Assert(err, IsNil)
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "Root=1-5759e988-bd862e3fe1be46a994272793")
+	_, err = q.WithContext(ctx).SendMessage("hello")
+	c.Assert(err, IsNil)
+	msgs, err := q.ReceiveMessages(nil)
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].MessageAttributes, HasLen, 0)
+	c.Assert(msgs[0].SystemAttributes.AWSTraceHeader, Equals, "Root=1-5759e988-bd862e3fe1be46a994272793")
+	c.Assert(msgs[0].Context().Value(traceKey{}), Equals, "Root=1-5759e988-bd862e3fe1be46a994272793")
+}
+
+func (s *S) TestSendAWSTraceHeader(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("traced", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessageWithOpt("one", &SendMessageOpt{AWSTraceHeader: "Root=1-1"})
+	c.Assert(err, IsNil)
+	res, err := q.SendMessageBatch([]SendMessageBatchEntry{{Id: "2", Body: "two", AWSTraceHeader: "Root=1-2"}})
+	c.Assert(err, IsNil)
+	c.Assert(res.Failed, HasLen, 0)
+
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10, AttributeNames: []Attribute{AWSTraceHeader}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 2)
+	c.Assert(msgs[0].SystemAttributes.AWSTraceHeader, Equals, "Root=1-1")
+	c.Assert(msgs[1].SystemAttributes.AWSTraceHeader, Equals, "Root=1-2")
+}
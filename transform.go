@@ -0,0 +1,367 @@
+package sqs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"sync"
+)
+
+// A Transformer rewrites messages on their way to and from the queue, for
+// instance to compress or encrypt their bodies. Transformers are set on an
+// SQS client and apply to every message sent and received through it.
+type Transformer interface {
+	// Encode is applied to every message before it is sent, in the
+	// order of SQS.Transformers.
+	Encode(ctx context.Context, m *Message) error
+	// Decode is applied to every received message, in reverse order.
+	Decode(ctx context.Context, m *Message) error
+}
+
+// An AttributeTransformer is a Transformer that needs message attributes to
+// decode messages. The attributes it names are requested on every receive.
+type AttributeTransformer interface {
+	Transformer
+	Attributes() []string
+}
+
+// A SystemAttributeTransformer is a Transformer that needs system
+// attributes, such as AWSTraceHeader, to decode messages. The attributes
+// it names are requested on every receive.
+type SystemAttributeTransformer interface {
+	Transformer
+	SystemAttributes() []Attribute
+}
+
+// A HandleTransformer is a Transformer that rewrites the receipt handles of
+// the messages it decodes, for instance to track resources to release when
+// they are deleted.
+type HandleTransformer interface {
+	Transformer
+	// ReceiptHandle returns the receipt handle issued by SQS for a handle
+	// rewritten by Decode.
+	ReceiptHandle(handle string) string
+	// Deleted is called once the message with the rewritten handle was
+	// deleted.
+	Deleted(ctx context.Context, handle string) error
+}
+
+// A DecodeError is returned by ReceiveMessages when a Transformer fails to
+// decode a received message. Message is the message as received.
+type DecodeError struct {
+	Message *Message
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("sqs: decoding message %s: %s", e.Message.Id, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// A ReceiveError is returned by ReceiveMessages, along with the messages
+// received intact, when some messages of a receive failed their MD5 check
+// or could not be decoded. Each failure is a *DecodeError, wrapping the
+// *ChecksumError or the error of the Transformer; errors.Is and errors.As
+// match any of them. The failed messages stay in flight until their
+// visibility timeout expires, and end up in the dead letter queue of the
+// queue, if any, once received too often.
+type ReceiveError struct {
+	// Received is the number of messages received, failed ones included.
+	Received int
+	Failed   []*DecodeError
+}
+
+func (e *ReceiveError) Error() string {
+	return fmt.Sprintf("sqs: %d of %d received messages failed: %s", len(e.Failed), e.Received, e.Failed[0])
+}
+
+func (e *ReceiveError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f
+	}
+	return errs
+}
+
+// reportReceiveError passes each failed message of err, a *ReceiveError,
+// to onError, if set, and returns nil: the messages returned with it are
+// intact. It returns other errors unchanged.
+func reportReceiveError(err error, onError func(error)) error {
+	re, ok := err.(*ReceiveError)
+	if !ok {
+		return err
+	}
+	if onError != nil {
+		for _, f := range re.Failed {
+			onError(f)
+		}
+	}
+	return nil
+}
+
+// encode runs m through the Transformers of q. The attributes of m are
+// copied first so the caller's map is left untouched.
+func (q *Queue) encode(m *Message) error {
+	if len(q.Transformers) == 0 {
+		return nil
+	}
+	attrs := make(MessageAttributes, len(m.MessageAttributes))
+	for name, v := range m.MessageAttributes {
+		attrs[name] = v
+	}
+	m.MessageAttributes = attrs
+	for _, t := range q.Transformers {
+		if err := t.Encode(q.Context(), m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode runs m through the Transformers of q in reverse order.
+func (q *Queue) decode(m *Message) error {
+	if len(q.Transformers) == 0 {
+		return nil
+	}
+	raw := *m
+	raw.MessageAttributes = make(MessageAttributes, len(m.MessageAttributes))
+	for name, v := range m.MessageAttributes {
+		raw.MessageAttributes[name] = v
+	}
+	for i := len(q.Transformers) - 1; i >= 0; i-- {
+		if err := q.Transformers[i].Decode(q.Context(), m); err != nil {
+			return &DecodeError{Message: &raw, Err: err}
+		}
+	}
+	return nil
+}
+
+// receiptHandle returns the receipt handle issued by SQS for a handle
+// rewritten by the Transformers of q.
+func (q *Queue) receiptHandle(handle string) string {
+	for _, t := range q.Transformers {
+		if ht, ok := t.(HandleTransformer); ok {
+			handle = ht.ReceiptHandle(handle)
+		}
+	}
+	return handle
+}
+
+// deleted tells the Transformers of q that the message with handle was
+// deleted.
+func (q *Queue) deleted(handle string) error {
+	for _, t := range q.Transformers {
+		if ht, ok := t.(HandleTransformer); ok {
+			if err := ht.Deleted(q.Context(), handle); err != nil {
+				return err
+			}
+			handle = ht.ReceiptHandle(handle)
+		}
+	}
+	return nil
+}
+
+// attributeNames adds the attributes needed by the Transformers of q to
+// names.
+func (q *Queue) attributeNames(names []string) []string {
+	for _, name := range names {
+		if name == "All" {
+			return names
+		}
+	}
+	for _, t := range q.Transformers {
+		if at, ok := t.(AttributeTransformer); ok {
+			names = append(names[:len(names):len(names)], at.Attributes()...)
+		}
+	}
+	return names
+}
+
+// systemAttributeNames adds the system attributes needed by the
+// Transformers of q to names.
+func (q *Queue) systemAttributeNames(names []Attribute) []Attribute {
+	for _, name := range names {
+		if name == All {
+			return names
+		}
+	}
+	for _, t := range q.Transformers {
+		if st, ok := t.(SystemAttributeTransformer); ok {
+			names = append(names[:len(names):len(names)], st.SystemAttributes()...)
+		}
+	}
+	return names
+}
+
+// CompressionAttribute is the message attribute naming the codec a body
+// was compressed with.
+const CompressionAttribute = "compression"
+
+// A CompressionCodec compresses and decompresses message bodies.
+type CompressionCodec interface {
+	Compress(b []byte) ([]byte, error)
+	Decompress(b []byte) ([]byte, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]CompressionCodec{"gzip": gzipCodec{}}
+)
+
+// RegisterCompression makes a compression codec available under name, the
+// value of the CompressionAttribute of the messages it compresses. The
+// "gzip" codec is built in; others, such as zstd, lz4 or snappy, can be
+// registered by wrapping their packages:
+//
+//	sqs.RegisterCompression("snappy", snappyCodec{})
+func RegisterCompression(name string, c CompressionCodec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[name] = c
+}
+
+func compressionCodec(name string) (CompressionCodec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("sqs: unknown compression codec %q", name)
+	}
+	return c, nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// A Compression transformer compresses message bodies with the registered
+// codec named Codec, base64 encoding the result since SQS bodies must be
+// text. On receive it decompresses the bodies compressed with any
+// registered codec, as named by their CompressionAttribute, so consumers
+// accept whatever codec producers chose.
+type Compression struct {
+	// Codec names the codec used to compress sent messages; if empty,
+	// messages are only decompressed.
+	Codec string
+	// Threshold is the body size, in bytes, up to which messages are sent
+	// uncompressed, since compressing small bodies saves little.
+	Threshold int
+}
+
+// Encode implements Transformer.
+func (c *Compression) Encode(ctx context.Context, m *Message) error {
+	if c.Codec == "" || len(m.Body) <= c.Threshold {
+		return nil
+	}
+	codec, err := compressionCodec(c.Codec)
+	if err != nil {
+		return err
+	}
+	b, err := codec.Compress([]byte(m.Body))
+	if err != nil {
+		return err
+	}
+	m.Body = base64.StdEncoding.EncodeToString(b)
+	m.MessageAttributes[CompressionAttribute] = MessageAttributeValue{DataType: "String", StringValue: c.Codec}
+	return nil
+}
+
+// Decode implements Transformer.
+func (c *Compression) Decode(ctx context.Context, m *Message) error {
+	v, ok := m.MessageAttributes[CompressionAttribute]
+	if !ok {
+		return nil
+	}
+	codec, err := compressionCodec(v.StringValue)
+	if err != nil {
+		return err
+	}
+	b, err := base64.StdEncoding.DecodeString(m.Body)
+	if err != nil {
+		return err
+	}
+	if b, err = codec.Decompress(b); err != nil {
+		return err
+	}
+	m.Body = string(b)
+	delete(m.MessageAttributes, CompressionAttribute)
+	return nil
+}
+
+// Attributes implements AttributeTransformer.
+func (c *Compression) Attributes() []string {
+	return []string{CompressionAttribute}
+}
+
+// EncodingAttribute is the message attribute naming the encoding of a body
+// encoded by BinarySafe.
+const EncodingAttribute = "body-encoding"
+
+// BinarySafe is a Transformer making any body, including binary payloads,
+// safe to send: bodies with characters SQS refuses, such as invalid UTF-8
+// or most control characters, are base64 encoded and marked with an
+// EncodingAttribute of "base64", and decoded on receive. Other bodies are
+// sent as is unless Always is set.
+type BinarySafe struct {
+	// Always encodes every body, for consumers expecting a consistent
+	// encoding.
+	Always bool
+}
+
+// Encode implements Transformer.
+func (t *BinarySafe) Encode(ctx context.Context, m *Message) error {
+	if !t.Always && validBody(m.Body) {
+		return nil
+	}
+	m.Body = base64.StdEncoding.EncodeToString([]byte(m.Body))
+	m.MessageAttributes[EncodingAttribute] = MessageAttributeValue{DataType: "String", StringValue: "base64"}
+	return nil
+}
+
+// Decode implements Transformer.
+func (t *BinarySafe) Decode(ctx context.Context, m *Message) error {
+	v, ok := m.MessageAttributes[EncodingAttribute]
+	if !ok {
+		return nil
+	}
+	if v.StringValue != "base64" {
+		return fmt.Errorf("sqs: unknown body encoding %q", v.StringValue)
+	}
+	b, err := base64.StdEncoding.DecodeString(m.Body)
+	if err != nil {
+		return err
+	}
+	m.Body = string(b)
+	delete(m.MessageAttributes, EncodingAttribute)
+	return nil
+}
+
+// Attributes implements AttributeTransformer.
+func (t *BinarySafe) Attributes() []string {
+	return []string{EncodingAttribute}
+}
@@ -0,0 +1,143 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+type reverseCodec struct{}
+
+func (reverseCodec) Compress(b []byte) ([]byte, error) {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r, nil
+}
+
+func (c reverseCodec) Decompress(b []byte) ([]byte, error) {
+	return c.Compress(b)
+}
+
+func (s *S) TestCompressionRoundTrip(c *C) {
+	RegisterCompression("reverse", reverseCodec{})
+	body := string(bytes.Repeat([]byte("hello "), 100))
+	for _, codec := range []string{"gzip", "reverse"} {
+		sender := &Queue{SQS: &SQS{Transformers: []Transformer{&Compression{Codec: codec}}}, path: "/123/q"}
+		attrs := MessageAttributes{"type": {DataType: "String", StringValue: "greeting"}}
+		m := &Message{Body: body, MessageAttributes: attrs}
+		c.Assert(sender.encode(m), IsNil)
+		c.Assert(m.Body, Not(Equals), body)
+		c.Assert(m.MessageAttributes[CompressionAttribute].StringValue, Equals, codec)
+		c.Assert(attrs, HasLen, 1)
+
+		// Receivers decompress whatever codec the sender chose.
+		receiver := &Queue{SQS: &SQS{Transformers: []Transformer{&Compression{}}}, path: "/123/q"}
+		c.Assert(receiver.decode(m), IsNil)
+		c.Assert(m.Body, Equals, body)
+		c.Assert(m.MessageAttributes, DeepEquals, attrs)
+	}
+}
+
+func (s *S) TestCompressionUnknownCodec(c *C) {
+	q := &Queue{SQS: &SQS{Transformers: []Transformer{&Compression{}}}, path: "/123/q"}
+	m := &Message{Id: "1", Body: "x", MessageAttributes: MessageAttributes{
+		CompressionAttribute: {DataType: "String", StringValue: "zstd"},
+	}}
+	err := q.decode(m)
+	var de *DecodeError
+	c.Assert(errors.As(err, &de), Equals, true)
+	c.Assert(de.Message.Body, Equals, "x")
+	c.Assert(err, ErrorMatches, `sqs: decoding message 1: sqs: unknown compression codec "zstd"`)
+
+	c.Assert(q.attributeNames([]string{"type"}), DeepEquals, []string{"type", CompressionAttribute})
+	c.Assert(q.attributeNames([]string{"All"}), DeepEquals, []string{"All"})
+}
+
+func (s *S) TestReceiveUndecodable(c *C) {
+	l := NewLocal()
+	q, err := l.SQS().CreateQueue("poisoned", nil)
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("one")
+	c.Assert(err, IsNil)
+	_, err = q.SendMessageWithOpt("poison", &SendMessageOpt{MessageAttributes: MessageAttributes{
+		CompressionAttribute: {DataType: "String", StringValue: "zstd"},
+	}})
+	c.Assert(err, IsNil)
+	_, err = q.SendMessage("two")
+	c.Assert(err, IsNil)
+
+	receiver := l.SQS()
+	receiver.Transformers = []Transformer{&Compression{}}
+	rq, err := receiver.Queue("poisoned")
+	c.Assert(err, IsNil)
+	msgs, err := rq.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10})
+	c.Assert(err, ErrorMatches, `sqs: 1 of 3 received messages failed: sqs: decoding message .*: sqs: unknown compression codec "zstd"`)
+	var re *ReceiveError
+	c.Assert(errors.As(err, &re), Equals, true)
+	c.Assert(re.Failed, HasLen, 1)
+	c.Assert(re.Failed[0].Message.Body, Equals, "poison")
+	var de *DecodeError
+	c.Assert(errors.As(err, &de), Equals, true)
+	c.Assert(msgs, HasLen, 2)
+	c.Assert(msgs[0].Body, Equals, "one")
+	c.Assert(msgs[1].Body, Equals, "two")
+
+	// Consumers handle the intact messages and report the other.
+	for _, m := range msgs {
+		c.Assert(rq.ChangeMessageVisibility(m, 0), IsNil)
+	}
+	c.Assert(rq.ChangeMessageVisibility(re.Failed[0].Message, 0), IsNil)
+	var errs []error
+	var b backoff
+	msgs = receive(context.Background(), rq, &ReceiveMessageOpt{MaxNumberOfMessages: 10}, &b, func(err error) { errs = append(errs, err) })
+	c.Assert(msgs, HasLen, 2)
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errors.As(errs[0], &de), Equals, true)
+	c.Assert(de.Message.Body, Equals, "poison")
+}
+
+func (s *S) TestCompressionThreshold(c *C) {
+	q := &Queue{SQS: &SQS{Transformers: []Transformer{&Compression{Codec: "gzip", Threshold: 100}}}, path: "/123/q"}
+	small := strings.Repeat("a", 100)
+	m := &Message{Body: small}
+	c.Assert(q.encode(m), IsNil)
+	c.Assert(m.Body, Equals, small)
+	c.Assert(m.MessageAttributes, HasLen, 0)
+
+	m = &Message{Body: small + "a"}
+	c.Assert(q.encode(m), IsNil)
+	c.Assert(m.MessageAttributes[CompressionAttribute].StringValue, Equals, "gzip")
+}
+
+func (s *S) TestBinarySafe(c *C) {
+	sqs := NewLocal().SQS()
+	sqs.Transformers = []Transformer{&BinarySafe{}}
+	q, err := sqs.CreateQueue("binary", nil)
+	c.Assert(err, IsNil)
+
+	binary := string([]byte{0, 1, 0xff, 0xfe, '\a', 'x'})
+	for _, body := range []string{"plain text", binary} {
+		_, err := q.SendMessage(body)
+		c.Assert(err, IsNil)
+	}
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 10})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 2)
+	c.Assert(msgs[0].Body, Equals, "plain text")
+	c.Assert(msgs[1].Body, Equals, binary)
+	c.Assert(msgs[1].MessageAttributes, HasLen, 0)
+
+	// Only encoded bodies are marked.
+	m := &Message{Body: "plain text", MessageAttributes: MessageAttributes{}}
+	c.Assert((&BinarySafe{Always: true}).Encode(context.Background(), m), IsNil)
+	c.Assert(m.Body, Equals, "cGxhaW4gdGV4dA==")
+	c.Assert(m.MessageAttributes[EncodingAttribute].StringValue, Equals, "base64")
+
+	m.MessageAttributes[EncodingAttribute] = MessageAttributeValue{DataType: "String", StringValue: "rot13"}
+	c.Assert((&BinarySafe{}).Decode(context.Background(), m), ErrorMatches, `sqs: unknown body encoding "rot13"`)
+}
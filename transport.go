@@ -0,0 +1,70 @@
+package sqs
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// TransportOpt tunes the connections of a client. The defaults suit
+// polling workloads, where every long poll holds a connection for up to
+// 20 seconds: http.DefaultTransport keeps only 2 idle connections per
+// host, so dozens of concurrent pollers keep opening and closing
+// connections, and can run out of local ports.
+type TransportOpt struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept to the
+	// endpoint; it defaults to 100. Set it to at least the number of
+	// concurrent requests, e.g. the Concurrency of a Consumer.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept; it
+	// defaults to 90 seconds.
+	IdleConnTimeout time.Duration
+	// TLSHandshakeTimeout bounds TLS handshakes; it defaults to 10
+	// seconds.
+	TLSHandshakeTimeout time.Duration
+	// DialTimeout bounds the establishment of connections, and KeepAlive
+	// is the interval of TCP keep-alive probes; they default to 30
+	// seconds.
+	DialTimeout time.Duration
+	KeepAlive   time.Duration
+}
+
+// NewTransport returns an http.Transport tuned by opt, which may be nil.
+func NewTransport(opt *TransportOpt) *http.Transport {
+	o := TransportOpt{}
+	if opt != nil {
+		o = *opt
+	}
+	orDefault := func(d, def time.Duration) time.Duration {
+		if d <= 0 {
+			return def
+		}
+		return d
+	}
+	idle := o.MaxIdleConnsPerHost
+	if idle <= 0 {
+		idle = 100
+	}
+	dialer := &net.Dialer{
+		Timeout:   orDefault(o.DialTimeout, 30*time.Second),
+		KeepAlive: orDefault(o.KeepAlive, 30*time.Second),
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		MaxIdleConns:          idle,
+		MaxIdleConnsPerHost:   idle,
+		IdleConnTimeout:       orDefault(o.IdleConnTimeout, 90*time.Second),
+		TLSHandshakeTimeout:   orDefault(o.TLSHandshakeTimeout, 10*time.Second),
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// httpClient returns the HTTP client built from the Transport of the
+// client, once.
+func (sqs *SQS) httpClient() *http.Client {
+	sqs.transportOnce.Do(func() {
+		sqs.transportClient = &http.Client{Transport: NewTransport(sqs.Transport)}
+	})
+	return sqs.transportClient
+}
@@ -0,0 +1,49 @@
+package sqs
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestNewTransport(c *C) {
+	t := NewTransport(nil)
+	c.Assert(t.MaxIdleConnsPerHost, Equals, 100)
+	c.Assert(t.IdleConnTimeout, Equals, 90*time.Second)
+	c.Assert(t.TLSHandshakeTimeout, Equals, 10*time.Second)
+	t = NewTransport(&TransportOpt{MaxIdleConnsPerHost: 64, IdleConnTimeout: time.Minute})
+	c.Assert(t.MaxIdleConnsPerHost, Equals, 64)
+	c.Assert(t.MaxIdleConns, Equals, 64)
+	c.Assert(t.IdleConnTimeout, Equals, time.Minute)
+}
+
+func (s *S) TestTransportReusesConnections(c *C) {
+	l := NewLocal()
+	srv := httptest.NewUnstartedServer(l)
+	var conns int64
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+	l.URL = srv.URL
+
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = srv.URL
+	sqs.Signer = NoSigner
+	sqs.Transport = &TransportOpt{MaxIdleConnsPerHost: 8}
+	q, err := sqs.CreateQueue("pooled", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 5; i++ {
+		_, err := q.SendMessage("hello")
+		c.Assert(err, IsNil)
+	}
+	c.Assert(sqs.doer(), Equals, Doer(sqs.httpClient()))
+	c.Assert(atomic.LoadInt64(&conns), Equals, int64(1))
+}
@@ -0,0 +1,198 @@
+package sqs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A ValidationError reports a request SQS would reject, caught by the
+// client before sending it. Set SQS.DisableValidation for SQS-compatible
+// backends with other limits.
+type ValidationError struct {
+	// Field is the parameter at fault, e.g. "MessageBody" or
+	// "MessageAttribute.kind".
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return "sqs: " + e.Message
+}
+
+func invalid(field, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// MaxMessageAttributes is the largest number of custom attributes of a
+// message.
+const MaxMessageAttributes = 10
+
+// validateMessage checks the body and custom attributes of a message to
+// be sent: the body must not be empty, both must only hold characters XML
+// allows, and together they must fit in DefaultMaxBodySize.
+func validateMessage(m *Message) error {
+	if m.Body == "" {
+		return invalid("MessageBody", "message body must not be empty")
+	}
+	if !validBody(m.Body) {
+		return invalid("MessageBody", "message body holds characters SQS does not allow; see BinarySafe")
+	}
+	if len(m.MessageAttributes) > MaxMessageAttributes {
+		return invalid("MessageAttribute", "message has %d attributes, more than %d", len(m.MessageAttributes), MaxMessageAttributes)
+	}
+	size := len(m.Body)
+	for name, v := range m.MessageAttributes {
+		if err := validateMessageAttribute(name, v); err != nil {
+			return err
+		}
+		size += len(name) + len(v.DataType) + len(v.StringValue) + len(v.BinaryValue)
+	}
+	if size > DefaultMaxBodySize {
+		return invalid("MessageBody", "message body and attributes take %d bytes, more than %d", size, DefaultMaxBodySize)
+	}
+	return nil
+}
+
+// validBody tells whether s only holds characters SQS allows in bodies.
+func validBody(s string) bool {
+	return validControlChars(s) && validXMLText([]byte(s))
+}
+
+// validControlChars tells whether the ASCII control characters of s are
+// those XML allows: tab, line feed and carriage return.
+func validControlChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 && c != '\t' && c != '\n' && c != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateMessageAttribute checks the name, data type and value of a
+// custom message attribute.
+func validateMessageAttribute(name string, v MessageAttributeValue) error {
+	field := "MessageAttribute." + name
+	lower := strings.ToLower(name)
+	switch {
+	case name == "" || len(name) > 256:
+		return invalid(field, "message attribute name %q must have 1 to 256 characters", name)
+	case strings.HasPrefix(lower, "aws.") || strings.HasPrefix(lower, "amazon."):
+		return invalid(field, "message attribute name %q uses a reserved prefix", name)
+	case name[0] == '.' || name[len(name)-1] == '.' || strings.Contains(name, ".."):
+		return invalid(field, "message attribute name %q has misplaced periods", name)
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return invalid(field, "message attribute name %q holds %q", name, c)
+		}
+	}
+	if len(v.DataType) > 256 {
+		return invalid(field, "data type of message attribute %s is longer than 256 characters", name)
+	}
+	base := v.DataType
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	switch base {
+	case "String":
+		if v.StringValue == "" || !validBody(v.StringValue) {
+			return invalid(field, "string message attribute %s is empty or holds characters SQS does not allow", name)
+		}
+	case "Number":
+		if _, err := strconv.ParseFloat(v.StringValue, 64); err != nil && !isRangeError(err) {
+			return invalid(field, "number message attribute %s has value %q", name, v.StringValue)
+		}
+	case "Binary":
+		if len(v.BinaryValue) == 0 {
+			return invalid(field, "binary message attribute %s is empty", name)
+		}
+	default:
+		return invalid(field, "message attribute %s has data type %q, want String, Number or Binary", name, v.DataType)
+	}
+	return nil
+}
+
+func isRangeError(err error) bool {
+	e, ok := err.(*strconv.NumError)
+	return ok && e.Err == strconv.ErrRange
+}
+
+// validateQueueName checks that name has up to 80 alphanumeric
+// characters, hyphens and underscores, FIFO queues ending in ".fifo".
+func validateQueueName(name string) error {
+	base := strings.TrimSuffix(name, ".fifo")
+	if base == "" || len(name) > 80 {
+		return invalid("QueueName", "queue name %q must have 1 to 80 characters", name)
+	}
+	for _, c := range base {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return invalid("QueueName", "queue name %q holds %q", name, c)
+		}
+	}
+	return nil
+}
+
+// validateVisibilityTimeout checks a visibility timeout in seconds.
+func validateVisibilityTimeout(v int) error {
+	if max := int(MaxVisibilityTimeout.Seconds()); v < 0 || v > max {
+		return invalid("VisibilityTimeout", "VisibilityTimeout must be between 0 and %d, got %d", max, v)
+	}
+	return nil
+}
+
+// validateReceive checks the options of a receive.
+func validateReceive(opt *ReceiveMessageOpt) error {
+	if n := opt.MaxNumberOfMessages; n < 0 || n > MaxBatchSize {
+		return invalid("MaxNumberOfMessages", "MaxNumberOfMessages must be between 1 and %d, got %d", MaxBatchSize, n)
+	}
+	if w := opt.WaitTimeSeconds; w < 0 || w > 20 {
+		return invalid("WaitTimeSeconds", "WaitTimeSeconds must be between 0 and 20, got %d", w)
+	}
+	if opt.VisibilityTimeout != VisibilityZero {
+		return validateVisibilityTimeout(opt.VisibilityTimeout)
+	}
+	return nil
+}
+
+// validateBatchSize checks the number of entries of a batch request.
+func validateBatchSize(n int) error {
+	if n == 0 || n > MaxBatchSize {
+		return invalid("Entries", "batch has %d entries, want 1 to %d", n, MaxBatchSize)
+	}
+	return nil
+}
+
+// validateBatch checks the number of entries of a batch request and their
+// IDs: up to 80 alphanumeric characters, hyphens and underscores, unique
+// in the batch.
+func validateBatch(ids []string) error {
+	if err := validateBatchSize(len(ids)); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id == "" || len(id) > 80 {
+			return invalid("Id", "batch entry ID %q must have 1 to 80 characters", id)
+		}
+		for _, c := range id {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+				return invalid("Id", "batch entry ID %q holds %q", id, c)
+			}
+		}
+		if seen[id] {
+			return invalid("Id", "batch entry ID %q is not unique", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// inEntry reports err, of a batch entry, as such.
+func inEntry(id string, err error) error {
+	if e, ok := err.(*ValidationError); ok {
+		return &ValidationError{Field: e.Field, Message: "batch entry " + id + ": " + e.Message}
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package sqs
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestValidation(c *C) {
+	sqs := NewLocal().SQS()
+	var calls int
+	sqs.Hooks.BeforeSign = func(action string, params url.Values) { calls++ }
+	q, err := sqs.CreateQueue("jobs", nil)
+	c.Assert(err, IsNil)
+	calls = 0
+
+	for _, t := range []struct {
+		err   error
+		field string
+		msg   string
+	}{{
+		err:   func() error { _, err := q.SendMessage(""); return err }(),
+		field: "MessageBody", msg: "sqs: message body must not be empty",
+	}, {
+		err:   func() error { _, err := q.SendMessage("bell\a"); return err }(),
+		field: "MessageBody", msg: "sqs: message body holds characters SQS does not allow; see BinarySafe",
+	}, {
+		err:   func() error { _, err := q.SendMessage(strings.Repeat("x", DefaultMaxBodySize+1)); return err }(),
+		field: "MessageBody", msg: "sqs: message body and attributes take 262145 bytes, more than 262144",
+	}, {
+		err: func() error {
+			_, err := q.SendMessageWithOpt("hi", &SendMessageOpt{MessageAttributes: MessageAttributes{"AWS.kind": {DataType: "String", StringValue: "x"}}})
+			return err
+		}(),
+		field: "MessageAttribute.AWS.kind", msg: `sqs: message attribute name "AWS.kind" uses a reserved prefix`,
+	}, {
+		err: func() error {
+			_, err := q.SendMessageWithOpt("hi", &SendMessageOpt{MessageAttributes: MessageAttributes{"n": {DataType: "Number", StringValue: "ten"}}})
+			return err
+		}(),
+		field: "MessageAttribute.n", msg: `sqs: number message attribute n has value "ten"`,
+	}, {
+		err: func() error {
+			_, err := q.SendMessageWithOpt("hi", &SendMessageOpt{MessageAttributes: MessageAttributes{"t": {DataType: "Date", StringValue: "now"}}})
+			return err
+		}(),
+		field: "MessageAttribute.t", msg: `sqs: message attribute t has data type "Date", want String, Number or Binary`,
+	}, {
+		err: func() error {
+			_, err := q.SendMessageBatch([]SendMessageBatchEntry{{Id: "a", Body: "hi"}, {Id: "a", Body: "ho"}})
+			return err
+		}(),
+		field: "Id", msg: `sqs: batch entry ID "a" is not unique`,
+	}, {
+		err: func() error {
+			_, err := q.SendMessageBatch([]SendMessageBatchEntry{{Id: "a", Body: "hi"}, {Id: "b"}})
+			return err
+		}(),
+		field: "MessageBody", msg: "sqs: batch entry b: message body must not be empty",
+	}, {
+		err:   func() error { _, err := q.DeleteMessageBatch(nil); return err }(),
+		field: "Entries", msg: "sqs: batch has 0 entries, want 1 to 10",
+	}, {
+		err:   func() error { _, err := q.ReceiveMessages(&ReceiveMessageOpt{MaxNumberOfMessages: 11}); return err }(),
+		field: "MaxNumberOfMessages", msg: "sqs: MaxNumberOfMessages must be between 1 and 10, got 11",
+	}, {
+		err:   func() error { _, err := q.ReceiveMessages(&ReceiveMessageOpt{WaitTimeSeconds: 21}); return err }(),
+		field: "WaitTimeSeconds", msg: "sqs: WaitTimeSeconds must be between 0 and 20, got 21",
+	}, {
+		err:   q.ChangeMessageVisibility(&Message{ReceiptHandle: "h"}, 43201),
+		field: "VisibilityTimeout", msg: "sqs: VisibilityTimeout must be between 0 and 43200, got 43201",
+	}, {
+		err:   func() error { _, err := sqs.CreateQueue("jobs.v2", nil); return err }(),
+		field: "QueueName", msg: `sqs: queue name "jobs.v2" holds '.'`,
+	}} {
+		var e *ValidationError
+		c.Assert(errors.As(t.err, &e), Equals, true, Commentf("%v", t.err))
+		c.Assert(e.Field, Equals, t.field)
+		c.Assert(e.Error(), Equals, t.msg)
+	}
+	c.Assert(calls, Equals, 0)
+
+	// Without validation, SQS decides.
+	sqs.DisableValidation = true
+	_, err = q.SendMessage("")
+	c.Assert(ErrorCode(err), Equals, ErrCodeMissingParameter)
+	c.Assert(calls, Equals, 1)
+}
@@ -0,0 +1,100 @@
+package sqs
+
+import (
+	"context"
+	"time"
+)
+
+// A Threshold of a Watcher calls OnCross when a quantity of the queue
+// crosses Level: with above true when it rises to Level or more, and
+// false when it falls back below.
+type Threshold struct {
+	Level int
+	// Of selects the quantity of the queue; it defaults to the visible
+	// messages, i.e. the backlog.
+	Of      func(st QueueStats) int
+	OnCross func(st QueueStats, above bool)
+}
+
+func (t *Threshold) value(st QueueStats) int {
+	if t.Of != nil {
+		return t.Of(st)
+	}
+	return st.Visible
+}
+
+// A Watcher polls the depth of a queue and calls back when it crosses
+// thresholds, e.g. to scale workers or alert on a backlog without a
+// CloudWatch poller:
+//
+//	w := &sqs.Watcher{
+//		Queue: q,
+//		Thresholds: []sqs.Threshold{{Level: 1000, OnCross: func(st sqs.QueueStats, above bool) {
+//			...
+//		}}},
+//		OnDrained: func() { ... },
+//	}
+//	go w.Run(ctx)
+type Watcher struct {
+	Queue *Queue
+	// Interval is the time between polls; it defaults to one minute.
+	Interval   time.Duration
+	Thresholds []Threshold
+	// OnDrained, if set, is called when the queue is found empty, see
+	// QueueStats.Empty, after it was not.
+	OnDrained func()
+	// OnSample, if set, is called with every poll.
+	OnSample func(st QueueStats)
+	// OnError, if set, is called with polling errors.
+	OnError func(err error)
+
+	sampled bool
+	above   []bool // by threshold
+	empty   bool
+}
+
+// Run polls the queue until ctx is done. Thresholds exceeded by the first
+// poll call back at once.
+func (w *Watcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	for {
+		if st, err := w.Queue.WithContext(ctx).Stats(); err != nil {
+			if ctx.Err() == nil && w.OnError != nil {
+				w.OnError(err)
+			}
+		} else {
+			w.sample(st)
+		}
+		if !sleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// sample calls back for the thresholds crossed since the previous poll.
+func (w *Watcher) sample(st QueueStats) {
+	if w.OnSample != nil {
+		w.OnSample(st)
+	}
+	if w.above == nil {
+		w.above = make([]bool, len(w.Thresholds))
+	}
+	for i := range w.Thresholds {
+		t := &w.Thresholds[i]
+		above := t.value(st) >= t.Level
+		if above != w.above[i] {
+			w.above[i] = above
+			if t.OnCross != nil {
+				t.OnCross(st, above)
+			}
+		}
+	}
+	empty := st.Empty()
+	if empty && !w.empty && w.sampled && w.OnDrained != nil {
+		w.OnDrained()
+	}
+	w.empty, w.sampled = empty, true
+}
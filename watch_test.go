@@ -0,0 +1,61 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestWatcherThresholds(c *C) {
+	var events []string
+	w := &Watcher{
+		Thresholds: []Threshold{
+			{Level: 100, OnCross: func(st QueueStats, above bool) {
+				events = append(events, fmt.Sprintf("backlog %d %v", st.Visible, above))
+			}},
+			{Level: 10, Of: func(st QueueStats) int { return st.InFlight }, OnCross: func(st QueueStats, above bool) {
+				events = append(events, fmt.Sprintf("in flight %d %v", st.InFlight, above))
+			}},
+		},
+		OnDrained: func() { events = append(events, "drained") },
+	}
+	w.sample(QueueStats{})
+	c.Assert(events, HasLen, 0)
+	w.sample(QueueStats{Visible: 150, InFlight: 5})
+	w.sample(QueueStats{Visible: 120, InFlight: 10})
+	w.sample(QueueStats{Visible: 20, InFlight: 10})
+	w.sample(QueueStats{Delayed: 1})
+	w.sample(QueueStats{})
+	w.sample(QueueStats{})
+	c.Assert(events, DeepEquals, []string{
+		"backlog 150 true",
+		"in flight 10 true",
+		"backlog 20 false",
+		"in flight 0 false",
+		"drained",
+	})
+}
+
+func (s *S) TestWatcherRun(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("watched", nil)
+	c.Assert(err, IsNil)
+	for i := 0; i < 3; i++ {
+		_, err := q.SendMessage("hello")
+		c.Assert(err, IsNil)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	crossed := make(chan QueueStats, 1)
+	w := &Watcher{
+		Queue:      q,
+		Interval:   time.Millisecond,
+		Thresholds: []Threshold{{Level: 3, OnCross: func(st QueueStats, above bool) { crossed <- st }}},
+	}
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	c.Assert((<-crossed).Visible, Equals, 3)
+	cancel()
+	c.Assert(<-done, Equals, context.Canceled)
+}
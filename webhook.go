@@ -0,0 +1,113 @@
+package sqs
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// DefaultMaxBodySize is the largest message body SQS accepts.
+const DefaultMaxBodySize = 256 * 1024
+
+// A WebhookBridge is an http.Handler that enqueues the body of every POST
+// it receives to Queue, turning any HTTP client into an SQS producer.
+// It answers 202 Accepted with the message ID as JSON once the message is
+// enqueued, and 400 Bad Request for bodies SQS would reject.
+type WebhookBridge struct {
+	Queue *Queue
+
+	// MaxBodySize limits the accepted body size; it defaults to
+	// DefaultMaxBodySize.
+	MaxBodySize int64
+	// Token, if set, must be presented as "Authorization: Bearer <Token>".
+	Token string
+	// Authorize, if set, replaces the Token check.
+	Authorize func(r *http.Request) bool
+	// HeaderAttributes maps request headers to the names of the string
+	// message attributes their values are copied to.
+	HeaderAttributes map[string]string
+	// Guard, if set, suppresses bodies identical to a recently enqueued
+	// one; duplicates are answered 200 OK with {"duplicate":true}.
+	Guard *SendGuard
+}
+
+func (b *WebhookBridge) authorized(r *http.Request) bool {
+	if b.Authorize != nil {
+		return b.Authorize(r)
+	}
+	if b.Token == "" {
+		return true
+	}
+	want := "Bearer " + b.Token
+	got := r.Header.Get("Authorization")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+func (b *WebhookBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !b.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	limit := b.MaxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		http.Error(w, "could not read body", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > limit {
+		http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(body) == 0 {
+		http.Error(w, "empty body", http.StatusBadRequest)
+		return
+	}
+
+	opt := &SendMessageOpt{MessageAttributes: b.attributes(r)}
+	var id string
+	if b.Guard != nil {
+		var sent bool
+		id, sent, err = b.Guard.Send(b.Queue, string(body), opt)
+		if err == nil && !sent {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]bool{"duplicate": true})
+			return
+		}
+	} else {
+		id, err = b.Queue.SendMessageWithOpt(string(body), opt)
+	}
+	var invalid *ValidationError
+	if errors.As(err, &invalid) {
+		http.Error(w, invalid.Message, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "could not enqueue message", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message_id": id})
+}
+
+// attributes maps the configured request headers to message attributes.
+func (b *WebhookBridge) attributes(r *http.Request) MessageAttributes {
+	attrs := make(MessageAttributes)
+	for h, name := range b.HeaderAttributes {
+		if v := r.Header.Get(h); v != "" {
+			attrs[name] = MessageAttributeValue{DataType: "String", StringValue: v}
+		}
+	}
+	return attrs
+}
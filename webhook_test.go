@@ -0,0 +1,57 @@
+package sqs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestWebhookBridgeRejects(c *C) {
+	b := &WebhookBridge{Queue: &Queue{SQS: s.sqs, path: "/123/q"}, Token: "secret", MaxBodySize: 4}
+
+	w := httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	c.Assert(w.Code, Equals, http.StatusMethodNotAllowed)
+
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("hi")))
+	c.Assert(w.Code, Equals, http.StatusUnauthorized)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/", strings.NewReader("hello"))
+	r.Header.Set("Authorization", "Bearer secret")
+	b.ServeHTTP(w, r)
+	c.Assert(w.Code, Equals, http.StatusRequestEntityTooLarge)
+}
+
+func (s *S) TestWebhookBridge(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("hooks", nil)
+	c.Assert(err, IsNil)
+	b := &WebhookBridge{Queue: q, HeaderAttributes: map[string]string{"X-Event": "event"}}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/", strings.NewReader("hello"))
+	r.Header.Set("X-Event", "push")
+	b.ServeHTTP(w, r)
+	c.Assert(w.Code, Equals, http.StatusAccepted)
+	var resp struct {
+		MessageId string `json:"message_id"`
+	}
+	c.Assert(json.NewDecoder(w.Body).Decode(&resp), IsNil)
+
+	msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{MessageAttributeNames: []string{"All"}})
+	c.Assert(err, IsNil)
+	c.Assert(msgs, HasLen, 1)
+	c.Assert(msgs[0].Id, Equals, resp.MessageId)
+	c.Assert(msgs[0].Body, Equals, "hello")
+	c.Assert(msgs[0].MessageAttributes["event"].StringValue, Equals, "push")
+
+	// Bodies SQS would reject are the client's fault.
+	w = httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("nul\x00")))
+	c.Assert(w.Code, Equals, http.StatusBadRequest)
+	c.Assert(w.Body.String(), Matches, "message body holds characters SQS does not allow.*\n")
+}
@@ -0,0 +1,165 @@
+package sqs
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// A Window is the group of messages sharing a key that arrived during one
+// tumbling time window.
+type Window struct {
+	Key        string
+	Start, End time.Time
+	Messages   []*Message
+}
+
+// A WindowHandler processes aggregated windows. Returning nil commits the
+// window and acknowledges its messages; returning an error leaves them to
+// be redelivered.
+type WindowHandler interface {
+	HandleWindow(w *Window) error
+}
+
+// The WindowHandlerFunc type is an adapter to allow the use of an ordinary
+// function as a WindowHandler.
+type WindowHandlerFunc func(w *Window) error
+
+// HandleWindow calls f(w).
+func (f WindowHandlerFunc) HandleWindow(w *Window) error {
+	return f(w)
+}
+
+// A WindowAggregator groups received messages by key over tumbling windows
+// of length Size, aligned to the wall clock, and delivers each group to
+// Handler when its window closes.
+//
+// Messages are held for up to Size before being handled, so the
+// VisibilityTimeout should exceed Size plus the time Handler takes.
+type WindowAggregator struct {
+	Queue   QueueReceiver
+	Handler WindowHandler
+	Size    time.Duration
+
+	// KeyAttribute names the string message attribute holding the
+	// grouping key. KeyFunc, if set, takes precedence over it.
+	KeyAttribute string
+	KeyFunc      func(m *Message) string
+
+	// VisibilityTimeout, in seconds, overrides the queue default for the
+	// aggregated messages when positive.
+	VisibilityTimeout int
+	// OnError, if set, is called with receive, handler and delete errors.
+	OnError func(err error)
+}
+
+func (a *WindowAggregator) onError(err error) {
+	if a.OnError != nil {
+		a.OnError(err)
+	}
+}
+
+func (a *WindowAggregator) key(m *Message) string {
+	if a.KeyFunc != nil {
+		return a.KeyFunc(m)
+	}
+	return m.MessageAttributes[a.KeyAttribute].StringValue
+}
+
+// Run aggregates and delivers windows until ctx is done, which also ends a
+// long poll in progress if Queue is a *Queue. The open window is delivered
+// early before Run returns.
+func (a *WindowAggregator) Run(ctx context.Context) error {
+	size := a.Size
+	if size <= 0 {
+		size = time.Minute
+	}
+	var attrs []string
+	if a.KeyFunc == nil && a.KeyAttribute != "" {
+		attrs = []string{a.KeyAttribute}
+	} else {
+		attrs = []string{"All"}
+	}
+
+	queue := a.Queue
+	if q, ok := queue.(*Queue); ok {
+		// Stop long polling once ctx is done.
+		queue = q.WithContext(ctx)
+	}
+
+	start := time.Now().Truncate(size)
+	groups := make(map[string]*Window)
+	var b backoff
+	for ctx.Err() == nil {
+		end := start.Add(size)
+		if !time.Now().Before(end) {
+			a.flush(groups)
+			groups = make(map[string]*Window)
+			start = time.Now().Truncate(size)
+			continue
+		}
+
+		wait := time.Until(end)
+		if wait > 20*time.Second {
+			wait = 20 * time.Second
+		}
+		polled := time.Now()
+		msgs, err := queue.ReceiveMessages(&ReceiveMessageOpt{
+			MaxNumberOfMessages:   10,
+			VisibilityTimeout:     a.VisibilityTimeout,
+			WaitTimeSeconds:       int(wait / time.Second),
+			MessageAttributeNames: attrs,
+		})
+		if err = reportReceiveError(err, a.onError); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			a.onError(err)
+			b.wait(ctx)
+			continue
+		}
+		b.reset()
+		if len(msgs) == 0 && time.Since(polled) < wait {
+			// The endpoint did not long-poll; don't spin on it.
+			pause := time.Until(end)
+			if pause > time.Second {
+				pause = time.Second
+			}
+			sleepContext(ctx, pause)
+			continue
+		}
+		for _, m := range msgs {
+			k := a.key(m)
+			w := groups[k]
+			if w == nil {
+				w = &Window{Key: k, Start: start, End: end}
+				groups[k] = w
+			}
+			w.Messages = append(w.Messages, m)
+		}
+	}
+	a.flush(groups)
+	return ctx.Err()
+}
+
+// flush delivers every group in key order and acknowledges the committed
+// ones.
+func (a *WindowAggregator) flush(groups map[string]*Window) {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		w := groups[k]
+		if err := a.Handler.HandleWindow(w); err != nil {
+			a.onError(err)
+			continue
+		}
+		for _, m := range w.Messages {
+			if err := a.Queue.DeleteMessage(m); err != nil {
+				a.onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "launchpad.net/gocheck"
+)
+
+func (s *S) TestWindowAggregator(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("windows", nil)
+	c.Assert(err, IsNil)
+	for i, user := range []string{"a", "b", "a"} {
+		_, err := q.SendMessageWithOpt(fmt.Sprint(i), &SendMessageOpt{MessageAttributes: MessageAttributes{
+			"user": {DataType: "String", StringValue: user},
+		}})
+		c.Assert(err, IsNil)
+	}
+
+	// The groups of a window are delivered in key order once it closes.
+	const size = 100 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	var windows []string
+	total := 0
+	a := &WindowAggregator{
+		Queue:        q,
+		Size:         size,
+		KeyAttribute: "user",
+		Handler: WindowHandlerFunc(func(w *Window) error {
+			c.Check(w.End.Sub(w.Start), Equals, size)
+			c.Check(w.Start.Equal(w.Start.Truncate(size)), Equals, true)
+			c.Check(time.Now().Before(w.End), Equals, false)
+			var bodies []string
+			for _, m := range w.Messages {
+				bodies = append(bodies, m.Body)
+			}
+			windows = append(windows, fmt.Sprint(w.Key, bodies))
+			if total += len(w.Messages); total == 3 {
+				cancel()
+			}
+			return nil
+		}),
+	}
+	c.Assert(a.Run(ctx), Equals, context.Canceled)
+	c.Assert(windows, DeepEquals, []string{"a[0 2]", "b[1]"})
+	visible, inFlight := queueCounts(c, q)
+	c.Assert(visible+" "+inFlight, Equals, "0 0")
+}
+
+func (s *S) TestWindowAggregatorFlushOnCancel(c *C) {
+	q, err := NewLocal().SQS().CreateQueue("windows", nil)
+	c.Assert(err, IsNil)
+	sendBodies(c, q, "x", "y")
+
+	// The open window is delivered early, and a failed group left for
+	// redelivery.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			if _, inFlight := queueCounts(c, q); inFlight == "2" {
+				cancel()
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	var keys []string
+	var errs []error
+	a := &WindowAggregator{
+		Queue:   q,
+		Size:    time.Hour,
+		KeyFunc: func(m *Message) string { return m.Body },
+		Handler: WindowHandlerFunc(func(w *Window) error {
+			keys = append(keys, w.Key)
+			if w.Key == "x" {
+				return errors.New("sink down")
+			}
+			return nil
+		}),
+		OnError: func(err error) { errs = append(errs, err) },
+	}
+	start := time.Now()
+	c.Assert(a.Run(ctx), Equals, context.Canceled)
+	c.Assert(time.Since(start) < time.Second, Equals, true)
+	c.Assert(keys, DeepEquals, []string{"x", "y"})
+	c.Assert(errs, HasLen, 1)
+	c.Assert(errs[0], ErrorMatches, "sink down")
+	visible, inFlight := queueCounts(c, q)
+	c.Assert(visible+" "+inFlight, Equals, "0 1")
+}
@@ -0,0 +1,393 @@
+package sqs
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	. "launchpad.net/gocheck"
+)
+
+var wireRecord = flag.Bool("wire.record", false, "Rewrite the golden files of the wire tests")
+
+// wireEndpoint replaces the URL of the wire server in recorded requests,
+// so that golden files do not depend on its port.
+const wireEndpoint = "http://sqs.test"
+
+// wireResponses holds canned responses of SQS, by action. ENDPOINT is
+// replaced by the URL of the wire server.
+var wireResponses = map[string]string{
+	"CancelMessageMoveTask": `<CancelMessageMoveTaskResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<CancelMessageMoveTaskResult><ApproximateNumberOfMessagesMoved>42</ApproximateNumberOfMessagesMoved></CancelMessageMoveTaskResult>
+<ResponseMetadata><RequestId>req-cmmt</RequestId></ResponseMetadata>
+</CancelMessageMoveTaskResponse>`,
+	"ChangeMessageVisibility": `<ChangeMessageVisibilityResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ResponseMetadata><RequestId>req-cmv</RequestId></ResponseMetadata>
+</ChangeMessageVisibilityResponse>`,
+	"CreateQueue": `<CreateQueueResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<CreateQueueResult><QueueUrl>ENDPOINT/123456789012/jobs</QueueUrl></CreateQueueResult>
+<ResponseMetadata><RequestId>req-cq</RequestId></ResponseMetadata>
+</CreateQueueResponse>`,
+	"DeleteMessage": `<DeleteMessageResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ResponseMetadata><RequestId>req-dm</RequestId></ResponseMetadata>
+</DeleteMessageResponse>`,
+	"DeleteMessageBatch": `<DeleteMessageBatchResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<DeleteMessageBatchResult>
+<DeleteMessageBatchResultEntry><Id>0</Id></DeleteMessageBatchResultEntry>
+<BatchResultErrorEntry><Id>1</Id><Code>ReceiptHandleIsInvalid</Code><Message>The receipt handle is not valid.</Message><SenderFault>true</SenderFault></BatchResultErrorEntry>
+</DeleteMessageBatchResult>
+<ResponseMetadata><RequestId>req-dmb</RequestId></ResponseMetadata>
+</DeleteMessageBatchResponse>`,
+	"DeleteQueue": `<DeleteQueueResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ResponseMetadata><RequestId>req-dq</RequestId></ResponseMetadata>
+</DeleteQueueResponse>`,
+	"GetQueueAttributes": `<GetQueueAttributesResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<GetQueueAttributesResult>
+<Attribute><Name>ApproximateNumberOfMessages</Name><Value>7</Value></Attribute>
+<Attribute><Name>VisibilityTimeout</Name><Value>30</Value></Attribute>
+</GetQueueAttributesResult>
+<ResponseMetadata><RequestId>req-gqa</RequestId></ResponseMetadata>
+</GetQueueAttributesResponse>`,
+	"GetQueueUrl": `<GetQueueUrlResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<GetQueueUrlResult><QueueUrl>ENDPOINT/210987654321/jobs</QueueUrl></GetQueueUrlResult>
+<ResponseMetadata><RequestId>req-gqu</RequestId></ResponseMetadata>
+</GetQueueUrlResponse>`,
+	"ListDeadLetterSourceQueues": `<ListDeadLetterSourceQueuesResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ListDeadLetterSourceQueuesResult><QueueUrl>ENDPOINT/123456789012/jobs</QueueUrl></ListDeadLetterSourceQueuesResult>
+<ResponseMetadata><RequestId>req-ldlsq</RequestId></ResponseMetadata>
+</ListDeadLetterSourceQueuesResponse>`,
+	"ListMessageMoveTasks": `<ListMessageMoveTasksResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ListMessageMoveTasksResult>
+<ListMessageMoveTasksResultEntry>
+<TaskHandle>task-1</TaskHandle><Status>RUNNING</Status>
+<SourceArn>arn:aws:sqs:us-east-1:123456789012:jobs-dead</SourceArn>
+<MaxNumberOfMessagesPerSecond>50</MaxNumberOfMessagesPerSecond>
+<ApproximateNumberOfMessagesMoved>20</ApproximateNumberOfMessagesMoved>
+<ApproximateNumberOfMessagesToMove>100</ApproximateNumberOfMessagesToMove>
+<StartedTimestamp>1700000000000</StartedTimestamp>
+</ListMessageMoveTasksResultEntry>
+<ListMessageMoveTasksResultEntry>
+<Status>FAILED</Status>
+<SourceArn>arn:aws:sqs:us-east-1:123456789012:jobs-dead</SourceArn>
+<DestinationArn>arn:aws:sqs:us-east-1:123456789012:gone</DestinationArn>
+<ApproximateNumberOfMessagesMoved>0</ApproximateNumberOfMessagesMoved>
+<FailureReason>AWS.SimpleQueueService.NonExistentQueue</FailureReason>
+<StartedTimestamp>1690000000000</StartedTimestamp>
+</ListMessageMoveTasksResultEntry>
+</ListMessageMoveTasksResult>
+<ResponseMetadata><RequestId>req-lmmt</RequestId></ResponseMetadata>
+</ListMessageMoveTasksResponse>`,
+	"ListQueueTags": `<ListQueueTagsResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ListQueueTagsResult>
+<Tag><Key>team</Key><Value>ops</Value></Tag>
+<Tag><Key>env</Key><Value>prod</Value></Tag>
+</ListQueueTagsResult>
+<ResponseMetadata><RequestId>req-lqt</RequestId></ResponseMetadata>
+</ListQueueTagsResponse>`,
+	"ListQueues": `<ListQueuesResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ListQueuesResult>
+<QueueUrl>ENDPOINT/123456789012/jobs</QueueUrl>
+<QueueUrl>ENDPOINT/123456789012/jobs-dead</QueueUrl>
+</ListQueuesResult>
+<ResponseMetadata><RequestId>req-lq</RequestId></ResponseMetadata>
+</ListQueuesResponse>`,
+	"PurgeQueue": `<PurgeQueueResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ResponseMetadata><RequestId>req-pq</RequestId></ResponseMetadata>
+</PurgeQueueResponse>`,
+	"ReceiveMessage": `<ReceiveMessageResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ReceiveMessageResult><Message>
+<MessageId>id-1</MessageId><ReceiptHandle>rh-1</ReceiptHandle>
+<MD5OfBody>5d41402abc4b2a76b9719d911017c592</MD5OfBody><Body>hello</Body>
+<Attribute><Name>ApproximateReceiveCount</Name><Value>2</Value></Attribute>
+<MessageAttribute><Name>kind</Name><Value><DataType>String</DataType><StringValue>greeting</StringValue></Value></MessageAttribute>
+</Message></ReceiveMessageResult>
+<ResponseMetadata><RequestId>req-rm</RequestId></ResponseMetadata>
+</ReceiveMessageResponse>`,
+	"SendMessage": `<SendMessageResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<SendMessageResult><MD5OfMessageBody>5d41402abc4b2a76b9719d911017c592</MD5OfMessageBody><MessageId>id-2</MessageId></SendMessageResult>
+<ResponseMetadata><RequestId>req-sm</RequestId></ResponseMetadata>
+</SendMessageResponse>`,
+	"SendMessageBatch": `<SendMessageBatchResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<SendMessageBatchResult>
+<SendMessageBatchResultEntry><Id>a</Id><MessageId>id-3</MessageId><MD5OfMessageBody>5d41402abc4b2a76b9719d911017c592</MD5OfMessageBody></SendMessageBatchResultEntry>
+<BatchResultErrorEntry><Id>b</Id><Code>InvalidMessageContents</Code><Message>Invalid characters.</Message><SenderFault>true</SenderFault></BatchResultErrorEntry>
+</SendMessageBatchResult>
+<ResponseMetadata><RequestId>req-smb</RequestId></ResponseMetadata>
+</SendMessageBatchResponse>`,
+	"SetQueueAttributes": `<SetQueueAttributesResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ResponseMetadata><RequestId>req-sqa</RequestId></ResponseMetadata>
+</SetQueueAttributesResponse>`,
+	"StartMessageMoveTask": `<StartMessageMoveTaskResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<StartMessageMoveTaskResult><TaskHandle>task-1</TaskHandle></StartMessageMoveTaskResult>
+<ResponseMetadata><RequestId>req-smmt</RequestId></ResponseMetadata>
+</StartMessageMoveTaskResponse>`,
+	"TagQueue": `<TagQueueResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ResponseMetadata><RequestId>req-tq</RequestId></ResponseMetadata>
+</TagQueueResponse>`,
+	"UntagQueue": `<UntagQueueResponse xmlns="http://queue.amazonaws.com/doc/2012-11-05/">
+<ResponseMetadata><RequestId>req-uq</RequestId></ResponseMetadata>
+</UntagQueueResponse>`,
+}
+
+// wireServer replays wireResponses and records the requests it receives,
+// in the format of the golden files: the method and path, then every
+// parameter but the timestamp and signature, sorted, and whether the
+// signature is valid.
+type wireServer struct {
+	*httptest.Server
+	secret string
+
+	mu       sync.Mutex
+	requests map[string]string // by action
+}
+
+func newWireServer(secret string) *wireServer {
+	ws := &wireServer{secret: secret, requests: make(map[string]string)}
+	ws.Server = httptest.NewServer(ws)
+	return ws
+}
+
+func (ws *wireServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	action := r.Form.Get("Action")
+	resp, ok := wireResponses[action]
+	if !ok {
+		w.WriteHeader(400)
+		fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>InvalidAction</Code><Message>no canned response for %q</Message></Error></ErrorResponse>`, action)
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", r.Method, r.URL.Path)
+	keys := make([]string, 0, len(r.Form))
+	for k := range r.Form {
+		if k != "Timestamp" && k != "Signature" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range r.Form[k] {
+			fmt.Fprintf(&b, "%s=%s\n", k, strings.Replace(v, ws.URL, wireEndpoint, -1))
+		}
+	}
+	fmt.Fprintf(&b, "Signature=%s\n", ws.verify(r))
+
+	ws.mu.Lock()
+	ws.requests[action] = b.String()
+	ws.mu.Unlock()
+	fmt.Fprint(w, strings.Replace(resp, "ENDPOINT", ws.URL, -1))
+}
+
+// verify checks the Signature Version 2 of r.
+func (ws *wireServer) verify(r *http.Request) string {
+	params := make(url.Values, len(r.Form))
+	for k, v := range r.Form {
+		if k != "Signature" {
+			params[k] = v
+		}
+	}
+	sign(Credentials{AccessKey: r.Form.Get("AWSAccessKeyId"), SecretKey: ws.secret}.auth(),
+		r.Method, r.URL.Path, params, http.Header{"Host": {r.Host}})
+	if params.Get("Signature") != r.Form.Get("Signature") {
+		return "invalid"
+	}
+	return "valid"
+}
+
+// request returns the recorded request of action.
+func (ws *wireServer) request(action string) string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.requests[action]
+}
+
+// TestWire calls every action against canned responses, checking how the
+// results are decoded and, against testdata/wire, how the requests are
+// encoded. Run with -wire.record to rewrite the golden files after an
+// intended change of the encoding.
+func (s *S) TestWire(c *C) {
+	ws := newWireServer(s.sqs.Auth.SecretKey)
+	defer ws.Close()
+	sqs := New(s.sqs.Auth, s.sqs.Region)
+	sqs.Endpoint = ws.URL
+	q := &Queue{SQS: sqs, path: "/123456789012/jobs"}
+	m := &Message{Id: "id-1", ReceiptHandle: "rh-1"}
+
+	cases := []struct {
+		action string
+		call   func(c *C)
+	}{{
+		"CancelMessageMoveTask", func(c *C) {
+			moved, err := sqs.CancelMessageMoveTask("task-1")
+			c.Assert(err, IsNil)
+			c.Assert(moved, Equals, int64(42))
+		},
+	}, {
+		"ChangeMessageVisibility", func(c *C) {
+			c.Assert(q.ChangeMessageVisibility(m, 60), IsNil)
+		},
+	}, {
+		"CreateQueue", func(c *C) {
+			q, err := sqs.CreateQueue("jobs", &CreateQueueOpt{
+				VisibilityTimeout: 30,
+				DelaySeconds:      5,
+				RedrivePolicy:     &RedrivePolicy{DeadLetterTargetArn: "arn:aws:sqs:us-east-1:123456789012:jobs-dead", MaxReceiveCount: 3},
+			})
+			c.Assert(err, IsNil)
+			c.Assert(q.URL(), Equals, ws.URL+"/123456789012/jobs")
+		},
+	}, {
+		"DeleteMessage", func(c *C) {
+			c.Assert(q.DeleteMessage(m), IsNil)
+		},
+	}, {
+		"DeleteMessageBatch", func(c *C) {
+			res, err := q.DeleteMessageBatch([]*Message{m, {Id: "id-2", ReceiptHandle: "rh-2"}})
+			c.Assert(err, IsNil)
+			c.Assert(res.Successful, DeepEquals, []DeleteMessageBatchResultEntry{{Id: "0"}})
+			c.Assert(res.Failed, DeepEquals, []BatchResultErrorEntry{{Id: "1", Code: "ReceiptHandleIsInvalid", Message: "The receipt handle is not valid.", SenderFault: true}})
+		},
+	}, {
+		"DeleteQueue", func(c *C) {
+			c.Assert(q.DeleteQueue(), IsNil)
+		},
+	}, {
+		"GetQueueAttributes", func(c *C) {
+			attrs, err := q.GetQueueAttributes(ApproximateNumberOfMessages, VisibilityTimeout)
+			c.Assert(err, IsNil)
+			c.Assert(attrs.Attributes, HasLen, 2)
+			c.Assert(attrs.Attributes[0].Name, Equals, "ApproximateNumberOfMessages")
+			c.Assert(attrs.Attributes[0].Value, Equals, "7")
+		},
+	}, {
+		"GetQueueUrl", func(c *C) {
+			u, err := sqs.GetQueueUrl("jobs", &GetQueueUrlOpt{QueueOwnerAWSAccountId: "210987654321"})
+			c.Assert(err, IsNil)
+			c.Assert(u, Equals, ws.URL+"/210987654321/jobs")
+		},
+	}, {
+		"ListDeadLetterSourceQueues", func(c *C) {
+			queues, err := q.ListDeadLetterSourceQueues()
+			c.Assert(err, IsNil)
+			c.Assert(queues, HasLen, 1)
+			c.Assert(queues[0].Name(), Equals, "jobs")
+		},
+	}, {
+		"ListQueueTags", func(c *C) {
+			tags, err := q.ListQueueTags()
+			c.Assert(err, IsNil)
+			c.Assert(tags, DeepEquals, map[string]string{"team": "ops", "env": "prod"})
+		},
+	}, {
+		"ListQueues", func(c *C) {
+			queues, err := sqs.ListQueues("jobs")
+			c.Assert(err, IsNil)
+			c.Assert(queues, HasLen, 2)
+			c.Assert(queues[1].Name(), Equals, "jobs-dead")
+		},
+	}, {
+		"ListMessageMoveTasks", func(c *C) {
+			tasks, err := q.ListMessageMoveTasks(10)
+			c.Assert(err, IsNil)
+			c.Assert(tasks, HasLen, 2)
+			c.Assert(tasks[0].TaskHandle, Equals, "task-1")
+			c.Assert(tasks[0].Status, Equals, MoveTaskRunning)
+			c.Assert(tasks[0].ApproximateNumberOfMessagesToMove, Equals, int64(100))
+			c.Assert(tasks[0].Started().Unix(), Equals, int64(1700000000))
+			c.Assert(tasks[1].Status, Equals, MoveTaskFailed)
+			c.Assert(tasks[1].DestinationArn, Equals, "arn:aws:sqs:us-east-1:123456789012:gone")
+			c.Assert(tasks[1].FailureReason, Equals, "AWS.SimpleQueueService.NonExistentQueue")
+		},
+	}, {
+		"PurgeQueue", func(c *C) {
+			c.Assert(q.PurgeQueue(), IsNil)
+		},
+	}, {
+		"ReceiveMessage", func(c *C) {
+			msgs, err := q.ReceiveMessages(&ReceiveMessageOpt{
+				MaxNumberOfMessages:   10,
+				VisibilityTimeout:     30,
+				WaitTimeSeconds:       1,
+				MessageAttributeNames: []string{"kind"},
+				AttributeNames:        []Attribute{ApproximateReceiveCount},
+			})
+			c.Assert(err, IsNil)
+			c.Assert(msgs, HasLen, 1)
+			c.Assert(msgs[0].Body, Equals, "hello")
+			c.Assert(msgs[0].ReceiptHandle, Equals, "rh-1")
+			c.Assert(msgs[0].SystemAttributes.ApproximateReceiveCount, Equals, 2)
+			c.Assert(msgs[0].MessageAttributes["kind"].StringValue, Equals, "greeting")
+		},
+	}, {
+		"SendMessage", func(c *C) {
+			id, err := q.SendMessageWithOpt("hello", &SendMessageOpt{
+				DelaySeconds: 10,
+				MessageAttributes: MessageAttributes{
+					"kind": {DataType: "String", StringValue: "greeting"},
+					"blob": {DataType: "Binary", BinaryValue: []byte{1, 2}},
+				},
+				AWSTraceHeader: "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1",
+			})
+			c.Assert(err, IsNil)
+			c.Assert(id, Equals, "id-2")
+		},
+	}, {
+		"SendMessageBatch", func(c *C) {
+			res, err := q.SendMessageBatch([]SendMessageBatchEntry{
+				{Id: "a", Body: "hello", DelaySeconds: 5, AWSTraceHeader: "Root=1-5759e988-bd862e3fe1be46a994272793"},
+				{Id: "b", Body: "bad", MessageAttributes: MessageAttributes{"kind": {DataType: "String", StringValue: "greeting"}}},
+			})
+			c.Assert(err, IsNil)
+			c.Assert(res.Successful, HasLen, 1)
+			c.Assert(res.Successful[0].MessageId, Equals, "id-3")
+			c.Assert(res.Failed, HasLen, 1)
+			c.Assert(res.Failed[0].Code, Equals, "InvalidMessageContents")
+		},
+	}, {
+		"SetQueueAttributes", func(c *C) {
+			c.Assert(q.SetQueueAttributes(map[Attribute]string{VisibilityTimeout: "60", DelaySeconds: "0"}), IsNil)
+		},
+	}, {
+		"StartMessageMoveTask", func(c *C) {
+			dst := &Queue{SQS: sqs, path: "/123456789012/jobs-retry"}
+			handle, err := q.StartMessageMoveTask(&MessageMoveTaskOpt{Destination: dst, MaxNumberOfMessagesPerSecond: 50})
+			c.Assert(err, IsNil)
+			c.Assert(handle, Equals, "task-1")
+		},
+	}, {
+		"TagQueue", func(c *C) {
+			c.Assert(q.TagQueue(map[string]string{"team": "ops", "env": "prod"}), IsNil)
+		},
+	}, {
+		"UntagQueue", func(c *C) {
+			c.Assert(q.UntagQueue("team", "env"), IsNil)
+		},
+	}}
+
+	for _, tc := range cases {
+		tc.call(c)
+		got := ws.request(tc.action)
+		c.Assert(got, Not(Equals), "", Commentf("%s sent no request", tc.action))
+		golden := filepath.Join("testdata", "wire", tc.action+".txt")
+		if *wireRecord {
+			c.Assert(os.MkdirAll(filepath.Dir(golden), 0755), IsNil)
+			c.Assert(ioutil.WriteFile(golden, []byte(got), 0644), IsNil)
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		c.Assert(err, IsNil)
+		c.Check(got, Equals, string(want), Commentf("%s: run with -wire.record if the change is intended", tc.action))
+	}
+}